@@ -97,19 +97,28 @@ func (d *Document) ArgumentsAreEqual(left, right int) bool {
 		d.ValuesAreEqual(d.ArgumentValue(left), d.ArgumentValue(right))
 }
 
+// ArgumentSetsAreEquals compares two sets of arguments regardless of the order of the arguments
 func (d *Document) ArgumentSetsAreEquals(left, right []int) bool {
 	if len(left) != len(right) {
 		return false
 	}
 	for i := 0; i < len(left); i++ {
-		leftArgument, rightArgument := left[i], right[i]
-		if !d.ArgumentsAreEqual(leftArgument, rightArgument) {
+		if !d.argumentSetContainsArgument(right, left[i]) {
 			return false
 		}
 	}
 	return true
 }
 
+func (d *Document) argumentSetContainsArgument(set []int, argument int) bool {
+	for i := range set {
+		if d.ArgumentsAreEqual(set[i], argument) {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *Document) ArgumentsBefore(ancestor Node, argument int) []int {
 	switch ancestor.Kind {
 	case NodeKindField:
@@ -58,6 +58,17 @@ func (d *Document) FieldNameString(ref int) string {
 	return string(d.Input.ByteSlice(d.Fields[ref].Name))
 }
 
+// FieldTypeName returns the name of the named type of the field, e.g. "Character" for a field of type [Character!]!
+// enclosingType is the type definition node in the definition containing the field definition.
+// It returns nil if the enclosing type doesn't define the field, e.g. for __typename.
+func (d *Document) FieldTypeName(fieldRef int, enclosingType Node, definition *Document) ByteSlice {
+	fieldDefinition, exists := definition.NodeFieldDefinitionByName(enclosingType, d.FieldNameBytes(fieldRef))
+	if !exists {
+		return nil
+	}
+	return definition.ResolveTypeNameBytes(definition.FieldDefinitionType(fieldDefinition))
+}
+
 func (d *Document) AddField(field Field) Node {
 	d.Fields = append(d.Fields, field)
 	return Node{
@@ -128,3 +139,20 @@ func (d *Document) FieldsAreEqualFlat(left, right int) bool {
 		d.ArgumentSetsAreEquals(d.FieldArguments(left), d.FieldArguments(right)) && // arguments
 		d.DirectiveSetsAreEqual(d.FieldDirectives(left), d.FieldDirectives(right)) // directives
 }
+
+// FieldsAreEqual compares two fields including their sub selections, see SelectionSetsAreEqual
+func (d *Document) FieldsAreEqual(left, right int) bool {
+	if !bytes.Equal(d.FieldNameBytes(left), d.FieldNameBytes(right)) || // name
+		!bytes.Equal(d.FieldAliasBytes(left), d.FieldAliasBytes(right)) || // alias
+		!d.ArgumentSetsAreEquals(d.FieldArguments(left), d.FieldArguments(right)) || // arguments
+		!d.DirectiveSetsAreEqual(d.FieldDirectives(left), d.FieldDirectives(right)) { // directives
+		return false
+	}
+	if d.FieldHasSelections(left) != d.FieldHasSelections(right) {
+		return false
+	}
+	if !d.FieldHasSelections(left) {
+		return true
+	}
+	return d.SelectionSetsAreEqual(d.Fields[left].SelectionSet, d.Fields[right].SelectionSet) // selections
+}
@@ -25,6 +25,11 @@ func (d *Document) FieldAliasOrNameBytes(ref int) ByteSlice {
 	return d.FieldNameBytes(ref)
 }
 
+// FieldResponseKey returns the key of the field in the response, which is the alias if defined, otherwise the field name
+func (d *Document) FieldResponseKey(fieldRef int) ByteSlice {
+	return d.FieldAliasOrNameBytes(fieldRef)
+}
+
 func (d *Document) FieldAliasOrNameString(ref int) string {
 	return unsafebytes.BytesToString(d.FieldAliasOrNameBytes(ref))
 }
@@ -41,6 +46,12 @@ func (d *Document) FieldAliasIsDefined(ref int) bool {
 	return d.Fields[ref].Alias.IsDefined
 }
 
+// SetFieldAlias sets the alias of a field or replaces an existing one, the alias is appended to the input of the document
+func (d *Document) SetFieldAlias(ref int, alias []byte) {
+	d.Fields[ref].Alias.IsDefined = true
+	d.Fields[ref].Alias.Name = d.Input.AppendInputBytes(alias)
+}
+
 func (d *Document) RemoveFieldAlias(ref int) {
 	d.Fields[ref].Alias.IsDefined = false
 	d.Fields[ref].Alias.Name.Start = 0
@@ -0,0 +1,46 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jensneuse/graphql-go-tools/internal/pkg/unsafeparser"
+	"github.com/jensneuse/graphql-go-tools/pkg/astprinter"
+)
+
+func TestDocument_FieldResponseKey(t *testing.T) {
+	doc := unsafeparser.ParseGraphqlDocumentString(`
+		query {
+			me: user
+			name
+			__typename
+			typeName: __typename
+		}`)
+
+	selectionSet := doc.OperationDefinitions[0].SelectionSet
+	responseKeys := make([]string, 0, 4)
+	for _, selectionRef := range doc.SelectionSets[selectionSet].SelectionRefs {
+		responseKeys = append(responseKeys, string(doc.FieldResponseKey(doc.Selections[selectionRef].Ref)))
+	}
+
+	assert.Equal(t, []string{"me", "name", "__typename", "typeName"}, responseKeys)
+}
+
+func TestDocument_SetFieldAlias(t *testing.T) {
+	run := func(operation, alias, expectedOperation string) func(t *testing.T) {
+		return func(t *testing.T) {
+			doc := unsafeparser.ParseGraphqlDocumentString(operation)
+			doc.SetFieldAlias(0, []byte(alias))
+
+			assert.Equal(t, alias, doc.FieldAliasString(0))
+			assert.Equal(t, "name", doc.FieldNameString(0))
+			out, err := astprinter.PrintString(&doc, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOperation, out)
+		}
+	}
+
+	t.Run("add alias", run("{name id}", "userName", "{userName: name id}"))
+	t.Run("replace alias", run("{oldName: name id}", "newName", "{newName: name id}"))
+}
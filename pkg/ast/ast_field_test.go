@@ -0,0 +1,47 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jensneuse/graphql-go-tools/internal/pkg/unsafeparser"
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+)
+
+func TestDocument_FieldTypeName(t *testing.T) {
+	definition := unsafeparser.ParseGraphqlDocumentString(`
+		type Query {
+			name: String!
+			hero: Character
+			friends: [Character!]!
+		}
+		type Character {
+			name: String
+		}`)
+	operation := unsafeparser.ParseGraphqlDocumentString(`{name hero {name} friends {name} __typename}`)
+
+	query, exists := definition.Index.FirstNodeByNameStr("Query")
+	require.True(t, exists)
+
+	fieldRef := func(index int) int {
+		set := operation.OperationDefinitions[0].SelectionSet
+		selection := operation.Selections[operation.SelectionSets[set].SelectionRefs[index]]
+		require.Equal(t, ast.SelectionKindField, selection.Kind)
+		return selection.Ref
+	}
+
+	t.Run("scalar", func(t *testing.T) {
+		assert.Equal(t, "String", string(operation.FieldTypeName(fieldRef(0), query, &definition)))
+	})
+	t.Run("object", func(t *testing.T) {
+		assert.Equal(t, "Character", string(operation.FieldTypeName(fieldRef(1), query, &definition)))
+	})
+	t.Run("list", func(t *testing.T) {
+		assert.Equal(t, "Character", string(operation.FieldTypeName(fieldRef(2), query, &definition)))
+	})
+	t.Run("undefined field", func(t *testing.T) {
+		assert.Nil(t, operation.FieldTypeName(fieldRef(3), query, &definition))
+	})
+}
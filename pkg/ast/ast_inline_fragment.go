@@ -65,7 +65,63 @@ func (d *Document) InlineFragmentSelections(ref int) []int {
 	return d.SelectionSets[d.InlineFragments[ref].SelectionSet].SelectionRefs
 }
 
+// InlineFragmentTypeConditionNames returns the type condition names of the inline fragments directly inside the selection set
+// Inline fragments without a type condition are skipped.
+func (d *Document) InlineFragmentTypeConditionNames(set int) (names [][]byte) {
+	d.EachSelection(set, func(kind SelectionKind, ref int) bool {
+		if kind == SelectionKindInlineFragment && d.InlineFragmentHasTypeCondition(ref) {
+			names = append(names, d.InlineFragmentTypeConditionName(ref))
+		}
+		return true
+	})
+	return names
+}
+
+// FindInlineFragmentParentSelectionSet returns the selection set containing the inline fragment
+// The selection sets get scanned, so it should only be used outside of a walk where the parent isn't known
+func (d *Document) FindInlineFragmentParentSelectionSet(inlineFragmentRef int) (setRef int, ok bool) {
+	for setRef = range d.SelectionSets {
+		for _, selectionRef := range d.SelectionSets[setRef].SelectionRefs {
+			if d.Selections[selectionRef].Kind == SelectionKindInlineFragment &&
+				d.Selections[selectionRef].Ref == inlineFragmentRef {
+				return setRef, true
+			}
+		}
+	}
+	return -1, false
+}
+
 func (d *Document) AddInlineFragment(fragment InlineFragment) int {
 	d.InlineFragments = append(d.InlineFragments, fragment)
 	return len(d.InlineFragments) - 1
 }
+
+// WrapSelectionSetInInlineFragment adds an inline fragment with the type condition typeName
+// which takes over the given selection set, e.g. { name } results in ... on Dog { name }.
+// It returns the ref of the new inline fragment, adding it to a parent selection set is up to the caller.
+func (d *Document) WrapSelectionSetInInlineFragment(selectionSetRef int, typeName []byte) int {
+	return d.AddInlineFragment(InlineFragment{
+		TypeCondition: TypeCondition{
+			Type: d.AddNamedType(typeName),
+		},
+		SelectionSet:  selectionSetRef,
+		HasSelections: true,
+	})
+}
+
+// AddConcreteInlineFragments adds an inline fragment for each of the concreteTypeNames to the parentSet.
+// Each inline fragment gets its own copy of the selections of the sourceSet, e.g.
+// for the concrete types Dog and Cat the selection set { name } results in ... on Dog { name } ... on Cat { name }.
+// parentSet and sourceSet may be the same selection set, all copies are made before the fragments are added.
+func (d *Document) AddConcreteInlineFragments(parentSet, sourceSet int, concreteTypeNames []string) {
+	inlineFragmentRefs := make([]int, 0, len(concreteTypeNames))
+	for _, typeName := range concreteTypeNames {
+		inlineFragmentRefs = append(inlineFragmentRefs, d.WrapSelectionSetInInlineFragment(d.CopySelectionSet(sourceSet), unsafebytes.StringToBytes(typeName)))
+	}
+	for _, ref := range inlineFragmentRefs {
+		d.AddSelection(parentSet, Selection{
+			Kind: SelectionKindInlineFragment,
+			Ref:  ref,
+		})
+	}
+}
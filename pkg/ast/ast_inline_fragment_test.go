@@ -0,0 +1,169 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jensneuse/graphql-go-tools/internal/pkg/unsafeparser"
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/astprinter"
+)
+
+// firstFieldSelectionSet returns the selection set of the first root field of the first operation
+func firstFieldSelectionSet(t *testing.T, doc *ast.Document) int {
+	rootSet := doc.OperationDefinitions[0].SelectionSet
+	selection := doc.Selections[doc.SelectionSets[rootSet].SelectionRefs[0]]
+	require.Equal(t, ast.SelectionKindField, selection.Kind)
+	return doc.Fields[selection.Ref].SelectionSet
+}
+
+func TestDocument_AddConcreteInlineFragments(t *testing.T) {
+	t.Run("add fragments to the source set", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString(`{pet {name}}`)
+		set := firstFieldSelectionSet(t, &doc)
+
+		doc.AddConcreteInlineFragments(set, set, []string{"Dog", "Cat"})
+
+		out, err := astprinter.PrintString(&doc, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `{pet {name ... on Dog {name} ... on Cat {name}}}`, out)
+	})
+	t.Run("add fragments to another set", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString(`{pet {name age}}`)
+		set := firstFieldSelectionSet(t, &doc)
+		source := doc.CopySelectionSet(set)
+		doc.EmptySelectionSet(set)
+
+		doc.AddConcreteInlineFragments(set, source, []string{"Dog", "Cat"})
+
+		out, err := astprinter.PrintString(&doc, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `{pet {... on Dog {name age} ... on Cat {name age}}}`, out)
+	})
+	t.Run("copies are independent", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString(`{pet {name}}`)
+		set := firstFieldSelectionSet(t, &doc)
+		source := doc.CopySelectionSet(set)
+		doc.EmptySelectionSet(set)
+
+		doc.AddConcreteInlineFragments(set, source, []string{"Dog", "Cat"})
+		dogFragment := doc.Selections[doc.SelectionSets[set].SelectionRefs[0]].Ref
+		doc.EmptySelectionSet(doc.InlineFragments[dogFragment].SelectionSet)
+
+		out, err := astprinter.PrintString(&doc, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `{pet {... on Dog {} ... on Cat {name}}}`, out)
+	})
+	t.Run("no concrete types", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString(`{pet {name}}`)
+		set := firstFieldSelectionSet(t, &doc)
+
+		doc.AddConcreteInlineFragments(set, set, nil)
+
+		out, err := astprinter.PrintString(&doc, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `{pet {name}}`, out)
+	})
+}
+
+func TestDocument_FindInlineFragmentParentSelectionSet(t *testing.T) {
+	doc := unsafeparser.ParseGraphqlDocumentString(`
+		{
+			pet {
+				... on Dog {
+					name
+					... on Puppy {
+						age
+						... on Toy { color }
+					}
+				}
+			}
+			... on Query { hello }
+		}`)
+
+	inlineFragmentByTypeName := func(typeName string) int {
+		for i := range doc.InlineFragments {
+			if doc.InlineFragmentTypeConditionNameString(i) == typeName {
+				return i
+			}
+		}
+		t.Fatalf("inline fragment on %s not found", typeName)
+		return -1
+	}
+
+	run := func(typeName string, expectedSet int) func(t *testing.T) {
+		return func(t *testing.T) {
+			set, ok := doc.FindInlineFragmentParentSelectionSet(inlineFragmentByTypeName(typeName))
+			assert.True(t, ok)
+			assert.Equal(t, expectedSet, set)
+		}
+	}
+
+	t.Run("operation selection set", run("Query", doc.OperationDefinitions[0].SelectionSet))
+	t.Run("field selection set", run("Dog", firstFieldSelectionSet(t, &doc)))
+	t.Run("nested in inline fragment", run("Puppy", doc.InlineFragments[inlineFragmentByTypeName("Dog")].SelectionSet))
+	t.Run("nested in multiple inline fragments", run("Toy", doc.InlineFragments[inlineFragmentByTypeName("Puppy")].SelectionSet))
+	t.Run("not existing inline fragment", func(t *testing.T) {
+		set, ok := doc.FindInlineFragmentParentSelectionSet(len(doc.InlineFragments))
+		assert.False(t, ok)
+		assert.Equal(t, -1, set)
+	})
+}
+
+func TestDocument_WrapSelectionSetInInlineFragment(t *testing.T) {
+	t.Run("wrap field selection set", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString(`{pet {name age}}`)
+		set := firstFieldSelectionSet(t, &doc)
+		source := doc.CopySelectionSet(set)
+		doc.EmptySelectionSet(set)
+
+		ref := doc.WrapSelectionSetInInlineFragment(source, []byte("Dog"))
+		doc.AddSelection(set, ast.Selection{
+			Kind: ast.SelectionKindInlineFragment,
+			Ref:  ref,
+		})
+
+		assert.True(t, doc.InlineFragmentHasTypeCondition(ref))
+		assert.Equal(t, "Dog", doc.InlineFragmentTypeConditionNameString(ref))
+		assert.Equal(t, source, doc.InlineFragments[ref].SelectionSet)
+
+		out, err := astprinter.PrintString(&doc, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `{pet {... on Dog {name age}}}`, out)
+	})
+	t.Run("wrap new selection set", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString(`{pet {name}}`)
+		set := firstFieldSelectionSet(t, &doc)
+		wrapped := doc.AddSelectionSet()
+		doc.AddSelection(wrapped.Ref, ast.Selection{
+			Kind: ast.SelectionKindField,
+			Ref:  doc.AddField(ast.Field{Name: doc.Input.AppendInputString("barkVolume")}).Ref,
+		})
+
+		ref := doc.WrapSelectionSetInInlineFragment(wrapped.Ref, []byte("Dog"))
+		doc.AddSelection(set, ast.Selection{
+			Kind: ast.SelectionKindInlineFragment,
+			Ref:  ref,
+		})
+
+		out, err := astprinter.PrintString(&doc, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `{pet {name ... on Dog {barkVolume}}}`, out)
+	})
+}
+
+func TestDocument_InlineFragmentTypeConditionNames(t *testing.T) {
+	run := func(operation string, expected [][]byte) func(t *testing.T) {
+		return func(t *testing.T) {
+			doc := unsafeparser.ParseGraphqlDocumentString(operation)
+			set := firstFieldSelectionSet(t, &doc)
+			assert.Equal(t, expected, doc.InlineFragmentTypeConditionNames(set))
+		}
+	}
+
+	t.Run("multiple inline fragments", run(`{pet {... on Dog {name} name ... on Cat {name}}}`, [][]byte{[]byte("Dog"), []byte("Cat")}))
+	t.Run("inline fragment without type condition", run(`{pet {... @include(if: true) {name} ... on Dog {name}}}`, [][]byte{[]byte("Dog")}))
+	t.Run("no inline fragments", run(`{pet {name ...PetFields}}`, nil))
+}
@@ -102,6 +102,36 @@ func (d *Document) NumOfOperationDefinitions() (n int) {
 	return
 }
 
+// OperationContainsFragmentSpreads returns true if the operation contains at least one fragment spread
+// on any level, including spreads nested inside of inline fragments
+func (d *Document) OperationContainsFragmentSpreads(operationRef int) bool {
+	if !d.OperationDefinitions[operationRef].HasSelections {
+		return false
+	}
+	return d.selectionSetContainsFragmentSpreads(d.OperationDefinitions[operationRef].SelectionSet)
+}
+
+func (d *Document) selectionSetContainsFragmentSpreads(set int) bool {
+	for _, selectionRef := range d.SelectionSets[set].SelectionRefs {
+		selection := d.Selections[selectionRef]
+		switch selection.Kind {
+		case SelectionKindFragmentSpread:
+			return true
+		case SelectionKindField:
+			if d.Fields[selection.Ref].HasSelections &&
+				d.selectionSetContainsFragmentSpreads(d.Fields[selection.Ref].SelectionSet) {
+				return true
+			}
+		case SelectionKindInlineFragment:
+			if d.InlineFragments[selection.Ref].HasSelections &&
+				d.selectionSetContainsFragmentSpreads(d.InlineFragments[selection.Ref].SelectionSet) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 const (
 	alphabet = `abcdefghijklmnopqrstuvwxyz`
 )
@@ -47,3 +47,38 @@ func TestDocument_OperationNameExists(t *testing.T) {
 		true,
 	))
 }
+
+func TestDocument_OperationContainsFragmentSpreads(t *testing.T) {
+	run := func(operation string, expectedContains bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			doc := unsafeparser.ParseGraphqlDocumentString(operation)
+			contains := doc.OperationContainsFragmentSpreads(doc.RootNodes[0].Ref)
+			assert.Equal(t, expectedContains, contains)
+		}
+	}
+
+	t.Run("operation without fragment spreads", run(
+		"query MyOperation { user { id ... on User { name } } }",
+		false,
+	))
+
+	t.Run("operation with fragment spread on root level", run(
+		"query MyOperation { ...QueryFields } fragment QueryFields on Query { user { id } }",
+		true,
+	))
+
+	t.Run("operation with fragment spread in nested field", run(
+		"query MyOperation { user { id friends { ...UserFields } } } fragment UserFields on User { name }",
+		true,
+	))
+
+	t.Run("operation with fragment spread nested in inline fragment", run(
+		"query MyOperation { node { ... on User { ...UserFields } } } fragment UserFields on User { name }",
+		true,
+	))
+
+	t.Run("only fragment definition contains fragment spreads", run(
+		"query MyOperation { user { id } } fragment UserFields on User { friends { ...FriendFields } }",
+		false,
+	))
+}
@@ -139,6 +139,40 @@ func (d *Document) AddSelection(set int, selection Selection) {
 	d.SelectionSets[set].SelectionRefs = append(d.SelectionSets[set].SelectionRefs, d.AddSelectionToDocument(selection))
 }
 
+// AddFieldToSelectionSet appends a field without arguments and selections to the selection set and returns its ref
+// An alias is only set if it's not empty. If the selection set already selects the field with the same alias
+// and without arguments, e.g. __typename, no field is added and the ref of the existing field is returned.
+func (d *Document) AddFieldToSelectionSet(set int, fieldName, alias []byte) int {
+	for _, selection := range d.SelectionSets[set].SelectionRefs {
+		if d.Selections[selection].Kind != SelectionKindField {
+			continue
+		}
+		field := d.Selections[selection].Ref
+		if d.FieldHasArguments(field) || d.FieldAliasIsDefined(field) != (len(alias) != 0) {
+			continue
+		}
+		if bytes.Equal(d.FieldNameBytes(field), fieldName) && bytes.Equal(d.FieldAliasBytes(field), alias) {
+			return field
+		}
+	}
+
+	field := Field{
+		Name: d.Input.AppendInputBytes(fieldName),
+	}
+	if len(alias) != 0 {
+		field.Alias = Alias{
+			IsDefined: true,
+			Name:      d.Input.AppendInputBytes(alias),
+		}
+	}
+	addedField := d.AddField(field)
+	d.AddSelection(set, Selection{
+		Kind: SelectionKindField,
+		Ref:  addedField.Ref,
+	})
+	return addedField.Ref
+}
+
 func (d *Document) EmptySelectionSet(ref int) {
 	d.SelectionSets[ref].SelectionRefs = d.SelectionSets[ref].SelectionRefs[:0]
 }
@@ -155,6 +189,121 @@ func (d *Document) RemoveFromSelectionSet(ref int, index int) {
 	d.SelectionSets[ref].SelectionRefs = append(d.SelectionSets[ref].SelectionRefs[:index], d.SelectionSets[ref].SelectionRefs[index+1:]...)
 }
 
+// MergeInlineFragmentSiblings appends the selections of the inline fragment selection right to the inline fragment selection left
+// The selection right is removed from the selection set, left and right are refs of selections of the selection set.
+// Left gets a new selection set holding the selections of both, as its selection set can be shared,
+// e.g. with the fragment definition and other spreads of it if the fragment spread was replaced by an inline fragment.
+func (d *Document) MergeInlineFragmentSiblings(set, left, right int) {
+	leftSet := d.InlineFragments[d.Selections[left].Ref].SelectionSet
+	rightSet := d.InlineFragments[d.Selections[right].Ref].SelectionSet
+	refs := d.NewEmptyRefs()
+	refs = append(refs, d.SelectionSets[leftSet].SelectionRefs...)
+	refs = append(refs, d.SelectionSets[rightSet].SelectionRefs...)
+	d.InlineFragments[d.Selections[left].Ref].SelectionSet = d.AddSelectionSetToDocument(SelectionSet{
+		SelectionRefs: refs,
+	})
+	for i, selection := range d.SelectionSets[set].SelectionRefs {
+		if selection == right {
+			d.RemoveFromSelectionSet(set, i)
+			return
+		}
+	}
+}
+
+// RemoveFieldSelectionByName removes the first field selection with the given field name from the selection set.
+// It returns true if a selection was removed.
+func (d *Document) RemoveFieldSelectionByName(set int, name []byte) bool {
+	for i, j := range d.SelectionSets[set].SelectionRefs {
+		if d.Selections[j].Kind != SelectionKindField {
+			continue
+		}
+		if bytes.Equal(d.FieldNameBytes(d.Selections[j].Ref), name) {
+			d.RemoveFromSelectionSet(set, i)
+			return true
+		}
+	}
+	return false
+}
+
+// SelectionSetsAreEqual compares two selection sets recursively
+// The selections are compared in order as the order of the selections determines the order of the response,
+// arguments are compared regardless of their order.
+func (d *Document) SelectionSetsAreEqual(left, right int) bool {
+	leftSelections, rightSelections := d.SelectionSets[left].SelectionRefs, d.SelectionSets[right].SelectionRefs
+	if len(leftSelections) != len(rightSelections) {
+		return false
+	}
+	for i := 0; i < len(leftSelections); i++ {
+		if !d.SelectionsAreEqual(leftSelections[i], rightSelections[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectionsAreEqual compares two selections, fields and inline fragments including their sub selections
+func (d *Document) SelectionsAreEqual(left, right int) bool {
+	if d.Selections[left].Kind != d.Selections[right].Kind {
+		return false
+	}
+	leftRef, rightRef := d.Selections[left].Ref, d.Selections[right].Ref
+	switch d.Selections[left].Kind {
+	case SelectionKindField:
+		return d.FieldsAreEqual(leftRef, rightRef)
+	case SelectionKindFragmentSpread:
+		return bytes.Equal(d.FragmentSpreadNameBytes(leftRef), d.FragmentSpreadNameBytes(rightRef)) &&
+			d.DirectiveSetsAreEqual(d.FragmentSpreads[leftRef].Directives.Refs, d.FragmentSpreads[rightRef].Directives.Refs)
+	case SelectionKindInlineFragment:
+		if !bytes.Equal(d.InlineFragmentTypeConditionName(leftRef), d.InlineFragmentTypeConditionName(rightRef)) ||
+			!d.DirectiveSetsAreEqual(d.InlineFragments[leftRef].Directives.Refs, d.InlineFragments[rightRef].Directives.Refs) {
+			return false
+		}
+		if d.InlineFragments[leftRef].HasSelections != d.InlineFragments[rightRef].HasSelections {
+			return false
+		}
+		if !d.InlineFragments[leftRef].HasSelections {
+			return true
+		}
+		return d.SelectionSetsAreEqual(d.InlineFragments[leftRef].SelectionSet, d.InlineFragments[rightRef].SelectionSet)
+	default:
+		return false
+	}
+}
+
+// SelectionSetSelectionCount returns the number of direct selections of the selection set.
+// Selections nested in fields or inline fragments of the selection set are not counted.
+func (d *Document) SelectionSetSelectionCount(ref int) int {
+	return len(d.SelectionSets[ref].SelectionRefs)
+}
+
+// SelectionKindAtIndex returns the kind of the selection at the given index of the selection set.
+func (d *Document) SelectionKindAtIndex(set int, index int) SelectionKind {
+	return d.Selections[d.SelectionSets[set].SelectionRefs[index]].Kind
+}
+
+// EachSelection calls fn for each direct selection of the selection set in order
+// with the kind of the selection and the ref of the field, fragment spread or inline fragment.
+// Iteration stops as soon as fn returns false.
+func (d *Document) EachSelection(set int, fn func(kind SelectionKind, ref int) bool) {
+	for _, i := range d.SelectionSets[set].SelectionRefs {
+		if !fn(d.Selections[i].Kind, d.Selections[i].Ref) {
+			return
+		}
+	}
+}
+
+// FieldSelectionRefs returns the refs of the fields directly selected by the selection set in order.
+// Fields selected by fragment spreads or inline fragments of the selection set are not included.
+func (d *Document) FieldSelectionRefs(set int) (refs []int) {
+	d.EachSelection(set, func(kind SelectionKind, ref int) bool {
+		if kind == SelectionKindField {
+			refs = append(refs, ref)
+		}
+		return true
+	})
+	return refs
+}
+
 func (d *Document) SelectionSetHasFieldSelectionWithNameOrAliasBytes(set int, nameOrAlias []byte) bool {
 	for _, i := range d.SelectionSets[set].SelectionRefs {
 		if d.Selections[i].Kind != SelectionKindField {
@@ -0,0 +1,264 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jensneuse/graphql-go-tools/internal/pkg/unsafeparser"
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/astprinter"
+)
+
+func TestDocument_RemoveFieldSelectionByName(t *testing.T) {
+	run := func(operation string, fieldName string, expectedRemoved bool, expectedOperation string) func(t *testing.T) {
+		return func(t *testing.T) {
+			doc := unsafeparser.ParseGraphqlDocumentString(operation)
+			set := doc.OperationDefinitions[0].SelectionSet
+			removed := doc.RemoveFieldSelectionByName(set, []byte(fieldName))
+			assert.Equal(t, expectedRemoved, removed)
+
+			out, err := astprinter.PrintString(&doc, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOperation, out)
+		}
+	}
+
+	t.Run("remove present field", run(
+		"{a b c}",
+		"b",
+		true,
+		"{a c}",
+	))
+	t.Run("absent field", run(
+		"{a b c}",
+		"d",
+		false,
+		"{a b c}",
+	))
+	t.Run("remove only the first of multiple matches", run(
+		"{__typename a __typename}",
+		"__typename",
+		true,
+		"{a __typename}",
+	))
+	t.Run("match on field name not alias", run(
+		"{b: a c: b}",
+		"b",
+		true,
+		"{b: a}",
+	))
+	t.Run("ignore fragments", run(
+		"{... on A {b} b}",
+		"b",
+		true,
+		"{... on A {b}}",
+	))
+}
+
+func TestDocument_SelectionSetSelectionCount(t *testing.T) {
+	run := func(operation string, expectedCount int) func(t *testing.T) {
+		return func(t *testing.T) {
+			doc := unsafeparser.ParseGraphqlDocumentString(operation)
+			set := doc.OperationDefinitions[0].SelectionSet
+			assert.Equal(t, expectedCount, doc.SelectionSetSelectionCount(set))
+		}
+	}
+
+	t.Run("fields", run("{a b c}", 3))
+	t.Run("fields, fragment spreads and inline fragments", run("{a ...B ... on C {c}}", 3))
+	t.Run("nested selections are not counted", run("{a {b c} ... on C {c d}}", 2))
+}
+
+func TestDocument_SelectionKindAtIndex(t *testing.T) {
+	doc := unsafeparser.ParseGraphqlDocumentString("{a ...B ... on C {c}}")
+	set := doc.OperationDefinitions[0].SelectionSet
+
+	assert.Equal(t, ast.SelectionKindField, doc.SelectionKindAtIndex(set, 0))
+	assert.Equal(t, ast.SelectionKindFragmentSpread, doc.SelectionKindAtIndex(set, 1))
+	assert.Equal(t, ast.SelectionKindInlineFragment, doc.SelectionKindAtIndex(set, 2))
+}
+
+func TestDocument_EachSelection(t *testing.T) {
+	type selection struct {
+		kind ast.SelectionKind
+		name string
+	}
+
+	collect := func(doc *ast.Document, set int, stopAfter int) []selection {
+		var selections []selection
+		doc.EachSelection(set, func(kind ast.SelectionKind, ref int) bool {
+			var name string
+			switch kind {
+			case ast.SelectionKindField:
+				name = doc.FieldNameString(ref)
+			case ast.SelectionKindFragmentSpread:
+				name = doc.FragmentSpreadNameString(ref)
+			case ast.SelectionKindInlineFragment:
+				name = doc.InlineFragmentTypeConditionNameString(ref)
+			}
+			selections = append(selections, selection{kind: kind, name: name})
+			return len(selections) < stopAfter
+		})
+		return selections
+	}
+
+	t.Run("selections in order", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString("{a ...B ... on C {c} d}")
+		set := doc.OperationDefinitions[0].SelectionSet
+
+		assert.Equal(t, []selection{
+			{kind: ast.SelectionKindField, name: "a"},
+			{kind: ast.SelectionKindFragmentSpread, name: "B"},
+			{kind: ast.SelectionKindInlineFragment, name: "C"},
+			{kind: ast.SelectionKindField, name: "d"},
+		}, collect(&doc, set, 10))
+	})
+	t.Run("stop when fn returns false", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString("{a ...B ... on C {c} d}")
+		set := doc.OperationDefinitions[0].SelectionSet
+
+		assert.Equal(t, []selection{
+			{kind: ast.SelectionKindField, name: "a"},
+			{kind: ast.SelectionKindFragmentSpread, name: "B"},
+		}, collect(&doc, set, 2))
+	})
+	t.Run("empty selection set", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString("{a}")
+		set := doc.OperationDefinitions[0].SelectionSet
+		doc.EmptySelectionSet(set)
+
+		assert.Nil(t, collect(&doc, set, 10))
+	})
+}
+
+func TestDocument_FieldSelectionRefs(t *testing.T) {
+	run := func(operation string, expectedFieldNames []string) func(t *testing.T) {
+		return func(t *testing.T) {
+			doc := unsafeparser.ParseGraphqlDocumentString(operation)
+			set := doc.OperationDefinitions[0].SelectionSet
+
+			var fieldNames []string
+			for _, ref := range doc.FieldSelectionRefs(set) {
+				fieldNames = append(fieldNames, doc.FieldNameString(ref))
+			}
+			assert.Equal(t, expectedFieldNames, fieldNames)
+		}
+	}
+
+	t.Run("fields", run("{a b c}", []string{"a", "b", "c"}))
+	t.Run("fields mixed with fragment spreads and inline fragments", run("{a ...B ... on C {c} d}", []string{"a", "d"}))
+	t.Run("nested fields are not included", run("{a {b} c}", []string{"a", "c"}))
+	t.Run("fragments only", run("{...B ... on C {c}}", nil))
+}
+
+func TestDocument_SelectionSetsAreEqual(t *testing.T) {
+	run := func(left, right string, expectedEqual bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			doc := unsafeparser.ParseGraphqlDocumentString(left + " " + right)
+			leftSet, rightSet := doc.OperationDefinitions[0].SelectionSet, doc.OperationDefinitions[1].SelectionSet
+			assert.Equal(t, expectedEqual, doc.SelectionSetsAreEqual(leftSet, rightSet))
+			assert.Equal(t, expectedEqual, doc.SelectionSetsAreEqual(rightSet, leftSet))
+		}
+	}
+
+	t.Run("same fields", run(
+		"query a {a b: c}",
+		"query b {a b: c}",
+		true,
+	))
+	t.Run("different alias", run(
+		"query a {a b: c}",
+		"query b {a c}",
+		false,
+	))
+	t.Run("different field order", run(
+		"query a {a b}",
+		"query b {b a}",
+		false,
+	))
+	t.Run("arguments in different order", run(
+		`query a {user(id: 1, name: "a", filter: {active: true})}`,
+		`query b {user(filter: {active: true}, name: "a", id: 1)}`,
+		true,
+	))
+	t.Run("different argument values", run(
+		"query a {user(id: 1)}",
+		"query b {user(id: 2)}",
+		false,
+	))
+	t.Run("missing argument", run(
+		"query a {user(id: 1, name: null)}",
+		"query b {user(id: 1)}",
+		false,
+	))
+	t.Run("different directives", run(
+		"query a {user @skip(if: true)}",
+		"query b {user @include(if: true)}",
+		false,
+	))
+	t.Run("nested sub selections", run(
+		"query a {user(id: 1) {name friends(first: 10) {name ... on Admin {permissions}}}}",
+		"query b {user(id: 1) {name friends(first: 10) {name ... on Admin {permissions}}}}",
+		true,
+	))
+	t.Run("different nested sub selections", run(
+		"query a {user {name friends {name ... on Admin {permissions}}}}",
+		"query b {user {name friends {name ... on Admin {roles}}}}",
+		false,
+	))
+	t.Run("field with and without sub selections", run(
+		"query a {user {name}}",
+		"query b {user}",
+		false,
+	))
+	t.Run("fragment spreads", run(
+		"query a {...userFields ...other}",
+		"query b {...userFields ...other}",
+		true,
+	))
+	t.Run("different fragment spreads", run(
+		"query a {...userFields}",
+		"query b {...adminFields}",
+		false,
+	))
+	t.Run("inline fragments with different type conditions", run(
+		"query a {... on User {name}}",
+		"query b {... on Admin {name}}",
+		false,
+	))
+}
+
+func TestDocument_AddFieldToSelectionSet(t *testing.T) {
+	run := func(operation, fieldName, alias string, expectedOperation string) func(t *testing.T) {
+		return func(t *testing.T) {
+			doc := unsafeparser.ParseGraphqlDocumentString(operation)
+			set := doc.OperationDefinitions[0].SelectionSet
+			field := doc.AddFieldToSelectionSet(set, []byte(fieldName), []byte(alias))
+			assert.Equal(t, fieldName, doc.FieldNameString(field))
+
+			out, err := astprinter.PrintString(&doc, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOperation, out)
+		}
+	}
+
+	t.Run("add field", run("{id}", "__typename", "", "{id __typename}"))
+	t.Run("add aliased field", run("{id}", "id", "key", "{id key: id}"))
+	t.Run("field already selected", run("{__typename id}", "__typename", "", "{__typename id}"))
+	t.Run("aliased field already selected", run("{key: id}", "id", "key", "{key: id}"))
+	t.Run("field selected with another alias", run("{typeName: __typename}", "__typename", "", "{typeName: __typename __typename}"))
+	t.Run("field selected with arguments", run("{id(format: SHORT)}", "id", "", "{id(format: SHORT) id}"))
+
+	t.Run("__typename isn't added twice", func(t *testing.T) {
+		doc := unsafeparser.ParseGraphqlDocumentString("{user {name}}")
+		set := doc.OperationDefinitions[0].SelectionSet
+		first := doc.AddFieldToSelectionSet(set, []byte("__typename"), nil)
+		second := doc.AddFieldToSelectionSet(set, []byte("__typename"), nil)
+		assert.Equal(t, first, second)
+
+		out, err := astprinter.PrintString(&doc, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "{user {name} __typename}", out)
+	})
+}
@@ -31,3 +31,29 @@ func (d *Document) ImportObjectValue(fieldRefs []int) (ref int) {
 		Refs: fieldRefs,
 	})
 }
+
+// ObjectFieldInput describes a field of an object value built by AddInputObjectValue
+type ObjectFieldInput struct {
+	Name  []byte
+	Value Value // the value of the field, ignored if Fields is set
+	// Fields makes the value of the field a nested object value with the given fields
+	Fields []ObjectFieldInput
+}
+
+// AddInputObjectValue adds an object value with the given fields, including nested object values,
+// e.g. to inject an input object as argument or default value of a variable.
+// It returns the ref of the object value.
+func (d *Document) AddInputObjectValue(fields []ObjectFieldInput) (ref int) {
+	refs := d.NewEmptyRefs()
+	for i := range fields {
+		value := fields[i].Value
+		if fields[i].Fields != nil {
+			value = Value{
+				Kind: ValueKindObject,
+				Ref:  d.AddInputObjectValue(fields[i].Fields),
+			}
+		}
+		refs = append(refs, d.ImportObjectField(fields[i].Name, value))
+	}
+	return d.ImportObjectValue(refs)
+}
@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocument_AddInputObjectValue(t *testing.T) {
+	printValue := func(t *testing.T, doc *Document, ref int) string {
+		buf := &bytes.Buffer{}
+		err := doc.PrintValue(Value{Kind: ValueKindObject, Ref: ref}, buf)
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	t.Run("two fields", func(t *testing.T) {
+		doc := NewDocument()
+		ref := doc.AddInputObjectValue([]ObjectFieldInput{
+			{
+				Name:  []byte("lon"),
+				Value: Value{Kind: ValueKindFloat, Ref: doc.ImportFloatValue([]byte("12.43"), false)},
+			},
+			{
+				Name:  []byte("lat"),
+				Value: Value{Kind: ValueKindFloat, Ref: doc.ImportFloatValue([]byte("53.211"), true)},
+			},
+		})
+
+		assert.Equal(t, `{lon: 12.43,lat: -53.211}`, printValue(t, doc, ref))
+		assert.Equal(t, "lon", doc.ObjectFieldNameString(doc.ObjectValues[ref].Refs[0]))
+		assert.Equal(t, "lat", doc.ObjectFieldNameString(doc.ObjectValues[ref].Refs[1]))
+	})
+	t.Run("nested object value", func(t *testing.T) {
+		doc := NewDocument()
+		ref := doc.AddInputObjectValue([]ObjectFieldInput{
+			{
+				Name:  []byte("name"),
+				Value: Value{Kind: ValueKindString, Ref: doc.ImportStringValue([]byte("home"), false)},
+			},
+			{
+				Name: []byte("location"),
+				Fields: []ObjectFieldInput{
+					{
+						Name:  []byte("lon"),
+						Value: Value{Kind: ValueKindFloat, Ref: doc.ImportFloatValue([]byte("12.43"), false)},
+					},
+				},
+			},
+		})
+
+		assert.Equal(t, `{name: "home",location: {lon: 12.43}}`, printValue(t, doc, ref))
+	})
+	t.Run("empty object value", func(t *testing.T) {
+		doc := NewDocument()
+		ref := doc.AddInputObjectValue(nil)
+
+		assert.Equal(t, `{}`, printValue(t, doc, ref))
+	})
+}
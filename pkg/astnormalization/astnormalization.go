@@ -125,10 +125,14 @@ func NewWithOpts(opts ...Option) *OperationNormalizer {
 }
 
 type options struct {
-	removeFragmentDefinitions bool
-	extractVariables          bool
-	removeUnusedVariables     bool
-	normalizeDefinition       bool
+	removeFragmentDefinitions       bool
+	removeUnusedFragmentDefinitions bool
+	extractVariables                bool
+	removeUnusedVariables           bool
+	normalizeDefinition             bool
+	maxSelectionSetSelections       int
+	validateSelections              bool
+	sortSelections                  bool
 }
 
 type Option func(options *options)
@@ -145,6 +149,14 @@ func WithRemoveFragmentDefinitions() Option {
 	}
 }
 
+// WithRemoveUnusedFragmentDefinitions removes the fragment definitions which are no longer referenced after fragment spreads were inlined
+// Other than WithRemoveFragmentDefinitions fragments still referenced by an operation are kept.
+func WithRemoveUnusedFragmentDefinitions() Option {
+	return func(options *options) {
+		options.removeUnusedFragmentDefinitions = true
+	}
+}
+
 func WithRemoveUnusedVariables() Option {
 	return func(options *options) {
 		options.removeUnusedVariables = true
@@ -157,6 +169,31 @@ func WithNormalizeDefinition() Option {
 	}
 }
 
+// WithValidateSelections adds a final pass checking that all selections of the normalized operation are valid on their enclosing type
+// Use it to catch normalization rules producing invalid operations before they get planned.
+func WithValidateSelections() Option {
+	return func(options *options) {
+		options.validateSelections = true
+	}
+}
+
+// WithSortSelections sorts the selections of each selection set into a canonical order
+// Operations only differing in the order of their selections normalize to the same operation, e.g. to share a plan cache key.
+// The keys of the response follow the sorted order, the root fields of mutations keep their order as they're executed serially.
+func WithSortSelections() Option {
+	return func(options *options) {
+		options.sortSelections = true
+	}
+}
+
+// WithMaxSelectionSetSelections aborts the normalization with an error
+// when a selection set has more than maxSelections selections after fragments got expanded
+func WithMaxSelectionSetSelections(maxSelections int) Option {
+	return func(options *options) {
+		options.maxSelectionSetSelections = maxSelections
+	}
+}
+
 func (o *OperationNormalizer) setupOperationWalkers() {
 	fragmentInline := astvisitor.NewWalker(48)
 	fragmentSpreadInline(&fragmentInline)
@@ -171,16 +208,32 @@ func (o *OperationNormalizer) setupOperationWalkers() {
 	inputCoercionForList(&other)
 	removeSelfAliasing(&other)
 	mergeInlineFragments(&other)
+	mergeInlineFragmentSiblings(&other)
 	mergeFieldSelections(&other)
 	deduplicateFields(&other)
 	extractVariablesDefaultValue(&other)
 	if o.options.removeFragmentDefinitions {
 		removeFragmentDefinitions(&other)
 	}
+	if o.options.removeUnusedFragmentDefinitions {
+		removeUnusedFragmentDefinitions(&other)
+	}
+	if o.options.sortSelections {
+		sortSelections(&other)
+	}
 	if o.options.removeUnusedVariables {
 		deleteUnusedVariables(&other)
 	}
+	if o.options.maxSelectionSetSelections > 0 {
+		limitSelectionSetSize(&other, o.options.maxSelectionSetSelections)
+	}
 	o.operationWalkers = append(o.operationWalkers, &fragmentInline, &extractVariablesWalker, &other)
+
+	if o.options.validateSelections {
+		selectionValidation := astvisitor.NewWalker(48)
+		validateSelections(&selectionValidation)
+		o.operationWalkers = append(o.operationWalkers, &selectionValidation)
+	}
 }
 
 func (o *OperationNormalizer) prepareDefinition(definition *ast.Document, report *operationreport.Report) {
@@ -97,6 +97,68 @@ func TestNormalizeOperation(t *testing.T) {
 					disallowedSecondRootField
 				}`, "", "")
 	})
+	t.Run("sibling inline fragments from fragment spreads", func(t *testing.T) {
+		run(t, testDefinition, `
+				query pets {
+					pet {
+						... dogName
+						... dogNickname
+					}
+				}
+				fragment dogName on Pet {
+					... on Dog { name }
+				}
+				fragment dogNickname on Pet {
+					... on Dog { name nickname }
+				}`, `
+				query pets {
+					pet {
+						... on Dog {
+							name
+							nickname
+						}
+					}
+				}`, "", "")
+	})
+	t.Run("duplicate fields with sub selections", func(t *testing.T) {
+		run(t, testDefinition, `
+				query duplicates {
+					dog {
+						name
+						extra { string }
+						name
+						extra { string noString }
+						extra: mustExtra { string }
+					}
+					dog {
+						extra { strings }
+					}
+				}`, `
+				query duplicates {
+					dog {
+						name
+						extra {
+							string
+							noString
+							strings
+						}
+						extra: mustExtra { string }
+					}
+				}`, "", "")
+	})
+	t.Run("fields with the same arguments extracted into different variables", func(t *testing.T) {
+		run(t, testDefinition, `
+				query sameArguments {
+					findDog(complex: {name: "Bello"}) { name }
+					findDog(complex: {name: "Bello"}) { nickname }
+				}`, `
+				query sameArguments($a: ComplexInput) {
+					findDog(complex: $a) {
+						name
+						nickname
+					}
+				}`, "", `{"a":{"name":"Bello"}}`)
+	})
 	t.Run("fragments", func(t *testing.T) {
 		run(t, testDefinition, `
 				query conflictingBecauseAlias ($unused: String) {
@@ -324,6 +386,39 @@ schema {
 			runNormalization(t, false, `fragment Fields on Country {name} query Q {country {name}}`)
 		})
 	})
+
+	t.Run("should sort selections", func(t *testing.T) {
+		normalize := func(query string) string {
+			definition := unsafeparser.ParseGraphqlDocumentString(testDefinition)
+			require.NoError(t, asttransform.MergeDefinitionWithBaseSchema(&definition))
+			operation := unsafeparser.ParseGraphqlDocumentString(query)
+
+			report := operationreport.Report{}
+			normalizer := NewWithOpts(WithSortSelections())
+			normalizer.NormalizeOperation(&operation, &definition, &report)
+			assert.False(t, report.HasErrors())
+			return unsafeprinter.Print(&operation, nil)
+		}
+
+		assert.Equal(t,
+			normalize(`query Q {dog {name ...extras} cat {name}} fragment extras on Dog {extras {string} barkVolume}`),
+			normalize(`query Q {cat {name} dog {...extras name}} fragment extras on Dog {barkVolume extras {string}}`),
+		)
+		assert.Equal(t, `query Q {cat {name} dog {barkVolume extras {string} name}} fragment extras on Dog {barkVolume extras {string}}`,
+			normalize(`query Q {dog {name ...extras} cat {name}} fragment extras on Dog {extras {string} barkVolume}`))
+	})
+
+	t.Run("should remove unused fragment definitions", func(t *testing.T) {
+		definition := unsafeparser.ParseGraphqlDocumentString(schema)
+		operation := unsafeparser.ParseGraphqlDocumentString(query)
+
+		report := operationreport.Report{}
+		normalizer := NewWithOpts(WithExtractVariables(), WithRemoveUnusedFragmentDefinitions())
+		normalizer.NormalizeOperation(&operation, &definition, &report)
+		assert.False(t, report.HasErrors())
+
+		assert.Equal(t, `query Q {country {name}}`, unsafeprinter.Print(&operation, nil))
+	})
 }
 
 func BenchmarkAstNormalization(b *testing.B) {
@@ -60,6 +60,19 @@ func TestMergeFieldSelections(t *testing.T) {
 					}`)
 		})
 	})
+	t.Run("same arguments", func(t *testing.T) {
+		run(mergeFieldSelections, testDefinition, `
+					query sameArguments {
+						findDog(complex: {name: "Bello"}) { name }
+						findDog(complex: {name: "Bello"}) { nickname }
+					}`, `
+					query sameArguments {
+						findDog(complex: {name: "Bello"}) {
+							name
+							nickname
+						}
+					}`)
+	})
 	t.Run("fields with directives", func(t *testing.T) {
 		run(mergeFieldSelections, testDefinition, `
 					{
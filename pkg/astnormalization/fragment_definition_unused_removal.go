@@ -0,0 +1,77 @@
+package astnormalization
+
+import (
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/asttransform"
+	"github.com/jensneuse/graphql-go-tools/pkg/astvisitor"
+)
+
+// removeUnusedFragmentDefinitions deletes fragment definitions which aren't referenced by an operation, neither directly nor through other fragments
+// It must run after fragment spreads were inlined, fragments referenced by inlined spreads only are removed as well.
+func removeUnusedFragmentDefinitions(walker *astvisitor.Walker) {
+	visitor := removeUnusedFragmentDefinitionsVisitor{
+		Walker: walker,
+	}
+	walker.RegisterDocumentVisitor(&visitor)
+	walker.RegisterEnterFragmentSpreadVisitor(&visitor)
+}
+
+type removeUnusedFragmentDefinitionsVisitor struct {
+	*astvisitor.Walker
+	operation   *ast.Document
+	transformer asttransform.Transformer
+	// used are the fragment definitions referenced by operations, later extended by the fragments they reference
+	used []int
+	// references are the fragment definitions referenced by each fragment definition
+	references map[int][]int
+}
+
+func (r *removeUnusedFragmentDefinitionsVisitor) EnterDocument(operation, definition *ast.Document) {
+	r.transformer.Reset()
+	r.operation = operation
+	r.used = r.used[:0]
+	r.references = map[int][]int{}
+}
+
+func (r *removeUnusedFragmentDefinitionsVisitor) EnterFragmentSpread(ref int) {
+	fragmentDefinition, exists := r.operation.FragmentDefinitionRef(r.operation.FragmentSpreadNameBytes(ref))
+	if !exists {
+		return
+	}
+
+	root := r.Ancestors[0]
+	switch root.Kind {
+	case ast.NodeKindOperationDefinition:
+		r.used = append(r.used, fragmentDefinition)
+	case ast.NodeKindFragmentDefinition:
+		r.references[root.Ref] = append(r.references[root.Ref], fragmentDefinition)
+	}
+}
+
+func (r *removeUnusedFragmentDefinitionsVisitor) isUsed(fragmentDefinition int) bool {
+	for i := range r.used {
+		if r.used[i] == fragmentDefinition {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *removeUnusedFragmentDefinitionsVisitor) LeaveDocument(operation, definition *ast.Document) {
+	for i := 0; i < len(r.used); i++ {
+		for _, reference := range r.references[r.used[i]] {
+			if !r.isUsed(reference) {
+				r.used = append(r.used, reference)
+			}
+		}
+	}
+
+	for i := range operation.RootNodes {
+		if operation.RootNodes[i].Kind != ast.NodeKindFragmentDefinition || r.isUsed(operation.RootNodes[i].Ref) {
+			continue
+		}
+		r.transformer.DeleteRootNode(asttransform.Precedence{Order: i}, operation.RootNodes[i])
+	}
+
+	r.transformer.ApplyTransformations(operation)
+}
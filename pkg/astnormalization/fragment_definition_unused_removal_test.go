@@ -0,0 +1,81 @@
+package astnormalization
+
+import "testing"
+
+func TestRemoveUnusedFragmentDefinitions(t *testing.T) {
+	t.Run("used and unused fragment", func(t *testing.T) {
+		run(removeUnusedFragmentDefinitions, testDefinition, `
+					query dog {
+						dog {
+							...dogName
+						}
+					}
+					fragment dogName on Dog {
+						name
+					}
+					fragment dogNickname on Dog {
+						nickname
+					}`, `
+					query dog {
+						dog {
+							...dogName
+						}
+					}
+					fragment dogName on Dog {
+						name
+					}`)
+	})
+	t.Run("fragment used through another fragment", func(t *testing.T) {
+		run(removeUnusedFragmentDefinitions, testDefinition, `
+					fragment unusedDog on Dog {
+						...dogBarkVolume
+					}
+					query dog {
+						dog {
+							...dogNames
+						}
+					}
+					fragment dogNames on Dog {
+						...dogName
+						nickname
+					}
+					fragment dogName on Dog {
+						name
+					}
+					fragment dogBarkVolume on Dog {
+						barkVolume
+					}`, `
+					query dog {
+						dog {
+							...dogNames
+						}
+					}
+					fragment dogNames on Dog {
+						...dogName
+						nickname
+					}
+					fragment dogName on Dog {
+						name
+					}`)
+	})
+	t.Run("inlined fragments", func(t *testing.T) {
+		run(removeUnusedFragmentDefinitions, testDefinition, `
+					query dog {
+						dog {
+							... on Dog {
+								name
+							}
+						}
+					}
+					fragment dogName on Dog {
+						name
+					}`, `
+					query dog {
+						dog {
+							... on Dog {
+								name
+							}
+						}
+					}`)
+	})
+}
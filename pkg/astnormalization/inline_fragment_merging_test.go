@@ -53,4 +53,102 @@ func TestResolveInlineFragments(t *testing.T) {
 						}
 					}`)
 	})
+	t.Run("single inline fragment on the enclosing type", func(t *testing.T) {
+		run(mergeInlineFragments, testDefinition, `
+					query redundantInlineFragment {
+						pet {
+							... on Pet {
+								name
+							}
+						}
+					}`,
+			`
+					query redundantInlineFragment {
+						pet {
+							name
+						}
+					}`)
+	})
+	t.Run("single inline fragment on an implementing type is kept", func(t *testing.T) {
+		run(mergeInlineFragments, testDefinition, `
+					query implementingType {
+						pet {
+							... on Dog {
+								name
+							}
+						}
+					}`,
+			`
+					query implementingType {
+						pet {
+							... on Dog {
+								name
+							}
+						}
+					}`)
+	})
+	t.Run("single inline fragment on a union member is kept", func(t *testing.T) {
+		run(mergeInlineFragments, testDefinition, `
+					query unionMember {
+						catOrDog {
+							... on Dog {
+								name
+							}
+						}
+					}`,
+			`
+					query unionMember {
+						catOrDog {
+							... on Dog {
+								name
+							}
+						}
+					}`)
+	})
+	t.Run("inline fragment on the union keeps its fields next to the member fragments", func(t *testing.T) {
+		run(mergeInlineFragments, testDefinition, `
+					query unionFragment {
+						catOrDog {
+							... on CatOrDog {
+								__typename
+								... on Dog {
+									name
+								}
+								... on Cat {
+									meowVolume
+								}
+							}
+						}
+					}`,
+			`
+					query unionFragment {
+						catOrDog {
+							__typename
+							... on Dog {
+								name
+							}
+							... on Cat {
+								meowVolume
+							}
+						}
+					}`)
+	})
+	t.Run("single inline fragment with directives is kept", func(t *testing.T) {
+		run(mergeInlineFragments, testDefinition, `
+					query withDirective {
+						dog {
+							... on Dog @include(if: true) {
+								name
+							}
+						}
+					}`,
+			`
+					query withDirective {
+						dog {
+							... on Dog @include(if: true) {
+								name
+							}
+						}
+					}`)
+	})
 }
@@ -0,0 +1,77 @@
+package astnormalization
+
+import (
+	"bytes"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/asttransform"
+	"github.com/jensneuse/graphql-go-tools/pkg/astvisitor"
+)
+
+// mergeInlineFragmentSiblings combines adjacent sibling inline fragments with the same type condition and directives into the first of them
+// The selections of the later fragments are appended in order, e.g. to reduce the fragments produced by expanding interfaces.
+// Only adjacent fragments are merged, merging fragments separated by other selections would change the order of the response fields.
+func mergeInlineFragmentSiblings(walker *astvisitor.Walker) {
+	visitor := inlineFragmentSiblingMergeVisitor{
+		Walker: walker,
+	}
+	walker.RegisterEnterDocumentVisitor(&visitor)
+	walker.RegisterEnterSelectionSetVisitor(&visitor)
+}
+
+type inlineFragmentSiblingMergeVisitor struct {
+	*astvisitor.Walker
+	operation   *ast.Document
+	transformer asttransform.Transformer
+}
+
+func (m *inlineFragmentSiblingMergeVisitor) EnterDocument(operation, definition *ast.Document) {
+	m.operation = operation
+}
+
+func (m *inlineFragmentSiblingMergeVisitor) isInlineFragmentWithTypeCondition(selection int) bool {
+	if m.operation.Selections[selection].Kind != ast.SelectionKindInlineFragment {
+		return false
+	}
+	inlineFragment := m.operation.Selections[selection].Ref
+	return m.operation.InlineFragmentHasTypeCondition(inlineFragment) && m.operation.InlineFragments[inlineFragment].HasSelections
+}
+
+func (m *inlineFragmentSiblingMergeVisitor) fragmentsCanMerge(left, right int) bool {
+	leftTypeName := m.operation.InlineFragmentTypeConditionName(left)
+	rightTypeName := m.operation.InlineFragmentTypeConditionName(right)
+	if !bytes.Equal(leftTypeName, rightTypeName) {
+		return false
+	}
+
+	leftDirectives := m.operation.InlineFragments[left].Directives.Refs
+	rightDirectives := m.operation.InlineFragments[right].Directives.Refs
+
+	return m.operation.DirectiveSetsAreEqual(leftDirectives, rightDirectives)
+}
+
+func (m *inlineFragmentSiblingMergeVisitor) EnterSelectionSet(ref int) {
+
+	if len(m.operation.SelectionSets[ref].SelectionRefs) < 2 {
+		return
+	}
+
+	m.transformer.Reset()
+
+	// target is the first fragment of the current run of mergeable fragments or -1 if the previous selection isn't a fragment
+	target := -1
+	for i, selection := range m.operation.SelectionSets[ref].SelectionRefs {
+		if !m.isInlineFragmentWithTypeCondition(selection) {
+			target = -1
+			continue
+		}
+		if target == -1 || !m.fragmentsCanMerge(m.operation.Selections[target].Ref, m.operation.Selections[selection].Ref) {
+			target = selection
+			continue
+		}
+		m.transformer.MergeInlineFragmentSiblings(asttransform.Precedence{Depth: m.Depth, Order: i}, ref, target, selection)
+	}
+
+	// the merges are applied right away, so the walker visits the merged selections and merges their adjacent siblings as well
+	m.transformer.ApplyTransformations(m.operation)
+}
@@ -0,0 +1,269 @@
+package astnormalization
+
+import (
+	"testing"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/astvisitor"
+)
+
+func TestMergeInlineFragmentSiblings(t *testing.T) {
+	t.Run("same type condition", func(t *testing.T) {
+		run(mergeInlineFragmentSiblings, testDefinition, `
+					query siblings {
+						pet {
+							... on Dog {
+								name
+							}
+							... on Dog {
+								nickname
+								barkVolume
+							}
+							... on Cat {
+								name
+							}
+						}
+					}`, `
+					query siblings {
+						pet {
+							... on Dog {
+								name
+								nickname
+								barkVolume
+							}
+							... on Cat {
+								name
+							}
+						}
+					}`)
+	})
+	t.Run("more than two siblings", func(t *testing.T) {
+		run(mergeInlineFragmentSiblings, testDefinition, `
+					query siblings {
+						catOrDog {
+							... on Cat {
+								name
+							}
+							... on Cat {
+								meowVolume
+							}
+							... on Cat {
+								nickname
+							}
+							... on Dog {
+								name
+							}
+						}
+					}`, `
+					query siblings {
+						catOrDog {
+							... on Cat {
+								name
+								meowVolume
+								nickname
+							}
+							... on Dog {
+								name
+							}
+						}
+					}`)
+	})
+	t.Run("fragments separated by another fragment keep their position", func(t *testing.T) {
+		run(mergeInlineFragmentSiblings, testDefinition, `
+					query siblings {
+						catOrDog {
+							... on Cat {
+								name
+							}
+							... on Cat {
+								meowVolume
+							}
+							... on Dog {
+								name
+							}
+							... on Cat {
+								nickname
+							}
+						}
+					}`, `
+					query siblings {
+						catOrDog {
+							... on Cat {
+								name
+								meowVolume
+							}
+							... on Dog {
+								name
+							}
+							... on Cat {
+								nickname
+							}
+						}
+					}`)
+	})
+	t.Run("fields between fragments keep their position", func(t *testing.T) {
+		run(mergeInlineFragmentSiblings, testDefinition, `
+					query siblings {
+						pet {
+							name
+							... on Dog {
+								nickname
+							}
+							__typename
+							... on Dog {
+								barkVolume
+							}
+						}
+					}`, `
+					query siblings {
+						pet {
+							name
+							... on Dog {
+								nickname
+							}
+							__typename
+							... on Dog {
+								barkVolume
+							}
+						}
+					}`)
+	})
+	t.Run("fragments becoming adjacent by merging their parents", func(t *testing.T) {
+		run(mergeInlineFragmentSiblings, testDefinition, `
+					query siblings {
+						pet {
+							... on Dog {
+								... on Dog {
+									name
+								}
+							}
+							... on Dog {
+								... on Dog {
+									nickname
+								}
+							}
+						}
+					}`, `
+					query siblings {
+						pet {
+							... on Dog {
+								... on Dog {
+									name
+									nickname
+								}
+							}
+						}
+					}`)
+	})
+	t.Run("same directives", func(t *testing.T) {
+		run(mergeInlineFragmentSiblings, testDefinition, `
+					query siblings($withDog: Boolean!) {
+						pet {
+							... on Dog @include(if: $withDog) {
+								name
+							}
+							... on Dog @include(if: $withDog) {
+								nickname
+							}
+						}
+					}`, `
+					query siblings($withDog: Boolean!) {
+						pet {
+							... on Dog @include(if: $withDog) {
+								name
+								nickname
+							}
+						}
+					}`)
+	})
+	t.Run("different directives", func(t *testing.T) {
+		run(mergeInlineFragmentSiblings, testDefinition, `
+					query siblings($withDog: Boolean!) {
+						pet {
+							... on Dog @include(if: $withDog) {
+								name
+							}
+							... on Dog {
+								nickname
+							}
+						}
+					}`, `
+					query siblings($withDog: Boolean!) {
+						pet {
+							... on Dog @include(if: $withDog) {
+								name
+							}
+							... on Dog {
+								nickname
+							}
+						}
+					}`)
+	})
+	t.Run("nested", func(t *testing.T) {
+		run(mergeInlineFragmentSiblings, testDefinition, `
+					query siblings {
+						pet {
+							... on Dog {
+								extra {
+									... on DogExtra {
+										string
+									}
+									... on DogExtra {
+										bool
+									}
+								}
+							}
+							... on Dog {
+								name
+							}
+						}
+					}`, `
+					query siblings {
+						pet {
+							... on Dog {
+								extra {
+									... on DogExtra {
+										string
+										bool
+									}
+								}
+								name
+							}
+						}
+					}`)
+	})
+	t.Run("fragment shared by other spreads stays unchanged", func(t *testing.T) {
+		// the second walk merges the inline fragment replacing the spread, which shares the selection set of the fragment
+		runWithVariablesAssert(t, func(walker *astvisitor.Walker) {
+			fragmentSpreadInline(walker)
+			mergeInlineFragmentSiblings(walker)
+		}, testDefinition, `
+					query siblings {
+						pet {
+							...dogFrag
+							... on Dog {
+								barkVolume
+							}
+						}
+						dog {
+							...dogFrag
+						}
+					}
+					fragment dogFrag on Dog {
+						name
+					}`, "siblings", `
+					query siblings {
+						pet {
+							... on Dog {
+								name
+								barkVolume
+							}
+						}
+						dog {
+							name
+						}
+					}
+					fragment dogFrag on Dog {
+						name
+					}`, "", "")
+	})
+}
@@ -0,0 +1,76 @@
+package astnormalization
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/astvisitor"
+)
+
+// sortSelections brings the selections of each selection set into a canonical order,
+// fields first, then fragment spreads, then inline fragments, each sorted by response key, fragment name or type condition.
+// Operations which only differ in the order of their selections print identically afterwards, e.g. to share a plan cache key.
+// The keys of the response follow the order of the selections, so they are sorted too.
+// GraphQL clients must not rely on the order of the keys, the selections of the root of a mutation are kept in order though
+// as the spec requires them to be executed serially in the order of the operation.
+func sortSelections(walker *astvisitor.Walker) {
+	visitor := sortSelectionsVisitor{
+		Walker: walker,
+	}
+	walker.RegisterEnterDocumentVisitor(&visitor)
+	walker.RegisterEnterSelectionSetVisitor(&visitor)
+}
+
+type sortSelectionsVisitor struct {
+	*astvisitor.Walker
+	operation *ast.Document
+}
+
+func (s *sortSelectionsVisitor) EnterDocument(operation, definition *ast.Document) {
+	s.operation = operation
+}
+
+func (s *sortSelectionsVisitor) isMutationRoot() bool {
+	parent := s.Ancestors[len(s.Ancestors)-1]
+	return parent.Kind == ast.NodeKindOperationDefinition &&
+		s.operation.OperationDefinitions[parent.Ref].OperationType == ast.OperationTypeMutation
+}
+
+func (s *sortSelectionsVisitor) selectionRank(selection int) int {
+	switch s.operation.Selections[selection].Kind {
+	case ast.SelectionKindField:
+		return 0
+	case ast.SelectionKindFragmentSpread:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (s *sortSelectionsVisitor) selectionName(selection int) ast.ByteSlice {
+	ref := s.operation.Selections[selection].Ref
+	switch s.operation.Selections[selection].Kind {
+	case ast.SelectionKindField:
+		return s.operation.FieldAliasOrNameBytes(ref)
+	case ast.SelectionKindFragmentSpread:
+		return s.operation.FragmentSpreadNameBytes(ref)
+	default:
+		return s.operation.InlineFragmentTypeConditionName(ref)
+	}
+}
+
+func (s *sortSelectionsVisitor) EnterSelectionSet(ref int) {
+	selections := s.operation.SelectionSets[ref].SelectionRefs
+	if len(selections) < 2 || s.isMutationRoot() {
+		return
+	}
+
+	sort.SliceStable(selections, func(i, j int) bool {
+		leftRank, rightRank := s.selectionRank(selections[i]), s.selectionRank(selections[j])
+		if leftRank != rightRank {
+			return leftRank < rightRank
+		}
+		return bytes.Compare(s.selectionName(selections[i]), s.selectionName(selections[j])) < 0
+	})
+}
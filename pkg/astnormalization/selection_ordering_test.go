@@ -0,0 +1,132 @@
+package astnormalization
+
+import "testing"
+
+func TestSortSelections(t *testing.T) {
+	t.Run("fields by response key", func(t *testing.T) {
+		run(sortSelections, testDefinition, `
+					query sorted {
+						dog {
+							nickname
+							barkVolume
+							alias: name
+						}
+					}`, `
+					query sorted {
+						dog {
+							alias: name
+							barkVolume
+							nickname
+						}
+					}`)
+	})
+	t.Run("fields before fragment spreads before inline fragments", func(t *testing.T) {
+		run(sortSelections, testDefinition, `
+					query sorted {
+						pet {
+							... on Dog {
+								nickname
+							}
+							...petName
+							... on Cat {
+								meowVolume
+							}
+							name
+						}
+					}
+					fragment petName on Pet {
+						name
+					}`, `
+					query sorted {
+						pet {
+							name
+							...petName
+							... on Cat {
+								meowVolume
+							}
+							... on Dog {
+								nickname
+							}
+						}
+					}
+					fragment petName on Pet {
+						name
+					}`)
+	})
+	t.Run("reordered operations normalize identically", func(t *testing.T) {
+		expected := `
+					query sorted {
+						cat {
+							name
+						}
+						dog {
+							barkVolume
+							extra {
+								bool
+								string
+							}
+							name
+						}
+					}`
+		run(sortSelections, testDefinition, `
+					query sorted {
+						dog {
+							name
+							extra {
+								string
+								bool
+							}
+							barkVolume
+						}
+						cat {
+							name
+						}
+					}`, expected)
+		run(sortSelections, testDefinition, `
+					query sorted {
+						cat {
+							name
+						}
+						dog {
+							extra {
+								bool
+								string
+							}
+							barkVolume
+							name
+						}
+					}`, expected)
+	})
+	t.Run("mutation root fields keep their order", func(t *testing.T) {
+		run(sortSelections, `
+					schema { mutation: Mutation }
+					type Mutation {
+						updateName(name: String): User
+						addFriend(id: ID): User
+					}
+					type User {
+						name: String
+						id: ID
+					}`, `
+					mutation update {
+						updateName(name: "Jens") {
+							name
+							id
+						}
+						addFriend(id: "1") {
+							name
+							id
+						}
+					}`, `
+					mutation update {
+						updateName(name: "Jens") {
+							id
+							name
+						}
+						addFriend(id: "1") {
+							id
+							name
+						}
+					}`)
+	})
+}
@@ -0,0 +1,36 @@
+package astnormalization
+
+import (
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/astvisitor"
+	"github.com/jensneuse/graphql-go-tools/pkg/operationreport"
+)
+
+// limitSelectionSetSize aborts the normalization when a selection set has more than maxSelections selections.
+// Fragment spreads on interfaces and unions get expanded into one inline fragment per possible type,
+// so a single selection set can grow very large for big interfaces.
+func limitSelectionSetSize(walker *astvisitor.Walker, maxSelections int) {
+	visitor := selectionSetSizeVisitor{
+		Walker:        walker,
+		maxSelections: maxSelections,
+	}
+	walker.RegisterEnterDocumentVisitor(&visitor)
+	walker.RegisterLeaveSelectionSetVisitor(&visitor)
+}
+
+type selectionSetSizeVisitor struct {
+	*astvisitor.Walker
+	operation     *ast.Document
+	maxSelections int
+}
+
+func (s *selectionSetSizeVisitor) EnterDocument(operation, definition *ast.Document) {
+	s.operation = operation
+}
+
+func (s *selectionSetSizeVisitor) LeaveSelectionSet(ref int) {
+	count := s.operation.SelectionSetSelectionCount(ref)
+	if count > s.maxSelections {
+		s.StopWithExternalErr(operationreport.ErrSelectionSetExceedsMaxSelections(count, s.maxSelections))
+	}
+}
@@ -0,0 +1,58 @@
+package astnormalization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jensneuse/graphql-go-tools/internal/pkg/unsafeparser"
+	"github.com/jensneuse/graphql-go-tools/pkg/asttransform"
+	"github.com/jensneuse/graphql-go-tools/pkg/operationreport"
+)
+
+func TestLimitSelectionSetSize(t *testing.T) {
+	const operation = `
+		query pets {
+			pet {
+				name
+				...dogFields
+				...catFields
+			}
+		}
+		fragment dogFields on Dog {
+			barkVolume
+		}
+		fragment catFields on Cat {
+			meowVolume
+		}`
+
+	run := func(t *testing.T, maxSelections int) operationreport.Report {
+		definitionDocument := unsafeparser.ParseGraphqlDocumentString(testDefinition)
+		require.NoError(t, asttransform.MergeDefinitionWithBaseSchema(&definitionDocument))
+		operationDocument := unsafeparser.ParseGraphqlDocumentString(operation)
+
+		report := operationreport.Report{}
+		normalizer := NewWithOpts(
+			WithRemoveFragmentDefinitions(),
+			WithMaxSelectionSetSelections(maxSelections),
+		)
+		normalizer.NormalizeOperation(&operationDocument, &definitionDocument, &report)
+		return report
+	}
+
+	t.Run("expanded selection set at the limit", func(t *testing.T) {
+		report := run(t, 3)
+		assert.False(t, report.HasErrors())
+	})
+	t.Run("expanded selection set exceeds the limit", func(t *testing.T) {
+		report := run(t, 2)
+		require.True(t, report.HasErrors())
+		require.Len(t, report.ExternalErrors, 1)
+		assert.Equal(t, "selection set has 3 selections after normalization, max allowed: 2", report.ExternalErrors[0].Message)
+	})
+	t.Run("no limit", func(t *testing.T) {
+		report := run(t, 0)
+		assert.False(t, report.HasErrors())
+	})
+}
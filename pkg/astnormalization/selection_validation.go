@@ -0,0 +1,61 @@
+package astnormalization
+
+import (
+	"bytes"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/astvisitor"
+	"github.com/jensneuse/graphql-go-tools/pkg/lexer/literal"
+	"github.com/jensneuse/graphql-go-tools/pkg/operationreport"
+)
+
+// validateSelections checks that the selections of the normalized operation are still valid on their enclosing type.
+// Fields must be defined on the enclosing type and inline fragment type conditions must be possible on it.
+// It's a sanity check for transformations of the operation, a violation means a normalization rule produced an invalid operation.
+func validateSelections(walker *astvisitor.Walker) {
+	visitor := selectionValidationVisitor{
+		Walker: walker,
+	}
+	walker.RegisterEnterDocumentVisitor(&visitor)
+	walker.RegisterEnterFieldVisitor(&visitor)
+	walker.RegisterEnterInlineFragmentVisitor(&visitor)
+}
+
+type selectionValidationVisitor struct {
+	*astvisitor.Walker
+	operation, definition *ast.Document
+}
+
+func (s *selectionValidationVisitor) EnterDocument(operation, definition *ast.Document) {
+	s.operation = operation
+	s.definition = definition
+}
+
+func (s *selectionValidationVisitor) EnterField(ref int) {
+	fieldName := s.operation.FieldNameBytes(ref)
+	if bytes.Equal(fieldName, literal.TYPENAME) {
+		return
+	}
+	if _, exists := s.definition.NodeFieldDefinitionByName(s.EnclosingTypeDefinition, fieldName); exists {
+		return
+	}
+	s.StopWithExternalErr(operationreport.ErrFieldUndefinedOnType(fieldName, s.definition.NodeNameBytes(s.EnclosingTypeDefinition)))
+}
+
+func (s *selectionValidationVisitor) EnterInlineFragment(ref int) {
+	if !s.operation.InlineFragmentHasTypeCondition(ref) {
+		return
+	}
+
+	typeName := s.operation.InlineFragmentTypeConditionName(ref)
+	node, exists := s.definition.Index.FirstNonExtensionNodeByNameBytes(typeName)
+	if !exists {
+		s.StopWithExternalErr(operationreport.ErrTypeUndefined(typeName))
+		return
+	}
+
+	if !s.definition.NodeFragmentIsAllowedOnNode(node, s.EnclosingTypeDefinition) {
+		enclosingTypeName := s.definition.NodeNameBytes(s.EnclosingTypeDefinition)
+		s.StopWithExternalErr(operationreport.ErrInlineFragmentOnTypeMismatchEnclosingType(typeName, enclosingTypeName))
+	}
+}
@@ -0,0 +1,111 @@
+package astnormalization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jensneuse/graphql-go-tools/internal/pkg/unsafeparser"
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/asttransform"
+	"github.com/jensneuse/graphql-go-tools/pkg/astvisitor"
+	"github.com/jensneuse/graphql-go-tools/pkg/operationreport"
+)
+
+func TestValidateSelections(t *testing.T) {
+	parseDefinition := func(t *testing.T) ast.Document {
+		definitionDocument := unsafeparser.ParseGraphqlDocumentString(testDefinition)
+		require.NoError(t, asttransform.MergeDefinitionWithBaseSchema(&definitionDocument))
+		return definitionDocument
+	}
+
+	normalize := func(t *testing.T, operation string) operationreport.Report {
+		definitionDocument := parseDefinition(t)
+		operationDocument := unsafeparser.ParseGraphqlDocumentString(operation)
+
+		report := operationreport.Report{}
+		normalizer := NewWithOpts(
+			WithRemoveFragmentDefinitions(),
+			WithValidateSelections(),
+		)
+		normalizer.NormalizeOperation(&operationDocument, &definitionDocument, &report)
+		return report
+	}
+
+	t.Run("valid operation", func(t *testing.T) {
+		report := normalize(t, `
+			query pets {
+				pet {
+					__typename
+					name
+					...dogFields
+					... on Cat {
+						meowVolume
+					}
+				}
+			}
+			fragment dogFields on Dog {
+				barkVolume
+			}`)
+		assert.False(t, report.HasErrors())
+	})
+	t.Run("field undefined on its type", func(t *testing.T) {
+		report := normalize(t, `
+			query pets {
+				pet {
+					barkVolume
+				}
+			}`)
+		require.Len(t, report.ExternalErrors, 1)
+		assert.Equal(t, "field: barkVolume not defined on type: Pet", report.ExternalErrors[0].Message)
+	})
+	t.Run("field invalid on its type after transformation", func(t *testing.T) {
+		definitionDocument := parseDefinition(t)
+		operationDocument := unsafeparser.ParseGraphqlDocumentString(`
+			query pets {
+				pet {
+					... on Dog {
+						name
+					}
+				}
+			}`)
+
+		report := operationreport.Report{}
+		NewNormalizer(true, false).NormalizeOperation(&operationDocument, &definitionDocument, &report)
+		require.False(t, report.HasErrors())
+
+		// move the Dog only field out of the inline fragment, like a broken merge rule would do
+		rootSelectionSet := operationDocument.OperationDefinitions[0].SelectionSet
+		petField := operationDocument.Selections[operationDocument.SelectionSets[rootSelectionSet].SelectionRefs[0]].Ref
+		petSelectionSet := operationDocument.Fields[petField].SelectionSet
+		operationDocument.AddSelection(petSelectionSet, ast.Selection{
+			Kind: ast.SelectionKindField,
+			Ref:  operationDocument.AddField(ast.Field{Name: operationDocument.Input.AppendInputString("barkVolume")}).Ref,
+		})
+
+		walker := astvisitor.NewWalker(48)
+		validateSelections(&walker)
+		walker.Walk(&operationDocument, &definitionDocument, &report)
+		require.Len(t, report.ExternalErrors, 1)
+		assert.Equal(t, "field: barkVolume not defined on type: Pet", report.ExternalErrors[0].Message)
+	})
+	t.Run("inline fragment type condition mismatches enclosing type", func(t *testing.T) {
+		definitionDocument := parseDefinition(t)
+		operationDocument := unsafeparser.ParseGraphqlDocumentString(`
+			query dog {
+				dog {
+					... on Cat {
+						meowVolume
+					}
+				}
+			}`)
+
+		report := operationreport.Report{}
+		walker := astvisitor.NewWalker(48)
+		validateSelections(&walker)
+		walker.Walk(&operationDocument, &definitionDocument, &report)
+		require.Len(t, report.ExternalErrors, 1)
+		assert.Equal(t, "inline fragment on type: Cat mismatches enclosing type: Dog", report.ExternalErrors[0].Message)
+	})
+}
@@ -30,6 +30,12 @@ type (
 		ReplaceFragmentSpread(selectionSet int, spreadRef int, replaceWithSelectionSet int)
 		// ReplaceFragmentSpreadWithInlineFragment marks a fragment spread to be replaces with an inline fragment
 		ReplaceFragmentSpreadWithInlineFragment(selectionSet int, spreadRef int, replaceWithSelectionSet int, typeCondition ast.TypeCondition)
+		// SetFieldAlias marks a field to get the alias set or replaced
+		SetFieldAlias(ref int, alias []byte)
+		// AddFieldToSelectionSet marks to append a field to a selectionset unless it's already selected
+		AddFieldToSelectionSet(set int, fieldName, alias []byte) int
+		// MergeInlineFragmentSiblings marks an inline fragment to be merged into a sibling inline fragment
+		MergeInlineFragmentSiblings(set, left, right int)
 	}
 	transformation interface {
 		apply(transformable Transformable)
@@ -121,6 +127,43 @@ func (t *Transformer) ReplaceFragmentSpreadWithInlineFragment(precedence Precede
 	})
 }
 
+// SetFieldAlias registers an action to set or replace the alias of a field
+func (t *Transformer) SetFieldAlias(precedence Precedence, fieldRef int, alias []byte) {
+	t.actions = append(t.actions, action{
+		precedence: precedence,
+		transformation: setFieldAlias{
+			fieldRef: fieldRef,
+			alias:    alias,
+		},
+	})
+}
+
+// AddFieldToSelectionSet registers an action to append a field with an optional alias to a selectionset
+// The field isn't added if the selectionset already selects it, e.g. to add __typename once.
+func (t *Transformer) AddFieldToSelectionSet(precedence Precedence, selectionSet int, fieldName, alias []byte) {
+	t.actions = append(t.actions, action{
+		precedence: precedence,
+		transformation: addFieldToSelectionSet{
+			selectionSet: selectionSet,
+			fieldName:    fieldName,
+			alias:        alias,
+		},
+	})
+}
+
+// MergeInlineFragmentSiblings registers an action to append the selections of the inline fragment selection right
+// to the inline fragment selection left and to remove right from the selectionset
+func (t *Transformer) MergeInlineFragmentSiblings(precedence Precedence, selectionSet int, left, right int) {
+	t.actions = append(t.actions, action{
+		precedence: precedence,
+		transformation: mergeInlineFragmentSiblings{
+			selectionSet: selectionSet,
+			left:         left,
+			right:        right,
+		},
+	})
+}
+
 type replaceFragmentSpread struct {
 	selectionSet            int
 	spreadRef               int
@@ -166,3 +209,32 @@ type appendSelectionSet struct {
 func (a appendSelectionSet) apply(transformable Transformable) {
 	transformable.AppendSelectionSet(a.ref, a.appendRef)
 }
+
+type setFieldAlias struct {
+	fieldRef int
+	alias    []byte
+}
+
+func (s setFieldAlias) apply(transformable Transformable) {
+	transformable.SetFieldAlias(s.fieldRef, s.alias)
+}
+
+type addFieldToSelectionSet struct {
+	selectionSet int
+	fieldName    []byte
+	alias        []byte
+}
+
+func (a addFieldToSelectionSet) apply(transformable Transformable) {
+	transformable.AddFieldToSelectionSet(a.selectionSet, a.fieldName, a.alias)
+}
+
+type mergeInlineFragmentSiblings struct {
+	selectionSet int
+	left         int
+	right        int
+}
+
+func (m mergeInlineFragmentSiblings) apply(transformable Transformable) {
+	transformable.MergeInlineFragmentSiblings(m.selectionSet, m.left, m.right)
+}
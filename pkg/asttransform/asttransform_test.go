@@ -0,0 +1,58 @@
+package asttransform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jensneuse/graphql-go-tools/internal/pkg/unsafeparser"
+	"github.com/jensneuse/graphql-go-tools/pkg/astprinter"
+)
+
+func TestTransformer_SetFieldAlias(t *testing.T) {
+	doc := unsafeparser.ParseGraphqlDocumentString(`{user {name} a: id b: id}`)
+
+	transformer := Transformer{}
+	transformer.SetFieldAlias(Precedence{Depth: 1}, 0, []byte("userName"))
+	transformer.SetFieldAlias(Precedence{Depth: 0, Order: 0}, 2, []byte("first"))
+	transformer.SetFieldAlias(Precedence{Depth: 0, Order: 1}, 3, []byte("second"))
+	transformer.ApplyTransformations(&doc)
+
+	out, err := astprinter.PrintString(&doc, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{user {userName: name} first: id second: id}`, out)
+}
+
+func TestTransformer_AddFieldToSelectionSet(t *testing.T) {
+	doc := unsafeparser.ParseGraphqlDocumentString(`{user {name} account {__typename id}}`)
+	rootFields := doc.FieldSelectionRefs(doc.OperationDefinitions[0].SelectionSet)
+	user := doc.Fields[rootFields[0]].SelectionSet
+	account := doc.Fields[rootFields[1]].SelectionSet
+
+	transformer := Transformer{}
+	transformer.AddFieldToSelectionSet(Precedence{Depth: 1, Order: 0}, user, []byte("__typename"), nil)
+	transformer.AddFieldToSelectionSet(Precedence{Depth: 1, Order: 1}, user, []byte("__typename"), nil)
+	transformer.AddFieldToSelectionSet(Precedence{Depth: 1, Order: 2}, account, []byte("__typename"), nil)
+	transformer.AddFieldToSelectionSet(Precedence{Depth: 1, Order: 3}, account, []byte("id"), []byte("accountID"))
+	transformer.ApplyTransformations(&doc)
+
+	out, err := astprinter.PrintString(&doc, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{user {name __typename} account {__typename id accountID: id}}`, out)
+}
+
+func TestTransformer_MergeInlineFragmentSiblings(t *testing.T) {
+	doc := unsafeparser.ParseGraphqlDocumentString(`{pet {... on Dog {name} ... on Dog {nickname} ... on Dog {barkVolume} __typename}}`)
+	rootFields := doc.FieldSelectionRefs(doc.OperationDefinitions[0].SelectionSet)
+	pet := doc.Fields[rootFields[0]].SelectionSet
+	fragments := doc.SelectionSets[pet].SelectionRefs
+
+	transformer := Transformer{}
+	transformer.MergeInlineFragmentSiblings(Precedence{Depth: 1, Order: 0}, pet, fragments[0], fragments[1])
+	transformer.MergeInlineFragmentSiblings(Precedence{Depth: 1, Order: 1}, pet, fragments[0], fragments[2])
+	transformer.ApplyTransformations(&doc)
+
+	out, err := astprinter.PrintString(&doc, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{pet {... on Dog {name nickname barkVolume} __typename}}`, out)
+}
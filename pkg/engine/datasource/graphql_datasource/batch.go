@@ -151,29 +151,80 @@ func (b *Batch) demultiplexBatch(responsePair *resolve.BufPair, responseMappings
 	if responsePair.HasData() {
 		_, err = jsonparser.ArrayEach(responsePair.Data.Bytes(), func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
 
-			if outPosition > len(responseMappings)+1 {
+			// entities beyond the requested representations can't be assigned
+			if outPosition >= len(responseMappings) {
 				return
 			}
 
-			mapping := responseMappings[outPosition]
-
-			for _, index := range mapping.assignedBufferIndices {
-				if resultBufPairs[index].Data.Len() != 0 {
-					resultBufPairs[index].Data.WriteBytes(literal.COMMA)
-				}
-				resultBufPairs[index].Data.WriteBytes(value)
-			}
+			writeBatchEntity(resultBufPairs, responseMappings[outPosition], value)
 
 			outPosition++
 		})
 		if err != nil {
 			return err
 		}
+
+		// entities missing at the end of a short response resolve as null instead of leaving their buffers empty
+		for ; outPosition < len(responseMappings); outPosition++ {
+			writeBatchEntity(resultBufPairs, responseMappings[outPosition], literal.NULL)
+		}
 	}
 
 	if responsePair.HasErrors() {
-		resultBufPairs[0].Errors.WriteBytes(responsePair.Errors.Bytes())
+		err = b.demultiplexErrors(responsePair, responseMappings, resultBufPairs)
 	}
 
 	return
 }
+
+// demultiplexErrors assigns errors with a path into _entities to the buffers of the entity,
+// so that the resolver reports them at the path of the entity in the response.
+// Errors which don't belong to an entity are assigned to the first buffer.
+func (b *Batch) demultiplexErrors(responsePair *resolve.BufPair, responseMappings []inputResponseBufferMappings, resultBufPairs []*resolve.BufPair) error {
+	errorsArray := pool.FastBuffer.Get()
+	defer pool.FastBuffer.Put(errorsArray)
+	errorsArray.WriteBytes(literal.LBRACK)
+	errorsArray.WriteBytes(responsePair.Errors.Bytes())
+	errorsArray.WriteBytes(literal.RBRACK)
+
+	_, err := jsonparser.ArrayEach(errorsArray.Bytes(), func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		entityIndex, ok := errorEntityIndex(value)
+		if !ok || entityIndex >= len(responseMappings) {
+			writeBatchError(resultBufPairs[0], value)
+			return
+		}
+		for _, index := range responseMappings[entityIndex].assignedBufferIndices {
+			writeBatchError(resultBufPairs[index], value)
+		}
+	})
+	return err
+}
+
+// errorEntityIndex returns the index of the entity of an error with a path like ["_entities",1,"name"]
+func errorEntityIndex(graphqlError []byte) (int, bool) {
+	entities, err := jsonparser.GetString(graphqlError, "path", "[0]")
+	if err != nil || entities != "_entities" {
+		return 0, false
+	}
+	index, err := jsonparser.GetInt(graphqlError, "path", "[1]")
+	if err != nil || index < 0 {
+		return 0, false
+	}
+	return int(index), true
+}
+
+func writeBatchError(bufPair *resolve.BufPair, graphqlError []byte) {
+	if bufPair.Errors.Len() != 0 {
+		bufPair.Errors.WriteBytes(literal.COMMA)
+	}
+	bufPair.Errors.WriteBytes(graphqlError)
+}
+
+func writeBatchEntity(resultBufPairs []*resolve.BufPair, mapping inputResponseBufferMappings, value []byte) {
+	for _, index := range mapping.assignedBufferIndices {
+		if resultBufPairs[index].Data.Len() != 0 {
+			resultBufPairs[index].Data.WriteBytes(literal.COMMA)
+		}
+		resultBufPairs[index].Data.WriteBytes(value)
+	}
+}
@@ -201,6 +201,50 @@ func TestBatch_Demultiplex(t *testing.T) {
 			},
 		)
 	})
+	t.Run("demultiplex errors of entities", func(t *testing.T) {
+		runTestDemultiplex(
+			t,
+			[]string{
+				`{"method":"POST","url":"http://product.service","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name price}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`,
+				`{"method":"POST","url":"http://product.service","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name price}}}","variables":{"representations":[{"upc":"top-2","__typename":"Product"}]}}}`,
+				`{"method":"POST","url":"http://product.service","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name price}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`,
+			},
+			newBufPair(`[{"name":"Name 1", "price": null, "__typename":"Product"},{"name":"Name 2", "price": 2.01, "__typename":"Product"}]`, `{"message":"price unavailable","path":["_entities",0,"price"]},{"message":"errorMessage"}`),
+			[]*resolve.BufPair{
+				newBufPair(`{"name":"Name 1", "price": null, "__typename":"Product"}`, `{"message":"price unavailable","path":["_entities",0,"price"]},{"message":"errorMessage"}`),
+				newBufPair(`{"name":"Name 2", "price": 2.01, "__typename":"Product"}`, ""),
+				newBufPair(`{"name":"Name 1", "price": null, "__typename":"Product"}`, `{"message":"price unavailable","path":["_entities",0,"price"]}`),
+			},
+		)
+	})
+	t.Run("demultiplex response with missing entities", func(t *testing.T) {
+		runTestDemultiplex(
+			t,
+			[]string{
+				`{"method":"POST","url":"http://product.service","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name price}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`,
+				`{"method":"POST","url":"http://product.service","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name price}}}","variables":{"representations":[{"upc":"top-2","__typename":"Product"}]}}}`,
+				`{"method":"POST","url":"http://product.service","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name price}}}","variables":{"representations":[{"upc":"top-3","__typename":"Product"}]}}}`,
+			},
+			newBufPair(`[null,{"name":"Name 2", "price": 2.01, "__typename":"Product"}]`, ""),
+			[]*resolve.BufPair{
+				newBufPair("null", ""),
+				newBufPair(`{"name":"Name 2", "price": 2.01, "__typename":"Product"}`, ""),
+				newBufPair("null", ""),
+			},
+		)
+	})
+	t.Run("demultiplex response with more entities than representations", func(t *testing.T) {
+		runTestDemultiplex(
+			t,
+			[]string{
+				`{"method":"POST","url":"http://product.service","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name price}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`,
+			},
+			newBufPair(`[{"name":"Name 1", "price": 1.01, "__typename":"Product"},{"name":"Name 2", "price": 2.01, "__typename":"Product"}]`, ""),
+			[]*resolve.BufPair{
+				newBufPair(`{"name":"Name 1", "price": 1.01, "__typename":"Product"}`, ""),
+			},
+		)
+	})
 	t.Run("demultiplex response with error", func(t *testing.T) {
 		runTestDemultiplex(
 			t,
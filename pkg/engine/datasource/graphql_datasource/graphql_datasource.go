@@ -584,14 +584,7 @@ func (p *Planner) fieldDefinition(fieldName, typeName string) *ast.FieldDefiniti
 func (p *Planner) addOneTypeInlineFragment() {
 	selectionSet := p.upstreamOperation.AddSelectionSet()
 	onTypeName := p.visitor.Config.Types.RenameTypeNameOnMatchBytes([]byte(p.lastFieldEnclosingTypeName))
-	typeRef := p.upstreamOperation.AddNamedType(onTypeName)
-	inlineFragment := p.upstreamOperation.AddInlineFragment(ast.InlineFragment{
-		HasSelections: true,
-		SelectionSet:  selectionSet.Ref,
-		TypeCondition: ast.TypeCondition{
-			Type: typeRef,
-		},
-	})
+	inlineFragment := p.upstreamOperation.WrapSelectionSetInInlineFragment(selectionSet.Ref, onTypeName)
 	p.upstreamOperation.AddSelection(p.nodes[len(p.nodes)-1].Ref, ast.Selection{
 		Kind: ast.SelectionKindInlineFragment,
 		Ref:  inlineFragment,
@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
 	"context"
 	"io/ioutil"
@@ -195,4 +196,61 @@ func TestHttpClientDo(t *testing.T) {
 		input = SetInputURL(input, []byte(server.URL))
 		t.Run("net", runTest(background, input, `ok`))
 	})
+
+	t.Run("gzip requested explicitly", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+			gzipWriter := gzip.NewWriter(w)
+			defer gzipWriter.Close()
+			w.Header().Set("Content-Encoding", "gzip")
+			_, err := gzipWriter.Write([]byte(`{"data":{"foo":"bar"}}`))
+			assert.NoError(t, err)
+		}))
+		defer server.Close()
+		var input []byte
+		input = SetInputMethod(input, []byte("GET"))
+		input = SetInputURL(input, []byte(server.URL))
+		input = SetInputHeader(input, []byte(`{"Accept-Encoding":["gzip"]}`))
+		t.Run("net", runTest(background, input, `{"data":{"foo":"bar"}}`))
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			flateWriter, err := flate.NewWriter(w, flate.DefaultCompression)
+			assert.NoError(t, err)
+			defer flateWriter.Close()
+			w.Header().Set("Content-Encoding", "deflate")
+			_, err = flateWriter.Write([]byte(`{"data":{"foo":"bar"}}`))
+			assert.NoError(t, err)
+		}))
+		defer server.Close()
+		var input []byte
+		input = SetInputMethod(input, []byte("GET"))
+		input = SetInputURL(input, []byte(server.URL))
+		t.Run("net", runTest(background, input, `{"data":{"foo":"bar"}}`))
+	})
+
+	t.Run("truncated gzip", func(t *testing.T) {
+		compressed := &bytes.Buffer{}
+		gzipWriter := gzip.NewWriter(compressed)
+		_, err := gzipWriter.Write([]byte(`{"data":{"foo":"bar"}}`))
+		assert.NoError(t, err)
+		assert.NoError(t, gzipWriter.Close())
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "gzip")
+			_, err := w.Write(compressed.Bytes()[:compressed.Len()-10])
+			assert.NoError(t, err)
+		}))
+		defer server.Close()
+		var input []byte
+		input = SetInputMethod(input, []byte("GET"))
+		input = SetInputURL(input, []byte(server.URL))
+		input = SetInputHeader(input, []byte(`{"Accept-Encoding":["gzip"]}`))
+
+		out := &bytes.Buffer{}
+		err = Do(http.DefaultClient, background, input, out)
+		assert.Error(t, err)
+		assert.Equal(t, "", out.String())
+	})
 }
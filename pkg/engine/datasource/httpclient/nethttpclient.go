@@ -5,6 +5,7 @@ import (
 	"compress/flate"
 	"compress/gzip"
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"time"
@@ -99,26 +100,42 @@ func Do(client *http.Client, ctx context.Context, requestInput []byte, out io.Wr
 	}
 	defer response.Body.Close()
 
-	respReader, err := respBodyReader(request, response)
+	encoding := response.Header.Get(ContentEncodingHeader)
+	if encoding != "gzip" && encoding != "deflate" {
+		_, err = io.Copy(out, response.Body)
+		return
+	}
+
+	decoded, err := decodeBody(encoding, response.Body)
 	if err != nil {
 		return err
 	}
-
-	_, err = io.Copy(out, respReader)
+	_, err = out.Write(decoded)
 	return
 }
 
-func respBodyReader(req *http.Request, resp *http.Response) (io.ReadCloser, error) {
-	if req.Header.Get(AcceptEncodingHeader) == "" {
-		return resp.Body, nil
-	}
-
-	switch resp.Header.Get(ContentEncodingHeader) {
+// decodeBody decompresses a gzip or deflate encoded response body
+// The body is decompressed completely before anything is written to the output,
+// so that a corrupt or truncated stream results in an error instead of partial JSON.
+func decodeBody(encoding string, body io.Reader) ([]byte, error) {
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+	switch encoding {
 	case "gzip":
-		return gzip.NewReader(resp.Body)
+		reader, err = gzip.NewReader(body)
 	case "deflate":
-		return flate.NewReader(resp.Body), nil
+		reader = flate.NewReader(body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s response body: %w", encoding, err)
 	}
+	defer reader.Close()
 
-	return resp.Body, nil
+	decoded := &bytes.Buffer{}
+	if _, err = io.Copy(decoded, reader); err != nil {
+		return nil, fmt.Errorf("failed to decode %s response body: %w", encoding, err)
+	}
+	return decoded.Bytes(), nil
 }
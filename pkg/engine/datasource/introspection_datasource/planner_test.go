@@ -47,6 +47,19 @@ const (
 			}
 		}
 	`
+
+	typeIntrospectionWithoutArgs = `
+		query typeIntrospection {
+			__type(name: "Query") {
+				fields {
+					name
+				}
+				enumValues {
+					name
+				}
+			}
+		}
+	`
 )
 
 func TestIntrospectionDataSourcePlanning(t *testing.T) {
@@ -300,4 +313,128 @@ func TestIntrospectionDataSourcePlanning(t *testing.T) {
 		},
 		planConfiguration,
 	))
+
+	t.Run("type introspection request with fields without args", datasourcetesting.RunTest(schema, typeIntrospectionWithoutArgs, "",
+		&plan.SynchronousResponsePlan{
+			Response: &resolve.GraphQLResponse{
+				Data: &resolve.Object{
+					Fetch: &resolve.SingleFetch{
+						BufferId:             0,
+						Input:                `{"request_type":2,"type_name":"$$0$$"}`,
+						DataSource:           &Source{},
+						DataSourceIdentifier: dataSourceIdentifier,
+						Variables: resolve.NewVariables(
+							&resolve.ContextVariable{
+								Path:     []string{"a"},
+								Renderer: resolve.NewPlainVariableRendererWithValidation(`{"type":["string"]}`),
+							},
+						),
+					},
+					Fields: []*resolve.Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("__type"),
+							Position: resolve.Position{
+								Line:   3,
+								Column: 4,
+							},
+							Value: &resolve.Object{
+								Nullable: true,
+								Fetch: &resolve.ParallelFetch{
+									Fetches: []resolve.Fetch{
+										&resolve.SingleFetch{
+											BufferId:   1,
+											Input:      `{"request_type":3,"on_type_name":$$0$$,"include_deprecated":$$1$$}`,
+											DataSource: &Source{},
+											Variables: resolve.NewVariables(
+												&resolve.ObjectVariable{
+													Path:     []string{"name"},
+													Renderer: resolve.NewPlainVariableRenderer(),
+												},
+												&resolve.ContextVariable{
+													Path:     []string{"b"},
+													Renderer: resolve.NewPlainVariableRendererWithValidation(`{"type":["boolean","null"]}`),
+												},
+											),
+											DataSourceIdentifier: dataSourceIdentifier,
+										},
+										&resolve.SingleFetch{
+											BufferId:   2,
+											Input:      `{"request_type":4,"on_type_name":$$0$$,"include_deprecated":$$1$$}`,
+											DataSource: &Source{},
+											Variables: resolve.NewVariables(
+												&resolve.ObjectVariable{
+													Path:     []string{"name"},
+													Renderer: resolve.NewPlainVariableRenderer(),
+												},
+												&resolve.ContextVariable{
+													Path:     []string{"c"},
+													Renderer: resolve.NewPlainVariableRendererWithValidation(`{"type":["boolean","null"]}`),
+												},
+											),
+											DataSourceIdentifier: dataSourceIdentifier,
+										},
+									},
+								},
+								Fields: []*resolve.Field{
+									{
+										BufferID:  1,
+										HasBuffer: true,
+										Name:      []byte("fields"),
+										Value: &resolve.Array{
+											Nullable: true,
+											Item: &resolve.Object{
+												Fields: []*resolve.Field{
+													{
+														Name: []byte("name"),
+														Value: &resolve.String{
+															Path: []string{"name"},
+														},
+														Position: resolve.Position{
+															Line:   5,
+															Column: 6,
+														},
+													},
+												},
+											},
+										}, Position: resolve.Position{
+											Line:   4,
+											Column: 5,
+										},
+									},
+									{
+										BufferID:  2,
+										HasBuffer: true,
+										Name:      []byte("enumValues"),
+										Value: &resolve.Array{
+											Nullable: true,
+											Item: &resolve.Object{
+												Fields: []*resolve.Field{
+													{
+														Name: []byte("name"),
+														Value: &resolve.String{
+															Path: []string{"name"},
+														},
+														Position: resolve.Position{
+															Line:   8,
+															Column: 6,
+														},
+													},
+												},
+											},
+										}, Position: resolve.Position{
+											Line:   7,
+											Column: 5,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		planConfiguration,
+	))
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/sebdah/goldie"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/jensneuse/graphql-go-tools/pkg/astparser"
@@ -58,6 +59,55 @@ func TestSource_Load(t *testing.T) {
 	})
 }
 
+func TestSource_LoadDeprecationReason(t *testing.T) {
+	def, report := astparser.ParseGraphqlDocumentString(`
+		schema { query: Query }
+		type Query {
+			me: Droid @deprecated(reason: "Use droid instead.")
+			droid(id: ID!): Droid
+		}
+		type Droid { name: String! }
+		enum Episode {
+			NEWHOPE
+			JEDI @deprecated(reason: "Spoiler.")
+		}`)
+	require.False(t, report.HasErrors())
+	require.NoError(t, asttransform.MergeDefinitionWithBaseSchema(&def))
+
+	var data introspection.Data
+	gen := introspection.NewGenerator()
+	gen.Generate(&def, &report, &data)
+	require.False(t, report.HasErrors())
+
+	load := func(input string) []byte {
+		buf := &bytes.Buffer{}
+		source := &Source{introspectionData: &data}
+		require.NoError(t, source.Load(context.Background(), []byte(input), buf))
+		return buf.Bytes()
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		var fields []introspection.Field
+		require.NoError(t, json.Unmarshal(load(`{"request_type":3,"on_type_name":"Query","include_deprecated":true}`), &fields))
+		require.Len(t, fields, 2)
+		assert.True(t, fields[0].IsDeprecated)
+		require.NotNil(t, fields[0].DeprecationReason)
+		assert.Equal(t, "Use droid instead.", *fields[0].DeprecationReason)
+		assert.False(t, fields[1].IsDeprecated)
+		assert.Nil(t, fields[1].DeprecationReason)
+	})
+
+	t.Run("enum values", func(t *testing.T) {
+		var enumValues []introspection.EnumValue
+		require.NoError(t, json.Unmarshal(load(`{"request_type":4,"on_type_name":"Episode","include_deprecated":true}`), &enumValues))
+		require.Len(t, enumValues, 2)
+		assert.False(t, enumValues[0].IsDeprecated)
+		assert.True(t, enumValues[1].IsDeprecated)
+		require.NotNil(t, enumValues[1].DeprecationReason)
+		assert.Equal(t, "Spoiler.", *enumValues[1].DeprecationReason)
+	})
+}
+
 const testSchema = `
 schema {
     query: Query
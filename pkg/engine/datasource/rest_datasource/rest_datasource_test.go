@@ -420,6 +420,155 @@ func TestFastHttpJsonDataSourcePlanning(t *testing.T) {
 			},
 		},
 	))
+	t.Run("mutation root fields are fetched serially", datasourcetesting.RunTest(authSchema, `
+		mutation Login ($phoneNumber: String! $code: String) {
+			start: postPasswordlessStart(postPasswordlessStartInput: {loginId: $phoneNumber}) {
+				code
+			}
+			login: postPasswordlessLogin(postPasswordlessLoginInput: {code: $code}) {
+				token
+			}
+		}
+`, "Login",
+		&plan.SynchronousResponsePlan{
+			Response: &resolve.GraphQLResponse{
+				Data: &resolve.Object{
+					Fetch: &resolve.SerialFetch{
+						Fetches: []resolve.Fetch{
+							&resolve.SingleFetch{
+								BufferId:   0,
+								Input:      `{"body":{"loginId":$$0$$},"method":"POST","url":"https://example.com/passwordless_start"}`,
+								DataSource: &Source{},
+								Variables: resolve.NewVariables(
+									&resolve.ContextVariable{
+										Path:     []string{"phoneNumber"},
+										Renderer: resolve.NewJSONVariableRendererWithValidation(`{"type":["string","null"]}`),
+									},
+								),
+								DataSourceIdentifier: []byte("rest_datasource.Source"),
+								DisallowSingleFlight: true,
+								DisableDataLoader:    true,
+							},
+							&resolve.SingleFetch{
+								BufferId:   1,
+								Input:      `{"body":{"code":$$0$$},"method":"POST","url":"https://example.com/passwordless_login"}`,
+								DataSource: &Source{},
+								Variables: resolve.NewVariables(
+									&resolve.ContextVariable{
+										Path:     []string{"code"},
+										Renderer: resolve.NewJSONVariableRendererWithValidation(`{"type":["string","null"]}`),
+									},
+								),
+								DataSourceIdentifier: []byte("rest_datasource.Source"),
+								DisallowSingleFlight: true,
+								DisableDataLoader:    true,
+							},
+						},
+					},
+					Fields: []*resolve.Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("start"),
+							Position: resolve.Position{
+								Line:   3,
+								Column: 4,
+							},
+							Value: &resolve.Object{
+								Nullable: true,
+								Fields: []*resolve.Field{
+									{
+										Name: []byte("code"),
+										Value: &resolve.String{
+											Path:     []string{"code"},
+											Nullable: true,
+										},
+										Position: resolve.Position{
+											Line:   4,
+											Column: 5,
+										},
+									},
+								},
+							},
+						},
+						{
+							BufferID:  1,
+							HasBuffer: true,
+							Name:      []byte("login"),
+							Position: resolve.Position{
+								Line:   6,
+								Column: 4,
+							},
+							Value: &resolve.Object{
+								Nullable: true,
+								Fields: []*resolve.Field{
+									{
+										Name: []byte("token"),
+										Value: &resolve.String{
+											Path:     []string{"token"},
+											Nullable: true,
+										},
+										Position: resolve.Position{
+											Line:   7,
+											Column: 5,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		plan.Configuration{
+			DataSources: []plan.DataSourceConfiguration{
+				{
+					RootNodes: []plan.TypeField{
+						{
+							TypeName:   "Mutation",
+							FieldNames: []string{"postPasswordlessStart"},
+						},
+					},
+					Custom: ConfigJSON(Configuration{
+						Fetch: FetchConfiguration{
+							URL:    "https://example.com/passwordless_start",
+							Method: "POST",
+							Body:   "{{ .arguments.postPasswordlessStartInput }}",
+						},
+					}),
+					Factory: &Factory{},
+				},
+				{
+					RootNodes: []plan.TypeField{
+						{
+							TypeName:   "Mutation",
+							FieldNames: []string{"postPasswordlessLogin"},
+						},
+					},
+					Custom: ConfigJSON(Configuration{
+						Fetch: FetchConfiguration{
+							URL:    "https://example.com/passwordless_login",
+							Method: "POST",
+							Body:   "{{ .arguments.postPasswordlessLoginInput }}",
+						},
+					}),
+					Factory: &Factory{},
+				},
+			},
+			Fields: []plan.FieldConfiguration{
+				{
+					TypeName:              "Mutation",
+					FieldName:             "postPasswordlessStart",
+					DisableDefaultMapping: true,
+				},
+				{
+					TypeName:              "Mutation",
+					FieldName:             "postPasswordlessLogin",
+					DisableDefaultMapping: true,
+				},
+			},
+		},
+	))
 	t.Run("get request with duplicated argument and alias", datasourcetesting.RunTest(schema, duplicatedArgumentOperationWithAlias, "ArgumentQuery",
 		&plan.SynchronousResponsePlan{
 			Response: &resolve.GraphQLResponse{
@@ -45,6 +45,10 @@ func (p *planKindVisitor) EnterDirective(ref int) {
 		case "stream":
 			p.hasStreamDirective = true
 		}
+	case ast.NodeKindInlineFragment:
+		if directiveName == "defer" {
+			p.hasDeferDirective = true
+		}
 	}
 }
 
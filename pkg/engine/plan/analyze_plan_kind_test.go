@@ -129,6 +129,20 @@ func TestAnalyzePlanKind(t *testing.T) {
 		mustStreaming(false),
 		mustSubscription(false),
 	))
+	t.Run("query defer inline fragment", run(testDefinition, `
+		query MyQuery {
+			hero {
+				name
+				... on Droid @defer {
+					primaryFunction
+				}
+			}
+		}`,
+		"MyQuery",
+		mustNotErr(),
+		mustStreaming(true),
+		mustSubscription(false),
+	))
 	t.Run("query defer different name", run(testDefinition, `
 		query MyQuery($id: ID!) {
 			droid(id: $id){
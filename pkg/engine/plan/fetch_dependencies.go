@@ -0,0 +1,120 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/resolve"
+)
+
+var ErrFetchCycle = errors.New("plan contains a fetch cycle")
+
+// ValidateFetchDependencies walks the resolve graph of a plan and returns ErrFetchCycle
+// if a node or a fetch is reachable from itself.
+// Such a plan would make the resolver recurse until the stack overflows or wait for a fetch forever,
+// because a fetch would depend on the output of its own fetch.
+func ValidateFetchDependencies(plan Plan) error {
+	v := &fetchDependencyValidator{
+		nodes:   map[resolve.Node]struct{}{},
+		fetches: map[resolve.Fetch]struct{}{},
+	}
+	switch p := plan.(type) {
+	case *SynchronousResponsePlan:
+		if p.Response != nil {
+			return v.validateNode(p.Response.Data)
+		}
+	case *StreamingResponsePlan:
+		if p.Response == nil {
+			return nil
+		}
+		if p.Response.InitialResponse != nil {
+			if err := v.validateNode(p.Response.InitialResponse.Data); err != nil {
+				return err
+			}
+		}
+		for i := range p.Response.Patches {
+			if err := v.validateNode(p.Response.Patches[i].Value); err != nil {
+				return err
+			}
+		}
+	case *SubscriptionResponsePlan:
+		if p.Response != nil && p.Response.Response != nil {
+			return v.validateNode(p.Response.Response.Data)
+		}
+	}
+	return nil
+}
+
+type fetchDependencyValidator struct {
+	path    []string
+	nodes   map[resolve.Node]struct{}
+	fetches map[resolve.Fetch]struct{}
+}
+
+func (v *fetchDependencyValidator) cycleErr() error {
+	return fmt.Errorf("%w at path: %s", ErrFetchCycle, strings.Join(append([]string{"query"}, v.path...), "."))
+}
+
+func (v *fetchDependencyValidator) validateNode(node resolve.Node) error {
+	switch n := node.(type) {
+	case *resolve.Object:
+		if _, ok := v.nodes[n]; ok {
+			return v.cycleErr()
+		}
+		v.nodes[n] = struct{}{}
+		defer delete(v.nodes, n)
+		fetches := flattenFetch(n.Fetch, nil)
+		for i := range fetches {
+			if _, ok := v.fetches[fetches[i]]; ok {
+				return v.cycleErr()
+			}
+		}
+		for i := range fetches {
+			v.fetches[fetches[i]] = struct{}{}
+		}
+		defer func() {
+			for i := range fetches {
+				delete(v.fetches, fetches[i])
+			}
+		}()
+		for i := range n.Fields {
+			v.path = append(v.path, string(n.Fields[i].Name))
+			if err := v.validateNode(n.Fields[i].Value); err != nil {
+				return err
+			}
+			v.path = v.path[:len(v.path)-1]
+		}
+	case *resolve.Array:
+		if _, ok := v.nodes[n]; ok {
+			return v.cycleErr()
+		}
+		v.nodes[n] = struct{}{}
+		defer delete(v.nodes, n)
+		return v.validateNode(n.Item)
+	}
+	return nil
+}
+
+// flattenFetch appends the fetch and all fetches it's composed of to fetches
+func flattenFetch(fetch resolve.Fetch, fetches []resolve.Fetch) []resolve.Fetch {
+	if fetch == nil {
+		return fetches
+	}
+	fetches = append(fetches, fetch)
+	switch f := fetch.(type) {
+	case *resolve.ParallelFetch:
+		for i := range f.Fetches {
+			fetches = flattenFetch(f.Fetches[i], fetches)
+		}
+	case *resolve.SerialFetch:
+		for i := range f.Fetches {
+			fetches = flattenFetch(f.Fetches[i], fetches)
+		}
+	case *resolve.BatchFetch:
+		if f.Fetch != nil {
+			fetches = flattenFetch(f.Fetch, fetches)
+		}
+	}
+	return fetches
+}
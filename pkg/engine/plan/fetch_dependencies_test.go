@@ -0,0 +1,99 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/resolve"
+)
+
+func TestValidateFetchDependencies(t *testing.T) {
+	t.Run("plan without cycles", func(t *testing.T) {
+		userFetch := &resolve.SingleFetch{BufferId: 0}
+		reviewsFetch := &resolve.SingleFetch{BufferId: 1}
+		plan := &SynchronousResponsePlan{
+			Response: &resolve.GraphQLResponse{
+				Data: &resolve.Object{
+					Fetch: userFetch,
+					Fields: []*resolve.Field{
+						{
+							Name: []byte("me"),
+							Value: &resolve.Object{
+								Fetch: reviewsFetch,
+								Fields: []*resolve.Field{
+									{
+										Name:  []byte("reviews"),
+										Value: &resolve.Array{Item: &resolve.String{}},
+									},
+								},
+							},
+						},
+						{
+							Name: []byte("other"),
+							Value: &resolve.Object{
+								// the same fetch on a sibling is not a cycle
+								Fetch: reviewsFetch,
+							},
+						},
+					},
+				},
+			},
+		}
+		assert.NoError(t, ValidateFetchDependencies(plan))
+	})
+	t.Run("fetch depending on its own output", func(t *testing.T) {
+		userFetch := &resolve.SingleFetch{BufferId: 0}
+		reviewsFetch := &resolve.SingleFetch{BufferId: 1}
+		plan := &SynchronousResponsePlan{
+			Response: &resolve.GraphQLResponse{
+				Data: &resolve.Object{
+					Fetch: &resolve.ParallelFetch{
+						Fetches: []resolve.Fetch{userFetch, reviewsFetch},
+					},
+					Fields: []*resolve.Field{
+						{
+							Name: []byte("me"),
+							Value: &resolve.Object{
+								Fetch: userFetch,
+							},
+						},
+					},
+				},
+			},
+		}
+		err := ValidateFetchDependencies(plan)
+		assert.ErrorIs(t, err, ErrFetchCycle)
+		assert.EqualError(t, err, "plan contains a fetch cycle at path: query.me")
+	})
+	t.Run("object referencing its ancestor", func(t *testing.T) {
+		user := &resolve.Object{
+			Fetch: &resolve.SingleFetch{BufferId: 0},
+		}
+		friends := &resolve.Object{
+			Fetch: &resolve.SingleFetch{BufferId: 1},
+			Fields: []*resolve.Field{
+				{
+					Name:  []byte("user"),
+					Value: user,
+				},
+			},
+		}
+		user.Fields = []*resolve.Field{
+			{
+				Name:  []byte("friends"),
+				Value: &resolve.Array{Item: friends},
+			},
+		}
+		plan := &SubscriptionResponsePlan{
+			Response: &resolve.GraphQLSubscription{
+				Response: &resolve.GraphQLResponse{
+					Data: user,
+				},
+			},
+		}
+		err := ValidateFetchDependencies(plan)
+		assert.ErrorIs(t, err, ErrFetchCycle)
+		assert.EqualError(t, err, "plan contains a fetch cycle at path: query.friends.user")
+	})
+}
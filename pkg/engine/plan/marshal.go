@@ -0,0 +1,441 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/resolve"
+)
+
+// Marshal dumps the resolve graph of a plan as indented JSON, e.g. to snapshot test plans or to diff them across schema changes
+// The output is stable for equal plans. DataSources and other implementations of interfaces are written as their type name,
+// functions like the ShouldRetry of a RetryPolicy are omitted.
+// Plans containing a fetch cycle can't be written and return the error of ValidateFetchDependencies.
+func Marshal(plan Plan) ([]byte, error) {
+	if err := ValidateFetchDependencies(plan); err != nil {
+		return nil, err
+	}
+
+	var out jsonPlan
+	switch p := plan.(type) {
+	case *SynchronousResponsePlan:
+		out = jsonPlan{
+			Kind:          "synchronous",
+			FlushInterval: p.FlushInterval,
+			Response:      marshalResponse(p.Response),
+		}
+	case *StreamingResponsePlan:
+		out = jsonPlan{
+			Kind:          "streaming",
+			FlushInterval: p.FlushInterval,
+		}
+		if p.Response != nil {
+			out.InitialResponse = marshalResponse(p.Response.InitialResponse)
+			for _, patch := range p.Response.Patches {
+				out.Patches = append(out.Patches, jsonPatch{
+					Operation: string(patch.Operation),
+					Label:     string(patch.Label),
+					Fetch:     marshalFetch(patch.Fetch),
+					Value:     marshalNode(patch.Value),
+				})
+			}
+		}
+	case *SubscriptionResponsePlan:
+		out = jsonPlan{
+			Kind:          "subscription",
+			FlushInterval: p.FlushInterval,
+		}
+		if p.Response != nil {
+			out.Trigger = &jsonTrigger{
+				Input:         string(p.Response.Trigger.Input),
+				InputTemplate: marshalInputTemplate(p.Response.Trigger.InputTemplate),
+				Source:        typeName(p.Response.Trigger.Source),
+			}
+			out.Response = marshalResponse(p.Response.Response)
+		}
+	default:
+		return nil, fmt.Errorf("unable to marshal plan of type %T", plan)
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+type jsonPlan struct {
+	Kind            string        `json:"kind"`
+	FlushInterval   int64         `json:"flush_interval,omitempty"`
+	Trigger         *jsonTrigger  `json:"trigger,omitempty"`
+	Response        *jsonResponse `json:"response,omitempty"`
+	InitialResponse *jsonResponse `json:"initial_response,omitempty"`
+	Patches         []jsonPatch   `json:"patches,omitempty"`
+}
+
+type jsonTrigger struct {
+	Input         string             `json:"input,omitempty"`
+	InputTemplate []jsonInputSegment `json:"input_template,omitempty"`
+	Source        string             `json:"source,omitempty"`
+}
+
+type jsonResponse struct {
+	Data       *jsonNode `json:"data,omitempty"`
+	Extensions *jsonNode `json:"extensions,omitempty"`
+}
+
+type jsonPatch struct {
+	Operation string     `json:"operation,omitempty"`
+	Label     string     `json:"label,omitempty"`
+	Fetch     *jsonFetch `json:"fetch,omitempty"`
+	Value     *jsonNode  `json:"value,omitempty"`
+}
+
+type jsonNode struct {
+	Kind                 string      `json:"kind"`
+	Path                 []string    `json:"path,omitempty"`
+	Nullable             bool        `json:"nullable,omitempty"`
+	Fetch                *jsonFetch  `json:"fetch,omitempty"`
+	Fields               []jsonField `json:"fields,omitempty"`
+	Item                 *jsonNode   `json:"item,omitempty"`
+	ResolveAsynchronous  bool        `json:"resolve_asynchronous,omitempty"`
+	MaxConcurrency       int         `json:"max_concurrency,omitempty"`
+	Stream               *jsonStream `json:"stream,omitempty"`
+	Values               []string    `json:"values,omitempty"`
+	ScalarName           string      `json:"scalar_name,omitempty"`
+	Value                string      `json:"value,omitempty"`
+	Export               *jsonExport `json:"export,omitempty"`
+	CoerceFloat          bool        `json:"coerce_float,omitempty"`
+	DecimalNotation      bool        `json:"decimal_notation,omitempty"`
+	Decoding             int         `json:"decoding,omitempty"`
+	Defer                *jsonDefer  `json:"defer,omitempty"`
+	UnescapeResponseJson bool        `json:"unescape_response_json,omitempty"`
+}
+
+type jsonField struct {
+	Name                string      `json:"name"`
+	BufferID            *int        `json:"buffer_id,omitempty"`
+	OnTypeName          string      `json:"on_type_name,omitempty"`
+	OnTypeNames         []string    `json:"on_type_names,omitempty"`
+	SkipVariableName    string      `json:"skip_variable_name,omitempty"`
+	IncludeVariableName string      `json:"include_variable_name,omitempty"`
+	Defer               bool        `json:"defer,omitempty"`
+	Stream              *jsonStream `json:"stream,omitempty"`
+	Value               *jsonNode   `json:"value"`
+}
+
+type jsonStream struct {
+	InitialBatchSize int    `json:"initial_batch_size"`
+	PatchIndex       int    `json:"patch_index,omitempty"`
+	Label            string `json:"label,omitempty"`
+}
+
+type jsonDefer struct {
+	PatchIndex int `json:"patch_index"`
+}
+
+type jsonExport struct {
+	Path     []string `json:"path"`
+	AsString bool     `json:"as_string,omitempty"`
+}
+
+type jsonFetch struct {
+	Kind                      string             `json:"kind"`
+	BufferID                  int                `json:"buffer_id"`
+	DataSource                string             `json:"data_source,omitempty"`
+	DataSourceIdentifier      string             `json:"data_source_identifier,omitempty"`
+	Input                     string             `json:"input,omitempty"`
+	InputTemplate             []jsonInputSegment `json:"input_template,omitempty"`
+	DisallowSingleFlight      bool               `json:"disallow_single_flight,omitempty"`
+	DisableDataLoader         bool               `json:"disable_data_loader,omitempty"`
+	ExtractGraphqlResponse    bool               `json:"extract_graphql_response,omitempty"`
+	ExtractFederationEntities bool               `json:"extract_federation_entities,omitempty"`
+	Timeout                   string             `json:"timeout,omitempty"`
+	Retry                     *jsonRetry         `json:"retry,omitempty"`
+	MaxErrors                 int                `json:"max_errors,omitempty"`
+	BatchFactory              string             `json:"batch_factory,omitempty"`
+	Fetches                   []*jsonFetch       `json:"fetches,omitempty"`
+}
+
+type jsonRetry struct {
+	MaxAttempts int    `json:"max_attempts"`
+	BackoffBase string `json:"backoff_base"`
+}
+
+type jsonInputSegment struct {
+	Data               string   `json:"data,omitempty"`
+	VariableKind       string   `json:"variable_kind,omitempty"`
+	VariableSourcePath []string `json:"variable_source_path,omitempty"`
+	Renderer           string   `json:"renderer,omitempty"`
+	Encoding           int      `json:"encoding,omitempty"`
+	EscapeForURL       bool     `json:"escape_for_url,omitempty"`
+	Default            string   `json:"default,omitempty"`
+	ListRendering      int      `json:"list_rendering,omitempty"`
+	ListKey            string   `json:"list_key,omitempty"`
+}
+
+func marshalResponse(response *resolve.GraphQLResponse) *jsonResponse {
+	if response == nil {
+		return nil
+	}
+	return &jsonResponse{
+		Data:       marshalNode(response.Data),
+		Extensions: marshalNode(response.Extensions),
+	}
+}
+
+func marshalNode(node resolve.Node) *jsonNode {
+	switch n := node.(type) {
+	case *resolve.Object:
+		out := &jsonNode{
+			Kind:                 "object",
+			Path:                 n.Path,
+			Nullable:             n.Nullable,
+			Fetch:                marshalFetch(n.Fetch),
+			UnescapeResponseJson: n.UnescapeResponseJson,
+		}
+		for _, field := range n.Fields {
+			out.Fields = append(out.Fields, marshalField(field))
+		}
+		return out
+	case *resolve.Array:
+		out := &jsonNode{
+			Kind:                 "array",
+			Path:                 n.Path,
+			Nullable:             n.Nullable,
+			Item:                 marshalNode(n.Item),
+			ResolveAsynchronous:  n.ResolveAsynchronous,
+			MaxConcurrency:       n.MaxConcurrency,
+			UnescapeResponseJson: n.UnescapeResponseJson,
+		}
+		if n.Stream.Enabled {
+			out.Stream = &jsonStream{
+				InitialBatchSize: n.Stream.InitialBatchSize,
+				PatchIndex:       n.Stream.PatchIndex,
+				Label:            string(n.Stream.Label),
+			}
+		}
+		return out
+	case *resolve.String:
+		return &jsonNode{
+			Kind:                 "string",
+			Path:                 n.Path,
+			Nullable:             n.Nullable,
+			Export:               marshalExport(n.Export),
+			Decoding:             int(n.Decoding),
+			UnescapeResponseJson: n.UnescapeResponseJson,
+		}
+	case *resolve.Enum:
+		out := &jsonNode{
+			Kind:     "enum",
+			Path:     n.Path,
+			Nullable: n.Nullable,
+		}
+		for _, value := range n.Values {
+			out.Values = append(out.Values, string(value))
+		}
+		return out
+	case *resolve.CustomScalar:
+		return &jsonNode{
+			Kind:       "custom_scalar",
+			Path:       n.Path,
+			Nullable:   n.Nullable,
+			ScalarName: n.ScalarName,
+		}
+	case *resolve.StaticString:
+		return &jsonNode{
+			Kind:  "static_string",
+			Value: string(n.Value),
+		}
+	case *resolve.Boolean:
+		return &jsonNode{
+			Kind:     "boolean",
+			Path:     n.Path,
+			Nullable: n.Nullable,
+			Export:   marshalExport(n.Export),
+		}
+	case *resolve.Integer:
+		return &jsonNode{
+			Kind:        "integer",
+			Path:        n.Path,
+			Nullable:    n.Nullable,
+			Export:      marshalExport(n.Export),
+			CoerceFloat: n.CoerceFloat,
+		}
+	case *resolve.Float:
+		return &jsonNode{
+			Kind:            "float",
+			Path:            n.Path,
+			Nullable:        n.Nullable,
+			Export:          marshalExport(n.Export),
+			DecimalNotation: n.DecimalNotation,
+		}
+	case *resolve.Null:
+		out := &jsonNode{
+			Kind: "null",
+		}
+		if n.Defer.Enabled {
+			out.Defer = &jsonDefer{
+				PatchIndex: n.Defer.PatchIndex,
+			}
+		}
+		return out
+	case *resolve.EmptyObject:
+		return &jsonNode{
+			Kind:     "empty_object",
+			Path:     n.Path,
+			Nullable: n.Nullable,
+		}
+	case *resolve.EmptyArray:
+		return &jsonNode{
+			Kind:     "empty_array",
+			Path:     n.Path,
+			Nullable: n.Nullable,
+		}
+	case nil:
+		return nil
+	default:
+		return &jsonNode{
+			Kind: typeName(node),
+		}
+	}
+}
+
+func marshalField(field *resolve.Field) jsonField {
+	out := jsonField{
+		Name:                string(field.Name),
+		OnTypeName:          string(field.OnTypeName),
+		SkipVariableName:    field.SkipVariableName,
+		IncludeVariableName: field.IncludeVariableName,
+		Defer:               field.Defer != nil,
+		Value:               marshalNode(field.Value),
+	}
+	for i := range field.OnTypeNames {
+		out.OnTypeNames = append(out.OnTypeNames, string(field.OnTypeNames[i]))
+	}
+	if field.HasBuffer {
+		bufferID := field.BufferID
+		out.BufferID = &bufferID
+	}
+	if field.Stream != nil {
+		out.Stream = &jsonStream{
+			InitialBatchSize: field.Stream.InitialBatchSize,
+			Label:            string(field.Stream.Label),
+		}
+	}
+	return out
+}
+
+func marshalExport(export *resolve.FieldExport) *jsonExport {
+	if export == nil {
+		return nil
+	}
+	return &jsonExport{
+		Path:     export.Path,
+		AsString: export.AsString,
+	}
+}
+
+func marshalFetch(fetch resolve.Fetch) *jsonFetch {
+	switch f := fetch.(type) {
+	case *resolve.SingleFetch:
+		return marshalSingleFetch(f)
+	case *resolve.BatchFetch:
+		out := marshalSingleFetch(f.Fetch)
+		out.Kind = "batch"
+		out.BatchFactory = typeName(f.BatchFactory)
+		return out
+	case *resolve.ParallelFetch:
+		out := &jsonFetch{
+			Kind: "parallel",
+		}
+		for _, fetch := range f.Fetches {
+			out.Fetches = append(out.Fetches, marshalFetch(fetch))
+		}
+		return out
+	case *resolve.SerialFetch:
+		out := &jsonFetch{
+			Kind: "serial",
+		}
+		for _, fetch := range f.Fetches {
+			out.Fetches = append(out.Fetches, marshalFetch(fetch))
+		}
+		return out
+	case nil:
+		return nil
+	default:
+		return &jsonFetch{
+			Kind: typeName(fetch),
+		}
+	}
+}
+
+func marshalSingleFetch(fetch *resolve.SingleFetch) *jsonFetch {
+	if fetch == nil {
+		return nil
+	}
+	out := &jsonFetch{
+		Kind:                      "single",
+		BufferID:                  fetch.BufferId,
+		DataSource:                typeName(fetch.DataSource),
+		DataSourceIdentifier:      string(fetch.DataSourceIdentifier),
+		Input:                     fetch.Input,
+		InputTemplate:             marshalInputTemplate(fetch.InputTemplate),
+		DisallowSingleFlight:      fetch.DisallowSingleFlight,
+		DisableDataLoader:         fetch.DisableDataLoader,
+		ExtractGraphqlResponse:    fetch.ProcessResponseConfig.ExtractGraphqlResponse,
+		ExtractFederationEntities: fetch.ProcessResponseConfig.ExtractFederationEntities,
+		MaxErrors:                 fetch.MaxErrors,
+	}
+	if fetch.Timeout != 0 {
+		out.Timeout = fetch.Timeout.String()
+	}
+	if fetch.Retry != nil {
+		out.Retry = &jsonRetry{
+			MaxAttempts: fetch.Retry.MaxAttempts,
+			BackoffBase: fetch.Retry.BackoffBase.String(),
+		}
+	}
+	return out
+}
+
+func marshalInputTemplate(template resolve.InputTemplate) []jsonInputSegment {
+	var out []jsonInputSegment
+	for _, segment := range template.Segments {
+		switch segment.SegmentType {
+		case resolve.StaticSegmentType:
+			out = append(out, jsonInputSegment{
+				Data: string(segment.Data),
+			})
+		case resolve.VariableSegmentType:
+			out = append(out, jsonInputSegment{
+				VariableKind:       variableKindName(segment.VariableKind),
+				VariableSourcePath: segment.VariableSourcePath,
+				Renderer:           typeName(segment.Renderer),
+				Encoding:           int(segment.Encoding),
+				EscapeForURL:       segment.EscapeForURL,
+				Default:            string(segment.Default),
+				ListRendering:      int(segment.ListRendering),
+				ListKey:            string(segment.ListKey),
+			})
+		}
+	}
+	return out
+}
+
+func variableKindName(kind resolve.VariableKind) string {
+	switch kind {
+	case resolve.ContextVariableKind:
+		return "context"
+	case resolve.ObjectVariableKind:
+		return "object"
+	case resolve.HeaderVariableKind:
+		return "header"
+	default:
+		return ""
+	}
+}
+
+// typeName returns the type name of an implementation of an interface, e.g. *httpclient.Source
+func typeName(i interface{}) string {
+	if i == nil {
+		return ""
+	}
+	return fmt.Sprintf("%T", i)
+}
@@ -0,0 +1,184 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/resolve"
+)
+
+type marshalTestDataSource struct {
+	resolve.DataSource
+}
+
+func TestMarshal(t *testing.T) {
+	t.Run("synchronous plan", func(t *testing.T) {
+		plan := &SynchronousResponsePlan{
+			Response: &resolve.GraphQLResponse{
+				Data: &resolve.Object{
+					Fetch: &resolve.SingleFetch{
+						BufferId:   0,
+						DataSource: &marshalTestDataSource{},
+						InputTemplate: resolve.InputTemplate{
+							Segments: []resolve.TemplateSegment{
+								{
+									SegmentType: resolve.StaticSegmentType,
+									Data:        []byte(`{"id":`),
+								},
+								{
+									SegmentType:        resolve.VariableSegmentType,
+									VariableKind:       resolve.ContextVariableKind,
+									VariableSourcePath: []string{"id"},
+								},
+								{
+									SegmentType: resolve.StaticSegmentType,
+									Data:        []byte(`}`),
+								},
+							},
+						},
+						DataSourceIdentifier: []byte("users"),
+					},
+					Fields: []*resolve.Field{
+						{
+							Name:      []byte("user"),
+							HasBuffer: true,
+							BufferID:  0,
+							Value: &resolve.Object{
+								Path:     []string{"user"},
+								Nullable: true,
+								Fields: []*resolve.Field{
+									{
+										Name: []byte("tags"),
+										Value: &resolve.Array{
+											Path: []string{"tags"},
+											Item: &resolve.String{},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		out, err := Marshal(plan)
+		require.NoError(t, err)
+		assert.Equal(t, `{
+  "kind": "synchronous",
+  "response": {
+    "data": {
+      "kind": "object",
+      "fetch": {
+        "kind": "single",
+        "buffer_id": 0,
+        "data_source": "*plan.marshalTestDataSource",
+        "data_source_identifier": "users",
+        "input_template": [
+          {
+            "data": "{\"id\":"
+          },
+          {
+            "variable_kind": "context",
+            "variable_source_path": [
+              "id"
+            ]
+          },
+          {
+            "data": "}"
+          }
+        ]
+      },
+      "fields": [
+        {
+          "name": "user",
+          "buffer_id": 0,
+          "value": {
+            "kind": "object",
+            "path": [
+              "user"
+            ],
+            "nullable": true,
+            "fields": [
+              {
+                "name": "tags",
+                "value": {
+                  "kind": "array",
+                  "path": [
+                    "tags"
+                  ],
+                  "item": {
+                    "kind": "string"
+                  }
+                }
+              }
+            ]
+          }
+        }
+      ]
+    }
+  }
+}`, string(out))
+	})
+	t.Run("subscription plan", func(t *testing.T) {
+		plan := &SubscriptionResponsePlan{
+			Response: &resolve.GraphQLSubscription{
+				Trigger: resolve.GraphQLSubscriptionTrigger{
+					Input: []byte(`{"topic":"users"}`),
+				},
+				Response: &resolve.GraphQLResponse{
+					Data: &resolve.Object{
+						Fields: []*resolve.Field{
+							{
+								Name:  []byte("count"),
+								Value: &resolve.Integer{Path: []string{"count"}},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		out, err := Marshal(plan)
+		require.NoError(t, err)
+		assert.Equal(t, `{
+  "kind": "subscription",
+  "trigger": {
+    "input": "{\"topic\":\"users\"}"
+  },
+  "response": {
+    "data": {
+      "kind": "object",
+      "fields": [
+        {
+          "name": "count",
+          "value": {
+            "kind": "integer",
+            "path": [
+              "count"
+            ]
+          }
+        }
+      ]
+    }
+  }
+}`, string(out))
+	})
+	t.Run("plan with fetch cycle", func(t *testing.T) {
+		user := &resolve.Object{}
+		user.Fields = []*resolve.Field{
+			{
+				Name:  []byte("self"),
+				Value: user,
+			},
+		}
+		_, err := Marshal(&SynchronousResponsePlan{
+			Response: &resolve.GraphQLResponse{
+				Data: user,
+			},
+		})
+		assert.ErrorIs(t, err, ErrFetchCycle)
+	})
+}
@@ -32,6 +32,8 @@ type Configuration struct {
 	DataSources                []DataSourceConfiguration
 	Fields                     FieldConfigurations
 	Types                      TypeConfigurations
+	// IncludeInfo adds the resolve.FieldInfo to the fields of the plan, e.g. for the messages of non-null violations
+	IncludeInfo bool
 }
 
 type DirectiveConfigurations []DirectiveConfiguration
@@ -308,6 +310,16 @@ func (p *Planner) Plan(operation, definition *ast.Document, operationName string
 	// process the plan
 
 	p.planningWalker.Walk(operation, definition, report)
+	if report.HasErrors() {
+		return p.planningVisitor.plan
+	}
+
+	// validate the plan
+
+	if err := ValidateFetchDependencies(p.planningVisitor.plan); err != nil {
+		report.AddInternalError(err)
+		return nil
+	}
 
 	return p.planningVisitor.plan
 }
@@ -373,6 +385,8 @@ type Visitor struct {
 	fieldConfigs          map[int]*FieldConfiguration
 	exportedVariables     map[string]struct{}
 	skipIncludeFields     map[int]skipIncludeField
+	deferredFields        map[int]struct{}
+	deferredFragments     map[int]struct{}
 }
 
 type skipIncludeField struct {
@@ -454,8 +468,15 @@ func (v *Visitor) EnterDirective(ref int) {
 					initialBatchSize = int(v.Operation.IntValueAsInt32(value.Ref))
 				}
 			}
+			var label []byte
+			if value, ok := v.Operation.DirectiveArgumentValueByName(ref, literal.LABEL); ok {
+				if value.Kind == ast.ValueKindString {
+					label = v.Operation.StringValueContentBytes(value.Ref)
+				}
+			}
 			v.currentField.Stream = &resolve.StreamField{
 				InitialBatchSize: initialBatchSize,
+				Label:            label,
 			}
 		case "defer":
 			v.currentField.Defer = &resolve.DeferField{}
@@ -467,6 +488,8 @@ func (v *Visitor) EnterInlineFragment(ref int) {
 	directives := v.Operation.InlineFragments[ref].Directives.Refs
 	skip, skipVariableName := v.resolveSkip(directives)
 	include, includeVariableName := v.resolveInclude(directives)
+	_, deferredParent := v.deferredFragments[ref]
+	deferred := deferredParent || v.hasDeferDirective(directives)
 	set := v.Operation.InlineFragments[ref].SelectionSet
 	if set == -1 {
 		return
@@ -475,6 +498,10 @@ func (v *Visitor) EnterInlineFragment(ref int) {
 		switch v.Operation.Selections[selection].Kind {
 		case ast.SelectionKindField:
 			ref := v.Operation.Selections[selection].Ref
+			if deferred {
+				// fields of a deferred fragment get deferred individually
+				v.deferredFields[ref] = struct{}{}
+			}
 			if skip || include {
 				v.skipIncludeFields[ref] = skipIncludeField{
 					skip:                skip,
@@ -483,6 +510,11 @@ func (v *Visitor) EnterInlineFragment(ref int) {
 					includeVariableName: includeVariableName,
 				}
 			}
+		case ast.SelectionKindInlineFragment:
+			if deferred {
+				// fragments nested in a deferred fragment defer their fields as well
+				v.deferredFragments[v.Operation.Selections[selection].Ref] = struct{}{}
+			}
 		}
 	}
 }
@@ -522,11 +554,13 @@ func (v *Visitor) EnterField(ref int) {
 				Line:   v.Operation.Fields[ref].Position.LineStart,
 				Column: v.Operation.Fields[ref].Position.CharStart,
 			},
+			Info:                    v.resolveFieldInfo(fieldName),
 			SkipDirectiveDefined:    skip,
 			SkipVariableName:        skipVariableName,
 			IncludeDirectiveDefined: include,
 			IncludeVariableName:     includeVariableName,
 		}
+		v.resolveDeferForField(ref)
 		*v.currentFields[len(v.currentFields)-1].fields = append(*v.currentFields[len(v.currentFields)-1].fields, v.currentField)
 		return
 	}
@@ -571,11 +605,13 @@ func (v *Visitor) EnterField(ref int) {
 			Line:   v.Operation.Fields[ref].Position.LineStart,
 			Column: v.Operation.Fields[ref].Position.CharStart,
 		},
+		Info:                    v.resolveFieldInfo(fieldName),
 		SkipDirectiveDefined:    skip,
 		SkipVariableName:        skipVariableName,
 		IncludeDirectiveDefined: include,
 		IncludeVariableName:     includeVariableName,
 	}
+	v.resolveDeferForField(ref)
 
 	*v.currentFields[len(v.currentFields)-1].fields = append(*v.currentFields[len(v.currentFields)-1].fields, v.currentField)
 
@@ -588,6 +624,17 @@ func (v *Visitor) EnterField(ref int) {
 	v.fieldConfigs[ref] = fieldConfig
 }
 
+// resolveFieldInfo returns the info of the current field if the configuration includes it
+func (v *Visitor) resolveFieldInfo(fieldName []byte) *resolve.FieldInfo {
+	if !v.Config.IncludeInfo {
+		return nil
+	}
+	return &resolve.FieldInfo{
+		Name:           fieldName,
+		ParentTypeName: v.Walker.EnclosingTypeDefinition.NameBytes(v.Definition),
+	}
+}
+
 func (v *Visitor) resolveSkipForField(ref int) (bool, string) {
 	skipInclude, ok := v.skipIncludeFields[ref]
 	if ok {
@@ -604,6 +651,21 @@ func (v *Visitor) resolveIncludeForField(ref int) (bool, string) {
 	return v.resolveInclude(v.Operation.Fields[ref].Directives.Refs)
 }
 
+func (v *Visitor) resolveDeferForField(ref int) {
+	if _, ok := v.deferredFields[ref]; ok {
+		v.currentField.Defer = &resolve.DeferField{}
+	}
+}
+
+func (v *Visitor) hasDeferDirective(directiveRefs []int) bool {
+	for _, i := range directiveRefs {
+		if v.Operation.DirectiveNameString(i) == "defer" {
+			return true
+		}
+	}
+	return false
+}
+
 func (v *Visitor) resolveSkip(directiveRefs []int) (bool, string) {
 	for _, i := range directiveRefs {
 		if v.Operation.DirectiveNameString(i) != "skip" {
@@ -926,6 +988,8 @@ func (v *Visitor) EnterDocument(operation, definition *ast.Document) {
 	v.fieldConfigs = map[int]*FieldConfiguration{}
 	v.exportedVariables = map[string]struct{}{}
 	v.skipIncludeFields = map[int]skipIncludeField{}
+	v.deferredFields = map[int]struct{}{}
+	v.deferredFragments = map[int]struct{}{}
 }
 
 func (v *Visitor) LeaveDocument(operation, definition *ast.Document) {
@@ -1165,6 +1229,18 @@ func (v *Visitor) configureObjectFetch(config objectFetchConfiguration) {
 		config.object.Fetch = fetch
 		return
 	}
+	if v.isMutationRootObject(config.object) {
+		// the root fields of a mutation must be executed serially in the order of the operation
+		switch existing := config.object.Fetch.(type) {
+		case *resolve.SerialFetch:
+			existing.Fetches = append(existing.Fetches, fetch)
+		default:
+			config.object.Fetch = &resolve.SerialFetch{
+				Fetches: []resolve.Fetch{existing, fetch},
+			}
+		}
+		return
+	}
 	switch existing := config.object.Fetch.(type) {
 	case *resolve.SingleFetch:
 		copyOfExisting := *existing
@@ -1183,6 +1259,14 @@ func (v *Visitor) configureObjectFetch(config objectFetchConfiguration) {
 	}
 }
 
+func (v *Visitor) isMutationRootObject(object *resolve.Object) bool {
+	if v.Operation.OperationDefinitions[v.operationDefinition].OperationType != ast.OperationTypeMutation {
+		return false
+	}
+	plan, ok := v.plan.(*SynchronousResponsePlan)
+	return ok && plan.Response != nil && plan.Response.Data == object
+}
+
 func (v *Visitor) configureFetch(internal objectFetchConfiguration, external FetchConfiguration) resolve.Fetch {
 	dataSourceType := reflect.TypeOf(external.DataSource).String()
 	dataSourceType = strings.TrimPrefix(dataSourceType, "*")
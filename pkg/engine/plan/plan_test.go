@@ -76,7 +76,7 @@ func TestPlanner_Plan(t *testing.T) {
 				friends @stream {
 					name
 				}
-				friendsWithInitialBatch: friends @stream(initialBatchSize: 5) {
+				friendsWithInitialBatch: friends @stream(initialBatchSize: 5, label: "friendsWithInitialBatch") {
 					name
 				}
 				primaryFunction
@@ -155,6 +155,7 @@ func TestPlanner_Plan(t *testing.T) {
 									},
 									Stream: &resolve.StreamField{
 										InitialBatchSize: 5,
+										Label:            []byte("friendsWithInitialBatch"),
 									},
 									Value: &resolve.Array{
 										Nullable: true,
@@ -208,6 +209,180 @@ func TestPlanner_Plan(t *testing.T) {
 		DefaultFlushIntervalMillis: 0,
 	}))
 
+	t.Run("defer inline fragment", test(testDefinition, `
+		query MyQuery {
+			hero {
+				name
+				... on Droid @defer {
+					primaryFunction
+				}
+			}
+		}
+	`, "MyQuery", &SynchronousResponsePlan{
+		Response: &resolve.GraphQLResponse{
+			Data: &resolve.Object{
+				Fields: []*resolve.Field{
+					{
+						Name: []byte("hero"),
+						Position: resolve.Position{
+							Line:   3,
+							Column: 4,
+						},
+						Value: &resolve.Object{
+							Path:     []string{"hero"},
+							Nullable: true,
+							Fields: []*resolve.Field{
+								{
+									Name: []byte("name"),
+									Value: &resolve.String{
+										Path: []string{"name"},
+									},
+									Position: resolve.Position{
+										Line:   4,
+										Column: 5,
+									},
+								},
+								{
+									Name:       []byte("primaryFunction"),
+									OnTypeName: []byte("Droid"),
+									Defer:      &resolve.DeferField{},
+									Value: &resolve.String{
+										Path: []string{"primaryFunction"},
+									},
+									Position: resolve.Position{
+										Line:   6,
+										Column: 6,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, Configuration{}))
+
+	t.Run("defer nested inline fragment", test(testDefinition, `
+		query MyQuery($withFunction: Boolean!) {
+			hero {
+				name
+				... @defer {
+					... on Droid @include(if: $withFunction) {
+						primaryFunction
+					}
+				}
+			}
+		}
+	`, "MyQuery", &SynchronousResponsePlan{
+		Response: &resolve.GraphQLResponse{
+			Data: &resolve.Object{
+				Fields: []*resolve.Field{
+					{
+						Name: []byte("hero"),
+						Position: resolve.Position{
+							Line:   3,
+							Column: 4,
+						},
+						Value: &resolve.Object{
+							Path:     []string{"hero"},
+							Nullable: true,
+							Fields: []*resolve.Field{
+								{
+									Name: []byte("name"),
+									Value: &resolve.String{
+										Path: []string{"name"},
+									},
+									Position: resolve.Position{
+										Line:   4,
+										Column: 5,
+									},
+								},
+								{
+									Name:       []byte("primaryFunction"),
+									OnTypeName: []byte("Droid"),
+									Defer:      &resolve.DeferField{},
+									Value: &resolve.String{
+										Path: []string{"primaryFunction"},
+									},
+									Position: resolve.Position{
+										Line:   7,
+										Column: 7,
+									},
+									IncludeDirectiveDefined: true,
+									IncludeVariableName:     "withFunction",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, Configuration{}))
+
+	t.Run("include info", test(testDefinition, `
+		query MyQuery {
+			hero {
+				heroName: name
+				... on Droid {
+					primaryFunction
+				}
+			}
+		}
+	`, "MyQuery", &SynchronousResponsePlan{
+		Response: &resolve.GraphQLResponse{
+			Data: &resolve.Object{
+				Fields: []*resolve.Field{
+					{
+						Name: []byte("hero"),
+						Position: resolve.Position{
+							Line:   3,
+							Column: 4,
+						},
+						Info: &resolve.FieldInfo{
+							Name:           []byte("hero"),
+							ParentTypeName: []byte("Query"),
+						},
+						Value: &resolve.Object{
+							Path:     []string{"hero"},
+							Nullable: true,
+							Fields: []*resolve.Field{
+								{
+									Name: []byte("heroName"),
+									Value: &resolve.String{
+										Path: []string{"name"},
+									},
+									Position: resolve.Position{
+										Line:   4,
+										Column: 5,
+									},
+									Info: &resolve.FieldInfo{
+										Name:           []byte("name"),
+										ParentTypeName: []byte("Character"),
+									},
+								},
+								{
+									Name:       []byte("primaryFunction"),
+									OnTypeName: []byte("Droid"),
+									Value: &resolve.String{
+										Path: []string{"primaryFunction"},
+									},
+									Position: resolve.Position{
+										Line:   6,
+										Column: 6,
+									},
+									Info: &resolve.FieldInfo{
+										Name:           []byte("primaryFunction"),
+										ParentTypeName: []byte("Droid"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, Configuration{IncludeInfo: true}))
+
 	t.Run("operation selection", func(t *testing.T) {
 		t.Run("should successfully plan a single named query by providing an operation name", test(testDefinition, `
 				query MyHero {
@@ -358,11 +533,11 @@ var expectedMyHeroPlanWithFragment = &SynchronousResponsePlan{
 
 const testDefinition = `
 
-directive @defer on FIELD
+directive @defer on FIELD | INLINE_FRAGMENT
 
 directive @flushInterval(milliSeconds: Int!) on QUERY | SUBSCRIPTION
 
-directive @stream(initialBatchSize: Int) on FIELD
+directive @stream(initialBatchSize: Int, label: String) on FIELD
 
 union SearchResult = Human | Droid | Starship
 
@@ -0,0 +1,167 @@
+package resolve
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/buger/jsonparser"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/lexer/literal"
+	"github.com/jensneuse/graphql-go-tools/pkg/pool"
+)
+
+type cacheBypassKey struct{}
+
+// withCacheBypass marks the context of a fetch whose response must not be cached, e.g. of a mutation
+func withCacheBypass(ctx context.Context) context.Context {
+	if ctx == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func isCacheBypassed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// CachingConfig configures a CachingDataSource
+type CachingConfig struct {
+	// TTL is the time a response is served from the cache after it was loaded
+	TTL time.Duration
+	// MaxEntries limits the number of cached responses, the oldest response is evicted to make room for a new one
+	// 0 means no limit, expired responses are still removed when a new response is added
+	MaxEntries int
+	// CacheErrors enables caching of responses containing GraphQL errors
+	// Responses of a failed Load are never cached.
+	CacheErrors bool
+}
+
+type cacheEntry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+	element   *list.Element
+}
+
+// CachingDataSource caches the responses of a DataSource for a limited time
+// Responses are keyed by the identifier of the DataSource and the input, a cached response is written without calling Load.
+// Fetches which disallow singleFlight, e.g. mutations, always call Load and their responses aren't cached.
+// It's safe for concurrent use, concurrent misses of the same input load concurrently, combine it with singleFlight to avoid that.
+type CachingDataSource struct {
+	source     DataSource
+	identifier []byte
+	config     CachingConfig
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	// order keeps the entries in the order they were added, the front is evicted first
+	order *list.List
+}
+
+// NewCachingDataSource wraps the DataSource with a cache
+// The identifier must be unique per upstream, usually it's the DataSourceIdentifier of the SingleFetch.
+func NewCachingDataSource(source DataSource, identifier []byte, config CachingConfig) *CachingDataSource {
+	return &CachingDataSource{
+		source:     source,
+		identifier: identifier,
+		config:     config,
+		now:        time.Now,
+		entries:    map[string]*cacheEntry{},
+		order:      list.New(),
+	}
+}
+
+func (c *CachingDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	if isCacheBypassed(ctx) {
+		return c.source.Load(ctx, input, w)
+	}
+
+	key := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(key)
+	key.Write(c.identifier)
+	key.Write(literal.SPACE)
+	key.Write(input)
+
+	if data, ok := c.get(key.Bytes()); ok {
+		_, err = w.Write(data)
+		return
+	}
+
+	buf := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(buf)
+
+	err = c.source.Load(ctx, input, buf)
+	if err != nil {
+		_, _ = w.Write(buf.Bytes())
+		return
+	}
+
+	if c.config.CacheErrors || !hasGraphQLErrors(buf.Bytes()) {
+		c.set(key.Bytes(), buf.Bytes())
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return
+}
+
+func (c *CachingDataSource) get(key []byte) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[string(key)]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(entry)
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func (c *CachingDataSource) set(key, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[string(key)]; ok {
+		c.remove(entry)
+	}
+	now := c.now()
+	// all entries share the TTL, so the entries expire in the order they were added
+	for front := c.order.Front(); front != nil && !now.Before(front.Value.(*cacheEntry).expiresAt); front = c.order.Front() {
+		c.remove(front.Value.(*cacheEntry))
+	}
+	for c.config.MaxEntries > 0 && len(c.entries) >= c.config.MaxEntries {
+		c.remove(c.order.Front().Value.(*cacheEntry))
+	}
+
+	entry := &cacheEntry{
+		key:       string(key),
+		data:      append([]byte(nil), data...),
+		expiresAt: now.Add(c.config.TTL),
+	}
+	entry.element = c.order.PushBack(entry)
+	c.entries[entry.key] = entry
+}
+
+func (c *CachingDataSource) remove(entry *cacheEntry) {
+	c.order.Remove(entry.element)
+	delete(c.entries, entry.key)
+}
+
+// hasGraphQLErrors returns true if the response contains a non empty errors array
+func hasGraphQLErrors(response []byte) bool {
+	hasErrors := false
+	_, _ = jsonparser.ArrayEach(response, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		hasErrors = true
+	}, "errors")
+	return hasErrors
+}
@@ -0,0 +1,168 @@
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/fastbuffer"
+)
+
+type _countingDataSource struct {
+	loads    int
+	response func(input []byte) string
+}
+
+func (c *_countingDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	c.loads++
+	_, err = w.Write([]byte(c.response(input)))
+	return
+}
+
+func TestCachingDataSource(t *testing.T) {
+	newSource := func() *_countingDataSource {
+		return &_countingDataSource{response: func(input []byte) string {
+			return `{"data":{"input":` + string(input) + `}}`
+		}}
+	}
+	load := func(t *testing.T, dataSource DataSource, input string) string {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, dataSource.Load(context.Background(), []byte(input), buf))
+		return buf.String()
+	}
+
+	t.Run("hit", func(t *testing.T) {
+		source := newSource()
+		cache := NewCachingDataSource(source, []byte("users-service"), CachingConfig{TTL: time.Minute})
+
+		assert.Equal(t, `{"data":{"input":{"id":1}}}`, load(t, cache, `{"id":1}`))
+		assert.Equal(t, `{"data":{"input":{"id":1}}}`, load(t, cache, `{"id":1}`))
+		assert.Equal(t, 1, source.loads)
+	})
+
+	t.Run("miss", func(t *testing.T) {
+		source := newSource()
+		cache := NewCachingDataSource(source, []byte("users-service"), CachingConfig{TTL: time.Minute})
+
+		assert.Equal(t, `{"data":{"input":{"id":1}}}`, load(t, cache, `{"id":1}`))
+		assert.Equal(t, `{"data":{"input":{"id":2}}}`, load(t, cache, `{"id":2}`))
+		assert.Equal(t, 2, source.loads)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		source := newSource()
+		cache := NewCachingDataSource(source, []byte("users-service"), CachingConfig{TTL: time.Minute})
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+
+		load(t, cache, `{"id":1}`)
+		now = now.Add(59 * time.Second)
+		load(t, cache, `{"id":1}`)
+		assert.Equal(t, 1, source.loads)
+
+		now = now.Add(time.Second)
+		load(t, cache, `{"id":1}`)
+		assert.Equal(t, 2, source.loads)
+	})
+
+	t.Run("expired responses are removed when adding a response", func(t *testing.T) {
+		source := newSource()
+		cache := NewCachingDataSource(source, []byte("users-service"), CachingConfig{TTL: time.Minute})
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+
+		load(t, cache, `{"id":1}`)
+		load(t, cache, `{"id":2}`)
+		now = now.Add(30 * time.Second)
+		load(t, cache, `{"id":3}`)
+		assert.Equal(t, 3, len(cache.entries))
+
+		now = now.Add(30 * time.Second)
+		load(t, cache, `{"id":4}`)
+		assert.Equal(t, 2, len(cache.entries))
+		assert.Equal(t, 2, cache.order.Len())
+		assert.Contains(t, cache.entries, "users-service "+`{"id":3}`)
+		assert.Contains(t, cache.entries, "users-service "+`{"id":4}`)
+	})
+
+	t.Run("max entries evicts the oldest response", func(t *testing.T) {
+		source := newSource()
+		cache := NewCachingDataSource(source, []byte("users-service"), CachingConfig{TTL: time.Minute, MaxEntries: 2})
+
+		load(t, cache, `{"id":1}`)
+		load(t, cache, `{"id":2}`)
+		load(t, cache, `{"id":3}`)
+		assert.Equal(t, 3, source.loads)
+		assert.Equal(t, 2, len(cache.entries))
+
+		load(t, cache, `{"id":3}`)
+		assert.Equal(t, 3, source.loads)
+		load(t, cache, `{"id":1}`)
+		assert.Equal(t, 4, source.loads)
+	})
+
+	t.Run("error responses", func(t *testing.T) {
+		failing := func() *_countingDataSource {
+			return &_countingDataSource{response: func(input []byte) string {
+				return `{"errors":[{"message":"not found"}],"data":null}`
+			}}
+		}
+
+		t.Run("aren't cached by default", func(t *testing.T) {
+			source := failing()
+			cache := NewCachingDataSource(source, []byte("users-service"), CachingConfig{TTL: time.Minute})
+			load(t, cache, `{"id":1}`)
+			load(t, cache, `{"id":1}`)
+			assert.Equal(t, 2, source.loads)
+		})
+		t.Run("are cached if configured", func(t *testing.T) {
+			source := failing()
+			cache := NewCachingDataSource(source, []byte("users-service"), CachingConfig{TTL: time.Minute, CacheErrors: true})
+			load(t, cache, `{"id":1}`)
+			assert.Equal(t, `{"errors":[{"message":"not found"}],"data":null}`, load(t, cache, `{"id":1}`))
+			assert.Equal(t, 1, source.loads)
+		})
+	})
+
+	t.Run("data sources with different identifiers don't share responses", func(t *testing.T) {
+		source := newSource()
+		users := NewCachingDataSource(source, []byte("users-service"), CachingConfig{TTL: time.Minute})
+		accounts := NewCachingDataSource(source, []byte("accounts-service"), CachingConfig{TTL: time.Minute})
+
+		load(t, users, `{"id":1}`)
+		load(t, accounts, `{"id":1}`)
+		assert.Equal(t, 2, source.loads)
+	})
+
+	t.Run("writes bypass the cache", func(t *testing.T) {
+		source := newSource()
+		cache := NewCachingDataSource(source, []byte("users-service"), CachingConfig{TTL: time.Minute})
+		fetcher := NewFetcher(true)
+
+		fetch := func(disallowSingleFlight bool) string {
+			preparedInput := fastbuffer.New()
+			preparedInput.WriteBytes([]byte(`{"id":1}`))
+			buf := NewBufPair()
+			err := fetcher.Fetch(NewContext(context.Background()), &SingleFetch{
+				DataSource:           cache,
+				DataSourceIdentifier: []byte("users-service"),
+				DisallowSingleFlight: disallowSingleFlight,
+			}, preparedInput, buf)
+			assert.NoError(t, err)
+			return string(buf.Data.Bytes())
+		}
+
+		assert.Equal(t, `{"data":{"input":{"id":1}}}`, fetch(true))
+		assert.Equal(t, `{"data":{"input":{"id":1}}}`, fetch(true))
+		assert.Equal(t, 2, source.loads)
+		assert.Equal(t, 0, len(cache.entries))
+
+		fetch(false)
+		fetch(false)
+		assert.Equal(t, 3, source.loads)
+	})
+}
@@ -1,6 +1,7 @@
 package resolve
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -150,7 +151,8 @@ type dataLoader struct {
 }
 
 // Load fetches concurrently data for all siblings.
-func (d *dataLoader) Load(ctx *Context, fetch *SingleFetch, responsePair *BufPair) (err error) {
+// The siblings are loaded with loadCtx, the context of the fetch which is loaded first.
+func (d *dataLoader) Load(ctx *Context, loadCtx context.Context, fetch *SingleFetch, responsePair *BufPair) (err error) {
 	var fetchResult fetchState
 	var resultPair *BufPair
 
@@ -174,7 +176,7 @@ func (d *dataLoader) Load(ctx *Context, fetch *SingleFetch, responsePair *BufPai
 		}
 
 		pair := d.getResultBufPair()
-		err = d.fetcher.Fetch(ctx, fetch, buf.Data, pair)
+		err = d.fetcher.fetch(ctx, loadCtx, fetch, buf.Data, pair)
 		fetchResult = &singleFetchState{
 			fetchErrors: []error{err},
 			results:     []*BufPair{pair},
@@ -192,7 +194,7 @@ func (d *dataLoader) Load(ctx *Context, fetch *SingleFetch, responsePair *BufPai
 		return err
 	}
 
-	if fetchResult, err = d.resolveSingleFetch(ctx, fetch, fetchParams); err != nil {
+	if fetchResult, err = d.resolveSingleFetch(ctx, loadCtx, fetch, fetchParams); err != nil {
 		return err
 	}
 
@@ -205,7 +207,7 @@ func (d *dataLoader) Load(ctx *Context, fetch *SingleFetch, responsePair *BufPai
 }
 
 // LoadBatch builds and resolve batch request for all siblings.
-func (d *dataLoader) LoadBatch(ctx *Context, batchFetch *BatchFetch, responsePair *BufPair) (err error) {
+func (d *dataLoader) LoadBatch(ctx *Context, loadCtx context.Context, batchFetch *BatchFetch, responsePair *BufPair) (err error) {
 	var fetchResult fetchState
 	var resultPair *BufPair
 	fetchResult, ok := d.getFetchState(batchFetch.Fetch.BufferId)
@@ -227,7 +229,7 @@ func (d *dataLoader) LoadBatch(ctx *Context, batchFetch *BatchFetch, responsePai
 		return err
 	}
 
-	if fetchResult, err = d.resolveBatchFetch(ctx, batchFetch, fetchParams); err != nil {
+	if fetchResult, err = d.resolveBatchFetch(ctx, loadCtx, batchFetch, fetchParams); err != nil {
 		return err
 	}
 
@@ -238,7 +240,7 @@ func (d *dataLoader) LoadBatch(ctx *Context, batchFetch *BatchFetch, responsePai
 	return
 }
 
-func (d *dataLoader) resolveBatchFetch(ctx *Context, batchFetch *BatchFetch, fetchParams [][]byte) (fetchState *batchFetchState, err error) {
+func (d *dataLoader) resolveBatchFetch(ctx *Context, loadCtx context.Context, batchFetch *BatchFetch, fetchParams [][]byte) (fetchState *batchFetchState, err error) {
 	inputBufs := make([]*fastbuffer.FastBuffer, 0, len(fetchParams))
 
 	bufSlice := d.resourceProvider.getBufPairSlicePool()
@@ -264,7 +266,7 @@ func (d *dataLoader) resolveBatchFetch(ctx *Context, batchFetch *BatchFetch, fet
 
 	fetchState = &batchFetchState{}
 
-	if err = d.fetcher.FetchBatch(ctx, batchFetch, inputBufs, results); err != nil {
+	if err = d.fetcher.fetchBatch(ctx, loadCtx, batchFetch, inputBufs, results); err != nil {
 		fetchState.fetchError = err
 		return fetchState, nil
 	}
@@ -274,7 +276,7 @@ func (d *dataLoader) resolveBatchFetch(ctx *Context, batchFetch *BatchFetch, fet
 	return fetchState, nil
 }
 
-func (d *dataLoader) resolveSingleFetch(ctx *Context, fetch *SingleFetch, fetchParams [][]byte) (fetchState *singleFetchState, err error) {
+func (d *dataLoader) resolveSingleFetch(ctx *Context, loadCtx context.Context, fetch *SingleFetch, fetchParams [][]byte) (fetchState *singleFetchState, err error) {
 	wg := d.resourceProvider.getWaitGroup()
 	defer d.resourceProvider.freeWaitGroup(wg)
 
@@ -301,7 +303,7 @@ func (d *dataLoader) resolveSingleFetch(ctx *Context, fetch *SingleFetch, fetchP
 		pair := d.getResultBufPair()
 
 		go func(pos int, pair *BufPair) {
-			err := d.fetcher.Fetch(ctx, fetch, bufPair.Data, pair)
+			err := d.fetcher.fetch(ctx, loadCtx, fetch, bufPair.Data, pair)
 			resultCh <- fetchResult{result: pair, err: err, pos: pos}
 			wg.Done()
 		}(i, pair)
@@ -41,7 +41,7 @@ func TestDataLoader_Load(t *testing.T) {
 			t.Helper()
 
 			bufPair := NewBufPair()
-			err := dl.Load(ctx, fetch, bufPair)
+			err := dl.Load(ctx, ctx.Context, fetch, bufPair)
 			assert.NoError(t, err)
 			assert.Equal(t, expectedOutput, bufPair.Data.String())
 			ctrl.Finish()
@@ -62,7 +62,7 @@ func TestDataLoader_Load(t *testing.T) {
 			t.Helper()
 
 			bufPair := NewBufPair()
-			err := dl.Load(ctx, fetch, bufPair)
+			err := dl.Load(ctx, ctx.Context, fetch, bufPair)
 			assert.EqualError(t, err, expectedErr)
 			ctrl.Finish()
 		}
@@ -188,6 +188,7 @@ func TestDataLoader_Load(t *testing.T) {
 		bufPair := NewBufPair()
 		err := dl.Load(
 			&Context{Context: context.Background(), lastFetchID: 1, responseElements: []string{"someProp"}},
+			context.Background(),
 			&SingleFetch{
 				BufferId: 2,
 				InputTemplate: InputTemplate{
@@ -446,7 +447,7 @@ func TestDataLoader_LoadBatch(t *testing.T) {
 
 		return func(t *testing.T) {
 			bufPair := NewBufPair()
-			err := dl.LoadBatch(ctx, fetch, bufPair)
+			err := dl.LoadBatch(ctx, ctx.Context, fetch, bufPair)
 			assert.NoError(t, err)
 			assert.Equal(t, expectedOutput, bufPair.Data.String())
 			ctrl.Finish()
@@ -583,6 +584,7 @@ func TestDataLoader_LoadBatch(t *testing.T) {
 
 		err := dl.LoadBatch(
 			&Context{Context: context.Background(), lastFetchID: 1, responseElements: []string{"someProp"}},
+			context.Background(),
 			&BatchFetch{
 				Fetch: &SingleFetch{
 					BufferId: 2,
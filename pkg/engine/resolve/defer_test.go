@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/buger/jsonparser"
 	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -138,6 +139,11 @@ func TestJsonPatch(t *testing.T) {
 	patched, err = p2.Apply(patched)
 	assert.NoError(t, err)
 
+	hasNext, err := jsonparser.GetBoolean(patched, "hasNext")
+	assert.NoError(t, err)
+	assert.False(t, hasNext)
+	patched = jsonparser.Delete(patched, "hasNext")
+
 	expectedBytes, err := ioutil.ReadFile("./testdata/response_without_defer.json")
 	assert.NoError(t, err)
 	assert.JSONEq(t, string(expectedBytes), string(patched))
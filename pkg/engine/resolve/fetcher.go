@@ -1,6 +1,10 @@
 package resolve
 
 import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
 	"hash"
 	"sync"
 
@@ -48,6 +52,11 @@ func NewFetcher(enableSingleFlightLoader bool) *Fetcher {
 }
 
 func (f *Fetcher) Fetch(ctx *Context, fetch *SingleFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) (err error) {
+	return f.fetch(ctx, ctx.Context, fetch, preparedInput, buf)
+}
+
+// fetch loads the fetch with loadCtx, e.g. the context of its span or timeout, instead of the context of ctx
+func (f *Fetcher) fetch(ctx *Context, loadCtx context.Context, fetch *SingleFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) (err error) {
 	dataBuf := pool.BytesBuffer.Get()
 	defer pool.BytesBuffer.Put(dataBuf)
 
@@ -55,25 +64,23 @@ func (f *Fetcher) Fetch(ctx *Context, fetch *SingleFetch, preparedInput *fastbuf
 		ctx.beforeFetchHook.OnBeforeFetch(f.hookCtx(ctx), preparedInput.Bytes())
 	}
 
-	if !f.EnableSingleFlightLoader || fetch.DisallowSingleFlight {
-		err = fetch.DataSource.Load(ctx.Context, preparedInput.Bytes(), dataBuf)
-		extractResponse(dataBuf.Bytes(), buf, fetch.ProcessResponseConfig)
-
-		if ctx.afterFetchHook != nil {
-			if buf.HasData() {
-				ctx.afterFetchHook.OnData(f.hookCtx(ctx), buf.Data.Bytes(), false)
-			}
-			if buf.HasErrors() {
-				ctx.afterFetchHook.OnError(f.hookCtx(ctx), buf.Errors.Bytes(), false)
-			}
+	if !f.EnableSingleFlightLoader || fetch.DisallowSingleFlight || !ctx.singleFlightAllowed() {
+		if fetch.DisallowSingleFlight {
+			// write operations must not be served from or stored in a CachingDataSource
+			loadCtx = withCacheBypass(loadCtx)
 		}
+		err = ctx.dataSource(fetch).Load(loadCtx, preparedInput.Bytes(), dataBuf)
+		err = processResponse(fetch, dataBuf.Bytes(), buf, err)
+		f.afterFetch(ctx, buf, false)
 		return
 	}
 
-	hash64 := f.getHash64()
-	_, _ = hash64.Write(preparedInput.Bytes())
-	fetchID := hash64.Sum64()
-	f.putHash64(hash64)
+	fetchID := f.singleFlightKey(ctx, fetch, preparedInput.Bytes())
+
+	var done <-chan struct{}
+	if loadCtx != nil {
+		done = loadCtx.Done()
+	}
 
 	f.inflightFetchMu.Lock()
 	inflight, ok := f.inflightFetches[fetchID]
@@ -81,51 +88,63 @@ func (f *Fetcher) Fetch(ctx *Context, fetch *SingleFetch, preparedInput *fastbuf
 		inflight.waitFree.Add(1)
 		defer inflight.waitFree.Done()
 		f.inflightFetchMu.Unlock()
-		inflight.waitLoad.Wait()
+		// waiting fetches don't depend on the context of the loading fetch, they give up once their own context is done
+		select {
+		case <-inflight.loaded:
+		case <-done:
+			return loadCtx.Err()
+		}
+		f.afterFetch(ctx, &inflight.bufPair, true)
 		if inflight.bufPair.HasData() {
-			if ctx.afterFetchHook != nil {
-				ctx.afterFetchHook.OnData(f.hookCtx(ctx), inflight.bufPair.Data.Bytes(), true)
-			}
 			buf.Data.WriteBytes(inflight.bufPair.Data.Bytes())
 		}
 		if inflight.bufPair.HasErrors() {
-			if ctx.afterFetchHook != nil {
-				ctx.afterFetchHook.OnError(f.hookCtx(ctx), inflight.bufPair.Errors.Bytes(), true)
-			}
 			buf.Errors.WriteBytes(inflight.bufPair.Errors.Bytes())
 		}
 		return inflight.err
 	}
 
 	inflight = f.getInflightFetch()
-	inflight.waitLoad.Add(1)
 	f.inflightFetches[fetchID] = inflight
 
 	f.inflightFetchMu.Unlock()
 
-	err = fetch.DataSource.Load(ctx.Context, preparedInput.Bytes(), dataBuf)
-	extractResponse(dataBuf.Bytes(), &inflight.bufPair, fetch.ProcessResponseConfig)
+	if done != nil {
+		// a Load which doesn't return after the context is done must not block later fetches with the same key,
+		// so the fetch is removed from the inflight fetches, later fetches start a new load instead of waiting for it
+		go func(loaded <-chan struct{}) {
+			select {
+			case <-done:
+				f.inflightFetchMu.Lock()
+				select {
+				case <-loaded:
+					// the load completed meanwhile, the inflight fetch might already be reused for another fetch
+				default:
+					if f.inflightFetches[fetchID] == inflight {
+						delete(f.inflightFetches, fetchID)
+					}
+				}
+				f.inflightFetchMu.Unlock()
+			case <-loaded:
+			}
+		}(inflight.loaded)
+	}
+
+	err = ctx.dataSource(fetch).Load(loadCtx, preparedInput.Bytes(), dataBuf)
+	err = processResponse(fetch, dataBuf.Bytes(), &inflight.bufPair, err)
 	inflight.err = err
 
+	f.afterFetch(ctx, &inflight.bufPair, false)
 	if inflight.bufPair.HasData() {
-		if ctx.afterFetchHook != nil {
-			ctx.afterFetchHook.OnData(f.hookCtx(ctx), inflight.bufPair.Data.Bytes(), false)
-		}
 		buf.Data.WriteBytes(inflight.bufPair.Data.Bytes())
 	}
 
 	if inflight.bufPair.HasErrors() {
-		if ctx.afterFetchHook != nil {
-			ctx.afterFetchHook.OnError(f.hookCtx(ctx), inflight.bufPair.Errors.Bytes(), true)
-		}
 		buf.Errors.WriteBytes(inflight.bufPair.Errors.Bytes())
 	}
 
-	inflight.waitLoad.Done()
-
-	f.inflightFetchMu.Lock()
-	delete(f.inflightFetches, fetchID)
-	f.inflightFetchMu.Unlock()
+	close(inflight.loaded)
+	f.removeInflightFetch(fetchID, inflight)
 
 	go func() {
 		inflight.waitFree.Wait()
@@ -136,6 +155,10 @@ func (f *Fetcher) Fetch(ctx *Context, fetch *SingleFetch, preparedInput *fastbuf
 }
 
 func (f *Fetcher) FetchBatch(ctx *Context, fetch *BatchFetch, preparedInputs []*fastbuffer.FastBuffer, bufs []*BufPair) (err error) {
+	return f.fetchBatch(ctx, ctx.Context, fetch, preparedInputs, bufs)
+}
+
+func (f *Fetcher) fetchBatch(ctx *Context, loadCtx context.Context, fetch *BatchFetch, preparedInputs []*fastbuffer.FastBuffer, bufs []*BufPair) (err error) {
 	inputs := make([][]byte, len(preparedInputs))
 	for i := range preparedInputs {
 		inputs[i] = preparedInputs[i].Bytes()
@@ -149,7 +172,7 @@ func (f *Fetcher) FetchBatch(ctx *Context, fetch *BatchFetch, preparedInputs []*
 	buf := f.getBufPair()
 	defer f.freeBufPair(buf)
 
-	if err = f.Fetch(ctx, fetch.Fetch, batch.Input(), buf); err != nil {
+	if err = f.fetch(ctx, loadCtx, fetch.Fetch, batch.Input(), buf); err != nil {
 		return err
 	}
 
@@ -160,6 +183,53 @@ func (f *Fetcher) FetchBatch(ctx *Context, fetch *BatchFetch, preparedInputs []*
 	return
 }
 
+// fetchDataSourceBatch loads the fetches of a BatchDataSource with a single call to LoadBatch
+// The response of each fetch is processed according to its ProcessResponseConfig and written into the buffer of the fetch,
+// fetchErrs[i] is set to the error of the i-th fetch. Other than with Fetch, the results are not shared through singleFlight.
+func (f *Fetcher) fetchDataSourceBatch(ctx *Context, loadCtx context.Context, source BatchDataSource, fetches []*SingleFetch, preparedInputs []*fastbuffer.FastBuffer, bufs []*BufPair, fetchErrs []error) (err error) {
+	inputs := make([][]byte, len(fetches))
+	responses := make([]*BufPair, len(fetches))
+	for i := range fetches {
+		inputs[i] = preparedInputs[i].Bytes()
+		if ctx.beforeFetchHook != nil {
+			ctx.beforeFetchHook.OnBeforeFetch(f.hookCtx(ctx), inputs[i])
+		}
+		responses[i] = f.getBufPair()
+	}
+	defer func() {
+		for i := range responses {
+			f.freeBufPair(responses[i])
+		}
+	}()
+
+	err = source.LoadBatch(loadCtx, inputs, responses)
+
+	for i := range fetches {
+		fetchErrs[i] = processResponse(fetches[i], responses[i].Data.Bytes(), bufs[i], err)
+		if responses[i].HasErrors() {
+			if bufs[i].HasErrors() {
+				bufs[i].Errors.WriteBytes(comma)
+			}
+			bufs[i].Errors.WriteBytes(responses[i].Errors.Bytes())
+		}
+		f.afterFetch(ctx, bufs[i], false)
+	}
+	return
+}
+
+// processResponse extracts the response loaded for a fetch into buf
+// A response which isn't valid JSON isn't extracted, the fetch fails with errInvalidUpstreamJSON instead.
+func processResponse(fetch *SingleFetch, response []byte, buf *BufPair, loadErr error) error {
+	if len(response) != 0 && !json.Valid(response) {
+		if loadErr != nil {
+			return loadErr
+		}
+		return fmt.Errorf("%w: %s", errInvalidUpstreamJSON, fetch.DataSourceIdentifier)
+	}
+	extractResponse(response, buf, fetch.ProcessResponseConfig)
+	return loadErr
+}
+
 func (f *Fetcher) getBufPair() *BufPair {
 	return f.bufPairPool.Get().(*BufPair)
 }
@@ -170,7 +240,18 @@ func (f *Fetcher) freeBufPair(buf *BufPair) {
 }
 
 func (f *Fetcher) getInflightFetch() *inflightFetch {
-	return f.inflightFetchPool.Get().(*inflightFetch)
+	inflight := f.inflightFetchPool.Get().(*inflightFetch)
+	inflight.loaded = make(chan struct{})
+	return inflight
+}
+
+// removeInflightFetch removes the inflight fetch unless the key got taken over by a later fetch already
+func (f *Fetcher) removeInflightFetch(fetchID uint64, inflight *inflightFetch) {
+	f.inflightFetchMu.Lock()
+	if f.inflightFetches[fetchID] == inflight {
+		delete(f.inflightFetches, fetchID)
+	}
+	f.inflightFetchMu.Unlock()
 }
 
 func (f *Fetcher) freeInflightFetch(inflightFetch *inflightFetch) {
@@ -180,12 +261,64 @@ func (f *Fetcher) freeInflightFetch(inflightFetch *inflightFetch) {
 	f.inflightFetchPool.Put(inflightFetch)
 }
 
+// afterFetch calls the AfterFetchHook with the result of a fetch
+// A result without data and errors, e.g. from a silent upstream, is passed to OnData as empty output.
+func (f *Fetcher) afterFetch(ctx *Context, buf *BufPair, singleFlight bool) {
+	if ctx.afterFetchHook == nil {
+		return
+	}
+	if buf.HasData() {
+		ctx.afterFetchHook.OnData(f.hookCtx(ctx), buf.Data.Bytes(), singleFlight)
+	}
+	if buf.HasErrors() {
+		ctx.afterFetchHook.OnError(f.hookCtx(ctx), buf.Errors.Bytes(), singleFlight)
+	}
+	if !buf.HasData() && !buf.HasErrors() {
+		ctx.afterFetchHook.OnData(f.hookCtx(ctx), buf.Data.Bytes(), singleFlight)
+	}
+}
+
 func (f *Fetcher) hookCtx(ctx *Context) HookContext {
 	return HookContext{
-		CurrentPath: ctx.path(),
+		CurrentPath:    ctx.path(),
+		TracingEnabled: ctx.EnableTracing,
 	}
 }
 
+// singleFlightKey identifies fetches which can share a single load
+// Next to the input, the values of the SingleFlightKeyHeaders are part of the key,
+// so that fetches with e.g. different credentials don't share their responses.
+// The DataSourceIdentifier and the namespace of the Context keep data sources of different schemas apart.
+func (f *Fetcher) singleFlightKey(ctx *Context, fetch *SingleFetch, input []byte) uint64 {
+	hash64 := f.getHash64()
+	defer f.putHash64(hash64)
+
+	// every part is prefixed with its length, so that adjacent parts can't run into each other
+	writeSingleFlightKeyPart(hash64, ctx.singleFlightNamespace)
+	writeSingleFlightKeyPart(hash64, fetch.DataSourceIdentifier)
+	writeSingleFlightKeyPart(hash64, input)
+	for _, name := range fetch.SingleFlightKeyHeaders {
+		values := ctx.Request.Header.Values(name)
+		writeSingleFlightKeyPart(hash64, []byte(name))
+		writeSingleFlightKeyLength(hash64, len(values))
+		for _, value := range values {
+			writeSingleFlightKeyPart(hash64, []byte(value))
+		}
+	}
+	return hash64.Sum64()
+}
+
+func writeSingleFlightKeyPart(hash64 hash.Hash64, part []byte) {
+	writeSingleFlightKeyLength(hash64, len(part))
+	_, _ = hash64.Write(part)
+}
+
+func writeSingleFlightKeyLength(hash64 hash.Hash64, length int) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(length))
+	_, _ = hash64.Write(buf[:])
+}
+
 func (f *Fetcher) getHash64() hash.Hash64 {
 	return f.hash64Pool.Get().(hash.Hash64)
 }
@@ -0,0 +1,34 @@
+package resolve
+
+import (
+	"context"
+)
+
+// acquireFetch blocks until one of the MaxConcurrentFetches slots of the Resolver is free
+// It returns the error of the context if it's done before, in that case the fetch must not be loaded.
+func (r *Resolver) acquireFetch(ctx context.Context) error {
+	if r.MaxConcurrentFetches <= 0 {
+		return nil
+	}
+	r.fetchSlotsOnce.Do(func() {
+		r.fetchSlots = make(chan struct{}, r.MaxConcurrentFetches)
+	})
+	if ctx == nil {
+		r.fetchSlots <- struct{}{}
+		return nil
+	}
+	select {
+	case r.fetchSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseFetch frees the slot of a fetch acquired with acquireFetch
+func (r *Resolver) releaseFetch() {
+	if r.MaxConcurrentFetches <= 0 {
+		return
+	}
+	<-r.fetchSlots
+}
@@ -0,0 +1,126 @@
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// _concurrencyTrackingDataSource records the highest number of concurrent loads
+type _concurrencyTrackingDataSource struct {
+	delay             time.Duration
+	current, maxLoads int64
+}
+
+func (c *_concurrencyTrackingDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	current := atomic.AddInt64(&c.current, 1)
+	defer atomic.AddInt64(&c.current, -1)
+	for {
+		max := atomic.LoadInt64(&c.maxLoads)
+		if current <= max || atomic.CompareAndSwapInt64(&c.maxLoads, max, current) {
+			break
+		}
+	}
+	time.Sleep(c.delay)
+	_, err = w.Write([]byte(`{"value":"ok"}`))
+	return
+}
+
+// wideParallelFetchResponse has a root object with width fields, each loaded by its own fetch of a ParallelFetch
+func wideParallelFetchResponse(source DataSource, width int) *GraphQLResponse {
+	fetches := make([]Fetch, 0, width)
+	fields := make([]*Field, 0, width)
+	for i := 0; i < width; i++ {
+		fetches = append(fetches, &SingleFetch{
+			BufferId:   i,
+			DataSource: source,
+		})
+		fields = append(fields, &Field{
+			BufferID:  i,
+			HasBuffer: true,
+			Name:      []byte("field" + strconv.Itoa(i)),
+			Value: &String{
+				Path: []string{"value"},
+			},
+		})
+	}
+	return &GraphQLResponse{
+		Data: &Object{
+			Fetch: &ParallelFetch{
+				Fetches: fetches,
+			},
+			Fields: fields,
+		},
+	}
+}
+
+func TestResolver_MaxConcurrentFetches(t *testing.T) {
+	t.Run("limits concurrent loads", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.MaxConcurrentFetches = 2
+
+		source := &_concurrencyTrackingDataSource{delay: 5 * time.Millisecond}
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), wideParallelFetchResponse(source, 6), nil, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"field0":"ok","field1":"ok","field2":"ok","field3":"ok","field4":"ok","field5":"ok"}}`, buf.String())
+		assert.LessOrEqual(t, source.maxLoads, int64(2))
+	})
+
+	t.Run("waiting for a slot respects the context", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.MaxConcurrentFetches = 1
+
+		assert.NoError(t, r.acquireFetch(context.Background()))
+
+		ctx, cancelFetch := context.WithCancel(context.Background())
+		cancelFetch()
+		assert.Equal(t, context.Canceled, r.acquireFetch(ctx))
+
+		r.releaseFetch()
+		assert.NoError(t, r.acquireFetch(context.Background()))
+		r.releaseFetch()
+	})
+}
+
+func BenchmarkResolver_MaxConcurrentFetches(b *testing.B) {
+	const maxConcurrentFetches = 8
+
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newResolver(rCtx, false, false)
+	r.MaxConcurrentFetches = maxConcurrentFetches
+
+	source := &_concurrencyTrackingDataSource{delay: 50 * time.Microsecond}
+	response := wideParallelFetchResponse(source, 16)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	// every goroutine resolves a response with 16 parallel fetches, so the burst starts far more loads than the limit
+	b.RunParallel(func(pb *testing.PB) {
+		buf := &bytes.Buffer{}
+		for pb.Next() {
+			buf.Reset()
+			if err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.StopTimer()
+
+	maxLoads := atomic.LoadInt64(&source.maxLoads)
+	b.ReportMetric(float64(maxLoads), "max-concurrent-loads")
+	if maxLoads > maxConcurrentFetches {
+		b.Fatalf("%d concurrent loads exceed the limit of %d", maxLoads, maxConcurrentFetches)
+	}
+}
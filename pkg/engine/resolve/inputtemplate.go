@@ -2,11 +2,15 @@ package resolve
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"net/url"
+	"strings"
 
 	"github.com/buger/jsonparser"
 	"github.com/jensneuse/graphql-go-tools/pkg/fastbuffer"
 	"github.com/jensneuse/graphql-go-tools/pkg/lexer/literal"
+	"github.com/jensneuse/graphql-go-tools/pkg/pool"
 )
 
 type SegmentType int
@@ -16,12 +20,57 @@ const (
 	VariableSegmentType
 )
 
+// EncodingKind is the encoding of a variable rendered into an InputTemplate or of a String value returned by an upstream
+type EncodingKind int
+
+const (
+	EncodingNone EncodingKind = iota
+	EncodingBase64
+	EncodingBase64URL
+)
+
+func (e EncodingKind) encoding() *base64.Encoding {
+	switch e {
+	case EncodingBase64:
+		return base64.StdEncoding
+	case EncodingBase64URL:
+		return base64.URLEncoding
+	default:
+		return nil
+	}
+}
+
+// ListRenderingKind defines how list variables are rendered into an InputTemplate
+type ListRenderingKind int
+
+const (
+	// ListRenderingJSONArray renders lists as JSON arrays, e.g. ["a","b"]
+	ListRenderingJSONArray ListRenderingKind = iota
+	// ListRenderingCommaSeparated renders the items of lists separated by commas, e.g. a,b
+	ListRenderingCommaSeparated
+	// ListRenderingRepeated renders each item of lists as query parameter with the ListKey of the segment, e.g. id=a&id=b
+	ListRenderingRepeated
+)
+
 type TemplateSegment struct {
 	SegmentType        SegmentType
 	Data               []byte
 	VariableKind       VariableKind
 	VariableSourcePath []string
 	Renderer           VariableRenderer
+	// Encoding encodes the value of the variable before it's rendered, the encoded value is rendered as a string
+	Encoding EncodingKind
+	// EscapeForURL percent-encodes the rendered variable, e.g. for variables in the path or query of a URL
+	// Values which are already percent-encoded are written as is, null or missing values are rendered empty.
+	EscapeForURL bool
+	// Default is written verbatim instead of the variable when the variable is missing, e.g. null, 0 or ""
+	// Without a default, missing object and context variables render as null and missing headers render empty.
+	Default []byte
+	// ListRendering defines how a list variable is rendered, other than JSON arrays the items are rendered as plain text
+	// Null or missing lists render empty, a single value which isn't a list is rendered like a list with one item.
+	ListRendering ListRenderingKind
+	// ListKey is the key of the query parameter repeated for each item with ListRenderingRepeated
+	ListKey []byte
 }
 
 type InputTemplate struct {
@@ -34,15 +83,10 @@ func (i *InputTemplate) Render(ctx *Context, data []byte, preparedInput *fastbuf
 		case StaticSegmentType:
 			preparedInput.WriteBytes(i.Segments[j].Data)
 		case VariableSegmentType:
-			switch i.Segments[j].VariableKind {
-			case ObjectVariableKind:
-				err = i.renderObjectVariable(ctx, data, i.Segments[j], preparedInput)
-			case ContextVariableKind:
-				err = i.renderContextVariable(ctx, i.Segments[j], preparedInput)
-			case HeaderVariableKind:
-				err = i.renderHeaderVariable(ctx, i.Segments[j].VariableSourcePath, preparedInput)
-			default:
-				err = fmt.Errorf("InputTemplate.Render: cannot resolve variable of kind: %d", i.Segments[j].VariableKind)
+			if i.Segments[j].EscapeForURL && i.Segments[j].ListRendering == ListRenderingJSONArray {
+				err = i.renderURLEscapedVariable(ctx, data, i.Segments[j], preparedInput)
+			} else {
+				err = i.renderVariable(ctx, data, i.Segments[j], preparedInput)
 			}
 			if err != nil {
 				return err
@@ -52,12 +96,69 @@ func (i *InputTemplate) Render(ctx *Context, data []byte, preparedInput *fastbuf
 	return
 }
 
+func (i *InputTemplate) renderVariable(ctx *Context, data []byte, segment TemplateSegment, preparedInput *fastbuffer.FastBuffer) error {
+	switch segment.VariableKind {
+	case ObjectVariableKind:
+		return i.renderObjectVariable(ctx, data, segment, preparedInput)
+	case ContextVariableKind:
+		return i.renderContextVariable(ctx, segment, preparedInput)
+	case HeaderVariableKind:
+		return i.renderHeaderVariable(ctx, segment, preparedInput)
+	default:
+		return fmt.Errorf("InputTemplate.Render: cannot resolve variable of kind: %d", segment.VariableKind)
+	}
+}
+
+func (i *InputTemplate) renderURLEscapedVariable(ctx *Context, data []byte, segment TemplateSegment, preparedInput *fastbuffer.FastBuffer) error {
+	buf := pool.FastBuffer.Get()
+	defer pool.FastBuffer.Put(buf)
+
+	err := i.renderVariable(ctx, data, segment, buf)
+	if err != nil {
+		return err
+	}
+
+	preparedInput.WriteString(escapeForURL(buf.String()))
+	return nil
+}
+
+// escapeForURL percent-encodes the value so that it can be used in both the path and the query of a URL
+// A value which is already percent-encoded is returned as is, so that it's not encoded twice.
+func escapeForURL(value string) string {
+	if unescaped, err := url.PathUnescape(value); err == nil && escapeURLValue(unescaped) == value {
+		return value
+	}
+	return escapeURLValue(value)
+}
+
+func escapeURLValue(value string) string {
+	// QueryEscape escapes spaces as +, which is only valid in the query of a URL
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+func (i *InputTemplate) renderNull(segment TemplateSegment, preparedInput *fastbuffer.FastBuffer) {
+	if segment.EscapeForURL || segment.ListRendering != ListRenderingJSONArray {
+		return
+	}
+	preparedInput.WriteBytes(literal.NULL)
+}
+
 func (i *InputTemplate) renderObjectVariable(ctx context.Context, variables []byte, segment TemplateSegment, preparedInput *fastbuffer.FastBuffer) error {
 	value, valueType, offset, err := jsonparser.Get(variables, segment.VariableSourcePath...)
+	if err == jsonparser.KeyPathNotFoundError && segment.Default != nil {
+		preparedInput.WriteBytes(segment.Default)
+		return nil
+	}
 	if err != nil || valueType == jsonparser.Null {
-		preparedInput.WriteBytes(literal.NULL)
+		i.renderNull(segment, preparedInput)
 		return nil
 	}
+	if segment.Encoding != EncodingNone {
+		return i.renderEncodedVariable(ctx, value, valueType, segment, preparedInput)
+	}
+	if segment.ListRendering != ListRenderingJSONArray {
+		return i.renderListVariable(value, valueType, segment, preparedInput)
+	}
 	if valueType == jsonparser.String {
 		value = variables[offset-len(value)-2:offset]
 	}
@@ -66,22 +167,106 @@ func (i *InputTemplate) renderObjectVariable(ctx context.Context, variables []by
 
 func (i *InputTemplate) renderContextVariable(ctx *Context, segment TemplateSegment, preparedInput *fastbuffer.FastBuffer) error {
 	value, valueType, offset, err := jsonparser.Get(ctx.Variables, segment.VariableSourcePath...)
+	if err == jsonparser.KeyPathNotFoundError && segment.Default != nil {
+		preparedInput.WriteBytes(segment.Default)
+		return nil
+	}
 	if err != nil || valueType == jsonparser.Null {
-		preparedInput.WriteBytes(literal.NULL)
+		i.renderNull(segment, preparedInput)
 		return nil
 	}
+	if segment.Encoding != EncodingNone {
+		return i.renderEncodedVariable(ctx, value, valueType, segment, preparedInput)
+	}
+	if segment.ListRendering != ListRenderingJSONArray {
+		return i.renderListVariable(value, valueType, segment, preparedInput)
+	}
 	if valueType == jsonparser.String {
 		value = ctx.Variables[offset-len(value)-2:offset]
 	}
 	return segment.Renderer.RenderVariable(ctx, value, preparedInput)
 }
 
-func (i *InputTemplate) renderHeaderVariable(ctx *Context, path []string, preparedInput *fastbuffer.FastBuffer) error {
+// renderListVariable renders the items of a list as plain text according to the ListRendering of the segment
+// With EscapeForURL, each item is escaped on its own so that separators stay intact.
+func (i *InputTemplate) renderListVariable(value []byte, valueType jsonparser.ValueType, segment TemplateSegment, preparedInput *fastbuffer.FastBuffer) (err error) {
+	first := true
+	renderItem := func(item []byte, itemType jsonparser.ValueType) {
+		if err != nil {
+			return
+		}
+		if itemType == jsonparser.String {
+			var unescaped string
+			if unescaped, err = jsonparser.ParseString(item); err != nil {
+				return
+			}
+			item = []byte(unescaped)
+		}
+		if segment.EscapeForURL {
+			item = []byte(escapeForURL(string(item)))
+		}
+
+		switch segment.ListRendering {
+		case ListRenderingCommaSeparated:
+			if !first {
+				preparedInput.WriteBytes(literal.COMMA)
+			}
+		case ListRenderingRepeated:
+			if !first {
+				preparedInput.WriteBytes(literal.AND)
+			}
+			preparedInput.WriteBytes(segment.ListKey)
+			preparedInput.WriteBytes(literal.EQUALS)
+		}
+		preparedInput.WriteBytes(item)
+		first = false
+	}
+
+	if valueType != jsonparser.Array {
+		renderItem(value, valueType)
+		return err
+	}
+
+	_, arrayErr := jsonparser.ArrayEach(value, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+		renderItem(item, itemType)
+	})
+	if arrayErr != nil {
+		return arrayErr
+	}
+	return err
+}
+
+// renderEncodedVariable encodes the unescaped value of a string or the raw value of any other type
+// and renders the result as a JSON string, so that renderers can handle it like any other string variable
+func (i *InputTemplate) renderEncodedVariable(ctx context.Context, value []byte, valueType jsonparser.ValueType, segment TemplateSegment, preparedInput *fastbuffer.FastBuffer) error {
+	encoding := segment.Encoding.encoding()
+	if encoding == nil {
+		return fmt.Errorf("InputTemplate.Render: unknown encoding: %d", segment.Encoding)
+	}
+	if valueType == jsonparser.String {
+		unescaped, err := jsonparser.ParseString(value)
+		if err != nil {
+			return err
+		}
+		value = []byte(unescaped)
+	}
+
+	encoded := make([]byte, encoding.EncodedLen(len(value))+2)
+	encoded[0] = '"'
+	encoding.Encode(encoded[1:len(encoded)-1], value)
+	encoded[len(encoded)-1] = '"'
+	return segment.Renderer.RenderVariable(ctx, encoded, preparedInput)
+}
+
+// renderHeaderVariable writes the values of the header named by the only element of the variable's path
+func (i *InputTemplate) renderHeaderVariable(ctx *Context, segment TemplateSegment, preparedInput *fastbuffer.FastBuffer) error {
+	path := segment.VariableSourcePath
 	if len(path) != 1 {
 		return errHeaderPathInvalid
 	}
 	value := ctx.Request.Header.Values(path[0])
 	if len(value) == 0 {
+		preparedInput.WriteBytes(segment.Default)
 		return nil
 	}
 	if len(value) == 1 {
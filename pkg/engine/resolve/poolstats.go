@@ -0,0 +1,81 @@
+package resolve
+
+import (
+	"sync/atomic"
+)
+
+// PoolStats are the usage counters of one of the object pools of the Resolver
+// Gets without a matching put point to objects which are never returned to the pool.
+type PoolStats struct {
+	// Gets is the number of objects taken from the pool
+	Gets int64
+	// Puts is the number of objects returned to the pool
+	Puts int64
+	// Allocations is the number of gets which allocated a new object because the pool had none to reuse
+	Allocations int64
+}
+
+type poolCounter struct {
+	gets, puts, allocations int64
+}
+
+// poolStats counts the usage of the pools of the Resolver once enabled
+type poolStats struct {
+	enabled      bool
+	resultSet    poolCounter
+	byteSlices   poolCounter
+	waitGroup    poolCounter
+	bufPair      poolCounter
+	bufPairSlice poolCounter
+	errChan      poolCounter
+}
+
+func (s *poolStats) get(counter *poolCounter) {
+	if s.enabled {
+		atomic.AddInt64(&counter.gets, 1)
+	}
+}
+
+func (s *poolStats) put(counter *poolCounter) {
+	if s.enabled {
+		atomic.AddInt64(&counter.puts, 1)
+	}
+}
+
+func (s *poolStats) allocate(counter *poolCounter) {
+	if s.enabled {
+		atomic.AddInt64(&counter.allocations, 1)
+	}
+}
+
+func (s *poolStats) snapshot() map[string]PoolStats {
+	counters := map[string]*poolCounter{
+		"resultSet":    &s.resultSet,
+		"byteSlices":   &s.byteSlices,
+		"waitGroup":    &s.waitGroup,
+		"bufPair":      &s.bufPair,
+		"bufPairSlice": &s.bufPairSlice,
+		"errChan":      &s.errChan,
+	}
+	stats := make(map[string]PoolStats, len(counters))
+	for name, counter := range counters {
+		stats[name] = PoolStats{
+			Gets:        atomic.LoadInt64(&counter.gets),
+			Puts:        atomic.LoadInt64(&counter.puts),
+			Allocations: atomic.LoadInt64(&counter.allocations),
+		}
+	}
+	return stats
+}
+
+// EnablePoolStats enables counting the gets, puts and allocations of the object pools of the Resolver, e.g. to tune or debug them
+// Counting is disabled by default as it adds atomic operations to the hot path. It must be enabled before resolving starts.
+func (r *Resolver) EnablePoolStats() {
+	r.poolStats.enabled = true
+}
+
+// PoolStats returns the usage counters of the object pools of the Resolver keyed by the name of the pool
+// The counters are zero unless EnablePoolStats was called. While responses are resolved the counters are approximate.
+func (r *Resolver) PoolStats() map[string]PoolStats {
+	return r.poolStats.snapshot()
+}
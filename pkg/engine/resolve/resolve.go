@@ -5,12 +5,17 @@ package resolve
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"reflect"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/buger/jsonparser"
 	"github.com/cespare/xxhash/v2"
@@ -30,7 +35,6 @@ var (
 	comma             = []byte(",")
 	colon             = []byte(":")
 	quote             = []byte("\"")
-	quotedComma       = []byte(`","`)
 	null              = []byte("null")
 	literalData       = []byte("data")
 	literalErrors     = []byte("errors")
@@ -40,14 +44,29 @@ var (
 	literalColumn     = []byte("column")
 	literalPath       = []byte("path")
 	literalExtensions = []byte("extensions")
-
-	unableToResolveMsg = []byte("unable to resolve")
-	emptyArray         = []byte("[]")
+	literalWarnings   = []byte("warnings")
+	hasNextTrue       = []byte(`,"hasNext":true`)
+	hasNextDonePatch  = []byte(`{"op":"replace","path":"/hasNext","value":false}`)
+
+	unableToResolveMsg  = []byte("unable to resolve")
+	nonNullableNullMsg  = []byte("Cannot return null for non-nullable field ")
+	upstreamTimeoutMsg  = []byte("upstream timeout")
+	deadlineExceededMsg = []byte("deadline exceeded")
+	fetchFailedMsg      = []byte("failed to fetch: ")
+	nonIntegerValueMsg  = []byte("Int cannot represent non-integer value: ")
+	nonFiniteFloatMsg   = []byte("Float cannot represent non-finite value: ")
+	invalidEnumValueMsg = []byte("Enum cannot represent value: ")
+	undecodableValueMsg = []byte("String cannot decode value: ")
+	invalidJSONMsg      = []byte("upstream returned invalid JSON")
+	emptyArray          = []byte("[]")
 )
 
 var (
 	errNonNullableFieldValueIsNull = errors.New("non Nullable field value is null")
 	errTypeNameSkipped             = errors.New("skipped because of __typename condition")
+	errInvalidUpstreamJSON         = errors.New("upstream returned invalid JSON")
+	errResponseTooLarge            = errors.New("response exceeds the max response size")
+	errDuplicateObjectKey          = errors.New("duplicate key in object")
 	errHeaderPathInvalid           = errors.New("invalid header path: header variables must be of this format: .request.header.{{ key }} ")
 
 	ErrUnableToResolve = errors.New("unable to resolve operation")
@@ -94,25 +113,49 @@ const (
 	NodeKindBoolean
 	NodeKindInteger
 	NodeKindFloat
+	NodeKindEnum
+	NodeKindCustomScalar
+	NodeKindStaticString
+)
 
+const (
 	FetchKindSingle FetchKind = iota + 1
 	FetchKindParallel
 	FetchKindBatch
+	FetchKindSerial
 )
 
 type HookContext struct {
 	CurrentPath []byte
+	// TracingEnabled is true if tracing is enabled for the request, see Context.EnableTracing
+	TracingEnabled bool
 }
 
 type BeforeFetchHook interface {
 	OnBeforeFetch(ctx HookContext, input []byte)
 }
 
+// AfterFetchHook is called with the result of each fetch
+// output contains the raw data or errors returned by the DataSource.
+// If a fetch returned neither data nor errors, OnData is called with an empty output.
+// singleFlight is true if the result was shared from an inflight fetch with the same input instead of a fresh load.
 type AfterFetchHook interface {
 	OnData(ctx HookContext, output []byte, singleFlight bool)
 	OnError(ctx HookContext, output []byte, singleFlight bool)
 }
 
+// NullValueHook is called for nullable fields resolved to null, e.g. to track the completeness of upstream data
+// OnNull is called if the upstream data contains an explicit null, OnAbsent if the key is missing entirely.
+// Scalar, enum and object fields are reported.
+type NullValueHook interface {
+	OnNull(ctx HookContext)
+	OnAbsent(ctx HookContext)
+}
+
+// DataSourceResolver returns the DataSource to load a fetch with for the current request, e.g. the endpoint of a tenant
+// defaultDataSource is the DataSource of the planned fetch, returning nil loads the fetch with it.
+type DataSourceResolver func(ctx *Context, dataSourceIdentifier []byte, defaultDataSource DataSource) DataSource
+
 type Context struct {
 	context.Context
 	Variables        []byte
@@ -128,7 +171,61 @@ type Context struct {
 	dataLoader       *dataLoader
 	beforeFetchHook  BeforeFetchHook
 	afterFetchHook   AfterFetchHook
-	position         Position
+	nullValueHook    NullValueHook
+	// dataSourceResolver overrides the DataSource of fetches per request
+	dataSourceResolver DataSourceResolver
+	position           Position
+	fieldInfo          *FieldInfo
+	// RecordDataSourcePaths enables recording which response paths got populated by which DataSource
+	// The recorded paths can be retrieved with DataSourcePaths after resolving the response
+	RecordDataSourcePaths bool
+	// EnableTracing marks the request as traced, hooks and tracing integrations read it to skip collecting timings otherwise
+	// The flag doesn't change resolving, it's passed to the hooks as HookContext.TracingEnabled.
+	EnableTracing bool
+	// StreamResponse makes ResolveGraphQLResponse write the data to the writer while it's resolved instead of buffering the whole response,
+	// which keeps the memory of large responses down. Errors and extensions are written after the data.
+	// Only the parts of the data which can't turn into null anymore are written, so objects stream if all their fields are nullable
+	// and arrays if their items are nullable. Other responses, or if Resolver.MaxResponseBytes or Resolver.DuplicateKeys are set, are buffered.
+	// If resolving fails after the data started streaming the writer has received an incomplete response, see ResponseStreamed.
+	StreamResponse        bool
+	stream                *responseStream
+	responseStreamed      bool
+	dataSourcePaths       *dataSourcePaths
+	warnings              []string
+	responseSize          *responseSize
+	extensions            []responseExtension
+	singleFlightNamespace []byte
+}
+
+// responseExtension is a custom entry of the response extensions added with AddExtension
+type responseExtension struct {
+	name  string
+	value func() ([]byte, error)
+}
+
+// responseSize tracks the bytes written to the response to enforce Resolver.MaxResponseBytes
+// It's shared between the clones of a Context, so it has to be safe for concurrent use.
+type responseSize struct {
+	written int64
+	max     int64
+}
+
+func (s *responseSize) add(n int) error {
+	if atomic.AddInt64(&s.written, int64(n)) > s.max {
+		return errResponseTooLarge
+	}
+	return nil
+}
+
+type dataSourcePaths struct {
+	mu    sync.Mutex
+	paths map[string][]string
+}
+
+func (d *dataSourcePaths) add(dataSourceIdentifier, path string) {
+	d.mu.Lock()
+	d.paths[dataSourceIdentifier] = append(d.paths[dataSourceIdentifier], path)
+	d.mu.Unlock()
 }
 
 type Request struct {
@@ -184,7 +281,19 @@ func (c *Context) Clone() Context {
 		pathPrefix:      pathPrefix,
 		beforeFetchHook: c.beforeFetchHook,
 		afterFetchHook:  c.afterFetchHook,
+		nullValueHook:   c.nullValueHook,
 		position:        c.position,
+		fieldInfo:       c.fieldInfo,
+
+		dataSourceResolver: c.dataSourceResolver,
+
+		RecordDataSourcePaths: c.RecordDataSourcePaths,
+		EnableTracing:         c.EnableTracing,
+		dataSourcePaths:       c.dataSourcePaths,
+		warnings:              c.warnings,
+		responseSize:          c.responseSize,
+		extensions:            c.extensions,
+		singleFlightNamespace: c.singleFlightNamespace,
 	}
 }
 
@@ -202,9 +311,104 @@ func (c *Context) Free() {
 	c.maxPatch = -1
 	c.beforeFetchHook = nil
 	c.afterFetchHook = nil
+	c.nullValueHook = nil
+	c.dataSourceResolver = nil
 	c.Request.Header = nil
 	c.position = Position{}
+	c.fieldInfo = nil
 	c.dataLoader = nil
+	c.RecordDataSourcePaths = false
+	c.EnableTracing = false
+	c.StreamResponse = false
+	c.stream = nil
+	c.responseStreamed = false
+	c.dataSourcePaths = nil
+	c.warnings = c.warnings[:0]
+	c.responseSize = nil
+	c.extensions = c.extensions[:0]
+	c.singleFlightNamespace = nil
+}
+
+// AddWarning adds a warning which doesn't fail the request, e.g. for the usage of a deprecated field
+// Warnings are written as "warnings" to the extensions of the response
+func (c *Context) AddWarning(message string) {
+	c.warnings = append(c.warnings, message)
+}
+
+// AddExtension adds the JSON returned by value as name to the extensions of the response
+// value is called once the data got resolved, so it may report on the resolving itself, e.g. with timings.
+func (c *Context) AddExtension(name string, value func() ([]byte, error)) {
+	c.extensions = append(c.extensions, responseExtension{
+		name:  name,
+		value: value,
+	})
+}
+
+// WithValue attaches a request scoped value, e.g. a trace id or the authenticated principal, to the embedded context.Context
+// Every DataSource receives the embedded context on Load, so the value can be read with ctx.Value(key) during the fetch.
+// Like with context.WithValue the key should be of an unexported type to avoid collisions.
+func (c *Context) WithValue(key, value interface{}) {
+	if c.Context == nil {
+		c.Context = context.Background()
+	}
+	c.Context = context.WithValue(c.Context, key, value)
+}
+
+// DataSourcePaths returns the response paths populated by each DataSource, keyed by the DataSourceIdentifier of the fetch
+// Paths are only recorded if RecordDataSourcePaths is enabled
+func (c *Context) DataSourcePaths() map[string][]string {
+	if c.dataSourcePaths == nil {
+		return nil
+	}
+	c.dataSourcePaths.mu.Lock()
+	defer c.dataSourcePaths.mu.Unlock()
+	out := make(map[string][]string, len(c.dataSourcePaths.paths))
+	for identifier, paths := range c.dataSourcePaths.paths {
+		out[identifier] = append([]string(nil), paths...)
+	}
+	return out
+}
+
+// ResponseStreamed reports whether ResolveGraphQLResponse has written parts of the data to the writer with StreamResponse enabled
+// In that case nothing else can be written to the writer if resolving failed.
+func (c *Context) ResponseStreamed() bool {
+	return c.responseStreamed
+}
+
+func (c *Context) recordDataSourcePath(fetch Fetch, bufferID int) {
+	identifier, ok := fetchDataSourceIdentifier(fetch, bufferID)
+	if !ok || len(identifier) == 0 {
+		return
+	}
+	if c.dataSourcePaths == nil {
+		c.dataSourcePaths = &dataSourcePaths{
+			paths: map[string][]string{},
+		}
+	}
+	c.dataSourcePaths.add(string(identifier), string(c.path()))
+}
+
+// fetchDataSourceIdentifier returns the DataSourceIdentifier of the fetch writing into the buffer with bufferID
+func fetchDataSourceIdentifier(fetch Fetch, bufferID int) ([]byte, bool) {
+	switch f := fetch.(type) {
+	case *SingleFetch:
+		return f.DataSourceIdentifier, f.BufferId == bufferID
+	case *BatchFetch:
+		return fetchDataSourceIdentifier(f.Fetch, bufferID)
+	case *ParallelFetch:
+		for i := range f.Fetches {
+			if identifier, ok := fetchDataSourceIdentifier(f.Fetches[i], bufferID); ok {
+				return identifier, true
+			}
+		}
+	case *SerialFetch:
+		for i := range f.Fetches {
+			if identifier, ok := fetchDataSourceIdentifier(f.Fetches[i], bufferID); ok {
+				return identifier, true
+			}
+		}
+	}
+	return nil, false
 }
 
 func (c *Context) SetBeforeFetchHook(hook BeforeFetchHook) {
@@ -215,10 +419,70 @@ func (c *Context) SetAfterFetchHook(hook AfterFetchHook) {
 	c.afterFetchHook = hook
 }
 
+// BeforeFetchHook returns the hook set with SetBeforeFetchHook, e.g. to wrap it with another hook which calls it as well
+func (c *Context) BeforeFetchHook() BeforeFetchHook {
+	return c.beforeFetchHook
+}
+
+// AfterFetchHook returns the hook set with SetAfterFetchHook, e.g. to wrap it with another hook which calls it as well
+func (c *Context) AfterFetchHook() AfterFetchHook {
+	return c.afterFetchHook
+}
+
+// SetSingleFlightNamespace sets a namespace which is part of the singleFlight key of all fetches, e.g. a hash of the schema
+// Fetches share their result through singleFlight if they have the same input and DataSourceIdentifier.
+// If a Resolver is shared between schemas, the data sources of different schemas can have the same identifier and input,
+// setting a namespace per schema prevents them from sharing results.
+func (c *Context) SetSingleFlightNamespace(namespace []byte) {
+	c.singleFlightNamespace = namespace
+}
+
+// SingleFlightNamespace returns the namespace set with SetSingleFlightNamespace
+func (c *Context) SingleFlightNamespace() []byte {
+	return c.singleFlightNamespace
+}
+
+func (c *Context) SetNullValueHook(hook NullValueHook) {
+	c.nullValueHook = hook
+}
+
+// SetDataSourceResolver sets the resolver of the DataSource of each fetch for the request
+// It allows loading from per request upstreams, e.g. per tenant, while sharing cached plans between requests.
+// The resolved DataSource might differ between requests with the same DataSourceIdentifier and input,
+// so fetches skip singleFlight unless a namespace is set, e.g. the tenant, see SetSingleFlightNamespace.
+func (c *Context) SetDataSourceResolver(resolver DataSourceResolver) {
+	c.dataSourceResolver = resolver
+}
+
+// DataSourceResolver returns the resolver set with SetDataSourceResolver
+func (c *Context) DataSourceResolver() DataSourceResolver {
+	return c.dataSourceResolver
+}
+
+// singleFlightAllowed returns false if fetches could share their result with fetches loaded from another DataSource
+func (c *Context) singleFlightAllowed() bool {
+	return c.dataSourceResolver == nil || len(c.singleFlightNamespace) != 0
+}
+
+// dataSource returns the DataSource to load the fetch with
+func (c *Context) dataSource(fetch *SingleFetch) DataSource {
+	if c.dataSourceResolver == nil {
+		return fetch.DataSource
+	}
+	if source := c.dataSourceResolver(c, fetch.DataSourceIdentifier, fetch.DataSource); source != nil {
+		return source
+	}
+	return fetch.DataSource
+}
+
 func (c *Context) setPosition(position Position) {
 	c.position = position
 }
 
+func (c *Context) setFieldInfo(info *FieldInfo) {
+	c.fieldInfo = info
+}
+
 func (c *Context) addResponseElements(elements []string) {
 	c.responseElements = append(c.responseElements, elements...)
 }
@@ -271,6 +535,54 @@ func (c *Context) path() []byte {
 	return buf.Bytes()
 }
 
+// writeErrorPath writes the current path elements as a GraphQL error path, e.g. ["todos",0,"name"].
+// Array indices are written as integers while field names (or their aliases) are written as strings.
+// As GraphQL names must not start with a digit, an element consisting of digits only is always an array index.
+func (c *Context) writeErrorPath(buf *bytes.Buffer) {
+	buf.Write(lBrack)
+	for i := range c.pathElements {
+		if i != 0 {
+			buf.Write(comma)
+		}
+		if isArrayIndexPathElement(c.pathElements[i]) {
+			buf.Write(c.pathElements[i])
+			continue
+		}
+		buf.Write(quote)
+		buf.Write(c.pathElements[i])
+		buf.Write(quote)
+	}
+	buf.Write(rBrack)
+}
+
+// writeNonNullableFieldMessage writes the error message for a null value of the non-nullable field at the current path
+// The wording follows the GraphQL reference implementation, e.g. "Cannot return null for non-nullable field User.email."
+// It needs the FieldInfo of the field, which is only planned with plan.Configuration.IncludeInfo.
+func (c *Context) writeNonNullableFieldMessage(buf *bytes.Buffer) bool {
+	if c.fieldInfo == nil || len(c.fieldInfo.ParentTypeName) == 0 || len(c.fieldInfo.Name) == 0 {
+		return false
+	}
+
+	buf.Write(nonNullableNullMsg)
+	buf.Write(c.fieldInfo.ParentTypeName)
+	buf.Write(literal.DOT)
+	buf.Write(c.fieldInfo.Name)
+	buf.Write(literal.DOT)
+	return true
+}
+
+func isArrayIndexPathElement(elem []byte) bool {
+	if len(elem) == 0 {
+		return false
+	}
+	for i := range elem {
+		if elem[i] < '0' || elem[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *Context) addPatch(index int, path, extraPath, data []byte) {
 	next := patch{path: path, extraPath: extraPath, data: data, index: index}
 	c.patches = append(c.patches, next)
@@ -309,11 +621,44 @@ type DataSource interface {
 	Load(ctx context.Context, input []byte, w io.Writer) (err error)
 }
 
+// BatchDataSource is an optional interface of a DataSource which loads several inputs at once
+// If multiple fetches of a ParallelFetch have the same DataSourceIdentifier and DataSource and it implements BatchDataSource,
+// the fetches are loaded with a single call to LoadBatch instead of a call to Load per fetch.
+// LoadBatch writes the response of inputs[i] into bufPairs[i].Data, the same way Load writes it into its writer,
+// errors of a single input can be written into bufPairs[i].Errors.
+type BatchDataSource interface {
+	LoadBatch(ctx context.Context, inputs [][]byte, bufPairs []*BufPair) error
+}
+
+// UniqueIdentifierDataSource is an optional interface of a DataSource naming the upstream it loads from
+// The DataSourceIdentifier of a fetch is shared by all data sources of the same type, e.g. all GraphQL upstreams,
+// whereas the UniqueIdentifier tells them apart, e.g. by the name of a subgraph. The spans of fetches are named by it.
+type UniqueIdentifierDataSource interface {
+	UniqueIdentifier() []byte
+}
+
 type SubscriptionDataSource interface {
 	Start(ctx context.Context, input []byte, next chan<- []byte) error
 }
 
 type Resolver struct {
+	// MaxResponseBytes limits the number of bytes a single response may produce, resolving aborts once it's exceeded
+	// The limit is disabled when set to zero
+	MaxResponseBytes int
+	// PartialResponseOnDeadline writes the parts of the response resolved before the deadline of the operation's context
+	// Fetches failing on or started after the deadline resolve their fields as null with a "deadline exceeded" error
+	PartialResponseOnDeadline bool
+	// DeduplicateErrors removes byte-identical errors from the response, e.g. when a failing fetch is fanned out across many array items
+	// Errors are compared including their path, so distinct errors are preserved
+	DeduplicateErrors bool
+	// DuplicateKeys configures how keys written more than once into the same object are handled,
+	// e.g. when fields of multiple buffers are merged into one object
+	DuplicateKeys DuplicateKeyPolicy
+	// MaxConcurrentFetches limits the number of fetches loading at the same time across all responses resolved by the Resolver,
+	// e.g. to bound the goroutines and upstream connections under bursts of requests with wide ParallelFetches
+	// Fetches wait for a free slot until their context is done. The limit is disabled when set to zero, it must be set before resolving starts.
+	MaxConcurrentFetches int
+
 	ctx               context.Context
 	dataLoaderEnabled bool
 	resultSetPool     sync.Pool
@@ -325,21 +670,82 @@ type Resolver struct {
 	hash64Pool        sync.Pool
 	dataloaderFactory *dataLoaderFactory
 	fetcher           *Fetcher
+	errorFormatter    func(raw []byte) []byte
+	durationRecorder  func(dataSourceIdentifier []byte, duration time.Duration)
+	tracer            Tracer
+	scalarCoercers    map[string]func(raw []byte) ([]byte, error)
+	poolStats         *poolStats
+	fetchSlotsOnce    sync.Once
+	fetchSlots        chan struct{}
+}
+
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysAllowed writes duplicate keys as they are, no detection takes place
+	DuplicateKeysAllowed DuplicateKeyPolicy = iota
+	// DuplicateKeysError aborts resolving with an error once a key is about to be written twice
+	DuplicateKeysError
+	// DuplicateKeysKeepLast removes the earlier value of a duplicate key, so the last value is kept
+	DuplicateKeysKeepLast
+)
+
+// objectKey is the range of a key and its value written into an object, including the preceding brace or comma
+type objectKey struct {
+	name       []byte
+	start, end int
 }
 
 type inflightFetch struct {
-	waitLoad sync.WaitGroup
+	// loaded is closed once the data of the fetch is loaded
+	loaded   chan struct{}
 	waitFree sync.WaitGroup
 	err      error
 	bufPair  BufPair
 }
 
+// SetErrorFormatter sets a hook which is invoked on each error object before it's written to the response,
+// e.g. to strip internal details or to add an extensions code
+// The formatter gets the raw JSON of the error, which must not be modified, and returns the JSON to write instead
+// If the returned value isn't a valid JSON object, the original error is written
+func (r *Resolver) SetErrorFormatter(formatter func(raw []byte) []byte) {
+	r.errorFormatter = formatter
+}
+
+// SetFetchDurationRecorder sets a hook which is invoked with the DataSourceIdentifier and the duration of each load of a fetch,
+// e.g. to feed a latency histogram per data source
+// Retries are recorded as separate loads. The recorder is called concurrently for parallel fetches.
+func (r *Resolver) SetFetchDurationRecorder(recorder func(dataSourceIdentifier []byte, duration time.Duration)) {
+	r.durationRecorder = recorder
+}
+
+// SetTracer sets the Tracer creating a span for each SingleFetch, nil restores the NoopTracer
+// The tracer is called concurrently for parallel fetches.
+func (r *Resolver) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = NoopTracer{}
+	}
+	r.tracer = tracer
+}
+
+// SetScalarCoercer registers the coercer of the custom scalar with the given name, used by CustomScalar nodes
+// The coercer gets the raw JSON value of the upstream and returns the JSON value to write instead,
+// an error resolves the field to null with a field error. Coercers must be registered before resolving starts.
+func (r *Resolver) SetScalarCoercer(scalarName string, coercer func(raw []byte) ([]byte, error)) {
+	if r.scalarCoercers == nil {
+		r.scalarCoercers = map[string]func(raw []byte) ([]byte, error){}
+	}
+	r.scalarCoercers[scalarName] = coercer
+}
+
 // New returns a new Resolver, ctx.Done() is used to cancel all active subscriptions & streams
 func New(ctx context.Context, fetcher *Fetcher, enableDataLoader bool) *Resolver {
+	stats := &poolStats{}
 	return &Resolver{
 		ctx: ctx,
 		resultSetPool: sync.Pool{
 			New: func() interface{} {
+				stats.allocate(&stats.resultSet)
 				return &resultSet{
 					buffers: make(map[int]*BufPair, 8),
 				}
@@ -347,17 +753,20 @@ func New(ctx context.Context, fetcher *Fetcher, enableDataLoader bool) *Resolver
 		},
 		byteSlicesPool: sync.Pool{
 			New: func() interface{} {
+				stats.allocate(&stats.byteSlices)
 				slice := make([][]byte, 0, 24)
 				return &slice
 			},
 		},
 		waitGroupPool: sync.Pool{
 			New: func() interface{} {
+				stats.allocate(&stats.waitGroup)
 				return &sync.WaitGroup{}
 			},
 		},
 		bufPairPool: sync.Pool{
 			New: func() interface{} {
+				stats.allocate(&stats.bufPair)
 				pair := BufPair{
 					Data:   fastbuffer.New(),
 					Errors: fastbuffer.New(),
@@ -367,12 +776,14 @@ func New(ctx context.Context, fetcher *Fetcher, enableDataLoader bool) *Resolver
 		},
 		bufPairSlicePool: sync.Pool{
 			New: func() interface{} {
+				stats.allocate(&stats.bufPairSlice)
 				slice := make([]*BufPair, 0, 24)
 				return &slice
 			},
 		},
 		errChanPool: sync.Pool{
 			New: func() interface{} {
+				stats.allocate(&stats.errChan)
 				return make(chan error, 1)
 			},
 		},
@@ -384,10 +795,21 @@ func New(ctx context.Context, fetcher *Fetcher, enableDataLoader bool) *Resolver
 		dataloaderFactory: newDataloaderFactory(fetcher),
 		fetcher:           fetcher,
 		dataLoaderEnabled: enableDataLoader,
+		tracer:            NoopTracer{},
+		poolStats:         stats,
 	}
 }
 
 func (r *Resolver) resolveNode(ctx *Context, node Node, data []byte, bufPair *BufPair) (err error) {
+	if ctx.responseSize != nil && isLeafNode(node) {
+		written := bufPair.Data.Len()
+		defer func() {
+			if err == nil {
+				err = ctx.responseSize.add(bufPair.Data.Len() - written)
+			}
+		}()
+	}
+
 	switch n := node.(type) {
 	case *Object:
 		return r.resolveObject(ctx, n, data, bufPair)
@@ -407,12 +829,17 @@ func (r *Resolver) resolveNode(ctx *Context, node Node, data []byte, bufPair *Bu
 		return r.resolveInteger(ctx, n, data, bufPair)
 	case *Float:
 		return r.resolveFloat(ctx, n, data, bufPair)
-	case *EmptyObject:
-		r.resolveEmptyObject(bufPair.Data)
+	case *Enum:
+		return r.resolveEnum(ctx, n, data, bufPair)
+	case *CustomScalar:
+		return r.resolveCustomScalar(ctx, n, data, bufPair)
+	case *StaticString:
+		r.resolveStaticString(n, bufPair.Data)
 		return
+	case *EmptyObject:
+		return r.resolveEmptyValue(ctx, n.Path, n.Nullable, data, bufPair, r.resolveEmptyObject)
 	case *EmptyArray:
-		r.resolveEmptyArray(bufPair.Data)
-		return
+		return r.resolveEmptyValue(ctx, n.Path, n.Nullable, data, bufPair, r.resolveEmptyArray)
 	default:
 		return
 	}
@@ -490,6 +917,31 @@ func (r *Resolver) ResolveGraphQLResponse(ctx *Context, response *GraphQLRespons
 		}()
 	}
 
+	if r.MaxResponseBytes > 0 {
+		ctx.responseSize = &responseSize{
+			max: int64(r.MaxResponseBytes),
+		}
+	}
+
+	if ctx.RecordDataSourcePaths && ctx.dataSourcePaths == nil {
+		// created upfront so that contexts cloned for asynchronous resolving record into the same paths
+		ctx.dataSourcePaths = &dataSourcePaths{
+			paths: map[string][]string{},
+		}
+	}
+
+	ctx.responseStreamed = false
+	if ctx.StreamResponse && r.canStreamResponse(response) {
+		ctx.stream = &responseStream{
+			writer: writer,
+			buf:    buf,
+		}
+		defer func() {
+			ctx.responseStreamed = ctx.stream.started
+			ctx.stream = nil
+		}()
+	}
+
 	ignoreData := false
 	err = r.resolveNode(ctx, response.Data, responseBuf.Data.Bytes(), buf)
 	if err != nil {
@@ -502,7 +954,58 @@ func (r *Resolver) ResolveGraphQLResponse(ctx *Context, response *GraphQLRespons
 		r.MergeBufPairErrors(responseBuf, buf)
 	}
 
-	return writeGraphqlResponse(buf, writer, ignoreData)
+	if r.DeduplicateErrors {
+		deduplicateErrors(buf)
+	}
+
+	if response.Extensions == nil && len(ctx.warnings) == 0 && len(ctx.extensions) == 0 {
+		r.formatErrors(buf)
+		return ctx.stream.writeGraphqlResponse(buf, nil, writer, ignoreData)
+	}
+
+	extensionsBuf := r.getBufPair()
+	defer r.freeBufPair(extensionsBuf)
+
+	if response.Extensions != nil {
+		err = r.resolveNode(ctx, response.Extensions, responseBuf.Data.Bytes(), extensionsBuf)
+		if err != nil && !errors.Is(err, errNonNullableFieldValueIsNull) {
+			return
+		}
+		r.MergeBufPairErrors(extensionsBuf, buf)
+	}
+	if len(ctx.warnings) != 0 {
+		err = r.addWarningsToExtensions(ctx, extensionsBuf)
+		if err != nil {
+			return
+		}
+	}
+	for i := range ctx.extensions {
+		var value []byte
+		value, err = ctx.extensions[i].value()
+		if err != nil {
+			return
+		}
+		addToExtensions(extensionsBuf, []byte(ctx.extensions[i].name), value)
+	}
+	r.formatErrors(buf)
+
+	return ctx.stream.writeGraphqlResponse(buf, extensionsBuf.Data.Bytes(), writer, ignoreData)
+}
+
+// ResolveGraphQLResponseToBytes resolves the response like ResolveGraphQLResponse and returns the JSON
+// The response is written to a pooled buffer, the returned slice is a copy which stays valid after the buffer is reused.
+func (r *Resolver) ResolveGraphQLResponseToBytes(ctx *Context, response *GraphQLResponse, data []byte) ([]byte, error) {
+	buf := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(buf)
+
+	err := r.ResolveGraphQLResponse(ctx, response, data, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	return out, nil
 }
 
 func (r *Resolver) ResolveGraphQLSubscription(ctx *Context, subscription *GraphQLSubscription, writer FlushWriter) (err error) {
@@ -542,6 +1045,9 @@ func (r *Resolver) ResolveGraphQLSubscription(ctx *Context, subscription *GraphQ
 		default:
 			data, ok := <-next
 			if !ok {
+				if completer, ok := writer.(SubscriptionCompleter); ok {
+					completer.Complete()
+				}
 				return nil
 			}
 			err = r.ResolveGraphQLResponse(ctx, subscription.Response, data, writer)
@@ -559,7 +1065,24 @@ func (r *Resolver) ResolveGraphQLStreamingResponse(ctx *Context, response *Graph
 		return err
 	}
 
-	err = r.ResolveGraphQLResponse(ctx, response.InitialResponse, data, writer)
+	initialResponse := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(initialResponse)
+
+	err = r.ResolveGraphQLResponse(ctx, response.InitialResponse, data, initialResponse)
+	if err != nil {
+		return err
+	}
+
+	// hasNext announces the patches following the initial response
+	hasNext := ctx.currentPatch < ctx.maxPatch
+	if hasNext {
+		initial := initialResponse.Bytes()
+		err = writeSafe(err, writer, initial[:len(initial)-1])
+		err = writeSafe(err, writer, hasNextTrue)
+		err = writeSafe(err, writer, rBrace)
+	} else {
+		err = writeSafe(err, writer, initialResponse.Bytes())
+	}
 	if err != nil {
 		return err
 	}
@@ -599,6 +1122,13 @@ Loop:
 				return err
 			}
 
+			if ctx.currentPatch == ctx.maxPatch {
+				// no patch is left, so the last one sets hasNext to false
+				buf.Write(literal.COMMA)
+				buf.Write(hasNextDonePatch)
+				hasNext = false
+			}
+
 			now := time.Now()
 			if now.After(nextFlush) {
 				buf.Write(literal.RBRACK)
@@ -614,6 +1144,14 @@ Loop:
 		}
 	}
 
+	if hasNext {
+		// the last patch was skipped, so hasNext is set to false on its own
+		if buf.Len() != 1 {
+			buf.Write(literal.COMMA)
+		}
+		buf.Write(hasNextDonePatch)
+	}
+
 	if buf.Len() != 1 {
 		buf.Write(literal.RBRACK)
 		_, err = writer.Write(buf.Bytes())
@@ -684,7 +1222,17 @@ func (r *Resolver) ResolveGraphQLResponsePatch(ctx *Context, patch *GraphQLRespo
 		err = writeSafe(err, writer, literal.VALUE)
 		err = writeSafe(err, writer, quote)
 		err = writeSafe(err, writer, colon)
-		_, err = writer.Write(buf.Data.Bytes())
+		err = writeSafe(err, writer, buf.Data.Bytes())
+		if len(patch.Label) != 0 {
+			err = writeSafe(err, writer, comma)
+			err = writeSafe(err, writer, quote)
+			err = writeSafe(err, writer, literal.LABEL)
+			err = writeSafe(err, writer, quote)
+			err = writeSafe(err, writer, colon)
+			// the label is written as a JSON string, so it has to be escaped
+			label, _ := json.Marshal(string(patch.Label))
+			err = writeSafe(err, writer, label)
+		}
 		err = writeSafe(err, writer, rBrace)
 	}
 
@@ -701,6 +1249,70 @@ func (r *Resolver) resolveEmptyObject(b *fastbuffer.FastBuffer) {
 	b.WriteBytes(rBrace)
 }
 
+// resolveEmptyValue writes the empty value if the path is present in the data, without a path it's written unconditionally
+func (r *Resolver) resolveEmptyValue(ctx *Context, path []string, nullable bool, data []byte, bufPair *BufPair, resolveEmpty func(b *fastbuffer.FastBuffer)) error {
+	if len(path) != 0 {
+		_, valueType, _, err := jsonparser.Get(data, path...)
+		if err != nil || valueType == jsonparser.Null {
+			if !nullable {
+				return errNonNullableFieldValueIsNull
+			}
+			r.reportNullValue(ctx, valueType, err)
+			r.resolveNull(bufPair.Data)
+			return nil
+		}
+	}
+	resolveEmpty(bufPair.Data)
+	return nil
+}
+
+func hasObjectKey(keys []objectKey, name []byte) bool {
+	for i := range keys {
+		if bytes.Equal(keys[i].name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackObjectKey adds the key which has just been written to the tracked keys of the object
+// With DuplicateKeysKeepLast, an earlier key with the same name gets removed from the object
+func (r *Resolver) trackObjectKey(data *fastbuffer.FastBuffer, keys []objectKey, key objectKey) []objectKey {
+	if r.DuplicateKeys != DuplicateKeysKeepLast {
+		return append(keys, key)
+	}
+
+	for i := range keys {
+		if !bytes.Equal(keys[i].name, key.name) {
+			continue
+		}
+
+		// the first key starts with the brace of the object, so the brace stays and the comma of the next key is removed instead
+		start, end := keys[i].start, keys[i].end
+		if i == 0 {
+			start, end = start+1, end+1
+		}
+		written := data.Bytes()
+		tail := make([]byte, len(written)-end)
+		copy(tail, written[end:])
+		data.Reset()
+		data.WriteBytes(written[:start])
+		data.WriteBytes(tail)
+
+		removed := end - start
+		for j := i + 1; j < len(keys); j++ {
+			keys[j].start -= removed
+			keys[j].end -= removed
+		}
+		key.start -= removed
+		key.end -= removed
+		keys = append(keys[:i], keys[i+1:]...)
+		break
+	}
+
+	return append(keys, key)
+}
+
 func (r *Resolver) resolveArray(ctx *Context, array *Array, data []byte, arrayBuf *BufPair) (err error) {
 	if len(array.Path) != 0 {
 		data, _, _, _ = jsonparser.Get(data, array.Path...)
@@ -716,9 +1328,11 @@ func (r *Resolver) resolveArray(ctx *Context, array *Array, data []byte, arrayBu
 	}
 
 	arrayItems := r.byteSlicesPool.Get().(*[][]byte)
+	r.poolStats.get(&r.poolStats.byteSlices)
 	defer func() {
 		*arrayItems = (*arrayItems)[:0]
 		r.byteSlicesPool.Put(arrayItems)
+		r.poolStats.put(&r.poolStats.byteSlices)
 	}()
 
 	_, err = jsonparser.ArrayEach(data, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
@@ -732,12 +1346,20 @@ func (r *Resolver) resolveArray(ctx *Context, array *Array, data []byte, arrayBu
 	if len(*arrayItems) == 0 {
 		if !array.Nullable {
 			r.resolveEmptyArray(arrayBuf.Data)
+			r.addResolveError(ctx, arrayBuf)
 			return errNonNullableFieldValueIsNull
 		}
 		r.resolveNull(arrayBuf.Data)
 		return nil
 	}
 
+	if ctx.responseSize != nil {
+		// brackets and commas
+		if err = ctx.responseSize.add(len(*arrayItems) + 1); err != nil {
+			return
+		}
+	}
+
 	ctx.addResponseArrayElements(array.Path)
 	defer func() { ctx.removeResponseArrayLastElements(array.Path) }()
 
@@ -770,17 +1392,21 @@ func (r *Resolver) resolveArraySynchronous(ctx *Context, array *Array, arrayItem
 
 		ctx.addIntegerPathElement(i)
 		err = r.resolveNode(ctx, array.Item, (*arrayItems)[i], itemBuf)
+		if errors.Is(err, errNonNullableFieldValueIsNull) && isLeafNode(array.Item) {
+			r.addResolveError(ctx, itemBuf)
+		}
 		ctx.removeLastPathElement()
 		if err != nil {
+			if errors.Is(err, errTypeNameSkipped) {
+				err = nil
+				continue
+			}
+			r.MergeBufPairErrors(itemBuf, arrayBuf)
 			if errors.Is(err, errNonNullableFieldValueIsNull) && array.Nullable {
 				arrayBuf.Data.Reset()
 				r.resolveNull(arrayBuf.Data)
 				return nil
 			}
-			if errors.Is(err, errTypeNameSkipped) {
-				err = nil
-				continue
-			}
 			return
 		}
 		dataWritten += itemBuf.Data.Len()
@@ -788,6 +1414,9 @@ func (r *Resolver) resolveArraySynchronous(ctx *Context, array *Array, arrayItem
 		if !hasPreviousItem && dataWritten != 0 {
 			hasPreviousItem = true
 		}
+		if err = ctx.stream.drain(arrayBuf); err != nil {
+			return
+		}
 	}
 
 	arrayBuf.Data.WriteBytes(rBrack)
@@ -809,20 +1438,35 @@ func (r *Resolver) resolveArrayAsynchronous(ctx *Context, array *Array, arrayIte
 
 	wg.Add(len(*arrayItems))
 
+	// sem limits the number of items resolved concurrently if MaxConcurrency is set
+	var sem chan struct{}
+	if array.MaxConcurrency > 0 && array.MaxConcurrency < len(*arrayItems) {
+		sem = make(chan struct{}, array.MaxConcurrency)
+	}
+
 	for i := range *arrayItems {
+		if sem != nil {
+			sem <- struct{}{}
+		}
 		itemBuf := r.getBufPair()
 		*bufSlice = append(*bufSlice, itemBuf)
 		itemData := (*arrayItems)[i]
 		cloned := ctx.Clone()
 		go func(ctx Context, i int) {
-			ctx.addPathElement([]byte(strconv.Itoa(i)))
+			ctx.addIntegerPathElement(i)
 			if e := r.resolveNode(&ctx, array.Item, itemData, itemBuf); e != nil && !errors.Is(e, errTypeNameSkipped) {
+				if errors.Is(e, errNonNullableFieldValueIsNull) && isLeafNode(array.Item) {
+					r.addResolveError(&ctx, itemBuf)
+				}
 				select {
 				case errCh <- e:
 				default:
 				}
 			}
 			ctx.Free()
+			if sem != nil {
+				<-sem
+			}
 			wg.Done()
 		}(cloned, i)
 	}
@@ -835,6 +1479,9 @@ func (r *Resolver) resolveArrayAsynchronous(ctx *Context, array *Array, arrayIte
 	}
 
 	if err != nil {
+		for i := range *bufSlice {
+			r.MergeBufPairErrors((*bufSlice)[i], arrayBuf)
+		}
 		if errors.Is(err, errNonNullableFieldValueIsNull) && array.Nullable {
 			arrayBuf.Data.Reset()
 			r.resolveNull(arrayBuf.Data)
@@ -843,20 +1490,32 @@ func (r *Resolver) resolveArrayAsynchronous(ctx *Context, array *Array, arrayIte
 		return
 	}
 
+	r.mergeArrayItems(*bufSlice, arrayBuf)
+	arrayBuf.Data.WriteBytes(rBrack)
+	return
+}
+
+// mergeArrayItems merges the buffers of the resolved items into the array buffer
+// The array buffer is grown to the size of all items upfront, so that large arrays don't reallocate it repeatedly.
+func (r *Resolver) mergeArrayItems(itemBufs []*BufPair, arrayBuf *BufPair) {
+	size := 0
+	for i := range itemBufs {
+		// each item may be preceded by a comma
+		size += itemBufs[i].Data.Len() + 1
+	}
+	arrayBuf.Data.Grow(size)
+
 	var (
 		hasPreviousItem bool
 		dataWritten     int
 	)
-	for i := range *bufSlice {
-		dataWritten += (*bufSlice)[i].Data.Len()
-		r.MergeBufPairs((*bufSlice)[i], arrayBuf, hasPreviousItem)
+	for i := range itemBufs {
+		dataWritten += itemBufs[i].Data.Len()
+		r.MergeBufPairs(itemBufs[i], arrayBuf, hasPreviousItem)
 		if !hasPreviousItem && dataWritten != 0 {
 			hasPreviousItem = true
 		}
 	}
-
-	arrayBuf.Data.WriteBytes(rBrack)
-	return
 }
 
 func (r *Resolver) exportField(ctx *Context, export *FieldExport, value []byte) {
@@ -875,34 +1534,97 @@ func (r *Resolver) resolveInteger(ctx *Context, integer *Integer, data []byte, i
 		if !integer.Nullable {
 			return errNonNullableFieldValueIsNull
 		}
+		r.reportNullValue(ctx, dataType, err)
 		r.resolveNull(integerBuf.Data)
 		return nil
 	}
+	if integer.CoerceFloat && bytes.ContainsAny(value, ".eE") {
+		coerced, ok := coerceFloatToInteger(value)
+		if !ok {
+			r.addInvalidValueError(ctx, nonIntegerValueMsg, value, integerBuf)
+			if !integer.Nullable {
+				return errNonNullableFieldValueIsNull
+			}
+			r.resolveNull(integerBuf.Data)
+			return nil
+		}
+		value = coerced
+	}
 	integerBuf.Data.WriteBytes(value)
 	r.exportField(ctx, integer.Export, value)
 	return nil
 }
 
+// coerceFloatToInteger returns the integer representation of a float without a fractional part
+// GraphQL Int is a 32-bit signed integer, floats outside of its range can't be coerced.
+func coerceFloatToInteger(value []byte) ([]byte, bool) {
+	float, err := strconv.ParseFloat(unsafebytes.BytesToString(value), 64)
+	if err != nil || math.Trunc(float) != float || float < math.MinInt32 || float > math.MaxInt32 {
+		return nil, false
+	}
+	return strconv.AppendInt(nil, int64(float), 10), true
+}
+
+// addInvalidValueError adds an error for a value which can't be represented by the type of the field
+// The value must be JSON escaped as it's written into the message as is.
+func (r *Resolver) addInvalidValueError(ctx *Context, message, value []byte, buf *BufPair) {
+	var pathBytes []byte
+	if len(ctx.pathElements) > 0 {
+		path := pool.BytesBuffer.Get()
+		defer pool.BytesBuffer.Put(path)
+		ctx.writeErrorPath(path)
+		pathBytes = path.Bytes()
+	}
+
+	fullMessage := make([]byte, 0, len(message)+len(value))
+	fullMessage = append(fullMessage, message...)
+	fullMessage = append(fullMessage, value...)
+	buf.WriteErr(fullMessage, nil, pathBytes, nil)
+}
+
 func (r *Resolver) resolveFloat(ctx *Context, floatValue *Float, data []byte, floatBuf *BufPair) error {
 	value, dataType, _, err := jsonparser.Get(data, floatValue.Path...)
 	if err != nil || dataType != jsonparser.Number {
 		if !floatValue.Nullable {
 			return errNonNullableFieldValueIsNull
 		}
+		r.reportNullValue(ctx, dataType, err)
 		r.resolveNull(floatBuf.Data)
 		return nil
 	}
+	if floatValue.DecimalNotation && bytes.ContainsAny(value, "eE") {
+		formatted, ok := formatFloatDecimal(value)
+		if !ok {
+			r.addInvalidValueError(ctx, nonFiniteFloatMsg, value, floatBuf)
+			if !floatValue.Nullable {
+				return errNonNullableFieldValueIsNull
+			}
+			r.resolveNull(floatBuf.Data)
+			return nil
+		}
+		value = formatted
+	}
 	floatBuf.Data.WriteBytes(value)
 	r.exportField(ctx, floatValue.Export, value)
 	return nil
 }
 
+// formatFloatDecimal returns the float in decimal notation with the shortest representation which parses back to the same float64
+func formatFloatDecimal(value []byte) ([]byte, bool) {
+	float, err := strconv.ParseFloat(unsafebytes.BytesToString(value), 64)
+	if err != nil || math.IsInf(float, 0) || math.IsNaN(float) {
+		return nil, false
+	}
+	return strconv.AppendFloat(nil, float, 'f', -1, 64), true
+}
+
 func (r *Resolver) resolveBoolean(ctx *Context, boolean *Boolean, data []byte, booleanBuf *BufPair) error {
 	value, valueType, _, err := jsonparser.Get(data, boolean.Path...)
 	if err != nil || valueType != jsonparser.Boolean {
 		if !boolean.Nullable {
 			return errNonNullableFieldValueIsNull
 		}
+		r.reportNullValue(ctx, valueType, err)
 		r.resolveNull(booleanBuf.Data)
 		return nil
 	}
@@ -930,6 +1652,7 @@ func (r *Resolver) resolveString(ctx *Context, str *String, data []byte, stringB
 		if !str.Nullable {
 			return errNonNullableFieldValueIsNull
 		}
+		r.reportNullValue(ctx, valueType, err)
 		r.resolveNull(stringBuf.Data)
 		return nil
 	}
@@ -945,6 +1668,19 @@ func (r *Resolver) resolveString(ctx *Context, str *String, data []byte, stringB
 		return nil
 	}
 
+	if str.Decoding != EncodingNone {
+		decoded, ok := decodeString(str.Decoding, value)
+		if !ok {
+			r.addInvalidValueError(ctx, undecodableValueMsg, value, stringBuf)
+			if !str.Nullable {
+				return errNonNullableFieldValueIsNull
+			}
+			r.resolveNull(stringBuf.Data)
+			return nil
+		}
+		value = decoded
+	}
+
 	stringBuf.Data.WriteBytes(quote)
 	stringBuf.Data.WriteBytes(value)
 	stringBuf.Data.WriteBytes(quote)
@@ -952,24 +1688,148 @@ func (r *Resolver) resolveString(ctx *Context, str *String, data []byte, stringB
 	return nil
 }
 
-func (r *Resolver) preparePatch(ctx *Context, patchIndex int, extraPath, data []byte) {
-	buf := pool.BytesBuffer.Get()
-	ctx.usedBuffers = append(ctx.usedBuffers, buf)
-	_, _ = buf.Write(data)
-	path, data := ctx.path(), buf.Bytes()
-	ctx.addPatch(patchIndex, path, extraPath, data)
-}
-
-func (r *Resolver) resolveNull(b *fastbuffer.FastBuffer) {
-	b.WriteBytes(null)
+// decodeString decodes a JSON escaped string value and returns the decoded value JSON escaped
+// Decoded bytes which aren't valid UTF-8, e.g. binary data, can't be represented as a String and fail to decode.
+func decodeString(kind EncodingKind, value []byte) ([]byte, bool) {
+	encoding := kind.encoding()
+	if encoding == nil {
+		return nil, false
+	}
+	unescaped, err := jsonparser.ParseString(value)
+	if err != nil {
+		return nil, false
+	}
+	decoded, err := encoding.DecodeString(unescaped)
+	if err != nil || !utf8.Valid(decoded) {
+		return nil, false
+	}
+	escaped := &bytes.Buffer{}
+	encoder := json.NewEncoder(escaped)
+	encoder.SetEscapeHTML(false)
+	if err = encoder.Encode(string(decoded)); err != nil {
+		return nil, false
+	}
+	value = bytes.TrimSuffix(escaped.Bytes(), literal.LINETERMINATOR)
+	return value[1 : len(value)-1], true
+}
+
+func (r *Resolver) resolveEnum(ctx *Context, enum *Enum, data []byte, enumBuf *BufPair) error {
+	value, valueType, _, err := jsonparser.Get(data, enum.Path...)
+	if err != nil || valueType == jsonparser.Null {
+		if !enum.Nullable {
+			return errNonNullableFieldValueIsNull
+		}
+		r.reportNullValue(ctx, valueType, err)
+		r.resolveNull(enumBuf.Data)
+		return nil
+	}
+
+	if valueType != jsonparser.String || !enum.isAllowedValue(value) {
+		if valueType != jsonparser.String {
+			// string values are already escaped, other values have to be escaped to be part of the message
+			escaped, _ := json.Marshal(string(value))
+			value = escaped[1 : len(escaped)-1]
+		}
+		r.addInvalidValueError(ctx, invalidEnumValueMsg, value, enumBuf)
+		if !enum.Nullable {
+			return errNonNullableFieldValueIsNull
+		}
+		r.resolveNull(enumBuf.Data)
+		return nil
+	}
+
+	enumBuf.Data.WriteBytes(quote)
+	enumBuf.Data.WriteBytes(value)
+	enumBuf.Data.WriteBytes(quote)
+	return nil
+}
+
+func (e *Enum) isAllowedValue(value []byte) bool {
+	for i := range e.Values {
+		if bytes.Equal(e.Values[i], value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Resolver) resolveCustomScalar(ctx *Context, scalar *CustomScalar, data []byte, scalarBuf *BufPair) error {
+	value, valueType, offset, err := jsonparser.Get(data, scalar.Path...)
+	if err != nil || valueType == jsonparser.Null {
+		if !scalar.Nullable {
+			return errNonNullableFieldValueIsNull
+		}
+		r.reportNullValue(ctx, valueType, err)
+		r.resolveNull(scalarBuf.Data)
+		return nil
+	}
+
+	if valueType == jsonparser.String {
+		// jsonparser strips the quotes of strings, the coercer gets the raw JSON value
+		value = data[offset-len(value)-2 : offset]
+	}
+
+	coercer, ok := r.scalarCoercers[scalar.ScalarName]
+	if !ok {
+		scalarBuf.Data.WriteBytes(value)
+		return nil
+	}
+
+	coerced, err := coercer(value)
+	if err != nil {
+		escaped, _ := json.Marshal(err.Error())
+		r.addInvalidValueError(ctx, nil, escaped[1:len(escaped)-1], scalarBuf)
+		if !scalar.Nullable {
+			return errNonNullableFieldValueIsNull
+		}
+		r.resolveNull(scalarBuf.Data)
+		return nil
+	}
+
+	scalarBuf.Data.WriteBytes(coerced)
+	return nil
+}
+
+func (r *Resolver) preparePatch(ctx *Context, patchIndex int, extraPath, data []byte) {
+	buf := pool.BytesBuffer.Get()
+	ctx.usedBuffers = append(ctx.usedBuffers, buf)
+	_, _ = buf.Write(data)
+	path, data := ctx.path(), buf.Bytes()
+	ctx.addPatch(patchIndex, path, extraPath, data)
+}
+
+func (r *Resolver) resolveNull(b *fastbuffer.FastBuffer) {
+	b.WriteBytes(null)
+}
+
+func (r *Resolver) resolveStaticString(str *StaticString, b *fastbuffer.FastBuffer) {
+	b.WriteBytes(quote)
+	b.WriteBytes(str.Value)
+	b.WriteBytes(quote)
+}
+
+// reportNullValue calls the NullValueHook if the value of a nullable field is explicitly null or absent in the upstream data
+// Values of the wrong type are not reported.
+func (r *Resolver) reportNullValue(ctx *Context, valueType jsonparser.ValueType, err error) {
+	if ctx.nullValueHook == nil {
+		return
+	}
+	switch {
+	case errors.Is(err, jsonparser.KeyPathNotFoundError):
+		ctx.nullValueHook.OnAbsent(HookContext{CurrentPath: ctx.path(), TracingEnabled: ctx.EnableTracing})
+	case err == nil && valueType == jsonparser.Null:
+		ctx.nullValueHook.OnNull(HookContext{CurrentPath: ctx.path(), TracingEnabled: ctx.EnableTracing})
+	}
 }
 
 func (r *Resolver) addResolveError(ctx *Context, objectBuf *BufPair) {
-	locations, path := pool.BytesBuffer.Get(), pool.BytesBuffer.Get()
+	locations, path, message := pool.BytesBuffer.Get(), pool.BytesBuffer.Get(), pool.BytesBuffer.Get()
 	defer pool.BytesBuffer.Put(locations)
 	defer pool.BytesBuffer.Put(path)
+	defer pool.BytesBuffer.Put(message)
 
 	var pathBytes []byte
+	messageBytes := unableToResolveMsg
 
 	locations.Write(lBrack)
 	locations.Write(lBrace)
@@ -988,32 +1848,63 @@ func (r *Resolver) addResolveError(ctx *Context, objectBuf *BufPair) {
 	locations.Write(rBrack)
 
 	if len(ctx.pathElements) > 0 {
-		path.Write(lBrack)
-		path.Write(quote)
-		path.Write(bytes.Join(ctx.pathElements, quotedComma))
-		path.Write(quote)
-		path.Write(rBrack)
+		ctx.writeErrorPath(path)
+		pathBytes = path.Bytes()
+		if ctx.writeNonNullableFieldMessage(message) {
+			messageBytes = message.Bytes()
+		}
+	}
 
+	objectBuf.WriteErr(messageBytes, locations.Bytes(), pathBytes, nil)
+}
+
+func (r *Resolver) addFetchError(ctx *Context, buf *BufPair, message []byte) {
+	var pathBytes []byte
+	if len(ctx.pathElements) > 0 {
+		path := pool.BytesBuffer.Get()
+		defer pool.BytesBuffer.Put(path)
+		ctx.writeErrorPath(path)
 		pathBytes = path.Bytes()
 	}
 
-	objectBuf.WriteErr(unableToResolveMsg, locations.Bytes(), pathBytes, nil)
+	buf.WriteErr(message, nil, pathBytes, nil)
+}
+
+// isLeafNode returns true for scalar nodes which can't add a resolve error for their own path
+func isLeafNode(node Node) bool {
+	switch node.(type) {
+	case *Object, *Array:
+		return false
+	default:
+		return true
+	}
 }
 
 func (r *Resolver) resolveObject(ctx *Context, object *Object, data []byte, objectBuf *BufPair) (err error) {
+	var (
+		valueType = jsonparser.Null
+		lookupErr error
+	)
 	if len(object.Path) != 0 {
-		data, _, _, _ = jsonparser.Get(data, object.Path...)
-
-		if len(data) == 0 || bytes.Equal(data, literal.NULL) {
-			if object.Nullable {
-				r.resolveNull(objectBuf.Data)
-				return
-			}
+		data, valueType, _, lookupErr = jsonparser.Get(data, object.Path...)
+	}
 
-			r.addResolveError(ctx, objectBuf)
-			return errNonNullableFieldValueIsNull
+	// without a Path the data is the value itself, e.g. the buffer of a fetch, which can be null as well
+	// Empty data without a Path isn't missing, the fields are resolved from the buffers of fetches then.
+	missing := len(data) == 0 && len(object.Path) != 0
+	if missing || bytes.Equal(data, literal.NULL) {
+		// a null object doesn't resolve its fields, so non-nullable fields don't report errors for it
+		if object.Nullable {
+			r.reportNullValue(ctx, valueType, lookupErr)
+			r.resolveNull(objectBuf.Data)
+			return
 		}
 
+		r.addResolveError(ctx, objectBuf)
+		return errNonNullableFieldValueIsNull
+	}
+
+	if len(object.Path) != 0 {
 		ctx.addResponseElements(object.Path)
 		defer ctx.removeResponseLastElements(object.Path)
 	}
@@ -1040,10 +1931,13 @@ func (r *Resolver) resolveObject(ctx *Context, object *Object, data []byte, obje
 
 	responseElements := ctx.responseElements
 	lastFetchID := ctx.lastFetchID
+	// the fields of the object replace the field info, an error for the object itself needs the info of the field holding it
+	fieldInfo := ctx.fieldInfo
 
 	typeNameSkip := false
 	first := true
 	skipCount := 0
+	var writtenKeys []objectKey
 	for i := range object.Fields {
 
 		if object.Fields[i].SkipDirectiveDefined {
@@ -1063,10 +1957,12 @@ func (r *Resolver) resolveObject(ctx *Context, object *Object, data []byte, obje
 		}
 
 		var fieldData []byte
+		fromBuffer := false
 		if set != nil && object.Fields[i].HasBuffer {
 			buffer, ok := set.buffers[object.Fields[i].BufferID]
 			if ok {
 				fieldData = buffer.Data.Bytes()
+				fromBuffer = true
 				ctx.resetResponsePathElements()
 				ctx.lastFetchID = object.Fields[i].BufferID
 			}
@@ -1074,14 +1970,19 @@ func (r *Resolver) resolveObject(ctx *Context, object *Object, data []byte, obje
 			fieldData = data
 		}
 
-		if object.Fields[i].OnTypeName != nil {
+		if object.Fields[i].OnTypeName != nil || len(object.Fields[i].OnTypeNames) != 0 {
 			typeName, _, _, _ := jsonparser.Get(fieldData, "__typename")
-			if !bytes.Equal(typeName, object.Fields[i].OnTypeName) {
+			if !object.Fields[i].matchesTypeName(typeName) {
 				typeNameSkip = true
 				continue
 			}
 		}
 
+		if r.DuplicateKeys == DuplicateKeysError && hasObjectKey(writtenKeys, object.Fields[i].Name) {
+			return fmt.Errorf("%w: %s", errDuplicateObjectKey, object.Fields[i].Name)
+		}
+		keyStart := objectBuf.Data.Len()
+
 		if first {
 			objectBuf.Data.WriteBytes(lBrace)
 			first = false
@@ -1092,9 +1993,23 @@ func (r *Resolver) resolveObject(ctx *Context, object *Object, data []byte, obje
 		objectBuf.Data.WriteBytes(object.Fields[i].Name)
 		objectBuf.Data.WriteBytes(quote)
 		objectBuf.Data.WriteBytes(colon)
+		if ctx.responseSize != nil {
+			// quoted name, colon and either brace or comma
+			if err = ctx.responseSize.add(len(object.Fields[i].Name) + 4); err != nil {
+				return
+			}
+		}
+		if err = ctx.stream.attach(objectBuf, fieldBuf, object.Fields[i].Value); err != nil {
+			return
+		}
 		ctx.addPathElement(object.Fields[i].Name)
 		ctx.setPosition(object.Fields[i].Position)
+		ctx.setFieldInfo(object.Fields[i].Info)
+		if fromBuffer && ctx.RecordDataSourcePaths {
+			ctx.recordDataSourcePath(object.Fetch, object.Fields[i].BufferID)
+		}
 		err = r.resolveNode(ctx, object.Fields[i].Value, fieldData, fieldBuf)
+		ctx.stream.detach(fieldBuf, objectBuf)
 		ctx.removeLastPathElement()
 		ctx.responseElements = responseElements
 		ctx.lastFetchID = lastFetchID
@@ -1108,20 +2023,34 @@ func (r *Resolver) resolveObject(ctx *Context, object *Object, data []byte, obje
 				objectBuf.Data.Reset()
 				r.MergeBufPairErrors(fieldBuf, objectBuf)
 
+				// objects and arrays add the resolve error for their own path,
+				// for leaf values it has to be added on their behalf
+				if isLeafNode(object.Fields[i].Value) {
+					ctx.addPathElement(object.Fields[i].Name)
+					r.addResolveError(ctx, objectBuf)
+					ctx.removeLastPathElement()
+				}
+
+				// the null bubbles up until it reaches the nearest nullable parent
 				if object.Nullable {
 					r.resolveNull(objectBuf.Data)
 					return nil
 				}
-
-				// if fied is of object type than we should not add resolve error here
-				if _, ok := object.Fields[i].Value.(*Object); !ok {
-					r.addResolveError(ctx, objectBuf)
-				}
 			}
 
 			return
 		}
 		r.MergeBufPairs(fieldBuf, objectBuf, false)
+		if r.DuplicateKeys != DuplicateKeysAllowed {
+			writtenKeys = r.trackObjectKey(objectBuf.Data, writtenKeys, objectKey{
+				name:  object.Fields[i].Name,
+				start: keyStart,
+				end:   objectBuf.Data.Len(),
+			})
+		}
+		if err = ctx.stream.drain(objectBuf); err != nil {
+			return
+		}
 	}
 	allSkipped := len(object.Fields) != 0 && len(object.Fields) == skipCount
 	if allSkipped {
@@ -1135,6 +2064,7 @@ func (r *Resolver) resolveObject(ctx *Context, object *Object, data []byte, obje
 			return errTypeNameSkipped
 		}
 		if !object.Nullable {
+			ctx.setFieldInfo(fieldInfo)
 			r.addResolveError(ctx, objectBuf)
 			return errNonNullableFieldValueIsNull
 		}
@@ -1149,9 +2079,11 @@ func (r *Resolver) freeResultSet(set *resultSet) {
 	for i := range set.buffers {
 		set.buffers[i].Reset()
 		r.bufPairPool.Put(set.buffers[i])
+		r.poolStats.put(&r.poolStats.bufPair)
 		delete(set.buffers, i)
 	}
 	r.resultSetPool.Put(set)
+	r.poolStats.put(&r.poolStats.resultSet)
 }
 
 func (r *Resolver) resolveFetch(ctx *Context, fetch Fetch, data []byte, set *resultSet) (err error) {
@@ -1165,6 +2097,7 @@ func (r *Resolver) resolveFetch(ctx *Context, fetch Fetch, data []byte, set *res
 			return err
 		}
 		err = r.resolveSingleFetch(ctx, f, preparedInput.Data, set.buffers[f.BufferId])
+		err = r.captureFetchError(ctx, set.buffers[f.BufferId], err)
 	case *BatchFetch:
 		preparedInput := r.getBufPair()
 		defer r.freeBufPair(preparedInput)
@@ -1173,23 +2106,58 @@ func (r *Resolver) resolveFetch(ctx *Context, fetch Fetch, data []byte, set *res
 			return err
 		}
 		err = r.resolveBatchFetch(ctx, f, preparedInput.Data, set.buffers[f.Fetch.BufferId])
+		err = r.captureFetchError(ctx, set.buffers[f.Fetch.BufferId], err)
 	case *ParallelFetch:
 		err = r.resolveParallelFetch(ctx, f, data, set)
+	case *SerialFetch:
+		err = r.resolveSerialFetch(ctx, f, data, set)
 	}
 	return
 }
 
+// captureFetchError turns the error of a failed fetch below the root of the response into an error of the enclosing object
+// The fields backed by the buffer of the failed fetch resolve as null, fields backed by other buffers resolve as usual.
+// Failing fetches of the root object and cancellations of the operation's context still abort the response.
+func (r *Resolver) captureFetchError(ctx *Context, buf *BufPair, err error) error {
+	if len(ctx.pathElements) == 0 {
+		return err
+	}
+	return r.captureParallelFetchError(ctx, buf, err)
+}
+
+// captureParallelFetchError turns the error of a failed fetch of a ParallelFetch into an error of the enclosing object
+// The fetches of a ParallelFetch don't depend on each other, so failures of root fetches are captured as well.
+// Only cancellations of the operation's context abort the response.
+func (r *Resolver) captureParallelFetchError(ctx *Context, buf *BufPair, err error) error {
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	escaped, marshalErr := json.Marshal(err.Error())
+	if marshalErr != nil {
+		return err
+	}
+
+	message := make([]byte, 0, len(fetchFailedMsg)+len(escaped)-2)
+	message = append(message, fetchFailedMsg...)
+	message = append(message, escaped[1:len(escaped)-1]...)
+
+	buf.Data.Reset()
+	r.addFetchError(ctx, buf, message)
+	return nil
+}
+
 func (r *Resolver) resolveParallelFetch(ctx *Context, fetch *ParallelFetch, data []byte, set *resultSet) (err error) {
 	preparedInputs := r.getBufPairSlice()
 	defer r.freeBufPairSlice(preparedInputs)
 
 	resolvers := make([]func() error, 0, len(fetch.Fetches))
+	batchOf, batches := r.dataSourceBatches(ctx, fetch)
 
 	wg := r.getWaitGroup()
 	defer r.freeWaitGroup(wg)
 
 	for i := range fetch.Fetches {
-		wg.Add(1)
 		switch f := fetch.Fetches[i].(type) {
 		case *SingleFetch:
 			preparedInput := r.getBufPair()
@@ -1199,8 +2167,12 @@ func (r *Resolver) resolveParallelFetch(ctx *Context, fetch *ParallelFetch, data
 			}
 			*preparedInputs = append(*preparedInputs, preparedInput)
 			buf := set.buffers[f.BufferId]
+			if batchOf != nil && batchOf[i] != nil {
+				batchOf[i].add(f, preparedInput.Data, buf)
+				continue
+			}
 			resolvers = append(resolvers, func() error {
-				return r.resolveSingleFetch(ctx, f, preparedInput.Data, buf)
+				return r.captureParallelFetchError(ctx, buf, r.resolveSingleFetch(ctx, f, preparedInput.Data, buf))
 			})
 		case *BatchFetch:
 			preparedInput := r.getBufPair()
@@ -1211,14 +2183,28 @@ func (r *Resolver) resolveParallelFetch(ctx *Context, fetch *ParallelFetch, data
 			*preparedInputs = append(*preparedInputs, preparedInput)
 			buf := set.buffers[f.Fetch.BufferId]
 			resolvers = append(resolvers, func() error {
-				return r.resolveBatchFetch(ctx, f, preparedInput.Data, buf)
+				return r.captureParallelFetchError(ctx, buf, r.resolveBatchFetch(ctx, f, preparedInput.Data, buf))
 			})
 		}
 	}
+	for _, batch := range batches {
+		batch := batch
+		resolvers = append(resolvers, func() error {
+			return r.resolveDataSourceBatch(ctx, batch)
+		})
+	}
 
+	// failing fetches turn into errors of their fields, so only a done context returns an error here
+	// the in-flight fetches see the cancellation of the operation's context through their own context
+	wg.Add(len(resolvers))
+	var errOnce sync.Once
 	for _, resolver := range resolvers {
-		go func(r func() error) {
-			_ = r()
+		go func(resolve func() error) {
+			if resolveErr := resolve(); resolveErr != nil {
+				errOnce.Do(func() {
+					err = resolveErr
+				})
+			}
 			wg.Done()
 		}(resolver)
 	}
@@ -1228,6 +2214,130 @@ func (r *Resolver) resolveParallelFetch(ctx *Context, fetch *ParallelFetch, data
 	return
 }
 
+// dataSourceBatch collects the fetches of a ParallelFetch which are loaded with a single call to BatchDataSource.LoadBatch
+type dataSourceBatch struct {
+	source  BatchDataSource
+	size    int
+	fetches []*SingleFetch
+	inputs  []*fastbuffer.FastBuffer
+	bufs    []*BufPair
+}
+
+func (b *dataSourceBatch) add(fetch *SingleFetch, input *fastbuffer.FastBuffer, buf *BufPair) {
+	b.fetches = append(b.fetches, fetch)
+	b.inputs = append(b.inputs, input)
+	b.bufs = append(b.bufs, buf)
+}
+
+// dataSourceBatches groups the batchable fetches of the ParallelFetch by their DataSourceIdentifier and the DataSource they load from
+// batchOf[i] is the batch of the i-th fetch, fetches which are the only one of their group are not batched.
+func (r *Resolver) dataSourceBatches(ctx *Context, fetch *ParallelFetch) (batchOf []*dataSourceBatch, batches []*dataSourceBatch) {
+	type batchKey struct {
+		dataSourceIdentifier string
+		source               BatchDataSource
+	}
+	var groups map[batchKey]*dataSourceBatch
+	for i := range fetch.Fetches {
+		f, ok := fetch.Fetches[i].(*SingleFetch)
+		if !ok {
+			continue
+		}
+		source, ok := r.batchDataSource(ctx, f)
+		if !ok {
+			continue
+		}
+		if groups == nil {
+			groups = map[batchKey]*dataSourceBatch{}
+			batchOf = make([]*dataSourceBatch, len(fetch.Fetches))
+		}
+		key := batchKey{dataSourceIdentifier: string(f.DataSourceIdentifier), source: source}
+		batch, ok := groups[key]
+		if !ok {
+			batch = &dataSourceBatch{source: source}
+			groups[key] = batch
+			batches = append(batches, batch)
+		}
+		batch.size++
+		batchOf[i] = batch
+	}
+	if batches == nil {
+		return nil, nil
+	}
+	// a single fetch is loaded with Load as usual
+	for i := range batchOf {
+		if batchOf[i] != nil && batchOf[i].size < 2 {
+			batchOf[i] = nil
+		}
+	}
+	n := 0
+	for _, batch := range batches {
+		if batch.size >= 2 {
+			batches[n] = batch
+			n++
+		}
+	}
+	return batchOf, batches[:n]
+}
+
+// batchDataSource returns the BatchDataSource of a fetch which can be loaded together with other fetches
+// Fetches using the data loader, a timeout or retries are loaded one by one,
+// as well as fetches whose DataSource can't be compared to group them by it.
+func (r *Resolver) batchDataSource(ctx *Context, fetch *SingleFetch) (BatchDataSource, bool) {
+	if len(fetch.DataSourceIdentifier) == 0 || fetch.Timeout > 0 || fetch.Retry.enabled() {
+		return nil, false
+	}
+	if r.dataLoaderEnabled && !fetch.DisableDataLoader {
+		return nil, false
+	}
+	source, ok := ctx.dataSource(fetch).(BatchDataSource)
+	if !ok || !reflect.TypeOf(source).Comparable() {
+		return nil, false
+	}
+	return source, true
+}
+
+// resolveDataSourceBatch loads the fetches of a batch at once and handles the result of each fetch like resolveSingleFetch
+func (r *Resolver) resolveDataSourceBatch(ctx *Context, batch *dataSourceBatch) (err error) {
+	inputSize := 0
+	for i := range batch.inputs {
+		inputSize += batch.inputs[i].Len()
+	}
+	loadCtx, span := r.startFetchSpan(ctx, batch.fetches[0], batch.source, inputSize)
+	defer func() { span.End(err) }()
+
+	if r.skipFetchAfterDeadline(ctx, batch.bufs...) {
+		return nil
+	}
+
+	fetchErrs := make([]error, len(batch.fetches))
+	loadErr := r.loadFetch(loadCtx, batch.fetches[0], func() error {
+		return r.fetcher.fetchDataSourceBatch(ctx, loadCtx, batch.source, batch.fetches, batch.inputs, batch.bufs, fetchErrs)
+	})
+
+	for i, fetch := range batch.fetches {
+		fetchErr := fetchErrs[i]
+		if fetchErr == nil {
+			fetchErr = loadErr
+		}
+		fetchErr = r.finishFetch(ctx, fetch, batch.bufs[i], fetchErr)
+		if fetchErr = r.captureParallelFetchError(ctx, batch.bufs[i], fetchErr); fetchErr != nil && err == nil {
+			err = fetchErr
+		}
+	}
+	return err
+}
+
+// resolveSerialFetch resolves the fetches one after another in order
+// A fetch starts only after the previous fetch completed, a failing fetch aborts the remaining fetches.
+func (r *Resolver) resolveSerialFetch(ctx *Context, fetch *SerialFetch, data []byte, set *resultSet) error {
+	for i := range fetch.Fetches {
+		if err := r.resolveFetch(ctx, fetch.Fetches[i], data, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *Resolver) prepareSingleFetch(ctx *Context, fetch *SingleFetch, data []byte, set *resultSet, preparedInput *fastbuffer.FastBuffer) (err error) {
 	err = fetch.InputTemplate.Render(ctx, data, preparedInput)
 	buf := r.getBufPair()
@@ -1235,23 +2345,201 @@ func (r *Resolver) prepareSingleFetch(ctx *Context, fetch *SingleFetch, data []b
 	return
 }
 
-func (r *Resolver) resolveBatchFetch(ctx *Context, fetch *BatchFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) error {
-	if r.dataLoaderEnabled {
-		return ctx.dataLoader.LoadBatch(ctx, fetch, buf)
+func (r *Resolver) resolveBatchFetch(ctx *Context, fetch *BatchFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) (err error) {
+	if r.skipFetchAfterDeadline(ctx, buf) {
+		return nil
+	}
+	defer func() { err = r.finishFetch(ctx, fetch.Fetch, buf, err) }()
+
+	return r.loadFetch(ctx.Context, nil, func() error {
+		if r.dataLoaderEnabled {
+			return ctx.dataLoader.LoadBatch(ctx, ctx.Context, fetch, buf)
+		}
+		return r.fetcher.FetchBatch(ctx, fetch, []*fastbuffer.FastBuffer{preparedInput}, []*BufPair{buf})
+	})
+}
+
+func (r *Resolver) resolveSingleFetch(ctx *Context, fetch *SingleFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) (err error) {
+	loadCtx, span := r.startFetchSpan(ctx, fetch, nil, preparedInput.Len())
+	// deferred first, so that the span gets the error after the result of the fetch is finished
+	defer func() { span.End(err) }()
+
+	if r.skipFetchAfterDeadline(ctx, buf) {
+		return nil
+	}
+	defer func() { err = r.finishFetch(ctx, fetch, buf, err) }()
+
+	// the data loader caches fetch results, retrying would return the same result again
+	if fetch.Retry.enabled() && (!r.dataLoaderEnabled || fetch.DisableDataLoader) {
+		return r.resolveSingleFetchWithRetry(ctx, loadCtx, fetch, preparedInput, buf)
+	}
+	return r.resolveSingleFetchAttempt(ctx, loadCtx, fetch, preparedInput, buf)
+}
+
+// startFetchSpan starts the span of a fetch and returns the context to load the fetch with
+// source is the DataSource loading the fetch, nil for the DataSource of the fetch for ctx.
+// The span is named by the UniqueIdentifier of the source, or by the DataSourceIdentifier of the fetch if it has none.
+// Only the context is derived for the span, so the fetch doesn't need a copy of ctx.
+func (r *Resolver) startFetchSpan(ctx *Context, fetch *SingleFetch, source interface{}, inputSize int) (context.Context, FetchSpan) {
+	if r.tracer == nil || r.tracer == (NoopTracer{}) {
+		return ctx.Context, noopFetchSpan{}
+	}
+	if source == nil {
+		source = ctx.dataSource(fetch)
+	}
+	name := fetch.DataSourceIdentifier
+	if identified, ok := source.(UniqueIdentifierDataSource); ok {
+		name = identified.UniqueIdentifier()
+	}
+	return r.tracer.StartFetchSpan(ctx.Context, name, inputSize)
+}
+
+// skipFetchAfterDeadline adds a deadline error to the buffers of fetches starting after the deadline of the operation
+// The fields of skipped fetches resolve as null.
+func (r *Resolver) skipFetchAfterDeadline(ctx *Context, bufs ...*BufPair) bool {
+	if !r.PartialResponseOnDeadline || !deadlineExceeded(ctx) {
+		return false
+	}
+	for _, buf := range bufs {
+		r.addFetchError(ctx, buf, deadlineExceededMsg)
+	}
+	return true
+}
+
+// finishFetch applies the handling shared by all fetches to the result of a loaded fetch
+// Invalid JSON turns into an error of the fetched field, the paths of entity errors are rewritten,
+// the errors are capped to MaxErrors and failures after the deadline are dropped if PartialResponseOnDeadline is set.
+func (r *Resolver) finishFetch(ctx *Context, fetch *SingleFetch, buf *BufPair, err error) error {
+	err = invalidJSONFetchError(ctx, fetch, buf, err)
+	if fetch.ProcessResponseConfig.ExtractFederationEntities {
+		rewriteEntityErrorPaths(ctx, buf)
+	}
+	if fetch.MaxErrors > 0 {
+		capErrors(buf, fetch.MaxErrors)
+	}
+	if r.PartialResponseOnDeadline {
+		err = r.dropFetchAfterDeadline(ctx, buf, err)
 	}
+	return err
+}
 
-	if err := r.fetcher.FetchBatch(ctx, fetch, []*fastbuffer.FastBuffer{preparedInput}, []*BufPair{buf}); err != nil {
+// loadFetch calls load holding one of the MaxConcurrentFetches slots
+// The duration of load is recorded for the DataSourceIdentifier of recorded, nil doesn't record it,
+// e.g. for loads of the data loader which might return the results loaded for a sibling.
+func (r *Resolver) loadFetch(loadCtx context.Context, recorded *SingleFetch, load func() error) error {
+	if err := r.acquireFetch(loadCtx); err != nil {
 		return err
 	}
+	defer r.releaseFetch()
+
+	if r.durationRecorder == nil || recorded == nil {
+		return load()
+	}
+	start := time.Now()
+	err := load()
+	r.durationRecorder(recorded.DataSourceIdentifier, time.Since(start))
+	return err
+}
 
+// invalidJSONFetchError turns the failure of a fetch whose upstream returned invalid JSON into an error of the fetched field
+// Resolving the fields of the fetch from a truncated or non-JSON response would silently resolve them as null instead.
+func invalidJSONFetchError(ctx *Context, fetch *SingleFetch, buf *BufPair, err error) error {
+	if !errors.Is(err, errInvalidUpstreamJSON) {
+		return err
+	}
+	writeInvalidJSONError(ctx, fetch, buf)
 	return nil
 }
 
-func (r *Resolver) resolveSingleFetch(ctx *Context, fetch *SingleFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) error {
+// writeInvalidJSONError drops the data of the fetch and adds an error naming its DataSourceIdentifier
+func writeInvalidJSONError(ctx *Context, fetch *SingleFetch, buf *BufPair) {
+	message := invalidJSONMsg
+	if len(fetch.DataSourceIdentifier) != 0 {
+		escaped, _ := json.Marshal(string(fetch.DataSourceIdentifier))
+		message = make([]byte, 0, len(invalidJSONMsg)+len(escaped))
+		message = append(message, invalidJSONMsg...)
+		message = append(message, ": "...)
+		message = append(message, escaped[1:len(escaped)-1]...)
+	}
+
+	var pathBytes []byte
+	if len(ctx.pathElements) > 0 {
+		path := pool.BytesBuffer.Get()
+		defer pool.BytesBuffer.Put(path)
+		ctx.writeErrorPath(path)
+		pathBytes = path.Bytes()
+	}
+
+	buf.Data.Reset()
+	buf.WriteErr(message, nil, pathBytes, nil)
+}
+
+// dropFetchAfterDeadline drops the data of a fetch which failed because the deadline of the operation got exceeded
+// and adds a deadline error instead, so that the parts of the response resolved before the deadline can still be written
+func (r *Resolver) dropFetchAfterDeadline(ctx *Context, buf *BufPair, err error) error {
+	if err == nil || !deadlineExceeded(ctx) {
+		return err
+	}
+	buf.Data.Reset()
+	r.addFetchError(ctx, buf, deadlineExceededMsg)
+	return nil
+}
+
+func deadlineExceeded(ctx *Context) bool {
+	return ctx.Context != nil && ctx.Err() == context.DeadlineExceeded
+}
+
+func (r *Resolver) resolveSingleFetchWithRetry(ctx *Context, loadCtx context.Context, fetch *SingleFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) (err error) {
+	for attempt := 1; ; attempt++ {
+		err = r.resolveSingleFetchAttempt(ctx, loadCtx, fetch, preparedInput, buf)
+		if attempt >= fetch.Retry.MaxAttempts || !fetch.Retry.retryable(err, buf) {
+			return err
+		}
+
+		backoff := time.NewTimer(fetch.Retry.backoff(attempt))
+		select {
+		case <-loadCtx.Done():
+			backoff.Stop()
+			return err
+		case <-backoff.C:
+		}
+
+		buf.Data.Reset()
+		buf.Errors.Reset()
+	}
+}
+
+func (r *Resolver) resolveSingleFetchAttempt(ctx *Context, loadCtx context.Context, fetch *SingleFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) error {
+	if fetch.Timeout > 0 {
+		return r.resolveSingleFetchWithTimeout(ctx, loadCtx, fetch, preparedInput, buf)
+	}
+	return r.loadSingleFetch(ctx, loadCtx, fetch, preparedInput, buf)
+}
+
+func (r *Resolver) resolveSingleFetchWithTimeout(ctx *Context, loadCtx context.Context, fetch *SingleFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) error {
+	timeoutCtx, cancel := context.WithTimeout(loadCtx, fetch.Timeout)
+	defer cancel()
+
+	err := r.loadSingleFetch(ctx, timeoutCtx, fetch, preparedInput, buf)
+	if timeoutCtx.Err() != context.DeadlineExceeded || loadCtx.Err() != nil {
+		return err
+	}
+
+	// the upstream didn't respond in time, drop partial data and resolve the fields of this fetch as null
+	buf.Data.Reset()
+	r.addFetchError(ctx, buf, upstreamTimeoutMsg)
+	return nil
+}
+
+func (r *Resolver) loadSingleFetch(ctx *Context, loadCtx context.Context, fetch *SingleFetch, preparedInput *fastbuffer.FastBuffer, buf *BufPair) error {
 	if r.dataLoaderEnabled && !fetch.DisableDataLoader {
-		return ctx.dataLoader.Load(ctx, fetch, buf)
+		return r.loadFetch(loadCtx, nil, func() error {
+			return ctx.dataLoader.Load(ctx, loadCtx, fetch, buf)
+		})
 	}
-	return r.fetcher.Fetch(ctx, fetch, preparedInput, buf)
+	return r.loadFetch(loadCtx, fetch, func() error {
+		return r.fetcher.fetch(ctx, loadCtx, fetch, preparedInput, buf)
+	})
 }
 
 type Object struct {
@@ -1266,13 +2554,23 @@ func (_ *Object) NodeKind() NodeKind {
 	return NodeKindObject
 }
 
-type EmptyObject struct{}
+// EmptyObject resolves to {}
+// If Path is set the value must be present in the data, otherwise it resolves to null or fails if not Nullable.
+type EmptyObject struct {
+	Path     []string
+	Nullable bool
+}
 
 func (_ *EmptyObject) NodeKind() NodeKind {
 	return NodeKindEmptyObject
 }
 
-type EmptyArray struct{}
+// EmptyArray resolves to []
+// If Path is set the value must be present in the data, otherwise it resolves to null or fails if not Nullable.
+type EmptyArray struct {
+	Path     []string
+	Nullable bool
+}
 
 func (_ *EmptyArray) NodeKind() NodeKind {
 	return NodeKindEmptyArray
@@ -1282,24 +2580,50 @@ type Field struct {
 	Name                    []byte
 	Value                   Node
 	Position                Position
+	Info                    *FieldInfo
 	Defer                   *DeferField
 	Stream                  *StreamField
 	HasBuffer               bool
 	BufferID                int
 	OnTypeName              []byte
+	OnTypeNames             [][]byte
 	SkipDirectiveDefined    bool
 	SkipVariableName        string
 	IncludeDirectiveDefined bool
 	IncludeVariableName     string
 }
 
+// matchesTypeName reports whether the field applies to an object with the given __typename
+// OnTypeNames complements OnTypeName so that a single field can apply to several concrete types of an abstract type
+func (f *Field) matchesTypeName(typeName []byte) bool {
+	if f.OnTypeName != nil && bytes.Equal(typeName, f.OnTypeName) {
+		return true
+	}
+	for i := range f.OnTypeNames {
+		if bytes.Equal(typeName, f.OnTypeNames[i]) {
+			return true
+		}
+	}
+	return false
+}
+
 type Position struct {
 	Line   uint32
 	Column uint32
 }
 
+// FieldInfo describes the field in the schema, it's only planned if plan.Configuration.IncludeInfo is enabled
+type FieldInfo struct {
+	// Name is the name of the field in the schema, it differs from the name in the response for aliased fields
+	Name []byte
+	// ParentTypeName is the name of the type the field is selected on
+	ParentTypeName []byte
+}
+
 type StreamField struct {
 	InitialBatchSize int
+	// Label is the optional label argument of the @stream directive
+	Label []byte
 }
 
 type DeferField struct{}
@@ -1335,6 +2659,45 @@ type SingleFetch struct {
 	InputTemplate         InputTemplate
 	DataSourceIdentifier  []byte
 	ProcessResponseConfig ProcessResponseConfig
+	// Timeout limits the time the DataSource is allowed to take for loading
+	// If the deadline is exceeded the fetch resolves with an "upstream timeout" error instead of data
+	// 0 means no timeout, the fetch only depends on the request context
+	Timeout time.Duration
+	// Retry configures retries for transient DataSource failures
+	// nil or a zero policy disables retries, e.g. for mutations
+	Retry *RetryPolicy
+	// MaxErrors limits the number of errors of this fetch added to the response, further errors are dropped
+	// 0 means all errors are added
+	MaxErrors int
+	// SingleFlightKeyHeaders are the names of request headers whose values are part of the singleFlight key
+	// Use it if the upstream response depends on headers which are not part of the rendered input, e.g. "Authorization"
+	SingleFlightKeyHeaders []string
+}
+
+// RetryPolicy defines how often and when a SingleFetch is retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one
+	MaxAttempts int
+	// BackoffBase is the wait time before the first retry, it doubles with every further attempt
+	BackoffBase time.Duration
+	// ShouldRetry decides whether the result of an attempt is retryable
+	// If nil, an attempt is retried if the DataSource returned an error
+	ShouldRetry func(err error, bufPair *BufPair) bool
+}
+
+func (p *RetryPolicy) enabled() bool {
+	return p != nil && p.MaxAttempts > 1
+}
+
+func (p *RetryPolicy) retryable(err error, bufPair *BufPair) bool {
+	if p.ShouldRetry == nil {
+		return err != nil
+	}
+	return p.ShouldRetry(err, bufPair)
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	return p.BackoffBase << (attempt - 1)
 }
 
 type ProcessResponseConfig struct {
@@ -1354,6 +2717,16 @@ func (_ *ParallelFetch) FetchKind() FetchKind {
 	return FetchKindParallel
 }
 
+// SerialFetch resolves its fetches one after another in order
+// It's used for the root fields of a mutation, which the GraphQL spec requires to be executed serially.
+type SerialFetch struct {
+	Fetches []Fetch
+}
+
+func (_ *SerialFetch) FetchKind() FetchKind {
+	return FetchKindSerial
+}
+
 type BatchFetch struct {
 	Fetch        *SingleFetch
 	BatchFactory DataSourceBatchFactory
@@ -1371,16 +2744,54 @@ type FieldExport struct {
 }
 
 type String struct {
-	Path                 []string
-	Nullable             bool
-	Export               *FieldExport `json:"export,omitempty"`
-	UnescapeResponseJson bool         `json:"unescape_response_json,omitempty"`
+	Path     []string
+	Nullable bool
+	Export   *FieldExport `json:"export,omitempty"`
+	// UnescapeResponseJson renders the value as raw JSON, e.g. for JSON scalars
+	// The upstream bytes are written as is, so the key order of nested objects is preserved
+	UnescapeResponseJson bool `json:"unescape_response_json,omitempty"`
+	// Decoding decodes the upstream value, e.g. base64 encoded blobs, before it's written to the response
+	Decoding EncodingKind `json:"decoding,omitempty"`
 }
 
 func (_ *String) NodeKind() NodeKind {
 	return NodeKindString
 }
 
+// Enum resolves a string value which must be one of the allowed Values
+// Values the upstream returns which are not part of the enum resolve to null with a field error
+type Enum struct {
+	Path     []string
+	Nullable bool
+	Values   [][]byte
+}
+
+func (_ *Enum) NodeKind() NodeKind {
+	return NodeKindEnum
+}
+
+// CustomScalar resolves the value of a custom scalar with the coercer registered for ScalarName on the Resolver
+// Values of scalars without a registered coercer are written as they are
+type CustomScalar struct {
+	Path       []string
+	Nullable   bool
+	ScalarName string
+}
+
+func (_ *CustomScalar) NodeKind() NodeKind {
+	return NodeKindCustomScalar
+}
+
+// StaticString resolves to a string known upfront without looking at the data, e.g. the __typename of a concrete type
+// The Value is written as it is and must not need escaping.
+type StaticString struct {
+	Value []byte
+}
+
+func (_ *StaticString) NodeKind() NodeKind {
+	return NodeKindStaticString
+}
+
 type Boolean struct {
 	Path     []string
 	Nullable bool
@@ -1395,6 +2806,9 @@ type Float struct {
 	Path     []string
 	Nullable bool
 	Export   *FieldExport `json:"export,omitempty"`
+	// DecimalNotation rewrites floats in exponential notation returned by the upstream, e.g. 1e-7, to decimal notation
+	// Values out of the range of a float64 resolve to null with a field error
+	DecimalNotation bool
 }
 
 func (_ *Float) NodeKind() NodeKind {
@@ -1405,6 +2819,9 @@ type Integer struct {
 	Path     []string
 	Nullable bool
 	Export   *FieldExport `json:"export,omitempty"`
+	// CoerceFloat rewrites integral floats returned by the upstream, e.g. 3.0 or 3e2, to integers
+	// Floats with a fractional part resolve to null with a field error
+	CoerceFloat bool `json:"coerce_float,omitempty"`
 }
 
 func (_ *Integer) NodeKind() NodeKind {
@@ -1412,9 +2829,12 @@ func (_ *Integer) NodeKind() NodeKind {
 }
 
 type Array struct {
-	Path                 []string
-	Nullable             bool
-	ResolveAsynchronous  bool
+	Path                []string
+	Nullable            bool
+	ResolveAsynchronous bool
+	// MaxConcurrency limits the number of items resolved concurrently when ResolveAsynchronous is enabled
+	// 0 means no limit, one goroutine per item is used
+	MaxConcurrency       int
 	Item                 Node
 	Stream               Stream
 	UnescapeResponseJson bool `json:"unescape_response_json,omitempty"`
@@ -1424,6 +2844,8 @@ type Stream struct {
 	Enabled          bool
 	InitialBatchSize int
 	PatchIndex       int
+	// Label identifies the streamed items in patches, it's written as "label" if set
+	Label []byte
 }
 
 func (_ *Array) NodeKind() NodeKind {
@@ -1447,8 +2869,17 @@ type FlushWriter interface {
 	Flush()
 }
 
+// SubscriptionCompleter can be implemented by the FlushWriter of a subscription
+// Complete is called once the upstream stream ended normally, so that transports can send their protocol's complete message.
+// It's not called if the subscription terminates with an error or gets cancelled.
+type SubscriptionCompleter interface {
+	Complete()
+}
+
 type GraphQLResponse struct {
 	Data Node
+	// Extensions is optional, if set it's resolved after Data and written as "extensions" to the response
+	Extensions Node
 }
 
 type GraphQLStreamingResponse struct {
@@ -1461,6 +2892,8 @@ type GraphQLResponsePatch struct {
 	Value     Node
 	Fetch     Fetch
 	Operation []byte
+	// Label is written as "label" next to the value if set, e.g. the label of a @stream directive
+	Label []byte
 }
 
 type BufPair struct {
@@ -1551,6 +2984,222 @@ func (r *Resolver) MergeBufPairData(from, to *BufPair, prefixDataWithComma bool)
 	from.Data.Reset()
 }
 
+// addWarningsToExtensions adds the warnings of the Context as "warnings" to the resolved extensions object
+func (r *Resolver) addWarningsToExtensions(ctx *Context, extensionsBuf *BufPair) error {
+	out := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(out)
+
+	out.Write(lBrack)
+	for i := range ctx.warnings {
+		if i != 0 {
+			out.Write(comma)
+		}
+		message, err := json.Marshal(ctx.warnings[i])
+		if err != nil {
+			return err
+		}
+		out.Write(lBrace)
+		out.Write(quote)
+		out.Write(literalMessage)
+		out.Write(quote)
+		out.Write(colon)
+		out.Write(message)
+		out.Write(rBrace)
+	}
+	out.Write(rBrack)
+
+	addToExtensions(extensionsBuf, literalWarnings, out.Bytes())
+	return nil
+}
+
+// addToExtensions adds value as key to the resolved extensions object, which gets created if it's not set or null
+func addToExtensions(extensionsBuf *BufPair, key, value []byte) {
+	extensions := extensionsBuf.Data.Bytes()
+	if len(extensions) < 2 || extensions[0] != lBrace[0] {
+		// extensions are either not set or null
+		extensions = nil
+	}
+
+	out := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(out)
+
+	if extensions == nil {
+		out.Write(lBrace)
+	} else {
+		out.Write(extensions[:len(extensions)-1])
+		if len(extensions) > 2 {
+			out.Write(comma)
+		}
+	}
+	out.Write(quote)
+	out.Write(key)
+	out.Write(quote)
+	out.Write(colon)
+	out.Write(value)
+	out.Write(rBrace)
+
+	extensionsBuf.Data.Reset()
+	extensionsBuf.Data.WriteBytes(out.Bytes())
+}
+
+// formatErrors applies the error formatter to each error of the BufPair
+func (r *Resolver) formatErrors(buf *BufPair) {
+	if r.errorFormatter == nil || !buf.HasErrors() {
+		return
+	}
+
+	errorsArray := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(errorsArray)
+	errorsArray.Write(lBrack)
+	errorsArray.Write(buf.Errors.Bytes())
+	errorsArray.Write(rBrack)
+
+	buf.Errors.Reset()
+	first := true
+	_, _ = jsonparser.ArrayEach(errorsArray.Bytes(), func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if !first {
+			buf.Errors.WriteBytes(comma)
+		}
+		first = false
+		formatted := r.errorFormatter(value)
+		if _, formattedType, _, err := jsonparser.Get(formatted); err != nil || formattedType != jsonparser.Object || !json.Valid(formatted) {
+			formatted = value
+		}
+		buf.Errors.WriteBytes(formatted)
+	})
+}
+
+// deduplicateErrors drops errors of the BufPair which are byte-identical to a previous error
+func deduplicateErrors(buf *BufPair) {
+	if !buf.HasErrors() {
+		return
+	}
+
+	errorsArray := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(errorsArray)
+	errorsArray.Write(lBrack)
+	errorsArray.Write(buf.Errors.Bytes())
+	errorsArray.Write(rBrack)
+
+	buf.Errors.Reset()
+	seen := make(map[string]struct{})
+	_, _ = jsonparser.ArrayEach(errorsArray.Bytes(), func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if _, ok := seen[string(value)]; ok {
+			return
+		}
+		seen[string(value)] = struct{}{}
+		if len(seen) != 1 {
+			buf.Errors.WriteBytes(comma)
+		}
+		buf.Errors.WriteBytes(value)
+	})
+}
+
+// capErrors keeps the first max errors of the BufPair and drops the rest
+func capErrors(buf *BufPair, max int) {
+	if !buf.HasErrors() {
+		return
+	}
+
+	errorsArray := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(errorsArray)
+	errorsArray.Write(lBrack)
+	errorsArray.Write(buf.Errors.Bytes())
+	errorsArray.Write(rBrack)
+
+	buf.Errors.Reset()
+	count := 0
+	_, _ = jsonparser.ArrayEach(errorsArray.Bytes(), func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if count == max {
+			return
+		}
+		if count != 0 {
+			buf.Errors.WriteBytes(comma)
+		}
+		buf.Errors.WriteBytes(value)
+		count++
+	})
+}
+
+// rewriteEntityErrorPaths moves the paths of the errors of an entity fetch from the upstream _entities query into the response
+// The upstream reports paths relative to its own operation, e.g. ["_entities",0,"reviews",1,"body"],
+// which become ["me","reviews",1,"body"] for an entity fetch of the object at /data/me.
+// Errors without a path or with a path outside of _entities are kept as is.
+func rewriteEntityErrorPaths(ctx *Context, buf *BufPair) {
+	if !buf.HasErrors() {
+		return
+	}
+
+	errorsArray := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(errorsArray)
+	errorsArray.Write(lBrack)
+	errorsArray.Write(buf.Errors.Bytes())
+	errorsArray.Write(rBrack)
+
+	fetchPath := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(fetchPath)
+	ctx.writeErrorPath(fetchPath)
+
+	buf.Errors.Reset()
+	first := true
+	_, _ = jsonparser.ArrayEach(errorsArray.Bytes(), func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if !first {
+			buf.Errors.WriteBytes(comma)
+		}
+		first = false
+
+		path, pathType, _, pathErr := jsonparser.Get(value, "path")
+		if pathErr == nil && pathType == jsonparser.Array {
+			if rewritten, ok := entityErrorPath(fetchPath.Bytes(), path); ok {
+				if updated, setErr := jsonparser.Set(value, rewritten, "path"); setErr == nil {
+					value = updated
+				}
+			}
+		}
+		buf.Errors.WriteBytes(value)
+	})
+}
+
+// entityErrorPath replaces the leading "_entities" and entity index of path with the elements of fetchPath
+func entityErrorPath(fetchPath, path []byte) ([]byte, bool) {
+	rewritten := make([]byte, 0, len(fetchPath)+len(path))
+	rewritten = append(rewritten, fetchPath[:len(fetchPath)-1]...)
+	hasElements := len(fetchPath) > 2
+
+	position := 0
+	valid := true
+	_, _ = jsonparser.ArrayEach(path, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		defer func() { position++ }()
+		switch position {
+		case 0:
+			valid = dataType == jsonparser.String && string(value) == entitiesPath[0]
+			return
+		case 1:
+			valid = valid && dataType == jsonparser.Number
+			return
+		}
+		if !valid {
+			return
+		}
+		if hasElements {
+			rewritten = append(rewritten, comma...)
+		}
+		hasElements = true
+		if dataType == jsonparser.String {
+			rewritten = append(rewritten, quote...)
+			rewritten = append(rewritten, value...)
+			rewritten = append(rewritten, quote...)
+			return
+		}
+		rewritten = append(rewritten, value...)
+	})
+	if !valid || position < 2 {
+		return nil, false
+	}
+	rewritten = append(rewritten, rBrack...)
+	return rewritten, true
+}
+
 func (r *Resolver) MergeBufPairErrors(from, to *BufPair) {
 	if !from.HasErrors() {
 		return
@@ -1566,17 +3215,21 @@ func (r *Resolver) freeBufPair(pair *BufPair) {
 	pair.Data.Reset()
 	pair.Errors.Reset()
 	r.bufPairPool.Put(pair)
+	r.poolStats.put(&r.poolStats.bufPair)
 }
 
 func (r *Resolver) getResultSet() *resultSet {
+	r.poolStats.get(&r.poolStats.resultSet)
 	return r.resultSetPool.Get().(*resultSet)
 }
 
 func (r *Resolver) getBufPair() *BufPair {
+	r.poolStats.get(&r.poolStats.bufPair)
 	return r.bufPairPool.Get().(*BufPair)
 }
 
 func (r *Resolver) getBufPairSlice() *[]*BufPair {
+	r.poolStats.get(&r.poolStats.bufPairSlice)
 	return r.bufPairSlicePool.Get().(*[]*BufPair)
 }
 
@@ -1586,25 +3239,34 @@ func (r *Resolver) freeBufPairSlice(slice *[]*BufPair) {
 	}
 	*slice = (*slice)[:0]
 	r.bufPairSlicePool.Put(slice)
+	r.poolStats.put(&r.poolStats.bufPairSlice)
 }
 
 func (r *Resolver) getErrChan() chan error {
+	r.poolStats.get(&r.poolStats.errChan)
 	return r.errChanPool.Get().(chan error)
 }
 
 func (r *Resolver) freeErrChan(ch chan error) {
 	r.errChanPool.Put(ch)
+	r.poolStats.put(&r.poolStats.errChan)
 }
 
 func (r *Resolver) getWaitGroup() *sync.WaitGroup {
+	r.poolStats.get(&r.poolStats.waitGroup)
 	return r.waitGroupPool.Get().(*sync.WaitGroup)
 }
 
 func (r *Resolver) freeWaitGroup(wg *sync.WaitGroup) {
 	r.waitGroupPool.Put(wg)
+	r.poolStats.put(&r.poolStats.waitGroup)
 }
 
 func writeGraphqlResponse(buf *BufPair, writer io.Writer, ignoreData bool) (err error) {
+	return writeGraphqlResponseWithExtensions(buf, nil, writer, ignoreData)
+}
+
+func writeGraphqlResponseWithExtensions(buf *BufPair, extensions []byte, writer io.Writer, ignoreData bool) (err error) {
 	hasErrors := buf.Errors.Len() != 0
 	hasData := buf.Data.Len() != 0 && !ignoreData
 
@@ -1631,6 +3293,16 @@ func writeGraphqlResponse(buf *BufPair, writer io.Writer, ignoreData bool) (err
 	} else {
 		err = writeSafe(err, writer, literal.NULL)
 	}
+
+	if len(extensions) != 0 {
+		err = writeSafe(err, writer, comma)
+		err = writeSafe(err, writer, quote)
+		err = writeSafe(err, writer, literalExtensions)
+		err = writeSafe(err, writer, quote)
+		err = writeSafe(err, writer, colon)
+		err = writeSafe(err, writer, extensions)
+	}
+
 	err = writeSafe(err, writer, rBrace)
 
 	return err
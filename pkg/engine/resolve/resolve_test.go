@@ -5,8 +5,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
+	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -36,6 +40,17 @@ func FakeDataSource(data string) *_fakeDataSource {
 	}
 }
 
+// _blockingDataSource never responds and returns once the context is done
+type _blockingDataSource struct {
+	err error
+}
+
+func (b *_blockingDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	<-ctx.Done()
+	b.err = ctx.Err()
+	return b.err
+}
+
 type _fakeDataSourceBatch struct {
 	resultedInput *fastbuffer.FastBuffer
 	bufPairs      []*BufPair
@@ -880,6 +895,138 @@ func TestResolver_ResolveNode(t *testing.T) {
 			},
 		}, Context{Context: context.Background(), Variables: []byte(`{}`)}, `{"data":{"user":{"id":"1","name":"Jens","registered":true,"pet":{"name":"Barky"}}}}`
 	}))
+	t.Run("include field with skip and include when skip is false and include is true", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node Node, ctx Context, expectedOutput string) {
+		return &Object{
+			Fields: []*Field{
+				{
+					Name: []byte("data"),
+					Value: &Object{
+						Fields: []*Field{
+							{
+								Name: []byte("user"),
+								Value: &Object{
+									Fetch: &SingleFetch{
+										BufferId:   0,
+										DataSource: FakeDataSource(`{"id":"1","name":"Jens"}`),
+									},
+									Fields: []*Field{
+										{
+											BufferID:  0,
+											HasBuffer: true,
+											Name:      []byte("id"),
+											Value: &String{
+												Path: []string{"id"},
+											},
+										},
+										{
+											BufferID:  0,
+											HasBuffer: true,
+											Name:      []byte("name"),
+											Value: &String{
+												Path: []string{"name"},
+											},
+											SkipDirectiveDefined:    true,
+											SkipVariableName:        "skip",
+											IncludeDirectiveDefined: true,
+											IncludeVariableName:     "include",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background(), Variables: []byte(`{"skip":false,"include":true}`)}, `{"data":{"user":{"id":"1","name":"Jens"}}}`
+	}))
+	t.Run("exclude field with skip and include when skip is true and include is true", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node Node, ctx Context, expectedOutput string) {
+		return &Object{
+			Fields: []*Field{
+				{
+					Name: []byte("data"),
+					Value: &Object{
+						Fields: []*Field{
+							{
+								Name: []byte("user"),
+								Value: &Object{
+									Fetch: &SingleFetch{
+										BufferId:   0,
+										DataSource: FakeDataSource(`{"id":"1","name":"Jens"}`),
+									},
+									Fields: []*Field{
+										{
+											BufferID:  0,
+											HasBuffer: true,
+											Name:      []byte("id"),
+											Value: &String{
+												Path: []string{"id"},
+											},
+										},
+										{
+											BufferID:  0,
+											HasBuffer: true,
+											Name:      []byte("name"),
+											Value: &String{
+												Path: []string{"name"},
+											},
+											SkipDirectiveDefined:    true,
+											SkipVariableName:        "skip",
+											IncludeDirectiveDefined: true,
+											IncludeVariableName:     "include",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background(), Variables: []byte(`{"skip":true,"include":true}`)}, `{"data":{"user":{"id":"1"}}}`
+	}))
+	t.Run("exclude field with skip and include when skip is false and include is false", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node Node, ctx Context, expectedOutput string) {
+		return &Object{
+			Fields: []*Field{
+				{
+					Name: []byte("data"),
+					Value: &Object{
+						Fields: []*Field{
+							{
+								Name: []byte("user"),
+								Value: &Object{
+									Fetch: &SingleFetch{
+										BufferId:   0,
+										DataSource: FakeDataSource(`{"id":"1","name":"Jens"}`),
+									},
+									Fields: []*Field{
+										{
+											BufferID:  0,
+											HasBuffer: true,
+											Name:      []byte("id"),
+											Value: &String{
+												Path: []string{"id"},
+											},
+										},
+										{
+											BufferID:  0,
+											HasBuffer: true,
+											Name:      []byte("name"),
+											Value: &String{
+												Path: []string{"name"},
+											},
+											SkipDirectiveDefined:    true,
+											SkipVariableName:        "skip",
+											IncludeDirectiveDefined: true,
+											IncludeVariableName:     "include",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background(), Variables: []byte(`{"skip":false,"include":false}`)}, `{"data":{"user":{"id":"1"}}}`
+	}))
 	t.Run("fetch with context variable resolver", testFn(true, false, func(t *testing.T, ctrl *gomock.Controller) (node Node, ctx Context, expectedOutput string) {
 		mockDataSource := NewMockDataSource(ctrl)
 		mockDataSource.EXPECT().
@@ -1125,6 +1272,69 @@ func TestResolver_ResolveNode(t *testing.T) {
 			}, Context{Context: context.Background()},
 			`{"pets":[{"name":"Woofie"}]}`
 	}))
+	t.Run("field condition with multiple type names", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node Node, ctx Context, expectedOutput string) {
+		return &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`[{"__typename":"Dog","name":"Woofie"},{"__typename":"Cat","name":"Mietzie"},{"__typename":"Bird","name":"Tweety"}]`),
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("pets"),
+						Value: &Array{
+							Item: &Object{
+								Fields: []*Field{
+									{
+										BufferID:    0,
+										HasBuffer:   true,
+										OnTypeNames: [][]byte{[]byte("Dog"), []byte("Cat")},
+										Name:        []byte("name"),
+										Value: &String{
+											Path: []string{"name"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}, Context{Context: context.Background()},
+			`{"pets":[{"name":"Woofie"},{"name":"Mietzie"}]}`
+	}))
+	t.Run("field condition with single and multiple type names", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node Node, ctx Context, expectedOutput string) {
+		return &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`[{"__typename":"Dog","name":"Woofie"},{"__typename":"Cat","name":"Mietzie"},{"__typename":"Bird","name":"Tweety"}]`),
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("pets"),
+						Value: &Array{
+							Item: &Object{
+								Fields: []*Field{
+									{
+										BufferID:    0,
+										HasBuffer:   true,
+										OnTypeName:  []byte("Bird"),
+										OnTypeNames: [][]byte{[]byte("Cat")},
+										Name:        []byte("name"),
+										Value: &String{
+											Path: []string{"name"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}, Context{Context: context.Background()},
+			`{"pets":[{"name":"Mietzie"},{"name":"Tweety"}]}`
+	}))
 	t.Run("non null object with field condition can be null", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node Node, ctx Context, expectedOutput string) {
 		return &Object{
 				Fetch: &SingleFetch{
@@ -1665,7 +1875,7 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 					},
 				},
 			},
-		}, Context{Context: context.Background()}, `{"errors":[{"message":"errorMessage"},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["nestedObject"]}],"data":null}`
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"errorMessage"},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["nestedObject","foo"]}],"data":null}`
 	}))
 	t.Run("fetch with two Errors", testFn(true, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
 		mockDataSource := NewMockDataSource(ctrl)
@@ -1885,74 +2095,194 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 					},
 				},
 			},
-		}, Context{Context: context.Background()}, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["objectObject","objectField"]}],"data":{"stringObject":null,"integerObject":null,"floatObject":null,"booleanObject":null,"objectObject":null,"arrayObject":null,"asynchronousArrayObject":null,"nullableArray":null}}`
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["stringObject","stringField"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["integerObject","integerField"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["floatObject","floatField"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["booleanObject","booleanField"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["objectObject","objectField"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["arrayObject","arrayField",0]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["asynchronousArrayObject","arrayField",0]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["asynchronousArrayObject","arrayField",1]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["asynchronousArrayObject","arrayField",2]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["nullableArray",0]}],"data":{"stringObject":null,"integerObject":null,"floatObject":null,"booleanObject":null,"objectObject":null,"arrayObject":null,"asynchronousArrayObject":null,"nullableArray":null}}`
 	}))
-	t.Run("empty nullable array should resolve correctly", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+	t.Run("null in deeply nested non-nullable chain should bubble up to the nearest nullable parent", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
 		return &GraphQLResponse{
 			Data: &Object{
-				Nullable: true,
 				Fetch: &SingleFetch{
 					BufferId:   0,
-					DataSource: FakeDataSource(`[]`),
+					DataSource: FakeDataSource(`{"me":{"name":"Jens","account":{"profile":{"email":null}}},"status":"ok"}`),
 				},
 				Fields: []*Field{
 					{
 						HasBuffer: true,
 						BufferID:  0,
-						Name:      []byte("nullableArray"),
-						Value: &Array{
+						Name:      []byte("me"),
+						Value: &Object{
 							Nullable: true,
-							Item: &Object{
-								Nullable: false,
-								Fields: []*Field{
-									{
-										Name: []byte("foo"),
-										Value: &String{
-											Nullable: false,
+							Path:     []string{"me"},
+							Fields: []*Field{
+								{
+									Name: []byte("name"),
+									Value: &String{
+										Path: []string{"name"},
+									},
+								},
+								{
+									Name: []byte("account"),
+									Value: &Object{
+										Nullable: false,
+										Path:     []string{"account"},
+										Fields: []*Field{
+											{
+												Name: []byte("profile"),
+												Value: &Object{
+													Path: []string{"profile"},
+													Fields: []*Field{
+														{
+															Name: []byte("email"),
+															Value: &String{
+																Path: []string{"email"},
+															},
+														},
+													},
+												},
+											},
 										},
 									},
 								},
 							},
 						},
 					},
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("status"),
+						Value: &String{
+							Path: []string{"status"},
+						},
+					},
 				},
 			},
-		}, Context{Context: context.Background()}, `{"data":{"nullableArray":[]}}`
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["me","account","profile","email"]}],"data":{"me":null,"status":"ok"}}`
 	}))
-	t.Run("empty not nullable array should resolve correctly", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+	t.Run("null in nested non-nullable chain should stop at the first nullable parent", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
 		return &GraphQLResponse{
 			Data: &Object{
-				Nullable: false,
 				Fetch: &SingleFetch{
 					BufferId:   0,
-					DataSource: FakeDataSource(`{"some_path": []}`),
+					DataSource: FakeDataSource(`{"me":{"name":"Jens","account":{"profile":{"email":null}}},"status":"ok"}`),
 				},
 				Fields: []*Field{
 					{
 						HasBuffer: true,
 						BufferID:  0,
-						Name:      []byte("notNullableArray"),
-						Value: &Array{
-							Path:     []string{"some_path"},
-							Nullable: false,
-							Item: &Object{
-								Nullable: false,
-								Fields: []*Field{
-									{
-										Name: []byte("foo"),
-										Value: &String{
-											Nullable: false,
+						Name:      []byte("me"),
+						Value: &Object{
+							Nullable: true,
+							Path:     []string{"me"},
+							Fields: []*Field{
+								{
+									Name: []byte("name"),
+									Value: &String{
+										Path: []string{"name"},
+									},
+								},
+								{
+									Name: []byte("account"),
+									Value: &Object{
+										Nullable: true,
+										Path:     []string{"account"},
+										Fields: []*Field{
+											{
+												Name: []byte("profile"),
+												Value: &Object{
+													Path: []string{"profile"},
+													Fields: []*Field{
+														{
+															Name: []byte("email"),
+															Value: &String{
+																Path: []string{"email"},
+															},
+														},
+													},
+												},
+											},
 										},
 									},
 								},
 							},
 						},
 					},
-				},
-			},
-		}, Context{Context: context.Background()}, `{"data":{"notNullableArray":[]}}`
-	}))
-	t.Run("when data null not nullable array should resolve to data null and errors", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("status"),
+						Value: &String{
+							Path: []string{"status"},
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["me","account","profile","email"]}],"data":{"me":{"name":"Jens","account":null},"status":"ok"}}`
+	}))
+	t.Run("empty nullable array should resolve correctly", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		return &GraphQLResponse{
+			Data: &Object{
+				Nullable: true,
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`[]`),
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("nullableArray"),
+						Value: &Array{
+							Nullable: true,
+							Item: &Object{
+								Nullable: false,
+								Fields: []*Field{
+									{
+										Name: []byte("foo"),
+										Value: &String{
+											Nullable: false,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background()}, `{"data":{"nullableArray":[]}}`
+	}))
+	t.Run("empty not nullable array should resolve correctly", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		return &GraphQLResponse{
+			Data: &Object{
+				Nullable: false,
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"some_path": []}`),
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("notNullableArray"),
+						Value: &Array{
+							Path:     []string{"some_path"},
+							Nullable: false,
+							Item: &Object{
+								Nullable: false,
+								Fields: []*Field{
+									{
+										Name: []byte("foo"),
+										Value: &String{
+											Nullable: false,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background()}, `{"data":{"notNullableArray":[]}}`
+	}))
+	t.Run("when data null not nullable array should resolve to data null and errors", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
 		return &GraphQLResponse{
 			Data: &Object{
 				Nullable: false,
@@ -2002,7 +2332,7 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 					},
 				},
 			},
-		}, Context{Context: context.Background()}, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}]}],"data":null}`
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["nonNullArray"]}],"data":null}`
 	}))
 	t.Run("when data null and errors present not nullable array should result to null data upsteam error and resolve error", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
 		return &GraphQLResponse{
@@ -2040,249 +2370,222 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 					},
 				},
 			},
-		}, Context{Context: context.Background()}, `{"errors":[{"message":"Could not get a name","locations":[{"line":3,"column":5}],"path":["todos",0,"name"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}]}],"data":null}`
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"Could not get a name","locations":[{"line":3,"column":5}],"path":["todos",0,"name"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["todos"]}],"data":null}`
 	}))
-	t.Run("complex GraphQL Server plan", testFn(true, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
-		serviceOne := NewMockDataSource(ctrl)
-		serviceOne.EXPECT().
-			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
-			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
-				actual := string(input)
-				expected := `{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":123,"firstArg":"firstArgValue"}}}`
-				assert.Equal(t, expected, actual)
-				pair := NewBufPair()
-				pair.Data.WriteString(`{"serviceOne":{"fieldOne":"fieldOneValue"},"anotherServiceOne":{"fieldOne":"anotherFieldOneValue"},"reusingServiceOne":{"fieldOne":"reUsingFieldOneValue"}}`)
-				return writeGraphqlResponse(pair, w, false)
-			})
-
-		serviceTwo := NewMockDataSource(ctrl)
-		serviceTwo.EXPECT().
-			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
-			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
-				actual := string(input)
-				expected := `{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":12.34,"secondArg":true}}}`
-				assert.Equal(t, expected, actual)
-
-				pair := NewBufPair()
-				pair.Data.WriteString(`{"serviceTwo":{"fieldTwo":"fieldTwoValue"},"secondServiceTwo":{"fieldTwo":"secondFieldTwoValue"}}`)
-				return writeGraphqlResponse(pair, w, false)
-			})
-
-		nestedServiceOne := NewMockDataSource(ctrl)
-		nestedServiceOne.EXPECT().
-			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
-			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
-				actual := string(input)
-				expected := `{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`
-				assert.Equal(t, expected, actual)
-				pair := NewBufPair()
-				pair.Data.WriteString(`{"serviceOne":{"fieldOne":"fieldOneValue"}}`)
-				return writeGraphqlResponse(pair, w, false)
-			})
-
+	t.Run("error path should contain aliases and integer array indices", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
 		return &GraphQLResponse{
 			Data: &Object{
-				Fetch: &ParallelFetch{
-					Fetches: []Fetch{
-						&SingleFetch{
-							BufferId: 0,
-							Input:    `{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":$$1$$,"firstArg":$$0$$}}}`,
-							InputTemplate: InputTemplate{
-								Segments: []TemplateSegment{
-									{
-										SegmentType: StaticSegmentType,
-										Data:        []byte(`{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":`),
-									},
-									{
-										SegmentType:        VariableSegmentType,
-										VariableKind:       ContextVariableKind,
-										VariableSourcePath: []string{"thirdArg"},
-										Renderer:           NewPlainVariableRendererWithValidation(`{"type":"number"}`),
-									},
-									{
-										SegmentType: StaticSegmentType,
-										Data:        []byte(`,"firstArg":`),
-									},
-									{
-										SegmentType:        VariableSegmentType,
-										VariableKind:       ContextVariableKind,
-										VariableSourcePath: []string{"firstArg"},
-										Renderer:           NewPlainVariableRendererWithValidation(`{"type":"string"}`),
-									},
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"users":[{"name":"Jens"},{"name":null}],"admins":[{"name":"Jannik"},{"name":null}]}`),
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("users"),
+						Value: &Array{
+							Path:     []string{"users"},
+							Nullable: true,
+							Item: &Object{
+								Fields: []*Field{
 									{
-										SegmentType: StaticSegmentType,
-										Data:        []byte(`}}}`),
+										Name: []byte("userName"),
+										Value: &String{
+											Path: []string{"name"},
+										},
 									},
 								},
 							},
-							DataSource: serviceOne,
-							Variables: NewVariables(
-								&ContextVariable{
-									Path: []string{"firstArg"},
-								},
-								&ContextVariable{
-									Path: []string{"thirdArg"},
-								},
-							),
-							ProcessResponseConfig: ProcessResponseConfig{
-								ExtractGraphqlResponse: true,
-							},
 						},
-						&SingleFetch{
-							BufferId: 1,
-							Input:    `{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":$$1$$,"secondArg":$$0$$}}}`,
-							InputTemplate: InputTemplate{
-								Segments: []TemplateSegment{
-									{
-										SegmentType: StaticSegmentType,
-										Data:        []byte(`{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":`),
-									},
-									{
-										SegmentType:        VariableSegmentType,
-										VariableKind:       ContextVariableKind,
-										VariableSourcePath: []string{"fourthArg"},
-										Renderer:           NewPlainVariableRendererWithValidation(`{"type":"number"}`),
-									},
-									{
-										SegmentType: StaticSegmentType,
-										Data:        []byte(`,"secondArg":`),
-									},
-									{
-										SegmentType:        VariableSegmentType,
-										VariableKind:       ContextVariableKind,
-										VariableSourcePath: []string{"secondArg"},
-										Renderer:           NewPlainVariableRendererWithValidation(`{"type":"boolean"}`),
-									},
+					},
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("admins"),
+						Value: &Array{
+							Path:                []string{"admins"},
+							Nullable:            true,
+							ResolveAsynchronous: true,
+							Item: &Object{
+								Fields: []*Field{
 									{
-										SegmentType: StaticSegmentType,
-										Data:        []byte(`}}}`),
+										Name: []byte("adminName"),
+										Value: &String{
+											Path: []string{"name"},
+										},
 									},
 								},
 							},
-							DataSource: serviceTwo,
-							Variables: NewVariables(
-								&ContextVariable{
-									Path: []string{"secondArg"},
-								},
-								&ContextVariable{
-									Path: []string{"fourthArg"},
-								},
-							),
-							ProcessResponseConfig: ProcessResponseConfig{
-								ExtractGraphqlResponse: true,
-							},
 						},
 					},
 				},
+			},
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["users",1,"userName"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["admins",1,"adminName"]}],"data":{"users":null,"admins":null}}`
+	}))
+	t.Run("extensions should be written after data", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:              0,
+					DataSource:            FakeDataSource(`{"errors":[{"message":"errorMessage"}],"data":{"name":"Jens"}}`),
+					ProcessResponseConfig: ProcessResponseConfig{ExtractGraphqlResponse: true},
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("name"),
+						Value: &String{
+							Path: []string{"name"},
+						},
+					},
+				},
+			},
+			Extensions: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"cost":3,"cache":{"maxAge":60}}`),
+				},
 				Fields: []*Field{
 					{
+						HasBuffer: true,
 						BufferID:  0,
+						Name:      []byte("cost"),
+						Value: &Integer{
+							Path: []string{"cost"},
+						},
+					},
+					{
 						HasBuffer: true,
-						Name:      []byte("serviceOne"),
+						BufferID:  0,
+						Name:      []byte("cacheControl"),
 						Value: &Object{
-							Path: []string{"serviceOne"},
+							Path: []string{"cache"},
 							Fields: []*Field{
 								{
-									Name: []byte("fieldOne"),
-									Value: &String{
-										Path: []string{"fieldOne"},
+									Name: []byte("maxAge"),
+									Value: &Integer{
+										Path: []string{"maxAge"},
 									},
 								},
 							},
 						},
 					},
-					{
-						BufferID:  1,
-						HasBuffer: true,
-						Name:      []byte("serviceTwo"),
-						Value: &Object{
-							Path: []string{"serviceTwo"},
-							Fetch: &SingleFetch{
-								BufferId: 2,
-								Input:    `{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`,
-								InputTemplate: InputTemplate{
-									Segments: []TemplateSegment{
-										{
-											SegmentType: StaticSegmentType,
-											Data:        []byte(`{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`),
-										},
-									},
-								},
-								DataSource: nestedServiceOne,
-								Variables:  Variables{},
-								ProcessResponseConfig: ProcessResponseConfig{
-									ExtractGraphqlResponse: true,
-								},
-							},
-							Fields: []*Field{
-								{
-									Name: []byte("fieldTwo"),
-									Value: &String{
-										Path: []string{"fieldTwo"},
-									},
-								},
-								{
-									BufferID:  2,
-									HasBuffer: true,
-									Name:      []byte("serviceOneResponse"),
-									Value: &Object{
-										Path: []string{"serviceOne"},
-										Fields: []*Field{
-											{
-												Name: []byte("fieldOne"),
-												Value: &String{
-													Path: []string{"fieldOne"},
-												},
-											},
-										},
-									},
-								},
-							},
+				},
+			},
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"errorMessage"}],"data":{"name":"Jens"},"extensions":{"cost":3,"cacheControl":{"maxAge":60}}}`
+	}))
+	t.Run("non-nullable field of the root object is null", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"name":null}`),
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("name"),
+						Value: &String{
+							Path: []string{"name"},
+						},
+					},
+				},
+			},
+			Extensions: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"cost":3}`),
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("cost"),
+						Value: &Integer{
+							Path: []string{"cost"},
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["name"]}],"data":null,"extensions":{"cost":3}}`
+	}))
+	t.Run("fetch exceeding its timeout should resolve with an upstream timeout error", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &ParallelFetch{
+					Fetches: []Fetch{
+						&SingleFetch{
+							BufferId:   0,
+							DataSource: &_blockingDataSource{},
+							Timeout:    time.Millisecond * 10,
+						},
+						&SingleFetch{
+							BufferId:   1,
+							DataSource: FakeDataSource(`{"fast":"fastValue"}`),
+							Timeout:    time.Second * 10,
 						},
 					},
+				},
+				Fields: []*Field{
 					{
 						BufferID:  0,
 						HasBuffer: true,
-						Name:      []byte("anotherServiceOne"),
-						Value: &Object{
-							Path: []string{"anotherServiceOne"},
-							Fields: []*Field{
-								{
-									Name: []byte("fieldOne"),
-									Value: &String{
-										Path: []string{"fieldOne"},
-									},
-								},
-							},
+						Name:      []byte("slow"),
+						Value: &String{
+							Path:     []string{"slow"},
+							Nullable: true,
 						},
 					},
 					{
 						BufferID:  1,
 						HasBuffer: true,
-						Name:      []byte("secondServiceTwo"),
-						Value: &Object{
-							Path: []string{"secondServiceTwo"},
-							Fields: []*Field{
-								{
-									Name: []byte("fieldTwo"),
-									Value: &String{
-										Path: []string{"fieldTwo"},
-									},
-								},
-							},
+						Name:      []byte("fast"),
+						Value: &String{
+							Path:     []string{"fast"},
+							Nullable: true,
 						},
 					},
+				},
+			},
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"upstream timeout"}],"data":{"slow":null,"fast":"fastValue"}}`
+	}))
+	t.Run("nested fetch exceeding its timeout should add the path to the error", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"user":{"id":1}}`),
+				},
+				Fields: []*Field{
 					{
 						BufferID:  0,
 						HasBuffer: true,
-						Name:      []byte("reusingServiceOne"),
+						Name:      []byte("user"),
 						Value: &Object{
-							Path: []string{"reusingServiceOne"},
+							Path:     []string{"user"},
+							Nullable: true,
+							Fetch: &SingleFetch{
+								BufferId:   1,
+								DataSource: &_blockingDataSource{},
+								Timeout:    time.Millisecond * 10,
+							},
 							Fields: []*Field{
 								{
-									Name: []byte("fieldOne"),
+									Name: []byte("id"),
+									Value: &Integer{
+										Path: []string{"id"},
+									},
+								},
+								{
+									BufferID:  1,
+									HasBuffer: true,
+									Name:      []byte("name"),
 									Value: &String{
-										Path: []string{"fieldOne"},
+										Path:     []string{"name"},
+										Nullable: true,
 									},
 								},
 							},
@@ -2290,188 +2593,72 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 					},
 				},
 			},
-		}, Context{Context: context.Background(), Variables: []byte(`{"firstArg":"firstArgValue","thirdArg":123,"secondArg": true, "fourthArg": 12.34}`)}, `{"data":{"serviceOne":{"fieldOne":"fieldOneValue"},"serviceTwo":{"fieldTwo":"fieldTwoValue","serviceOneResponse":{"fieldOne":"fieldOneValue"}},"anotherServiceOne":{"fieldOne":"anotherFieldOneValue"},"secondServiceTwo":{"fieldTwo":"secondFieldTwoValue"},"reusingServiceOne":{"fieldOne":"reUsingFieldOneValue"}}}`
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"upstream timeout","path":["user"]}],"data":{"user":{"id":1,"name":null}}}`
 	}))
-	t.Run("federation", testFn(true, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
-
-		userService := NewMockDataSource(ctrl)
-		userService.EXPECT().
-			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
-			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
-				actual := string(input)
-				expected := `{"method":"POST","url":"http://localhost:4001","body":{"query":"{me {id username}}"}}`
-				assert.Equal(t, expected, actual)
-				pair := NewBufPair()
-				pair.Data.WriteString(`{"me": {"id": "1234","username": "Me","__typename": "User"}}`)
-				return writeGraphqlResponse(pair, w, false)
-			})
-
-		reviewsService := NewMockDataSource(ctrl)
-		reviewsService.EXPECT().
-			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
-			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
-				actual := string(input)
-				expected := `{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`
-				assert.Equal(t, expected, actual)
-				pair := NewBufPair()
-				pair.Data.WriteString(`{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-1","__typename": "Product"}}]}`)
-				return writeGraphqlResponse(pair, w, false)
-			})
-
-		productServiceCallCount := 0
-
-		productService := NewMockDataSource(ctrl)
-		productService.EXPECT().
-			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
-			Do(func(ctx context.Context, input []byte, w io.Writer) (err error) {
-				actual := string(input)
-				productServiceCallCount++
-				switch productServiceCallCount {
-				case 1:
-					expected := `{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`
-					assert.Equal(t, expected, actual)
-					pair := NewBufPair()
-					pair.Data.WriteString(`{"name": "Trilby"}`)
-					return writeGraphqlResponse(pair, w, false)
-				case 2:
-					expected := `{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`
-					assert.Equal(t, expected, actual)
-					pair := NewBufPair()
-					pair.Data.WriteString(`{"name": "Trilby"}`)
-					return writeGraphqlResponse(pair, w, false)
-				}
-				return
-			}).
-			Return(nil).Times(2)
-
+	t.Run("upstream error extensions should be passed through", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
 		return &GraphQLResponse{
 			Data: &Object{
 				Fetch: &SingleFetch{
-					BufferId: 0,
-					InputTemplate: InputTemplate{
-						Segments: []TemplateSegment{
-							{
-								Data:        []byte(`{"method":"POST","url":"http://localhost:4001","body":{"query":"{me {id username}}"}}`),
-								SegmentType: StaticSegmentType,
-							},
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"errors":[{"message":"forbidden","path":["user","email"],"extensions":{"code":"FORBIDDEN","details":{"scopes":["read:email"],"retry":false}}},{"message":"no extensions","locations":[{"line":1,"column":2}]}],"data":{"name":"Jens"}}`),
+					ProcessResponseConfig: ProcessResponseConfig{
+						ExtractGraphqlResponse: true,
+					},
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("name"),
+						Value: &String{
+							Path: []string{"name"},
 						},
 					},
-					DataSource: userService,
+				},
+			},
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"forbidden","path":["user","email"],"extensions":{"code":"FORBIDDEN","details":{"scopes":["read:email"],"retry":false}}},{"message":"no extensions","locations":[{"line":1,"column":2}]}],"data":{"name":"Jens"}}`
+	}))
+	t.Run("errors of a fetch should be capped to its max errors", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"errors":[{"message":"row 1 failed"},{"message":"row 2 failed"},{"message":"row 3 failed"}],"data":{"user":{"id":1}}}`),
+					MaxErrors:  1,
 					ProcessResponseConfig: ProcessResponseConfig{
 						ExtractGraphqlResponse: true,
 					},
 				},
 				Fields: []*Field{
 					{
-						HasBuffer: true,
 						BufferID:  0,
-						Name:      []byte("me"),
+						HasBuffer: true,
+						Name:      []byte("user"),
 						Value: &Object{
+							Path: []string{"user"},
 							Fetch: &SingleFetch{
-								BufferId: 1,
-								InputTemplate: InputTemplate{
-									Segments: []TemplateSegment{
-										{
-											Data:        []byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":`),
-											SegmentType: StaticSegmentType,
-										},
-										{
-											SegmentType:        VariableSegmentType,
-											VariableKind:       ObjectVariableKind,
-											VariableSourcePath: []string{"id"},
-											Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
-										},
-										{
-											Data:        []byte(`,"__typename":"User"}]}}}`),
-											SegmentType: StaticSegmentType,
-										},
-									},
-								},
-								DataSource: reviewsService,
+								BufferId:   1,
+								DataSource: FakeDataSource(`{"errors":[{"message":"review 1 failed"},{"message":"review 2 failed"}],"data":{"reviews":null}}`),
 								ProcessResponseConfig: ProcessResponseConfig{
 									ExtractGraphqlResponse: true,
 								},
 							},
-							Path:     []string{"me"},
-							Nullable: true,
 							Fields: []*Field{
 								{
 									Name: []byte("id"),
-									Value: &String{
+									Value: &Integer{
 										Path: []string{"id"},
 									},
 								},
 								{
-									Name: []byte("username"),
-									Value: &String{
-										Path: []string{"username"},
-									},
-								},
-								{
-
-									HasBuffer: true,
 									BufferID:  1,
+									HasBuffer: true,
 									Name:      []byte("reviews"),
 									Value: &Array{
 										Path:     []string{"reviews"},
 										Nullable: true,
-										Item: &Object{
-											Nullable: true,
-											Fields: []*Field{
-												{
-													Name: []byte("body"),
-													Value: &String{
-														Path: []string{"body"},
-													},
-												},
-												{
-													Name: []byte("product"),
-													Value: &Object{
-														Path: []string{"product"},
-														Fetch: &SingleFetch{
-															BufferId:   2,
-															DataSource: productService,
-															InputTemplate: InputTemplate{
-																Segments: []TemplateSegment{
-																	{
-																		Data:        []byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":`),
-																		SegmentType: StaticSegmentType,
-																	},
-																	{
-																		SegmentType:        VariableSegmentType,
-																		VariableKind:       ObjectVariableKind,
-																		VariableSourcePath: []string{"upc"},
-																		Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
-																	},
-																	{
-																		Data:        []byte(`,"__typename":"Product"}]}}}`),
-																		SegmentType: StaticSegmentType,
-																	},
-																},
-															},
-															ProcessResponseConfig: ProcessResponseConfig{
-																ExtractGraphqlResponse: true,
-															},
-														},
-														Fields: []*Field{
-															{
-																Name: []byte("upc"),
-																Value: &String{
-																	Path: []string{"upc"},
-																},
-															},
-															{
-																HasBuffer: true,
-																BufferID:  2,
-																Name:      []byte("name"),
-																Value: &String{
-																	Path: []string{"name"},
-																},
-															},
-														},
-													},
-												},
-											},
+										Item: &String{
+											Path: []string{"body"},
 										},
 									},
 								},
@@ -2480,203 +2667,317 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 					},
 				},
 			},
-		}, Context{Context: context.Background(), Variables: nil}, `{"data":{"me":{"id":"1234","username":"Me","reviews":[{"body":"A highly effective form of birth control.","product":{"upc":"top-1","name":"Trilby"}},{"body":"Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product":{"upc":"top-1","name":"Trilby"}}]}}}`
+		}, Context{Context: context.Background()}, `{"errors":[{"message":"row 1 failed"},{"message":"review 1 failed"},{"message":"review 2 failed"}],"data":{"user":{"id":1,"reviews":null}}}`
 	}))
-	t.Run("federation with enabled dataloader", testFn(true, true, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
-		userService := NewMockDataSource(ctrl)
-		userService.EXPECT().
+	t.Run("json scalar should preserve upstream key order", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"meta":{"z":1,"a":{"y":true,"b":[3,2,1]},"m":null},"escaped":"{\"z\":1,\"a\":{\"y\":2,\"b\":3}}"}`),
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("meta"),
+						Value: &String{
+							Path:                 []string{"meta"},
+							UnescapeResponseJson: true,
+						},
+					},
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("escaped"),
+						Value: &String{
+							Path:                 []string{"escaped"},
+							UnescapeResponseJson: true,
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background()}, `{"data":{"meta":{"z":1,"a":{"y":true,"b":[3,2,1]},"m":null},"escaped":{"z":1,"a":{"y":2,"b":3}}}}`
+	}))
+	t.Run("json scalars from parallel fetches should preserve upstream key order", testFn(false, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &ParallelFetch{
+					Fetches: []Fetch{
+						&SingleFetch{
+							BufferId:   0,
+							DataSource: FakeDataSource(`{"first":{"z":1,"y":2,"x":3}}`),
+						},
+						&SingleFetch{
+							BufferId:   1,
+							DataSource: FakeDataSource(`{"second":{"c":1,"b":2,"a":3}}`),
+						},
+					},
+				},
+				Fields: []*Field{
+					{
+						BufferID:  1,
+						HasBuffer: true,
+						Name:      []byte("second"),
+						Value: &String{
+							Path:                 []string{"second"},
+							UnescapeResponseJson: true,
+						},
+					},
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("first"),
+						Value: &String{
+							Path:                 []string{"first"},
+							UnescapeResponseJson: true,
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background()}, `{"data":{"second":{"c":1,"b":2,"a":3},"first":{"z":1,"y":2,"x":3}}}`
+	}))
+	t.Run("complex GraphQL Server plan", testFn(true, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		serviceOne := NewMockDataSource(ctrl)
+		serviceOne.EXPECT().
 			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
 			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
 				actual := string(input)
-				expected := `{"method":"POST","url":"http://localhost:4001","body":{"query":"{me {id username}}"}}`
+				expected := `{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":123,"firstArg":"firstArgValue"}}}`
 				assert.Equal(t, expected, actual)
 				pair := NewBufPair()
-				pair.Data.WriteString(`{"me": {"id": "1234","username": "Me","__typename": "User"}}`)
+				pair.Data.WriteString(`{"serviceOne":{"fieldOne":"fieldOneValue"},"anotherServiceOne":{"fieldOne":"anotherFieldOneValue"},"reusingServiceOne":{"fieldOne":"reUsingFieldOneValue"}}`)
 				return writeGraphqlResponse(pair, w, false)
 			})
 
-		reviewBatchFactory := NewMockDataSourceBatchFactory(ctrl)
-		reviewBatchFactory.EXPECT().
-			CreateBatch([][]byte{
-				[]byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`),
-			}).
-			Return(NewFakeDataSourceBatch(
-				`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`,
-				[]resultedBufPair{
-					{data: `{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-2","__typename": "Product"}}]}`},
-				}), nil)
-		reviewsService := NewMockDataSource(ctrl)
-		reviewsService.EXPECT().
+		serviceTwo := NewMockDataSource(ctrl)
+		serviceTwo.EXPECT().
 			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
 			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
 				actual := string(input)
-				expected := `{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`
+				expected := `{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":12.34,"secondArg":true}}}`
 				assert.Equal(t, expected, actual)
+
 				pair := NewBufPair()
-				pair.Data.WriteString(`{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-2","__typename": "Product"}}]}`)
+				pair.Data.WriteString(`{"serviceTwo":{"fieldTwo":"fieldTwoValue"},"secondServiceTwo":{"fieldTwo":"secondFieldTwoValue"}}`)
 				return writeGraphqlResponse(pair, w, false)
 			})
 
-		productBatchFactory := NewMockDataSourceBatchFactory(ctrl)
-		productBatchFactory.EXPECT().
-			CreateBatch(
-				[][]byte{
-					[]byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`),
-					[]byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-2","__typename":"Product"}]}}}`),
-				},
-			).Return(NewFakeDataSourceBatch(
-			`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"},{"upc":"top-2","__typename":"Product"}]}}}`,
-			[]resultedBufPair{
-				{data: `{"name": "Trilby"}`},
-				{data: `{"name": "Fedora"}`},
-			}), nil)
-		productService := NewMockDataSource(ctrl)
-		productService.EXPECT().
+		nestedServiceOne := NewMockDataSource(ctrl)
+		nestedServiceOne.EXPECT().
 			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
 			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
 				actual := string(input)
-				expected := `{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"},{"upc":"top-2","__typename":"Product"}]}}}`
+				expected := `{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`
 				assert.Equal(t, expected, actual)
 				pair := NewBufPair()
-				pair.Data.WriteString(`[{"name": "Trilby"},{"name": "Fedora"}]`)
+				pair.Data.WriteString(`{"serviceOne":{"fieldOne":"fieldOneValue"}}`)
 				return writeGraphqlResponse(pair, w, false)
 			})
 
 		return &GraphQLResponse{
 			Data: &Object{
-				Fetch: &SingleFetch{
-					BufferId: 0,
-					InputTemplate: InputTemplate{
-						Segments: []TemplateSegment{
-							{
-								Data:        []byte(`{"method":"POST","url":"http://localhost:4001","body":{"query":"{me {id username}}"}}`),
-								SegmentType: StaticSegmentType,
+				Fetch: &ParallelFetch{
+					Fetches: []Fetch{
+						&SingleFetch{
+							BufferId: 0,
+							Input:    `{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":$$1$$,"firstArg":$$0$$}}}`,
+							InputTemplate: InputTemplate{
+								Segments: []TemplateSegment{
+									{
+										SegmentType: StaticSegmentType,
+										Data:        []byte(`{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":`),
+									},
+									{
+										SegmentType:        VariableSegmentType,
+										VariableKind:       ContextVariableKind,
+										VariableSourcePath: []string{"thirdArg"},
+										Renderer:           NewPlainVariableRendererWithValidation(`{"type":"number"}`),
+									},
+									{
+										SegmentType: StaticSegmentType,
+										Data:        []byte(`,"firstArg":`),
+									},
+									{
+										SegmentType:        VariableSegmentType,
+										VariableKind:       ContextVariableKind,
+										VariableSourcePath: []string{"firstArg"},
+										Renderer:           NewPlainVariableRendererWithValidation(`{"type":"string"}`),
+									},
+									{
+										SegmentType: StaticSegmentType,
+										Data:        []byte(`}}}`),
+									},
+								},
+							},
+							DataSource: serviceOne,
+							Variables: NewVariables(
+								&ContextVariable{
+									Path: []string{"firstArg"},
+								},
+								&ContextVariable{
+									Path: []string{"thirdArg"},
+								},
+							),
+							ProcessResponseConfig: ProcessResponseConfig{
+								ExtractGraphqlResponse: true,
+							},
+						},
+						&SingleFetch{
+							BufferId: 1,
+							Input:    `{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":$$1$$,"secondArg":$$0$$}}}`,
+							InputTemplate: InputTemplate{
+								Segments: []TemplateSegment{
+									{
+										SegmentType: StaticSegmentType,
+										Data:        []byte(`{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":`),
+									},
+									{
+										SegmentType:        VariableSegmentType,
+										VariableKind:       ContextVariableKind,
+										VariableSourcePath: []string{"fourthArg"},
+										Renderer:           NewPlainVariableRendererWithValidation(`{"type":"number"}`),
+									},
+									{
+										SegmentType: StaticSegmentType,
+										Data:        []byte(`,"secondArg":`),
+									},
+									{
+										SegmentType:        VariableSegmentType,
+										VariableKind:       ContextVariableKind,
+										VariableSourcePath: []string{"secondArg"},
+										Renderer:           NewPlainVariableRendererWithValidation(`{"type":"boolean"}`),
+									},
+									{
+										SegmentType: StaticSegmentType,
+										Data:        []byte(`}}}`),
+									},
+								},
+							},
+							DataSource: serviceTwo,
+							Variables: NewVariables(
+								&ContextVariable{
+									Path: []string{"secondArg"},
+								},
+								&ContextVariable{
+									Path: []string{"fourthArg"},
+								},
+							),
+							ProcessResponseConfig: ProcessResponseConfig{
+								ExtractGraphqlResponse: true,
 							},
 						},
-					},
-					DataSource: userService,
-					ProcessResponseConfig: ProcessResponseConfig{
-						ExtractGraphqlResponse: true,
 					},
 				},
 				Fields: []*Field{
 					{
-						HasBuffer: true,
 						BufferID:  0,
-						Name:      []byte("me"),
+						HasBuffer: true,
+						Name:      []byte("serviceOne"),
 						Value: &Object{
-							Fetch: &BatchFetch{
-								Fetch: &SingleFetch{
-									BufferId: 1,
-									InputTemplate: InputTemplate{
-										Segments: []TemplateSegment{
-											{
-												Data:        []byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":`),
-												SegmentType: StaticSegmentType,
-											},
-											{
-												SegmentType:        VariableSegmentType,
-												VariableKind:       ObjectVariableKind,
-												VariableSourcePath: []string{"id"},
-												Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
-											},
+							Path: []string{"serviceOne"},
+							Fields: []*Field{
+								{
+									Name: []byte("fieldOne"),
+									Value: &String{
+										Path: []string{"fieldOne"},
+									},
+								},
+							},
+						},
+					},
+					{
+						BufferID:  1,
+						HasBuffer: true,
+						Name:      []byte("serviceTwo"),
+						Value: &Object{
+							Path: []string{"serviceTwo"},
+							Fetch: &SingleFetch{
+								BufferId: 2,
+								Input:    `{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`,
+								InputTemplate: InputTemplate{
+									Segments: []TemplateSegment{
+										{
+											SegmentType: StaticSegmentType,
+											Data:        []byte(`{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`),
+										},
+									},
+								},
+								DataSource: nestedServiceOne,
+								Variables:  Variables{},
+								ProcessResponseConfig: ProcessResponseConfig{
+									ExtractGraphqlResponse: true,
+								},
+							},
+							Fields: []*Field{
+								{
+									Name: []byte("fieldTwo"),
+									Value: &String{
+										Path: []string{"fieldTwo"},
+									},
+								},
+								{
+									BufferID:  2,
+									HasBuffer: true,
+									Name:      []byte("serviceOneResponse"),
+									Value: &Object{
+										Path: []string{"serviceOne"},
+										Fields: []*Field{
 											{
-												Data:        []byte(`,"__typename":"User"}]}}}`),
-												SegmentType: StaticSegmentType,
+												Name: []byte("fieldOne"),
+												Value: &String{
+													Path: []string{"fieldOne"},
+												},
 											},
 										},
 									},
-									DataSource: reviewsService,
-									ProcessResponseConfig: ProcessResponseConfig{
-										ExtractGraphqlResponse: true,
-									},
 								},
-								BatchFactory: reviewBatchFactory,
 							},
-							Path:     []string{"me"},
-							Nullable: true,
+						},
+					},
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("anotherServiceOne"),
+						Value: &Object{
+							Path: []string{"anotherServiceOne"},
 							Fields: []*Field{
 								{
-									Name: []byte("id"),
+									Name: []byte("fieldOne"),
 									Value: &String{
-										Path: []string{"id"},
+										Path: []string{"fieldOne"},
 									},
 								},
+							},
+						},
+					},
+					{
+						BufferID:  1,
+						HasBuffer: true,
+						Name:      []byte("secondServiceTwo"),
+						Value: &Object{
+							Path: []string{"secondServiceTwo"},
+							Fields: []*Field{
 								{
-									Name: []byte("username"),
+									Name: []byte("fieldTwo"),
 									Value: &String{
-										Path: []string{"username"},
+										Path: []string{"fieldTwo"},
 									},
 								},
+							},
+						},
+					},
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("reusingServiceOne"),
+						Value: &Object{
+							Path: []string{"reusingServiceOne"},
+							Fields: []*Field{
 								{
-
-									HasBuffer: true,
-									BufferID:  1,
-									Name:      []byte("reviews"),
-									Value: &Array{
-										Path:     []string{"reviews"},
-										Nullable: true,
-										Item: &Object{
-											Nullable: true,
-											Fields: []*Field{
-												{
-													Name: []byte("body"),
-													Value: &String{
-														Path: []string{"body"},
-													},
-												},
-												{
-													Name: []byte("product"),
-													Value: &Object{
-														Path: []string{"product"},
-														Fetch: &BatchFetch{
-															Fetch: &SingleFetch{
-																BufferId:   2,
-																DataSource: productService,
-																InputTemplate: InputTemplate{
-																	Segments: []TemplateSegment{
-																		{
-																			Data:        []byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":`),
-																			SegmentType: StaticSegmentType,
-																		},
-																		{
-																			SegmentType:        VariableSegmentType,
-																			VariableKind:       ObjectVariableKind,
-																			VariableSourcePath: []string{"upc"},
-																			Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
-																		},
-																		{
-																			Data:        []byte(`,"__typename":"Product"}]}}}`),
-																			SegmentType: StaticSegmentType,
-																		},
-																	},
-																},
-																ProcessResponseConfig: ProcessResponseConfig{
-																	ExtractGraphqlResponse: true,
-																},
-															},
-															BatchFactory: productBatchFactory,
-														},
-														Fields: []*Field{
-															{
-																Name: []byte("upc"),
-																Value: &String{
-																	Path: []string{"upc"},
-																},
-															},
-															{
-																HasBuffer: true,
-																BufferID:  2,
-																Name:      []byte("name"),
-																Value: &String{
-																	Path: []string{"name"},
-																},
-															},
-														},
-													},
-												},
-											},
-										},
+									Name: []byte("fieldOne"),
+									Value: &String{
+										Path: []string{"fieldOne"},
 									},
 								},
 							},
@@ -2684,9 +2985,9 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 					},
 				},
 			},
-		}, Context{Context: context.Background(), Variables: nil}, `{"data":{"me":{"id":"1234","username":"Me","reviews":[{"body":"A highly effective form of birth control.","product":{"upc":"top-1","name":"Trilby"}},{"body":"Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product":{"upc":"top-2","name":"Fedora"}}]}}}`
+		}, Context{Context: context.Background(), Variables: []byte(`{"firstArg":"firstArgValue","thirdArg":123,"secondArg": true, "fourthArg": 12.34}`)}, `{"data":{"serviceOne":{"fieldOne":"fieldOneValue"},"serviceTwo":{"fieldTwo":"fieldTwoValue","serviceOneResponse":{"fieldOne":"fieldOneValue"}},"anotherServiceOne":{"fieldOne":"anotherFieldOneValue"},"secondServiceTwo":{"fieldTwo":"secondFieldTwoValue"},"reusingServiceOne":{"fieldOne":"reUsingFieldOneValue"}}}`
 	}))
-	t.Run("federation with enabled dataloader and fetch error ", testFn(true, true, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+	t.Run("federation", testFn(true, false, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
 
 		userService := NewMockDataSource(ctrl)
 		userService.EXPECT().
@@ -2700,16 +3001,6 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 				return writeGraphqlResponse(pair, w, false)
 			})
 
-		reviewBatchFactory := NewMockDataSourceBatchFactory(ctrl)
-		reviewBatchFactory.EXPECT().
-			CreateBatch([][]byte{
-				[]byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`),
-			}).
-			Return(NewFakeDataSourceBatch(
-				`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`,
-				[]resultedBufPair{
-					{data: `{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-2","__typename": "Product"}}]}`},
-				}), nil)
 		reviewsService := NewMockDataSource(ctrl)
 		reviewsService.EXPECT().
 			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
@@ -2718,31 +3009,35 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 				expected := `{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`
 				assert.Equal(t, expected, actual)
 				pair := NewBufPair()
-				pair.Data.WriteString(`{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-2","__typename": "Product"}}]}`)
+				pair.Data.WriteString(`{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-1","__typename": "Product"}}]}`)
 				return writeGraphqlResponse(pair, w, false)
 			})
 
-		productBatchFactory := NewMockDataSourceBatchFactory(ctrl)
-		productBatchFactory.EXPECT().
-			CreateBatch(
-				[][]byte{
-					[]byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`),
-					[]byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-2","__typename":"Product"}]}}}`),
-				},
-			).Return(NewFakeDataSourceBatch(
-			`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"},{"upc":"top-2","__typename":"Product"}]}}}`,
-			[]resultedBufPair{{data: `null`, err: "errorMessage"}, {data: `null`}}), nil)
+		productServiceCallCount := 0
+
 		productService := NewMockDataSource(ctrl)
 		productService.EXPECT().
 			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
-			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
+			Do(func(ctx context.Context, input []byte, w io.Writer) (err error) {
 				actual := string(input)
-				expected := `{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"},{"upc":"top-2","__typename":"Product"}]}}}`
-				assert.Equal(t, expected, actual)
-				pair := NewBufPair()
-				pair.WriteErr([]byte("errorMessage"), nil, nil, nil)
-				return writeGraphqlResponse(pair, w, false)
-			})
+				productServiceCallCount++
+				switch productServiceCallCount {
+				case 1:
+					expected := `{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`
+					assert.Equal(t, expected, actual)
+					pair := NewBufPair()
+					pair.Data.WriteString(`{"name": "Trilby"}`)
+					return writeGraphqlResponse(pair, w, false)
+				case 2:
+					expected := `{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`
+					assert.Equal(t, expected, actual)
+					pair := NewBufPair()
+					pair.Data.WriteString(`{"name": "Trilby"}`)
+					return writeGraphqlResponse(pair, w, false)
+				}
+				return
+			}).
+			Return(nil).Times(2)
 
 		return &GraphQLResponse{
 			Data: &Object{
@@ -2767,28 +3062,229 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 						BufferID:  0,
 						Name:      []byte("me"),
 						Value: &Object{
-							Fetch: &BatchFetch{
-								Fetch: &SingleFetch{
-									BufferId: 1,
-									InputTemplate: InputTemplate{
-										Segments: []TemplateSegment{
-											{
-												Data:        []byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":`),
-												SegmentType: StaticSegmentType,
-											},
-											{
-												SegmentType:        VariableSegmentType,
-												VariableKind:       ObjectVariableKind,
-												VariableSourcePath: []string{"id"},
-												Renderer:           NewPlainVariableRendererWithValidation(`{"type":"string"}`),
-											},
-											{
-												Data:        []byte(`,"__typename":"User"}]}}}`),
-												SegmentType: StaticSegmentType,
-											},
-										},
-									},
+							Fetch: &SingleFetch{
+								BufferId: 1,
+								InputTemplate: InputTemplate{
+									Segments: []TemplateSegment{
+										{
+											Data:        []byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":`),
+											SegmentType: StaticSegmentType,
+										},
+										{
+											SegmentType:        VariableSegmentType,
+											VariableKind:       ObjectVariableKind,
+											VariableSourcePath: []string{"id"},
+											Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
+										},
+										{
+											Data:        []byte(`,"__typename":"User"}]}}}`),
+											SegmentType: StaticSegmentType,
+										},
+									},
+								},
+								DataSource: reviewsService,
+								ProcessResponseConfig: ProcessResponseConfig{
+									ExtractGraphqlResponse: true,
+								},
+							},
+							Path:     []string{"me"},
+							Nullable: true,
+							Fields: []*Field{
+								{
+									Name: []byte("id"),
+									Value: &String{
+										Path: []string{"id"},
+									},
+								},
+								{
+									Name: []byte("username"),
+									Value: &String{
+										Path: []string{"username"},
+									},
+								},
+								{
+
+									HasBuffer: true,
+									BufferID:  1,
+									Name:      []byte("reviews"),
+									Value: &Array{
+										Path:     []string{"reviews"},
+										Nullable: true,
+										Item: &Object{
+											Nullable: true,
+											Fields: []*Field{
+												{
+													Name: []byte("body"),
+													Value: &String{
+														Path: []string{"body"},
+													},
+												},
+												{
+													Name: []byte("product"),
+													Value: &Object{
+														Path: []string{"product"},
+														Fetch: &SingleFetch{
+															BufferId:   2,
+															DataSource: productService,
+															InputTemplate: InputTemplate{
+																Segments: []TemplateSegment{
+																	{
+																		Data:        []byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":`),
+																		SegmentType: StaticSegmentType,
+																	},
+																	{
+																		SegmentType:        VariableSegmentType,
+																		VariableKind:       ObjectVariableKind,
+																		VariableSourcePath: []string{"upc"},
+																		Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
+																	},
+																	{
+																		Data:        []byte(`,"__typename":"Product"}]}}}`),
+																		SegmentType: StaticSegmentType,
+																	},
+																},
+															},
+															ProcessResponseConfig: ProcessResponseConfig{
+																ExtractGraphqlResponse: true,
+															},
+														},
+														Fields: []*Field{
+															{
+																Name: []byte("upc"),
+																Value: &String{
+																	Path: []string{"upc"},
+																},
+															},
+															{
+																HasBuffer: true,
+																BufferID:  2,
+																Name:      []byte("name"),
+																Value: &String{
+																	Path: []string{"name"},
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background(), Variables: nil}, `{"data":{"me":{"id":"1234","username":"Me","reviews":[{"body":"A highly effective form of birth control.","product":{"upc":"top-1","name":"Trilby"}},{"body":"Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product":{"upc":"top-1","name":"Trilby"}}]}}}`
+	}))
+	t.Run("federation with enabled dataloader", testFn(true, true, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
+		userService := NewMockDataSource(ctrl)
+		userService.EXPECT().
+			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
+			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
+				actual := string(input)
+				expected := `{"method":"POST","url":"http://localhost:4001","body":{"query":"{me {id username}}"}}`
+				assert.Equal(t, expected, actual)
+				pair := NewBufPair()
+				pair.Data.WriteString(`{"me": {"id": "1234","username": "Me","__typename": "User"}}`)
+				return writeGraphqlResponse(pair, w, false)
+			})
+
+		reviewBatchFactory := NewMockDataSourceBatchFactory(ctrl)
+		reviewBatchFactory.EXPECT().
+			CreateBatch([][]byte{
+				[]byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`),
+			}).
+			Return(NewFakeDataSourceBatch(
+				`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`,
+				[]resultedBufPair{
+					{data: `{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-2","__typename": "Product"}}]}`},
+				}), nil)
+		reviewsService := NewMockDataSource(ctrl)
+		reviewsService.EXPECT().
+			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
+			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
+				actual := string(input)
+				expected := `{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`
+				assert.Equal(t, expected, actual)
+				pair := NewBufPair()
+				pair.Data.WriteString(`{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-2","__typename": "Product"}}]}`)
+				return writeGraphqlResponse(pair, w, false)
+			})
+
+		productBatchFactory := NewMockDataSourceBatchFactory(ctrl)
+		productBatchFactory.EXPECT().
+			CreateBatch(
+				[][]byte{
+					[]byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`),
+					[]byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-2","__typename":"Product"}]}}}`),
+				},
+			).Return(NewFakeDataSourceBatch(
+			`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"},{"upc":"top-2","__typename":"Product"}]}}}`,
+			[]resultedBufPair{
+				{data: `{"name": "Trilby"}`},
+				{data: `{"name": "Fedora"}`},
+			}), nil)
+		productService := NewMockDataSource(ctrl)
+		productService.EXPECT().
+			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
+			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
+				actual := string(input)
+				expected := `{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"},{"upc":"top-2","__typename":"Product"}]}}}`
+				assert.Equal(t, expected, actual)
+				pair := NewBufPair()
+				pair.Data.WriteString(`[{"name": "Trilby"},{"name": "Fedora"}]`)
+				return writeGraphqlResponse(pair, w, false)
+			})
+
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId: 0,
+					InputTemplate: InputTemplate{
+						Segments: []TemplateSegment{
+							{
+								Data:        []byte(`{"method":"POST","url":"http://localhost:4001","body":{"query":"{me {id username}}"}}`),
+								SegmentType: StaticSegmentType,
+							},
+						},
+					},
+					DataSource: userService,
+					ProcessResponseConfig: ProcessResponseConfig{
+						ExtractGraphqlResponse: true,
+					},
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("me"),
+						Value: &Object{
+							Fetch: &BatchFetch{
+								Fetch: &SingleFetch{
+									BufferId: 1,
+									InputTemplate: InputTemplate{
+										Segments: []TemplateSegment{
+											{
+												Data:        []byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":`),
+												SegmentType: StaticSegmentType,
+											},
+											{
+												SegmentType:        VariableSegmentType,
+												VariableKind:       ObjectVariableKind,
+												VariableSourcePath: []string{"id"},
+												Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
+											},
+											{
+												Data:        []byte(`,"__typename":"User"}]}}}`),
+												SegmentType: StaticSegmentType,
+											},
+										},
+									},
 									DataSource: reviewsService,
+									ProcessResponseConfig: ProcessResponseConfig{
+										ExtractGraphqlResponse: true,
+									},
 								},
 								BatchFactory: reviewBatchFactory,
 							},
@@ -2842,7 +3338,7 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 																			SegmentType:        VariableSegmentType,
 																			VariableKind:       ObjectVariableKind,
 																			VariableSourcePath: []string{"upc"},
-																			Renderer:           NewPlainVariableRendererWithValidation(`{"type":"string"}`),
+																			Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
 																		},
 																		{
 																			Data:        []byte(`,"__typename":"Product"}]}}}`),
@@ -2883,29 +3379,287 @@ func TestResolver_ResolveGraphQLResponse(t *testing.T) {
 					},
 				},
 			},
-		}, Context{Context: context.Background(), Variables: nil}, `{"errors":[{"message":"errorMessage"},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["me","reviews","0","product"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["me","reviews","1","product"]}],"data":{"me":{"id":"1234","username":"Me","reviews":[null,null]}}}`
+		}, Context{Context: context.Background(), Variables: nil}, `{"data":{"me":{"id":"1234","username":"Me","reviews":[{"body":"A highly effective form of birth control.","product":{"upc":"top-1","name":"Trilby"}},{"body":"Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product":{"upc":"top-2","name":"Fedora"}}]}}}`
 	}))
-}
-
-func TestResolver_WithHeader(t *testing.T) {
-	cases := []struct {
-		name, header, variable string
-	}{
-		{"header and variable are of equal case", "Authorization", "Authorization"},
-		{"header is downcased and variable is uppercased", "authorization", "AUTHORIZATION"},
-		{"header is uppercasesed and variable is downcased", "AUTHORIZATION", "authorization"},
-	}
+	t.Run("federation with enabled dataloader and fetch error ", testFn(true, true, func(t *testing.T, ctrl *gomock.Controller) (node *GraphQLResponse, ctx Context, expectedOutput string) {
 
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			rCtx, cancel := context.WithCancel(context.Background())
-			defer cancel()
-			resolver := newResolver(rCtx, false, false)
+		userService := NewMockDataSource(ctrl)
+		userService.EXPECT().
+			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
+			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
+				actual := string(input)
+				expected := `{"method":"POST","url":"http://localhost:4001","body":{"query":"{me {id username}}"}}`
+				assert.Equal(t, expected, actual)
+				pair := NewBufPair()
+				pair.Data.WriteString(`{"me": {"id": "1234","username": "Me","__typename": "User"}}`)
+				return writeGraphqlResponse(pair, w, false)
+			})
 
-			header := make(http.Header)
-			header.Set(tc.header, "foo")
-			ctx := &Context{
-				Context: context.Background(),
+		reviewBatchFactory := NewMockDataSourceBatchFactory(ctrl)
+		reviewBatchFactory.EXPECT().
+			CreateBatch([][]byte{
+				[]byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`),
+			}).
+			Return(NewFakeDataSourceBatch(
+				`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`,
+				[]resultedBufPair{
+					{data: `{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-2","__typename": "Product"}}]}`},
+				}), nil)
+		reviewsService := NewMockDataSource(ctrl)
+		reviewsService.EXPECT().
+			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
+			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
+				actual := string(input)
+				expected := `{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":"1234","__typename":"User"}]}}}`
+				assert.Equal(t, expected, actual)
+				pair := NewBufPair()
+				pair.Data.WriteString(`{"reviews": [{"body": "A highly effective form of birth control.","product": {"upc": "top-1","__typename": "Product"}},{"body": "Fedoras are one of the most fashionable hats around and can look great with a variety of outfits.","product": {"upc": "top-2","__typename": "Product"}}]}`)
+				return writeGraphqlResponse(pair, w, false)
+			})
+
+		productBatchFactory := NewMockDataSourceBatchFactory(ctrl)
+		productBatchFactory.EXPECT().
+			CreateBatch(
+				[][]byte{
+					[]byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"}]}}}`),
+					[]byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-2","__typename":"Product"}]}}}`),
+				},
+			).Return(NewFakeDataSourceBatch(
+			`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"},{"upc":"top-2","__typename":"Product"}]}}}`,
+			[]resultedBufPair{{data: `null`, err: "errorMessage"}, {data: `null`}}), nil)
+		productService := NewMockDataSource(ctrl)
+		productService.EXPECT().
+			Load(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&bytes.Buffer{})).
+			DoAndReturn(func(ctx context.Context, input []byte, w io.Writer) (err error) {
+				actual := string(input)
+				expected := `{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":"top-1","__typename":"Product"},{"upc":"top-2","__typename":"Product"}]}}}`
+				assert.Equal(t, expected, actual)
+				pair := NewBufPair()
+				pair.WriteErr([]byte("errorMessage"), nil, nil, nil)
+				return writeGraphqlResponse(pair, w, false)
+			})
+
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId: 0,
+					InputTemplate: InputTemplate{
+						Segments: []TemplateSegment{
+							{
+								Data:        []byte(`{"method":"POST","url":"http://localhost:4001","body":{"query":"{me {id username}}"}}`),
+								SegmentType: StaticSegmentType,
+							},
+						},
+					},
+					DataSource: userService,
+					ProcessResponseConfig: ProcessResponseConfig{
+						ExtractGraphqlResponse: true,
+					},
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("me"),
+						Value: &Object{
+							Fetch: &BatchFetch{
+								Fetch: &SingleFetch{
+									BufferId: 1,
+									InputTemplate: InputTemplate{
+										Segments: []TemplateSegment{
+											{
+												Data:        []byte(`{"method":"POST","url":"http://localhost:4002","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on User {reviews {body product {upc __typename}}}}}","variables":{"representations":[{"id":`),
+												SegmentType: StaticSegmentType,
+											},
+											{
+												SegmentType:        VariableSegmentType,
+												VariableKind:       ObjectVariableKind,
+												VariableSourcePath: []string{"id"},
+												Renderer:           NewPlainVariableRendererWithValidation(`{"type":"string"}`),
+											},
+											{
+												Data:        []byte(`,"__typename":"User"}]}}}`),
+												SegmentType: StaticSegmentType,
+											},
+										},
+									},
+									DataSource: reviewsService,
+								},
+								BatchFactory: reviewBatchFactory,
+							},
+							Path:     []string{"me"},
+							Nullable: true,
+							Fields: []*Field{
+								{
+									Name: []byte("id"),
+									Value: &String{
+										Path: []string{"id"},
+									},
+								},
+								{
+									Name: []byte("username"),
+									Value: &String{
+										Path: []string{"username"},
+									},
+								},
+								{
+
+									HasBuffer: true,
+									BufferID:  1,
+									Name:      []byte("reviews"),
+									Value: &Array{
+										Path:     []string{"reviews"},
+										Nullable: true,
+										Item: &Object{
+											Nullable: true,
+											Fields: []*Field{
+												{
+													Name: []byte("body"),
+													Value: &String{
+														Path: []string{"body"},
+													},
+												},
+												{
+													Name: []byte("product"),
+													Value: &Object{
+														Path: []string{"product"},
+														Fetch: &BatchFetch{
+															Fetch: &SingleFetch{
+																BufferId:   2,
+																DataSource: productService,
+																InputTemplate: InputTemplate{
+																	Segments: []TemplateSegment{
+																		{
+																			Data:        []byte(`{"method":"POST","url":"http://localhost:4003","body":{"query":"query($representations: [_Any!]!){_entities(representations: $representations){... on Product {name}}}","variables":{"representations":[{"upc":`),
+																			SegmentType: StaticSegmentType,
+																		},
+																		{
+																			SegmentType:        VariableSegmentType,
+																			VariableKind:       ObjectVariableKind,
+																			VariableSourcePath: []string{"upc"},
+																			Renderer:           NewPlainVariableRendererWithValidation(`{"type":"string"}`),
+																		},
+																		{
+																			Data:        []byte(`,"__typename":"Product"}]}}}`),
+																			SegmentType: StaticSegmentType,
+																		},
+																	},
+																},
+																ProcessResponseConfig: ProcessResponseConfig{
+																	ExtractGraphqlResponse: true,
+																},
+															},
+															BatchFactory: productBatchFactory,
+														},
+														Fields: []*Field{
+															{
+																Name: []byte("upc"),
+																Value: &String{
+																	Path: []string{"upc"},
+																},
+															},
+															{
+																HasBuffer: true,
+																BufferID:  2,
+																Name:      []byte("name"),
+																Value: &String{
+																	Path: []string{"name"},
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, Context{Context: context.Background(), Variables: nil}, `{"errors":[{"message":"errorMessage"},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["me","reviews",0,"product","name"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["me","reviews",1,"product","name"]}],"data":{"me":{"id":"1234","username":"Me","reviews":[null,null]}}}`
+	}))
+}
+
+func TestResolver_ResolveGraphQLResponseToBytes(t *testing.T) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newResolver(rCtx, false, false)
+
+	response := func(name string) *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(fmt.Sprintf(`{"name":"%s"}`, name)),
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("name"),
+						Value: &String{
+							Path: []string{"name"},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	first, err := r.ResolveGraphQLResponseToBytes(NewContext(context.Background()), response("Jens"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `{"data":{"name":"Jens"}}`, string(first))
+
+	// resolving again reuses the pooled buffer, the first result must stay untouched
+	second, err := r.ResolveGraphQLResponseToBytes(NewContext(context.Background()), response("Jannik"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `{"data":{"name":"Jannik"}}`, string(second))
+	assert.Equal(t, `{"data":{"name":"Jens"}}`, string(first))
+}
+
+func TestBufPair_WriteErr(t *testing.T) {
+	t.Run("locations and path", func(t *testing.T) {
+		pair := NewBufPair()
+		pair.WriteErr([]byte("errorMessage"), []byte(`[{"line":3,"column":5}]`), []byte(`["user","name"]`), nil)
+		assert.Equal(t, `{"message":"errorMessage","locations":[{"line":3,"column":5}],"path":["user","name"]}`, pair.Errors.String())
+	})
+	t.Run("path without locations", func(t *testing.T) {
+		pair := NewBufPair()
+		pair.WriteErr([]byte("errorMessage"), nil, []byte(`["user"]`), []byte(`{"code":"NOT_FOUND"}`))
+		assert.Equal(t, `{"message":"errorMessage","path":["user"],"extensions":{"code":"NOT_FOUND"}}`, pair.Errors.String())
+	})
+	t.Run("locations without path", func(t *testing.T) {
+		pair := NewBufPair()
+		pair.WriteErr([]byte("errorMessage"), []byte(`[{"line":1,"column":1}]`), nil, nil)
+		assert.Equal(t, `{"message":"errorMessage","locations":[{"line":1,"column":1}]}`, pair.Errors.String())
+	})
+}
+
+func TestResolver_WithHeader(t *testing.T) {
+	cases := []struct {
+		name, header, variable string
+	}{
+		{"header and variable are of equal case", "Authorization", "Authorization"},
+		{"header is downcased and variable is uppercased", "authorization", "AUTHORIZATION"},
+		{"header is uppercasesed and variable is downcased", "AUTHORIZATION", "authorization"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			resolver := newResolver(rCtx, false, false)
+
+			header := make(http.Header)
+			header.Set(tc.header, "foo")
+			ctx := &Context{
+				Context: context.Background(),
 				Request: Request{
 					Header: header,
 				},
@@ -2958,230 +3712,314 @@ func TestResolver_WithHeader(t *testing.T) {
 	}
 }
 
-type TestFlushWriter struct {
-	flushed []string
-	buf     bytes.Buffer
+type _flakyDataSource struct {
+	failures int
+	attempts int
+	data     []byte
 }
 
-func (t *TestFlushWriter) Write(p []byte) (n int, err error) {
-	return t.buf.Write(p)
+func (f *_flakyDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	f.attempts++
+	if f.attempts <= f.failures {
+		return fmt.Errorf("transient failure %d", f.attempts)
+	}
+	_, err = w.Write(f.data)
+	return
 }
 
-func (t *TestFlushWriter) Flush() {
-	t.flushed = append(t.flushed, t.buf.String())
-	t.buf.Reset()
-}
+func TestResolver_ResolveSingleFetchWithRetry(t *testing.T) {
+	run := func(t *testing.T, ctx context.Context, dataSource DataSource, retry *RetryPolicy, expectedErr bool, expectedOutput string) {
+		t.Helper()
 
-func FakeStream(cancelFunc func(), messageFunc func(count int) (message string, ok bool)) *_fakeStream {
-	return &_fakeStream{
-		cancel:      cancelFunc,
-		messageFunc: messageFunc,
-	}
-}
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, true, false)
 
-type _fakeStream struct {
-	cancel      context.CancelFunc
-	messageFunc func(counter int) (message string, ok bool)
-}
-
-func (f *_fakeStream) Start(ctx context.Context, input []byte, next chan<- []byte) error {
-	go func() {
-		time.Sleep(time.Millisecond)
-		count := 0
-		for {
-			if count == 3 {
-				f.cancel()
-				return
-			}
-			message, ok := f.messageFunc(count)
-			next <- []byte(message)
-			if !ok {
-				f.cancel()
-				return
-			}
-			count++
-		}
-	}()
-	return nil
-}
-
-func TestResolver_ResolveGraphQLSubscription(t *testing.T) {
-
-	setup := func(ctx context.Context, stream *_fakeStream) (*Resolver, *GraphQLSubscription, *TestFlushWriter) {
-		plan := &GraphQLSubscription{
-			Trigger: GraphQLSubscriptionTrigger{
-				Source: stream,
-			},
-			Response: &GraphQLResponse{
-				Data: &Object{
-					Fields: []*Field{
-						{
-							Name: []byte("counter"),
-							Value: &Integer{
-								Path: []string{"counter"},
-							},
+		response := &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: dataSource,
+					Retry:      retry,
+					ProcessResponseConfig: ProcessResponseConfig{
+						ExtractGraphqlResponse: true,
+					},
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("name"),
+						Value: &String{
+							Path:     []string{"name"},
+							Nullable: true,
 						},
 					},
 				},
 			},
 		}
 
-		out := &TestFlushWriter{
-			buf: bytes.Buffer{},
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(&Context{Context: ctx}, response, nil, buf)
+		if expectedErr {
+			assert.Error(t, err)
+			return
 		}
-
-		return newResolver(ctx, false, false), plan, out
+		assert.NoError(t, err)
+		assert.Equal(t, expectedOutput, buf.String())
 	}
 
-	t.Run("should return errors if the upstream data has errors", func(t *testing.T) {
-		c, cancel := context.WithCancel(context.Background())
+	t.Run("retry until the fetch succeeds", func(t *testing.T) {
+		ds := &_flakyDataSource{failures: 2, data: []byte(`{"data":{"name":"Jens"}}`)}
+		run(t, context.Background(), ds, &RetryPolicy{MaxAttempts: 3, BackoffBase: time.Millisecond}, false, `{"data":{"name":"Jens"}}`)
+		assert.Equal(t, 3, ds.attempts)
+	})
+	t.Run("give up after max attempts", func(t *testing.T) {
+		ds := &_flakyDataSource{failures: 5, data: []byte(`{"data":{"name":"Jens"}}`)}
+		run(t, context.Background(), ds, &RetryPolicy{MaxAttempts: 3, BackoffBase: time.Millisecond}, true, "")
+		assert.Equal(t, 3, ds.attempts)
+	})
+	t.Run("zero policy disables retries", func(t *testing.T) {
+		ds := &_flakyDataSource{failures: 1, data: []byte(`{"data":{"name":"Jens"}}`)}
+		run(t, context.Background(), ds, &RetryPolicy{}, true, "")
+		assert.Equal(t, 1, ds.attempts)
+	})
+	t.Run("predicate decides whether to retry", func(t *testing.T) {
+		ds := &_flakyDataSource{failures: 1, data: []byte(`{"data":{"name":"Jens"}}`)}
+		run(t, context.Background(), ds, &RetryPolicy{
+			MaxAttempts: 3,
+			BackoffBase: time.Millisecond,
+			ShouldRetry: func(err error, bufPair *BufPair) bool {
+				return false
+			},
+		}, true, "")
+		assert.Equal(t, 1, ds.attempts)
+	})
+	t.Run("retry on upstream errors", func(t *testing.T) {
+		predicateCalls := 0
+		ds := FakeDataSource(`{"errors":[{"message":"service unavailable"}]}`)
+		run(t, context.Background(), ds, &RetryPolicy{
+			MaxAttempts: 2,
+			BackoffBase: time.Millisecond,
+			ShouldRetry: func(err error, bufPair *BufPair) bool {
+				predicateCalls++
+				return bufPair.HasErrors()
+			},
+		}, false, `{"errors":[{"message":"service unavailable"}],"data":{"name":null}}`)
+		assert.Equal(t, 1, predicateCalls)
+	})
+	t.Run("stop retrying when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
 		defer cancel()
+		ds := &_flakyDataSource{failures: 5, data: []byte(`{"data":{"name":"Jens"}}`)}
+		run(t, ctx, ds, &RetryPolicy{MaxAttempts: 3, BackoffBase: time.Hour}, true, "")
+		assert.Equal(t, 1, ds.attempts)
+	})
+}
 
-		fakeStream := FakeStream(cancel, func(count int) (message string, ok bool) {
-			return `{"errors":[{"message":"Validation error occurred","locations":[{"line":1,"column":1}],"extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}],"data":null}`, false
-		})
+func TestResolver_ResolveParallelFetchErrors(t *testing.T) {
+	parallelResponse := func(first, second DataSource) *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &ParallelFetch{
+					Fetches: []Fetch{
+						&SingleFetch{
+							BufferId:   0,
+							DataSource: first,
+						},
+						&SingleFetch{
+							BufferId:   1,
+							DataSource: second,
+						},
+					},
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("first"),
+						Value: &String{
+							Path:     []string{"first"},
+							Nullable: true,
+						},
+					},
+					{
+						BufferID:  1,
+						HasBuffer: true,
+						Name:      []byte("second"),
+						Value: &String{
+							Path:     []string{"second"},
+							Nullable: true,
+						},
+					},
+				},
+			},
+		}
+	}
 
-		resolver, plan, out := setup(c, fakeStream)
+	t.Run("failing root fetch doesn't abort its siblings", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
 
-		ctx := Context{
-			Context: c,
-		}
+		response := parallelResponse(FakeDataSource(`{"first":"ok"}`), &_flakyDataSource{failures: 1})
 
-		err := resolver.ResolveGraphQLSubscription(&ctx, plan, out)
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
 		assert.NoError(t, err)
-		assert.Equal(t, 1, len(out.flushed))
-		assert.Equal(t, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}]},{"message":"Validation error occurred","locations":[{"line":1,"column":1}],"extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}],"data":null}`, out.flushed[0])
+		assert.Equal(t, `{"errors":[{"message":"failed to fetch: transient failure 1"}],"data":{"first":"ok","second":null}}`, buf.String())
 	})
 
-	t.Run("should successfully get result from upstream", func(t *testing.T) {
-		c, cancel := context.WithCancel(context.Background())
+	t.Run("cancelling the operation cancels in-flight fetches", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
 		defer cancel()
+		r := newResolver(rCtx, false, false)
 
-		fakeStream := FakeStream(cancel, func(count int) (message string, ok bool) {
-			return fmt.Sprintf(`{"data":{"counter":%d}}`, count), true
-		})
+		slow := &_blockingDataSource{}
+		response := parallelResponse(slow, FakeDataSource(`{"second":"ok"}`))
 
-		resolver, plan, out := setup(c, fakeStream)
+		ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancelCtx()
 
-		ctx := Context{
-			Context: c,
+		err := r.ResolveGraphQLResponse(NewContext(ctx), response, nil, &bytes.Buffer{})
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, context.DeadlineExceeded, slow.err)
+	})
+}
+
+func TestResolver_ResolveNestedFetchFailure(t *testing.T) {
+	userObject := func(fetch Fetch) *Object {
+		return &Object{
+			Path:     []string{"user"},
+			Nullable: true,
+			Fetch:    fetch,
+			Fields: []*Field{
+				{
+					Name: []byte("id"),
+					Value: &Integer{
+						Path: []string{"id"},
+					},
+				},
+				{
+					BufferID:  1,
+					HasBuffer: true,
+					Name:      []byte("reviews"),
+					Value: &String{
+						Path:     []string{"reviews"},
+						Nullable: true,
+					},
+				},
+				{
+					BufferID:  2,
+					HasBuffer: true,
+					Name:      []byte("name"),
+					Value: &String{
+						Path:     []string{"name"},
+						Nullable: true,
+					},
+				},
+			},
 		}
+	}
 
-		err := resolver.ResolveGraphQLSubscription(&ctx, plan, out)
-		assert.NoError(t, err)
-		assert.Equal(t, 3, len(out.flushed))
-		assert.Equal(t, `{"data":{"counter":0}}`, out.flushed[0])
-		assert.Equal(t, `{"data":{"counter":1}}`, out.flushed[1])
-		assert.Equal(t, `{"data":{"counter":2}}`, out.flushed[2])
-	})
+	run := func(fetch Fetch, expectedOutput string) func(t *testing.T) {
+		return func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SingleFetch{
+						BufferId:   0,
+						DataSource: FakeDataSource(`{"user":{"id":1}}`),
+					},
+					Fields: []*Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("user"),
+							Value:     userObject(fetch),
+						},
+					},
+				},
+			}
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOutput, buf.String())
+		}
+	}
+
+	t.Run("failing fetch of parallel fetches", run(
+		&ParallelFetch{
+			Fetches: []Fetch{
+				&SingleFetch{
+					BufferId:   1,
+					DataSource: &_flakyDataSource{failures: 1},
+				},
+				&SingleFetch{
+					BufferId:   2,
+					DataSource: FakeDataSource(`{"name":"Jens"}`),
+				},
+			},
+		},
+		`{"errors":[{"message":"failed to fetch: transient failure 1","path":["user"]}],"data":{"user":{"id":1,"reviews":null,"name":"Jens"}}}`,
+	))
+	t.Run("failing single fetch", run(
+		&SingleFetch{
+			BufferId:   1,
+			DataSource: &_flakyDataSource{failures: 1},
+		},
+		`{"errors":[{"message":"failed to fetch: transient failure 1","path":["user"]}],"data":{"user":{"id":1,"reviews":null,"name":null}}}`,
+	))
 }
 
-func BenchmarkResolver_ResolveNode(b *testing.B) {
+func TestResolver_SetFetchDurationRecorder(t *testing.T) {
 	rCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	r := newResolver(rCtx, false, false)
 
-	resolver := newResolver(rCtx, true, false)
-
-	serviceOneDS := FakeDataSource(`{"serviceOne":{"fieldOne":"fieldOneValue"},"anotherServiceOne":{"fieldOne":"anotherFieldOneValue"},"reusingServiceOne":{"fieldOne":"reUsingFieldOneValue"}}`)
-	serviceTwoDS := FakeDataSource(`{"serviceTwo":{"fieldTwo":"fieldTwoValue"},"secondServiceTwo":{"fieldTwo":"secondFieldTwoValue"}}`)
-	nestedServiceOneDS := FakeDataSource(`{"serviceOne":{"fieldOne":"fieldOneValue"}}`)
+	mu := sync.Mutex{}
+	durations := map[string][]time.Duration{}
+	r.SetFetchDurationRecorder(func(dataSourceIdentifier []byte, duration time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		durations[string(dataSourceIdentifier)] = append(durations[string(dataSourceIdentifier)], duration)
+	})
 
-	plan := &GraphQLResponse{
+	response := &GraphQLResponse{
 		Data: &Object{
 			Fetch: &ParallelFetch{
 				Fetches: []Fetch{
 					&SingleFetch{
 						BufferId: 0,
-						Input:    `{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":$$1$$,"firstArg":$$0$$}}}`,
-						InputTemplate: InputTemplate{
-							Segments: []TemplateSegment{
-								{
-									SegmentType: StaticSegmentType,
-									Data:        []byte(`{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":`),
-								},
-								{
-									SegmentType:        VariableSegmentType,
-									VariableKind:       ContextVariableKind,
-									VariableSourcePath: []string{"thirdArg"},
-								},
-								{
-									SegmentType: StaticSegmentType,
-									Data:        []byte(`,"firstArg":`),
-								},
-								{
-									SegmentType:        VariableSegmentType,
-									VariableKind:       ContextVariableKind,
-									VariableSourcePath: []string{"firstArg"},
-								},
-								{
-									SegmentType: StaticSegmentType,
-									Data:        []byte(`}}}`),
-								},
-							},
+						DataSource: &_fakeDataSource{
+							data:              []byte(`{"me":{"name":"Jens"}}`),
+							artificialLatency: time.Millisecond * 20,
 						},
-						DataSource: serviceOneDS,
-						Variables: NewVariables(
-							&ContextVariable{
-								Path: []string{"firstArg"},
-							},
-							&ContextVariable{
-								Path: []string{"thirdArg"},
-							},
-						),
+						DataSourceIdentifier: []byte("accounts"),
 					},
 					&SingleFetch{
-						BufferId: 1,
-						Input:    `{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":$$1$$,"secondArg":$$0$$}}}`,
-						InputTemplate: InputTemplate{
-							Segments: []TemplateSegment{
-								{
-									SegmentType: StaticSegmentType,
-									Data:        []byte(`{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":`),
-								},
-								{
-									SegmentType:        VariableSegmentType,
-									VariableKind:       ContextVariableKind,
-									VariableSourcePath: []string{"fourthArg"},
-								},
-								{
-									SegmentType: StaticSegmentType,
-									Data:        []byte(`,"secondArg":`),
-								},
-								{
-									SegmentType:        VariableSegmentType,
-									VariableKind:       ContextVariableKind,
-									VariableSourcePath: []string{"secondArg"},
-								},
-								{
-									SegmentType: StaticSegmentType,
-									Data:        []byte(`}}}`),
-								},
-							},
-						},
-						DataSource: serviceTwoDS,
-						Variables: NewVariables(
-							&ContextVariable{
-								Path: []string{"secondArg"},
-							},
-							&ContextVariable{
-								Path: []string{"fourthArg"},
-							},
-						),
-					},
-				},
-			},
-			Fields: []*Field{
-				{
-					BufferID:  0,
-					HasBuffer: true,
-					Name:      []byte("serviceOne"),
-					Value: &Object{
-						Path: []string{"serviceOne"},
-						Fields: []*Field{
-							{
-								Name: []byte("fieldOne"),
-								Value: &String{
-									Path: []string{"fieldOne"},
+						BufferId:             1,
+						DataSource:           FakeDataSource(`{"topProducts":[]}`),
+						DataSourceIdentifier: []byte("products"),
+					},
+				},
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("me"),
+					Value: &Object{
+						Path: []string{"me"},
+						Fields: []*Field{
+							{
+								Name: []byte("name"),
+								Value: &String{
+									Path: []string{"name"},
 								},
 							},
 						},
@@ -3190,178 +4028,2891 @@ func BenchmarkResolver_ResolveNode(b *testing.B) {
 				{
 					BufferID:  1,
 					HasBuffer: true,
-					Name:      []byte("serviceTwo"),
-					Value: &Object{
-						Path: []string{"serviceTwo"},
-						Fetch: &SingleFetch{
-							BufferId: 2,
-							Input:    `{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`,
-							InputTemplate: InputTemplate{
-								Segments: []TemplateSegment{
-									{
-										SegmentType: StaticSegmentType,
-										Data:        []byte(`{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`),
-									},
-								},
-							},
-							DataSource: nestedServiceOneDS,
-							Variables:  Variables{},
-						},
-						Fields: []*Field{
-							{
-								Name: []byte("fieldTwo"),
-								Value: &String{
-									Path: []string{"fieldTwo"},
-								},
-							},
-							{
-								BufferID:  2,
-								HasBuffer: true,
-								Name:      []byte("serviceOneResponse"),
-								Value: &Object{
-									Path: []string{"serviceOne"},
-									Fields: []*Field{
-										{
-											Name: []byte("fieldOne"),
-											Value: &String{
-												Path: []string{"fieldOne"},
-											},
-										},
-									},
-								},
-							},
-						},
+					Name:      []byte("topProducts"),
+					Value: &Array{
+						Path: []string{"topProducts"},
+						Item: &Object{},
 					},
 				},
-				{
-					BufferID:  0,
-					HasBuffer: true,
-					Name:      []byte("anotherServiceOne"),
-					Value: &Object{
-						Path: []string{"anotherServiceOne"},
-						Fields: []*Field{
+			},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"me":{"name":"Jens"},"topProducts":[]}}`, buf.String())
+
+	if !assert.Len(t, durations["accounts"], 1) || !assert.Len(t, durations["products"], 1) {
+		return
+	}
+	assert.GreaterOrEqual(t, int64(durations["accounts"][0]), int64(time.Millisecond*20))
+	assert.Less(t, int64(durations["products"][0]), int64(time.Millisecond*20))
+}
+
+type _recordedFetchSpan struct {
+	uniqueIdentifier string
+	inputSize        int
+	parentValue      interface{}
+	err              error
+	ended            bool
+}
+
+type _recordingTracer struct {
+	mu    sync.Mutex
+	spans []*_recordedFetchSpan
+}
+
+type _recordingTracerKey struct{}
+
+// StartFetchSpan passes the name of the span to the DataSource as value of _contextValueKey
+func (r *_recordingTracer) StartFetchSpan(ctx context.Context, uniqueIdentifier []byte, inputSize int) (context.Context, FetchSpan) {
+	span := &_recordedFetchSpan{
+		uniqueIdentifier: string(uniqueIdentifier),
+		inputSize:        inputSize,
+		parentValue:      ctx.Value(_recordingTracerKey{}),
+	}
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+	return context.WithValue(ctx, _contextValueKey{}, "span "+span.uniqueIdentifier), &_recordingFetchSpan{tracer: r, span: span}
+}
+
+// _uniqueIdentifierDataSource tells apart a DataSource by its UniqueIdentifier
+type _uniqueIdentifierDataSource struct {
+	DataSource
+	uniqueIdentifier []byte
+}
+
+func (u *_uniqueIdentifierDataSource) UniqueIdentifier() []byte {
+	return u.uniqueIdentifier
+}
+
+type _recordingFetchSpan struct {
+	tracer *_recordingTracer
+	span   *_recordedFetchSpan
+}
+
+func (e *_recordingFetchSpan) End(err error) {
+	e.tracer.mu.Lock()
+	defer e.tracer.mu.Unlock()
+	e.span.err = err
+	e.span.ended = true
+}
+
+func TestResolver_PoolStats(t *testing.T) {
+	resolve := func(t *testing.T, r *Resolver) {
+		plan, _ := asynchronousArrayResponse(10, 0)
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), plan, nil, buf)
+		assert.NoError(t, err)
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		resolve(t, r)
+
+		for name, stats := range r.PoolStats() {
+			assert.Equal(t, PoolStats{}, stats, name)
+		}
+	})
+	t.Run("all objects are returned to the pools", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.EnablePoolStats()
+		resolve(t, r)
+		resolve(t, r)
+
+		stats := r.PoolStats()
+		assert.Len(t, stats, 6)
+		for name, poolStats := range stats {
+			assert.Equal(t, poolStats.Gets, poolStats.Puts, name)
+			assert.LessOrEqual(t, poolStats.Allocations, poolStats.Gets, name)
+		}
+		assert.Greater(t, stats["bufPair"].Gets, int64(10))
+		assert.Greater(t, stats["waitGroup"].Gets, int64(0))
+	})
+	t.Run("wait groups are reused across array resolutions", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.EnablePoolStats()
+		for i := 0; i < 100; i++ {
+			resolve(t, r)
+		}
+
+		waitGroups := r.PoolStats()["waitGroup"]
+		assert.Equal(t, int64(100), waitGroups.Gets)
+		// the pool may drop objects, e.g. on garbage collection, so a few allocations are expected
+		assert.Less(t, waitGroups.Allocations, waitGroups.Gets/2)
+	})
+}
+
+func TestResolver_SetTracer(t *testing.T) {
+	response := func(nestedDataSource DataSource) *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId: 0,
+					InputTemplate: InputTemplate{
+						Segments: []TemplateSegment{
 							{
-								Name: []byte("fieldOne"),
-								Value: &String{
-									Path: []string{"fieldOne"},
-								},
+								SegmentType: StaticSegmentType,
+								Data:        []byte(`{"query":"{me}"}`),
 							},
 						},
 					},
+					DataSource:           FakeDataSource(`{"me":{"id":1}}`),
+					DataSourceIdentifier: []byte("accounts"),
 				},
-				{
-					BufferID:  1,
-					HasBuffer: true,
-					Name:      []byte("secondServiceTwo"),
-					Value: &Object{
-						Path: []string{"secondServiceTwo"},
-						Fields: []*Field{
-							{
-								Name: []byte("fieldTwo"),
-								Value: &String{
-									Path: []string{"fieldTwo"},
-								},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("me"),
+						Value: &Object{
+							Path:     []string{"me"},
+							Nullable: true,
+							Fetch: &SingleFetch{
+								BufferId:             1,
+								DataSource:           nestedDataSource,
+								DataSourceIdentifier: []byte("reviews"),
 							},
-						},
-					},
-				},
-				{
-					BufferID:  0,
-					HasBuffer: true,
-					Name:      []byte("reusingServiceOne"),
-					Value: &Object{
-						Path: []string{"reusingServiceOne"},
-						Fields: []*Field{
-							{
-								Name: []byte("fieldOne"),
-								Value: &String{
-									Path: []string{"fieldOne"},
+							Fields: []*Field{
+								{
+									BufferID:  1,
+									HasBuffer: true,
+									Name:      []byte("reviews"),
+									Value: &Array{
+										Path:     []string{"reviews"},
+										Nullable: true,
+										Item:     &Object{},
+									},
 								},
 							},
 						},
 					},
 				},
 			},
-		},
+		}
 	}
 
-	var err error
-	expected := []byte(`{"data":{"serviceOne":{"fieldOne":"fieldOneValue"},"serviceTwo":{"fieldTwo":"fieldTwoValue","serviceOneResponse":{"fieldOne":"fieldOneValue"}},"anotherServiceOne":{"fieldOne":"anotherFieldOneValue"},"secondServiceTwo":{"fieldTwo":"secondFieldTwoValue"},"reusingServiceOne":{"fieldOne":"reUsingFieldOneValue"}}}`)
+	run := func(t *testing.T, ctx context.Context, nestedDataSource DataSource) []*_recordedFetchSpan {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		tracer := &_recordingTracer{}
+		r.SetTracer(tracer)
 
-	pool := sync.Pool{
-		New: func() interface{} {
-			return bytes.NewBuffer(make([]byte, 0, 1024))
-		},
+		buf := &bytes.Buffer{}
+		_ = r.ResolveGraphQLResponse(NewContext(ctx), response(nestedDataSource), nil, buf)
+		return tracer.spans
 	}
 
-	variables := []byte(`{"firstArg":"firstArgValue","thirdArg":123,"secondArg": true, "fourthArg": 12.34}`)
+	t.Run("span per fetch", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), _recordingTracerKey{}, "parent")
+		spans := run(t, ctx, FakeDataSource(`{"reviews":[]}`))
+		assert.Equal(t, []*_recordedFetchSpan{
+			{uniqueIdentifier: "accounts", inputSize: len(`{"query":"{me}"}`), parentValue: "parent", ended: true},
+			{uniqueIdentifier: "reviews", inputSize: 0, parentValue: "parent", ended: true},
+		}, spans)
+	})
 
-	ctxPool := sync.Pool{
-		New: func() interface{} {
-			return NewContext(context.Background())
-		},
-	}
+	t.Run("data source loads with the context of the span", func(t *testing.T) {
+		source := &_contextValueDataSource{data: []byte(`{"reviews":[]}`)}
+		run(t, context.Background(), source)
+		assert.Equal(t, "span reviews", source.value)
+	})
 
-	runBench := func(b *testing.B) {
-		b.ReportAllocs()
-		b.SetBytes(int64(len(expected)))
-		b.ResetTimer()
+	t.Run("span is named by the UniqueIdentifier of the data source", func(t *testing.T) {
+		spans := run(t, context.Background(), &_uniqueIdentifierDataSource{
+			DataSource:       FakeDataSource(`{"reviews":[]}`),
+			uniqueIdentifier: []byte("reviews-eu"),
+		})
+		if !assert.Len(t, spans, 2) {
+			return
+		}
+		assert.Equal(t, "accounts", spans[0].uniqueIdentifier)
+		assert.Equal(t, "reviews-eu", spans[1].uniqueIdentifier)
+	})
 
-		b.RunParallel(func(pb *testing.PB) {
-			for pb.Next() {
-				// _ = resolver.ResolveGraphQLResponse(ctx, plan, nil, ioutil.Discard)
-				ctx := ctxPool.Get().(*Context)
-				ctx.Variables = variables
-				buf := pool.Get().(*bytes.Buffer)
-				err = resolver.ResolveGraphQLResponse(ctx, plan, nil, buf)
-				if err != nil {
-					b.Fatal(err)
-				}
-				if !bytes.Equal(expected, buf.Bytes()) {
-					b.Fatalf("want:\n%s\ngot:\n%s\n", string(expected), buf.String())
-				}
+	t.Run("failed fetch ends the span with the error", func(t *testing.T) {
+		spans := run(t, context.Background(), &_flakyDataSource{failures: 1})
+		if !assert.Len(t, spans, 2) {
+			return
+		}
+		assert.True(t, spans[1].ended)
+		assert.EqualError(t, spans[1].err, "transient failure 1")
+	})
 
-				buf.Reset()
-				pool.Put(buf)
+	t.Run("cancelled fetch ends the span", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		spans := run(t, ctx, &_blockingDataSource{})
+		if !assert.Len(t, spans, 2) {
+			return
+		}
+		assert.True(t, spans[1].ended)
+		assert.ErrorIs(t, spans[1].err, context.DeadlineExceeded)
+	})
 
-				ctx.Free()
-				ctxPool.Put(ctx)
-			}
-		})
-	}
+	t.Run("nil restores the noop tracer", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.SetTracer(nil)
 
-	b.Run("singleflight enabled (latency 0)", func(b *testing.B) {
-		serviceOneDS.artificialLatency = 0
-		serviceTwoDS.artificialLatency = 0
-		nestedServiceOneDS.artificialLatency = 0
-		runBench(b)
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), response(FakeDataSource(`{"reviews":[]}`)), nil, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"me":{"reviews":[]}}}`, buf.String())
 	})
 }
 
-type hookContextPathMatcher struct {
-	path string
+// _contextValueDataSource records the value of _contextValueKey of the context it got loaded with
+type _contextValueDataSource struct {
+	data  []byte
+	mu    sync.Mutex
+	value interface{}
 }
 
-func (h hookContextPathMatcher) Matches(x interface{}) bool {
-	path := string(x.(HookContext).CurrentPath)
-	return path == h.path
-}
+type _contextValueKey struct{}
 
-func (h hookContextPathMatcher) String() string {
-	return fmt.Sprintf("is equal to %s", h.path)
+func (c *_contextValueDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	c.mu.Lock()
+	c.value = ctx.Value(_contextValueKey{})
+	c.mu.Unlock()
+	_, err = w.Write(c.data)
+	return
 }
 
-func TestInputTemplate_Render(t *testing.T) {
-	runTest := func(t *testing.T, variables string, sourcePath []string, jsonSchema string, expectErr bool, expected string) {
-		t.Helper()
-
-		template := InputTemplate{
+func TestResolver_ContextValues(t *testing.T) {
+	singleFetch := func(bufferID int, dataSource DataSource) *SingleFetch {
+		return &SingleFetch{
+			BufferId: bufferID,
+			InputTemplate: InputTemplate{
+				Segments: []TemplateSegment{
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`{}`),
+					},
+				},
+			},
+			DataSource: dataSource,
+		}
+	}
+	field := func(bufferID int, name string) *Field {
+		return &Field{
+			BufferID:  bufferID,
+			HasBuffer: true,
+			Name:      []byte(name),
+			Value: &String{
+				Path: []string{name},
+			},
+		}
+	}
+
+	run := func(t *testing.T, ctx *Context, enableDataLoader bool, response *GraphQLResponse, expected string) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, enableDataLoader)
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(ctx, response, nil, buf)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expected, buf.String())
+	}
+
+	// the product gets fetched by a nested fetch after the root fetch
+	singleFetchResponse := func(me, product DataSource) *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: singleFetch(0, me),
+				Fields: []*Field{
+					field(0, "me"),
+					{
+						Name: []byte("topProduct"),
+						Value: &Object{
+							Fetch: singleFetch(1, product),
+							Fields: []*Field{
+								field(1, "product"),
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	parallelFetchResponse := func(me, product DataSource) *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &ParallelFetch{
+					Fetches: []Fetch{
+						singleFetch(0, me),
+						singleFetch(1, product),
+					},
+				},
+				Fields: []*Field{
+					field(0, "me"),
+					field(1, "product"),
+				},
+			},
+		}
+	}
+
+	for _, enableDataLoader := range []bool{false, true} {
+		t.Run(fmt.Sprintf("data loader enabled: %t", enableDataLoader), func(t *testing.T) {
+			t.Run("value of the context passed to NewContext reaches single fetches", func(t *testing.T) {
+				me := &_contextValueDataSource{data: []byte(`{"me":"user"}`)}
+				product := &_contextValueDataSource{data: []byte(`{"product":"top"}`)}
+				ctx := NewContext(context.WithValue(context.Background(), _contextValueKey{}, "trace-1"))
+
+				run(t, ctx, enableDataLoader, singleFetchResponse(me, product), `{"data":{"me":"user","topProduct":{"product":"top"}}}`)
+				assert.Equal(t, "trace-1", me.value)
+				assert.Equal(t, "trace-1", product.value)
+			})
+
+			t.Run("value of the context passed to NewContext reaches parallel fetches", func(t *testing.T) {
+				me := &_contextValueDataSource{data: []byte(`{"me":"user"}`)}
+				product := &_contextValueDataSource{data: []byte(`{"product":"top"}`)}
+				ctx := NewContext(context.WithValue(context.Background(), _contextValueKey{}, "trace-1"))
+
+				run(t, ctx, enableDataLoader, parallelFetchResponse(me, product), `{"data":{"me":"user","product":"top"}}`)
+				assert.Equal(t, "trace-1", me.value)
+				assert.Equal(t, "trace-1", product.value)
+			})
+
+			t.Run("value added with WithValue reaches single and parallel fetches", func(t *testing.T) {
+				me := &_contextValueDataSource{data: []byte(`{"me":"user"}`)}
+				product := &_contextValueDataSource{data: []byte(`{"product":"top"}`)}
+				ctx := NewContext(context.Background())
+				ctx.WithValue(_contextValueKey{}, "principal")
+
+				run(t, ctx, enableDataLoader, singleFetchResponse(me, product), `{"data":{"me":"user","topProduct":{"product":"top"}}}`)
+				assert.Equal(t, "principal", me.value)
+				assert.Equal(t, "principal", product.value)
+
+				parallelMe := &_contextValueDataSource{data: []byte(`{"me":"user"}`)}
+				parallelProduct := &_contextValueDataSource{data: []byte(`{"product":"top"}`)}
+				ctx = NewContext(context.Background())
+				ctx.WithValue(_contextValueKey{}, "principal")
+
+				run(t, ctx, enableDataLoader, parallelFetchResponse(parallelMe, parallelProduct), `{"data":{"me":"user","product":"top"}}`)
+				assert.Equal(t, "principal", parallelMe.value)
+				assert.Equal(t, "principal", parallelProduct.value)
+			})
+		})
+	}
+}
+
+// _orderedMutationDataSource records the order in which the mutations got executed
+// It fails if the mutation it depends on didn't complete before it got executed.
+type _orderedMutationDataSource struct {
+	name              string
+	dependsOn         string
+	artificialLatency time.Duration
+	mu                *sync.Mutex
+	completed         *[]string
+}
+
+func (o *_orderedMutationDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	o.mu.Lock()
+	dependencyCompleted := o.dependsOn == ""
+	for _, name := range *o.completed {
+		if name == o.dependsOn {
+			dependencyCompleted = true
+		}
+	}
+	o.mu.Unlock()
+	if !dependencyCompleted {
+		return fmt.Errorf("%s executed before %s completed", o.name, o.dependsOn)
+	}
+
+	time.Sleep(o.artificialLatency)
+
+	o.mu.Lock()
+	*o.completed = append(*o.completed, o.name)
+	o.mu.Unlock()
+	_, err = w.Write([]byte(fmt.Sprintf(`{"%s":{"id":1}}`, o.name)))
+	return
+}
+
+func TestResolver_SerialFetch(t *testing.T) {
+	mutation := func(bufferID int, dataSource DataSource) *SingleFetch {
+		return &SingleFetch{
+			BufferId: bufferID,
+			InputTemplate: InputTemplate{
+				Segments: []TemplateSegment{
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`{}`),
+					},
+				},
+			},
+			DataSource:           dataSource,
+			DataSourceIdentifier: []byte(fmt.Sprintf("mutation_%d", bufferID)),
+			DisallowSingleFlight: true,
+		}
+	}
+	field := func(bufferID int, name string) *Field {
+		return &Field{
+			BufferID:  bufferID,
+			HasBuffer: true,
+			Name:      []byte(name),
+			Value: &Object{
+				Path: []string{name},
+				Fields: []*Field{
+					{
+						Name: []byte("id"),
+						Value: &Integer{
+							Path: []string{"id"},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	for _, enableDataLoader := range []bool{false, true} {
+		t.Run(fmt.Sprintf("data loader enabled: %t", enableDataLoader), func(t *testing.T) {
+			mu := &sync.Mutex{}
+			var completed []string
+			createUser := &_orderedMutationDataSource{name: "createUser", artificialLatency: time.Millisecond * 20, mu: mu, completed: &completed}
+			updateUser := &_orderedMutationDataSource{name: "updateUser", dependsOn: "createUser", mu: mu, completed: &completed}
+
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SerialFetch{
+						Fetches: []Fetch{
+							mutation(0, createUser),
+							mutation(1, updateUser),
+						},
+					},
+					Fields: []*Field{
+						field(0, "createUser"),
+						field(1, "updateUser"),
+					},
+				},
+			}
+
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, enableDataLoader)
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, `{"data":{"createUser":{"id":1},"updateUser":{"id":1}}}`, buf.String())
+			assert.Equal(t, []string{"createUser", "updateUser"}, completed)
+		})
+	}
+
+	t.Run("failing fetch aborts the remaining fetches", func(t *testing.T) {
+		mu := &sync.Mutex{}
+		var completed []string
+		updateUser := &_orderedMutationDataSource{name: "updateUser", mu: mu, completed: &completed}
+
+		response := &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SerialFetch{
+					Fetches: []Fetch{
+						mutation(0, &_flakyDataSource{failures: 1}),
+						mutation(1, updateUser),
+					},
+				},
+				Fields: []*Field{
+					field(0, "createUser"),
+					field(1, "updateUser"),
+				},
+			},
+		}
+
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+		assert.EqualError(t, err, "transient failure 1")
+		assert.Empty(t, completed)
+	})
+}
+
+// _batchDataSource answers each input with the user of the id in the input and counts the calls of Load and LoadBatch
+// The user with the missingID is answered with an error.
+type _batchDataSource struct {
+	missingID int64
+	loads     int32
+	batches   int32
+	batchSize int32
+}
+
+func (b *_batchDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	atomic.AddInt32(&b.loads, 1)
+	id, err := jsonparser.GetInt(input, "id")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, `{"id":%d}`, id)
+	return err
+}
+
+func (b *_batchDataSource) LoadBatch(ctx context.Context, inputs [][]byte, bufPairs []*BufPair) error {
+	atomic.AddInt32(&b.batches, 1)
+	atomic.StoreInt32(&b.batchSize, int32(len(inputs)))
+	for i := range inputs {
+		id, err := jsonparser.GetInt(inputs[i], "id")
+		if err != nil {
+			return err
+		}
+		if id == b.missingID {
+			bufPairs[i].WriteErr([]byte("user not found"), nil, nil, nil)
+			continue
+		}
+		bufPairs[i].Data.WriteBytes([]byte(fmt.Sprintf(`{"id":%d}`, id)))
+	}
+	return nil
+}
+
+func TestResolver_BatchDataSource(t *testing.T) {
+	userFetch := func(bufferID int, id int, dataSource DataSource) *SingleFetch {
+		return &SingleFetch{
+			BufferId: bufferID,
+			InputTemplate: InputTemplate{
+				Segments: []TemplateSegment{
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(fmt.Sprintf(`{"id":%d}`, id)),
+					},
+				},
+			},
+			DataSource:           dataSource,
+			DataSourceIdentifier: []byte("users"),
+		}
+	}
+	postsFetch := &SingleFetch{
+		BufferId:             2,
+		DataSource:           FakeDataSource(`{"count":3}`),
+		DataSourceIdentifier: []byte("posts"),
+	}
+	field := func(bufferID int, name string) *Field {
+		return &Field{
+			BufferID:  bufferID,
+			HasBuffer: true,
+			Name:      []byte(name),
+			Value: &Object{
+				Nullable: true,
+				Fields: []*Field{
+					{
+						Name: []byte("id"),
+						Value: &Integer{
+							Path: []string{"id"},
+						},
+					},
+				},
+			},
+		}
+	}
+	postsField := &Field{
+		BufferID:  2,
+		HasBuffer: true,
+		Name:      []byte("posts"),
+		Value: &Integer{
+			Path: []string{"count"},
+		},
+	}
+
+	run := func(t *testing.T, enableDataLoader bool, dataSource *_batchDataSource, fetches []Fetch, fields []*Field, expectedOutput string) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, enableDataLoader)
+
+		response := &GraphQLResponse{
+			Data: &Object{
+				Fetch: &ParallelFetch{
+					Fetches: fetches,
+				},
+				Fields: fields,
+			},
+		}
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expectedOutput, buf.String())
+	}
+
+	t.Run("fetches of the same data source are loaded at once", func(t *testing.T) {
+		dataSource := &_batchDataSource{}
+		run(t, false, dataSource,
+			[]Fetch{userFetch(0, 1, dataSource), userFetch(1, 2, dataSource), postsFetch},
+			[]*Field{field(0, "first"), field(1, "second"), postsField},
+			`{"data":{"first":{"id":1},"second":{"id":2},"posts":3}}`)
+		assert.Equal(t, int32(1), dataSource.batches)
+		assert.Equal(t, int32(2), dataSource.batchSize)
+		assert.Equal(t, int32(0), dataSource.loads)
+	})
+	t.Run("fetches with the same identifier are batched per data source", func(t *testing.T) {
+		first, second := &_batchDataSource{}, &_batchDataSource{}
+		run(t, false, first,
+			[]Fetch{userFetch(0, 1, first), userFetch(1, 2, second), userFetch(3, 3, first), userFetch(4, 4, second)},
+			[]*Field{field(0, "first"), field(1, "second"), field(3, "third"), field(4, "fourth")},
+			`{"data":{"first":{"id":1},"second":{"id":2},"third":{"id":3},"fourth":{"id":4}}}`)
+		for _, dataSource := range []*_batchDataSource{first, second} {
+			assert.Equal(t, int32(1), dataSource.batches)
+			assert.Equal(t, int32(2), dataSource.batchSize)
+			assert.Equal(t, int32(0), dataSource.loads)
+		}
+	})
+	t.Run("single fetch of a batch data source is loaded with Load", func(t *testing.T) {
+		dataSource := &_batchDataSource{}
+		run(t, false, dataSource,
+			[]Fetch{userFetch(0, 1, dataSource), postsFetch},
+			[]*Field{field(0, "first"), postsField},
+			`{"data":{"first":{"id":1},"posts":3}}`)
+		assert.Equal(t, int32(0), dataSource.batches)
+		assert.Equal(t, int32(1), dataSource.loads)
+	})
+	t.Run("error of a single input", func(t *testing.T) {
+		dataSource := &_batchDataSource{missingID: 2}
+		run(t, false, dataSource,
+			[]Fetch{userFetch(0, 1, dataSource), userFetch(1, 2, dataSource)},
+			[]*Field{field(0, "first"), field(1, "second")},
+			`{"errors":[{"message":"user not found"},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["second","id"]}],"data":{"first":{"id":1},"second":null}}`)
+		assert.Equal(t, int32(1), dataSource.batches)
+	})
+	t.Run("fetches using the data loader are loaded one by one", func(t *testing.T) {
+		dataSource := &_batchDataSource{}
+		run(t, true, dataSource,
+			[]Fetch{userFetch(0, 1, dataSource), userFetch(1, 2, dataSource)},
+			[]*Field{field(0, "first"), field(1, "second")},
+			`{"data":{"first":{"id":1},"second":{"id":2}}}`)
+		assert.Equal(t, int32(0), dataSource.batches)
+		assert.Equal(t, int32(2), dataSource.loads)
+	})
+}
+
+// _entityBatchFactory creates batches which assign the n-th entity of an _entities response to the n-th input
+// All errors are assigned to the first input, like upstream errors which don't belong to an entity.
+type _entityBatchFactory struct{}
+
+func (_entityBatchFactory) CreateBatch(inputs [][]byte) (DataSourceBatch, error) {
+	input := fastbuffer.New()
+	input.WriteBytes(inputs[0])
+	return &_entityBatch{input: input}, nil
+}
+
+type _entityBatch struct {
+	input *fastbuffer.FastBuffer
+}
+
+func (b *_entityBatch) Input() *fastbuffer.FastBuffer {
+	return b.input
+}
+
+func (b *_entityBatch) Demultiplex(responseBufPair *BufPair, bufPairs []*BufPair) (err error) {
+	i := 0
+	_, err = jsonparser.ArrayEach(responseBufPair.Data.Bytes(), func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if i < len(bufPairs) {
+			bufPairs[i].Data.WriteBytes(value)
+		}
+		i++
+	})
+	bufPairs[0].Errors.WriteBytes(responseBufPair.Errors.Bytes())
+	return err
+}
+
+func TestResolver_EntityErrorPaths(t *testing.T) {
+	entityFetch := func(bufferID int, dataSource DataSource) *SingleFetch {
+		return &SingleFetch{
+			BufferId: bufferID,
+			InputTemplate: InputTemplate{
+				Segments: []TemplateSegment{
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`{"representations":[{"__typename":"User"}]}`),
+					},
+				},
+			},
+			DataSource: dataSource,
+			ProcessResponseConfig: ProcessResponseConfig{
+				ExtractGraphqlResponse:    true,
+				ExtractFederationEntities: true,
+			},
+		}
+	}
+	reviews := func(bufferID int) *Field {
+		return &Field{
+			BufferID:  bufferID,
+			HasBuffer: true,
+			Name:      []byte("reviews"),
+			Value: &Array{
+				Path:     []string{"reviews"},
+				Nullable: true,
+				Item: &Object{
+					Fields: []*Field{
+						{
+							Name: []byte("body"),
+							Value: &String{
+								Path:     []string{"body"},
+								Nullable: true,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	entityResponse := `{"errors":[{"message":"body unavailable","path":["_entities",0,"reviews",1,"body"]},{"message":"rate limited"}],"data":{"_entities":[{"reviews":[{"body":"a"},{"body":null}]}]}}`
+
+	run := func(t *testing.T, response *GraphQLResponse, expectedOutput string) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expectedOutput, buf.String())
+	}
+
+	t.Run("entity fetch", func(t *testing.T) {
+		run(t, &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"me":{"id":"1"}}`),
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("me"),
+						Value: &Object{
+							Path: []string{"me"},
+							Fetch: &BatchFetch{
+								Fetch:        entityFetch(1, FakeDataSource(entityResponse)),
+								BatchFactory: _entityBatchFactory{},
+							},
+							Fields: []*Field{
+								reviews(1),
+							},
+						},
+					},
+				},
+			},
+		}, `{"errors":[{"message":"body unavailable","path":["me","reviews",1,"body"]},{"message":"rate limited"}],"data":{"me":{"reviews":[{"body":"a"},{"body":null}]}}}`)
+	})
+
+	t.Run("entity fetch of list items", func(t *testing.T) {
+		run(t, &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"users":[{"id":"1"},{"id":"2"}]}`),
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("users"),
+						Value: &Array{
+							Path: []string{"users"},
+							Item: &Object{
+								Fetch: &BatchFetch{
+									Fetch:        entityFetch(1, FakeDataSource(entityResponse)),
+									BatchFactory: _entityBatchFactory{},
+								},
+								Fields: []*Field{
+									reviews(1),
+								},
+							},
+						},
+					},
+				},
+			},
+		}, `{"errors":[{"message":"body unavailable","path":["users",0,"reviews",1,"body"]},{"message":"rate limited"},{"message":"body unavailable","path":["users",1,"reviews",1,"body"]},{"message":"rate limited"}],"data":{"users":[{"reviews":[{"body":"a"},{"body":null}]},{"reviews":[{"body":"a"},{"body":null}]}]}}`)
+	})
+
+	t.Run("single entity fetch", func(t *testing.T) {
+		run(t, &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"me":{"id":"1"}}`),
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("me"),
+						Value: &Object{
+							Path:  []string{"me"},
+							Fetch: entityFetch(1, FakeDataSource(`{"errors":[{"message":"body unavailable","path":["_entities",0,"reviews",1,"body"]}],"data":{"_entities":[{"reviews":[{"body":"a"},{"body":null}]}]}}`)),
+							Fields: []*Field{
+								{
+									BufferID:  1,
+									HasBuffer: true,
+									Name:      []byte("reviews"),
+									Value: &Array{
+										Path:     []string{"[0]", "reviews"},
+										Nullable: true,
+										Item: &Object{
+											Fields: []*Field{
+												{
+													Name: []byte("body"),
+													Value: &String{
+														Path:     []string{"body"},
+														Nullable: true,
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}, `{"errors":[{"message":"body unavailable","path":["me","reviews",1,"body"]}],"data":{"me":{"reviews":[{"body":"a"},{"body":null}]}}}`)
+	})
+}
+
+func TestEntityErrorPath(t *testing.T) {
+	t.Run("entity path is moved below the fetch path", func(t *testing.T) {
+		path, ok := entityErrorPath([]byte(`["users",1]`), []byte(`["_entities",0,"reviews",1,"body"]`))
+		assert.True(t, ok)
+		assert.Equal(t, `["users",1,"reviews",1,"body"]`, string(path))
+	})
+	t.Run("path of the entity itself", func(t *testing.T) {
+		path, ok := entityErrorPath([]byte(`["me"]`), []byte(`["_entities",0]`))
+		assert.True(t, ok)
+		assert.Equal(t, `["me"]`, string(path))
+	})
+	t.Run("empty fetch path", func(t *testing.T) {
+		path, ok := entityErrorPath([]byte(`[]`), []byte(`["_entities",0,"name"]`))
+		assert.True(t, ok)
+		assert.Equal(t, `["name"]`, string(path))
+	})
+	t.Run("path outside of _entities is kept", func(t *testing.T) {
+		_, ok := entityErrorPath([]byte(`["me"]`), []byte(`["me","name"]`))
+		assert.False(t, ok)
+	})
+	t.Run("path without entity index is kept", func(t *testing.T) {
+		_, ok := entityErrorPath([]byte(`["me"]`), []byte(`["_entities"]`))
+		assert.False(t, ok)
+	})
+}
+
+func TestResolver_NonNullableFieldMessage(t *testing.T) {
+	run := func(data string, fields []*Field, expectedOutput string) func(t *testing.T) {
+		return func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SingleFetch{
+						BufferId:   0,
+						DataSource: FakeDataSource(data),
+					},
+					Nullable: true,
+					Fields:   fields,
+				},
+			}
+			for i := range fields {
+				fields[i].HasBuffer = true
+			}
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOutput, buf.String())
+		}
+	}
+
+	t.Run("nested field", run(`{"me":{"profile":{"email":null}}}`, []*Field{
+		{
+			Name: []byte("me"),
+			Info: &FieldInfo{Name: []byte("me"), ParentTypeName: []byte("Query")},
+			Value: &Object{
+				Path:     []string{"me"},
+				Nullable: true,
+				Fields: []*Field{
+					{
+						Name: []byte("profile"),
+						Info: &FieldInfo{Name: []byte("profile"), ParentTypeName: []byte("User")},
+						Value: &Object{
+							Path: []string{"profile"},
+							Fields: []*Field{
+								{
+									Name: []byte("email"),
+									Info: &FieldInfo{Name: []byte("email"), ParentTypeName: []byte("Profile")},
+									Value: &String{
+										Path: []string{"email"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, `{"errors":[{"message":"Cannot return null for non-nullable field Profile.email.","locations":[{"line":0,"column":0}],"path":["me","profile","email"]}],"data":{"me":null}}`))
+	t.Run("null buffer of a nullable object doesn't report its fields", run(`null`, []*Field{
+		{
+			Name: []byte("__type"),
+			Info: &FieldInfo{Name: []byte("__type"), ParentTypeName: []byte("Query")},
+			Value: &Object{
+				Nullable: true,
+				Fields: []*Field{
+					{
+						Name: []byte("kind"),
+						Info: &FieldInfo{Name: []byte("kind"), ParentTypeName: []byte("__Type")},
+						Value: &String{
+							Path: []string{"kind"},
+						},
+					},
+				},
+			},
+		},
+	}, `{"data":{"__type":null}}`))
+	t.Run("aliased field uses the name of the field", run(`{"users":[{"name":"Jens"},{"name":null}]}`, []*Field{
+		{
+			Name: []byte("users"),
+			Info: &FieldInfo{Name: []byte("users"), ParentTypeName: []byte("Query")},
+			Value: &Array{
+				Path:     []string{"users"},
+				Nullable: true,
+				Item: &Object{
+					Fields: []*Field{
+						{
+							Name: []byte("userName"),
+							Info: &FieldInfo{Name: []byte("name"), ParentTypeName: []byte("User")},
+							Value: &String{
+								Path: []string{"name"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, `{"errors":[{"message":"Cannot return null for non-nullable field User.name.","locations":[{"line":0,"column":0}],"path":["users",1,"userName"]}],"data":{"users":null}}`))
+	t.Run("null item uses the list field", run(`{"tags":["a",null]}`, []*Field{
+		{
+			Name: []byte("tags"),
+			Info: &FieldInfo{Name: []byte("tags"), ParentTypeName: []byte("Query")},
+			Value: &Array{
+				Path:     []string{"tags"},
+				Nullable: true,
+				Item: &String{
+					Nullable: false,
+				},
+			},
+		},
+	}, `{"errors":[{"message":"Cannot return null for non-nullable field Query.tags.","locations":[{"line":0,"column":0}],"path":["tags",1]}],"data":{"tags":null}}`))
+	t.Run("field without info", run(`{"name":null}`, []*Field{
+		{
+			Name: []byte("name"),
+			Value: &String{
+				Path: []string{"name"},
+			},
+		},
+	}, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["name"]}],"data":null}`))
+}
+
+func TestResolver_SetErrorFormatter(t *testing.T) {
+	response := func() *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"errors":[{"message":"connection to 10.0.0.1 refused"}],"data":{"name":null}}`),
+					ProcessResponseConfig: ProcessResponseConfig{
+						ExtractGraphqlResponse: true,
+					},
+				},
+				Nullable: true,
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("name"),
+						Value: &String{
+							Path: []string{"name"},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	run := func(t *testing.T, formatter func(raw []byte) []byte, expectedOutput string) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.SetErrorFormatter(formatter)
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), response(), nil, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedOutput, buf.String())
+	}
+
+	t.Run("formatter is applied to upstream and resolver errors", func(t *testing.T) {
+		run(t, func(raw []byte) []byte {
+			if bytes.Contains(raw, []byte("10.0.0.1")) {
+				return []byte(`{"message":"upstream failed","extensions":{"code":"UPSTREAM_ERROR"}}`)
+			}
+			formatted := append([]byte{}, raw[:len(raw)-1]...)
+			return append(formatted, []byte(`,"extensions":{"code":"INTERNAL"}}`)...)
+		}, `{"errors":[{"message":"upstream failed","extensions":{"code":"UPSTREAM_ERROR"}},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["name"],"extensions":{"code":"INTERNAL"}}],"data":null}`)
+	})
+	t.Run("invalid output keeps the original error", func(t *testing.T) {
+		run(t, func(raw []byte) []byte {
+			return []byte(`{"message":`)
+		}, `{"errors":[{"message":"connection to 10.0.0.1 refused"},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["name"]}],"data":null}`)
+	})
+	t.Run("no-op when unset", func(t *testing.T) {
+		run(t, nil, `{"errors":[{"message":"connection to 10.0.0.1 refused"},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["name"]}],"data":null}`)
+	})
+}
+
+func TestResolver_Warnings(t *testing.T) {
+	data := func() *Object {
+		return &Object{
+			Fetch: &SingleFetch{
+				BufferId:   0,
+				DataSource: FakeDataSource(`{"name":"Luke"}`),
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("name"),
+					Value: &String{
+						Path: []string{"name"},
+					},
+				},
+			},
+		}
+	}
+
+	run := func(t *testing.T, response *GraphQLResponse, warnings []string, expectedOutput string) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+
+		ctx := NewContext(context.Background())
+		for i := range warnings {
+			ctx.AddWarning(warnings[i])
+		}
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(ctx, response, nil, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedOutput, buf.String())
+	}
+
+	t.Run("without extensions", func(t *testing.T) {
+		run(t, &GraphQLResponse{Data: data()}, []string{`The field "Query.hero" is deprecated.`, "second"},
+			`{"data":{"name":"Luke"},"extensions":{"warnings":[{"message":"The field \"Query.hero\" is deprecated."},{"message":"second"}]}}`)
+	})
+	t.Run("with extensions", func(t *testing.T) {
+		run(t, &GraphQLResponse{
+			Data: data(),
+			Extensions: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(`{"cost":3}`),
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("cost"),
+						Value: &Integer{
+							Path: []string{"cost"},
+						},
+					},
+				},
+			},
+		}, []string{"deprecated"},
+			`{"data":{"name":"Luke"},"extensions":{"cost":3,"warnings":[{"message":"deprecated"}]}}`)
+	})
+	t.Run("no warnings", func(t *testing.T) {
+		run(t, &GraphQLResponse{Data: data()}, nil, `{"data":{"name":"Luke"}}`)
+	})
+}
+
+func TestResolver_AddExtension(t *testing.T) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newResolver(rCtx, false, false)
+
+	response := &GraphQLResponse{
+		Data: &Object{
+			Fetch: &SingleFetch{
+				BufferId:   0,
+				DataSource: FakeDataSource(`{"name":"Luke"}`),
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("name"),
+					Value: &String{
+						Path: []string{"name"},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("value is called after resolving", func(t *testing.T) {
+		fetched := false
+		ctx := NewContext(context.Background())
+		ctx.SetBeforeFetchHook(beforeFetchHookFunc(func() { fetched = true }))
+		ctx.AddExtension("fetched", func() ([]byte, error) {
+			return []byte(fmt.Sprintf("%t", fetched)), nil
+		})
+		ctx.AddWarning("deprecated")
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(ctx, response, nil, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"name":"Luke"},"extensions":{"warnings":[{"message":"deprecated"}],"fetched":true}}`, buf.String())
+	})
+
+	t.Run("error of value aborts the response", func(t *testing.T) {
+		ctx := NewContext(context.Background())
+		ctx.AddExtension("broken", func() ([]byte, error) {
+			return nil, fmt.Errorf("broken extension")
+		})
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(ctx, response, nil, buf)
+		assert.EqualError(t, err, "broken extension")
+		assert.Equal(t, "", buf.String())
+	})
+}
+
+type beforeFetchHookFunc func()
+
+func (f beforeFetchHookFunc) OnBeforeFetch(ctx HookContext, input []byte) {
+	f()
+}
+
+func TestResolver_ResolveIntegerCoerceFloat(t *testing.T) {
+	run := func(upstreamValue string, integer *Integer, expectedOutput string) func(t *testing.T) {
+		return func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+
+			integer.Path = []string{"count"}
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SingleFetch{
+						BufferId:   0,
+						DataSource: FakeDataSource(fmt.Sprintf(`{"count":%s}`, upstreamValue)),
+					},
+					Nullable: true,
+					Fields: []*Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("count"),
+							Value:     integer,
+						},
+					},
+				},
+			}
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOutput, buf.String())
+		}
+	}
+
+	t.Run("integral float", run(`3.0`, &Integer{CoerceFloat: true}, `{"data":{"count":3}}`))
+	t.Run("scientific notation", run(`3e2`, &Integer{CoerceFloat: true}, `{"data":{"count":300}}`))
+	t.Run("negative integral float", run(`-12.00`, &Integer{CoerceFloat: true}, `{"data":{"count":-12}}`))
+	t.Run("integer is not changed", run(`3`, &Integer{CoerceFloat: true}, `{"data":{"count":3}}`))
+	t.Run("fractional float on nullable field", run(`3.5`, &Integer{CoerceFloat: true, Nullable: true},
+		`{"errors":[{"message":"Int cannot represent non-integer value: 3.5","path":["count"]}],"data":{"count":null}}`))
+	t.Run("fractional float on non nullable field", run(`3.5`, &Integer{CoerceFloat: true},
+		`{"errors":[{"message":"Int cannot represent non-integer value: 3.5","path":["count"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["count"]}],"data":null}`))
+	t.Run("float passes through without coercion", run(`3.0`, &Integer{}, `{"data":{"count":3.0}}`))
+	t.Run("largest Int", run(`2147483647.0`, &Integer{CoerceFloat: true}, `{"data":{"count":2147483647}}`))
+	t.Run("smallest Int", run(`-2.147483648e9`, &Integer{CoerceFloat: true}, `{"data":{"count":-2147483648}}`))
+	t.Run("integral float above the Int range", run(`3e9`, &Integer{CoerceFloat: true, Nullable: true},
+		`{"errors":[{"message":"Int cannot represent non-integer value: 3e9","path":["count"]}],"data":{"count":null}}`))
+	t.Run("huge integral float", run(`1e300`, &Integer{CoerceFloat: true, Nullable: true},
+		`{"errors":[{"message":"Int cannot represent non-integer value: 1e300","path":["count"]}],"data":{"count":null}}`))
+	t.Run("integral float below the Int range", run(`-2147483649.0`, &Integer{CoerceFloat: true, Nullable: true},
+		`{"errors":[{"message":"Int cannot represent non-integer value: -2147483649.0","path":["count"]}],"data":{"count":null}}`))
+}
+
+func TestResolver_ResolveFloatDecimalNotation(t *testing.T) {
+	run := func(upstreamValue string, float *Float, expectedOutput string) func(t *testing.T) {
+		return func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+
+			float.Path = []string{"price"}
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SingleFetch{
+						BufferId:   0,
+						DataSource: FakeDataSource(fmt.Sprintf(`{"price":%s}`, upstreamValue)),
+					},
+					Nullable: true,
+					Fields: []*Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("price"),
+							Value:     float,
+						},
+					},
+				},
+			}
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOutput, buf.String())
+		}
+	}
+
+	t.Run("small exponent", run(`1e-7`, &Float{DecimalNotation: true}, `{"data":{"price":0.0000001}}`))
+	t.Run("positive exponent", run(`1.5e3`, &Float{DecimalNotation: true}, `{"data":{"price":1500}}`))
+	t.Run("upper case exponent", run(`-2.25E-2`, &Float{DecimalNotation: true}, `{"data":{"price":-0.0225}}`))
+	t.Run("large exponent", run(`1.2345e22`, &Float{DecimalNotation: true}, `{"data":{"price":12345000000000000000000}}`))
+	t.Run("large integer is not changed", run(`123456789012345678901234567890`, &Float{DecimalNotation: true},
+		`{"data":{"price":123456789012345678901234567890}}`))
+	t.Run("decimal is not changed", run(`0.1`, &Float{DecimalNotation: true}, `{"data":{"price":0.1}}`))
+	t.Run("out of range on nullable field", run(`1e400`, &Float{DecimalNotation: true, Nullable: true},
+		`{"errors":[{"message":"Float cannot represent non-finite value: 1e400","path":["price"]}],"data":{"price":null}}`))
+	t.Run("out of range on non nullable field", run(`-1e400`, &Float{DecimalNotation: true},
+		`{"errors":[{"message":"Float cannot represent non-finite value: -1e400","path":["price"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["price"]}],"data":null}`))
+	t.Run("exponent passes through without formatting", run(`1e-7`, &Float{}, `{"data":{"price":1e-7}}`))
+}
+
+func TestResolver_ResolveEnum(t *testing.T) {
+	run := func(upstreamValue string, nullable bool, expectedOutput string) func(t *testing.T) {
+		return func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SingleFetch{
+						BufferId:   0,
+						DataSource: FakeDataSource(fmt.Sprintf(`{"episode":%s}`, upstreamValue)),
+					},
+					Nullable: true,
+					Fields: []*Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("episode"),
+							Value: &Enum{
+								Path:     []string{"episode"},
+								Nullable: nullable,
+								Values:   [][]byte{[]byte("NEWHOPE"), []byte("EMPIRE"), []byte("JEDI")},
+							},
+						},
+					},
+				},
+			}
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOutput, buf.String())
+		}
+	}
+
+	t.Run("allowed value", run(`"EMPIRE"`, false, `{"data":{"episode":"EMPIRE"}}`))
+	t.Run("null on nullable enum", run(`null`, true, `{"data":{"episode":null}}`))
+	t.Run("not allowed value on nullable enum", run(`"PHANTOM"`, true,
+		`{"errors":[{"message":"Enum cannot represent value: PHANTOM","path":["episode"]}],"data":{"episode":null}}`))
+	t.Run("not allowed value on non nullable enum", run(`"PHANTOM"`, false,
+		`{"errors":[{"message":"Enum cannot represent value: PHANTOM","path":["episode"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["episode"]}],"data":null}`))
+	t.Run("value is case sensitive", run(`"empire"`, true,
+		`{"errors":[{"message":"Enum cannot represent value: empire","path":["episode"]}],"data":{"episode":null}}`))
+	t.Run("non string value", run(`{"name":"EMPIRE"}`, true,
+		`{"errors":[{"message":"Enum cannot represent value: {\"name\":\"EMPIRE\"}","path":["episode"]}],"data":{"episode":null}}`))
+}
+
+// bigIntAsString coerces BigInt values, which upstreams return as numbers or strings, to strings
+// so that clients don't lose precision when parsing them as floats
+func bigIntAsString(raw []byte) ([]byte, error) {
+	value := string(raw)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	number, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return nil, fmt.Errorf("BigInt cannot represent value: %s", raw)
+	}
+	return []byte(strconv.Quote(number.String())), nil
+}
+
+func TestResolver_ResolveEmptyValues(t *testing.T) {
+	run := func(upstream string, value Node, expectedOutput string) func(t *testing.T) {
+		return func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SingleFetch{
+						BufferId:   0,
+						DataSource: FakeDataSource(upstream),
+					},
+					Nullable: true,
+					Fields: []*Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("settings"),
+							Value:     value,
+						},
+					},
+				},
+			}
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOutput, buf.String())
+		}
+	}
+
+	t.Run("object", func(t *testing.T) {
+		t.Run("without path", run(`{}`, &EmptyObject{}, `{"data":{"settings":{}}}`))
+		t.Run("present empty", run(`{"settings":{}}`, &EmptyObject{Path: []string{"settings"}, Nullable: true}, `{"data":{"settings":{}}}`))
+		t.Run("absent", run(`{}`, &EmptyObject{Path: []string{"settings"}, Nullable: true}, `{"data":{"settings":null}}`))
+		t.Run("explicit null", run(`{"settings":null}`, &EmptyObject{Path: []string{"settings"}, Nullable: true}, `{"data":{"settings":null}}`))
+		t.Run("absent on non nullable", run(`{}`, &EmptyObject{Path: []string{"settings"}},
+			`{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["settings"]}],"data":null}`))
+	})
+	t.Run("array", func(t *testing.T) {
+		t.Run("without path", run(`{}`, &EmptyArray{}, `{"data":{"settings":[]}}`))
+		t.Run("present empty", run(`{"settings":[]}`, &EmptyArray{Path: []string{"settings"}, Nullable: true}, `{"data":{"settings":[]}}`))
+		t.Run("absent", run(`{}`, &EmptyArray{Path: []string{"settings"}, Nullable: true}, `{"data":{"settings":null}}`))
+		t.Run("explicit null", run(`{"settings":null}`, &EmptyArray{Path: []string{"settings"}, Nullable: true}, `{"data":{"settings":null}}`))
+		t.Run("explicit null on non nullable", run(`{"settings":null}`, &EmptyArray{Path: []string{"settings"}},
+			`{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["settings"]}],"data":null}`))
+	})
+}
+
+func TestResolver_ResolveStaticString(t *testing.T) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newResolver(rCtx, false, false)
+
+	response := &GraphQLResponse{
+		Data: &Object{
+			Fetch: &SingleFetch{
+				BufferId:   0,
+				DataSource: FakeDataSource(`{"me":{"id":"1"}}`),
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("me"),
+					Value: &Object{
+						Path: []string{"me"},
+						Fields: []*Field{
+							{
+								Name: []byte("__typename"),
+								Value: &StaticString{
+									Value: []byte("User"),
+								},
+							},
+							{
+								Name: []byte("id"),
+								Value: &String{
+									Path: []string{"id"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"me":{"__typename":"User","id":"1"}}}`, buf.String())
+}
+
+type tenantKey struct{}
+
+func TestResolver_DataSourceResolver(t *testing.T) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newResolver(rCtx, false, false)
+
+	upstreams := map[string]DataSource{
+		"a": FakeDataSource(`{"name":"tenant a"}`),
+		"b": FakeDataSource(`{"name":"tenant b"}`),
+	}
+	resolveDataSource := func(ctx *Context, dataSourceIdentifier []byte, defaultDataSource DataSource) DataSource {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return upstreams[tenant]
+	}
+
+	// the response is planned once and shared between the requests like a cached plan
+	response := &GraphQLResponse{
+		Data: &Object{
+			Fetch: &SingleFetch{
+				BufferId:             0,
+				DataSource:           FakeDataSource(`{"name":"default"}`),
+				DataSourceIdentifier: []byte("tenants"),
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("name"),
+					Value: &String{
+						Path: []string{"name"},
+					},
+				},
+			},
+		},
+	}
+
+	resolveForTenant := func(tenant string) string {
+		ctx := NewContext(context.Background())
+		ctx.WithValue(tenantKey{}, tenant)
+		ctx.SetDataSourceResolver(resolveDataSource)
+		buf := &bytes.Buffer{}
+		assert.NoError(t, r.ResolveGraphQLResponse(ctx, response, nil, buf))
+		return buf.String()
+	}
+
+	assert.Equal(t, `{"data":{"name":"tenant a"}}`, resolveForTenant("a"))
+	assert.Equal(t, `{"data":{"name":"tenant b"}}`, resolveForTenant("b"))
+	// a resolver returning nil loads from the planned DataSource
+	assert.Equal(t, `{"data":{"name":"default"}}`, resolveForTenant("c"))
+}
+
+func TestResolver_ResolveCustomScalar(t *testing.T) {
+	run := func(upstreamValue string, nullable bool, scalarName string, expectedOutput string) func(t *testing.T) {
+		return func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+			r.SetScalarCoercer("BigInt", bigIntAsString)
+
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SingleFetch{
+						BufferId:   0,
+						DataSource: FakeDataSource(fmt.Sprintf(`{"balance":%s}`, upstreamValue)),
+					},
+					Nullable: true,
+					Fields: []*Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("balance"),
+							Value: &CustomScalar{
+								Path:       []string{"balance"},
+								Nullable:   nullable,
+								ScalarName: scalarName,
+							},
+						},
+					},
+				},
+			}
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOutput, buf.String())
+		}
+	}
+
+	t.Run("number", run(`123456789012345678901234567890`, false, "BigInt", `{"data":{"balance":"123456789012345678901234567890"}}`))
+	t.Run("string", run(`"123456789012345678901234567890"`, false, "BigInt", `{"data":{"balance":"123456789012345678901234567890"}}`))
+	t.Run("null on nullable scalar", run(`null`, true, "BigInt", `{"data":{"balance":null}}`))
+	t.Run("invalid value on nullable scalar", run(`"1.5"`, true, "BigInt",
+		`{"errors":[{"message":"BigInt cannot represent value: \"1.5\"","path":["balance"]}],"data":{"balance":null}}`))
+	t.Run("invalid value on non nullable scalar", run(`true`, false, "BigInt",
+		`{"errors":[{"message":"BigInt cannot represent value: true","path":["balance"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["balance"]}],"data":null}`))
+	t.Run("scalar without coercer", run(`{"amount":1}`, false, "Money", `{"data":{"balance":{"amount":1}}}`))
+}
+
+func TestResolver_DuplicateKeys(t *testing.T) {
+	// mergedResponse merges the fields of two buffers into one object, both buffers resolve the field name
+	mergedResponse := func(fields ...*Field) *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &ParallelFetch{
+					Fetches: []Fetch{
+						&SingleFetch{
+							BufferId:   0,
+							DataSource: FakeDataSource(`{"id":1,"name":"accounts"}`),
+						},
+						&SingleFetch{
+							BufferId:   1,
+							DataSource: FakeDataSource(`{"name":"reviews","stars":5}`),
+						},
+					},
+				},
+				Fields: fields,
+			},
+		}
+	}
+	stringField := func(bufferID int, name string) *Field {
+		return &Field{
+			BufferID:  bufferID,
+			HasBuffer: true,
+			Name:      []byte(name),
+			Value: &String{
+				Path: []string{name},
+			},
+		}
+	}
+	integerField := func(bufferID int, name string) *Field {
+		return &Field{
+			BufferID:  bufferID,
+			HasBuffer: true,
+			Name:      []byte(name),
+			Value: &Integer{
+				Path: []string{name},
+			},
+		}
+	}
+
+	run := func(policy DuplicateKeyPolicy, response *GraphQLResponse) (string, error) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.DuplicateKeys = policy
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+		return buf.String(), err
+	}
+
+	t.Run("duplicate keys are written when allowed", func(t *testing.T) {
+		out, err := run(DuplicateKeysAllowed, mergedResponse(stringField(0, "name"), stringField(1, "name")))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"name":"accounts","name":"reviews"}}`, out)
+	})
+	t.Run("duplicate key fails the response", func(t *testing.T) {
+		out, err := run(DuplicateKeysError, mergedResponse(integerField(0, "id"), stringField(0, "name"), stringField(1, "name")))
+		assert.ErrorIs(t, err, errDuplicateObjectKey)
+		assert.EqualError(t, err, "duplicate key in object: name")
+		assert.Equal(t, "", out)
+	})
+	t.Run("keep last value of duplicate key", func(t *testing.T) {
+		out, err := run(DuplicateKeysKeepLast, mergedResponse(integerField(0, "id"), stringField(0, "name"), integerField(1, "stars"), stringField(1, "name")))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"id":1,"stars":5,"name":"reviews"}}`, out)
+	})
+	t.Run("keep last value of duplicate first key", func(t *testing.T) {
+		out, err := run(DuplicateKeysKeepLast, mergedResponse(stringField(0, "name"), integerField(0, "id"), stringField(1, "name"), integerField(1, "stars")))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"id":1,"name":"reviews","stars":5}}`, out)
+	})
+	t.Run("keep last value of key written three times", func(t *testing.T) {
+		out, err := run(DuplicateKeysKeepLast, mergedResponse(stringField(0, "name"), stringField(1, "name"), integerField(0, "id"), stringField(1, "name")))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"id":1,"name":"reviews"}}`, out)
+	})
+	t.Run("objects without duplicate keys are unchanged", func(t *testing.T) {
+		out, err := run(DuplicateKeysKeepLast, mergedResponse(integerField(0, "id"), stringField(0, "name"), integerField(1, "stars")))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"id":1,"name":"accounts","stars":5}}`, out)
+	})
+}
+
+func TestResolver_MaxResponseBytes(t *testing.T) {
+	// nestedListsResponse creates a response with users, each having friends with a name, resolved by nested fetches
+	nestedListsResponse := func(count int) *GraphQLResponse {
+		list := func(itemTemplate string) string {
+			items := &bytes.Buffer{}
+			for i := 0; i < count; i++ {
+				if i != 0 {
+					items.WriteString(",")
+				}
+				items.WriteString(fmt.Sprintf(itemTemplate, i))
+			}
+			return items.String()
+		}
+
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(fmt.Sprintf(`{"users":[%s]}`, list(`{"id":%d}`))),
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("users"),
+						Value: &Array{
+							Path: []string{"users"},
+							Item: &Object{
+								Fetch: &SingleFetch{
+									BufferId:   1,
+									DataSource: FakeDataSource(fmt.Sprintf(`{"friends":[%s]}`, list(`{"name":"friend %d"}`))),
+								},
+								Fields: []*Field{
+									{
+										Name: []byte("id"),
+										Value: &Integer{
+											Path: []string{"id"},
+										},
+									},
+									{
+										BufferID:  1,
+										HasBuffer: true,
+										Name:      []byte("friends"),
+										Value: &Array{
+											Path: []string{"friends"},
+											Item: &Object{
+												Fields: []*Field{
+													{
+														Name: []byte("name"),
+														Value: &String{
+															Path: []string{"name"},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	run := func(t *testing.T, maxResponseBytes, count int) (string, error) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.MaxResponseBytes = maxResponseBytes
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(context.Background()), nestedListsResponse(count), nil, buf)
+		return buf.String(), err
+	}
+
+	t.Run("response within the limit", func(t *testing.T) {
+		out, err := run(t, 1024, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"users":[{"id":0,"friends":[{"name":"friend 0"},{"name":"friend 1"}]},{"id":1,"friends":[{"name":"friend 0"},{"name":"friend 1"}]}]}}`, out)
+	})
+	t.Run("response exceeding the limit", func(t *testing.T) {
+		out, err := run(t, 1024, 100)
+		assert.Equal(t, errResponseTooLarge, err)
+		assert.Equal(t, "", out)
+	})
+	t.Run("limit is disabled when zero", func(t *testing.T) {
+		out, err := run(t, 0, 100)
+		assert.NoError(t, err)
+		assert.Greater(t, len(out), 100*100*len(`{"name":"friend 0"}`))
+	})
+}
+
+func TestResolver_PartialResponseOnDeadline(t *testing.T) {
+	response := func() *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: &ParallelFetch{
+					Fetches: []Fetch{
+						&SingleFetch{
+							BufferId:   0,
+							DataSource: &_blockingDataSource{},
+						},
+						&SingleFetch{
+							BufferId:   1,
+							DataSource: FakeDataSource(`{"fast":"fastValue"}`),
+						},
+					},
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("slow"),
+						Value: &String{
+							Path:     []string{"slow"},
+							Nullable: true,
+						},
+					},
+					{
+						BufferID:  1,
+						HasBuffer: true,
+						Name:      []byte("fast"),
+						Value: &String{
+							Path:     []string{"fast"},
+							Nullable: true,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	run := func(t *testing.T, partialResponseOnDeadline bool) (string, error) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.PartialResponseOnDeadline = partialResponseOnDeadline
+
+		ctx, cancelCtx := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancelCtx()
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(NewContext(ctx), response(), nil, buf)
+		return buf.String(), err
+	}
+
+	t.Run("fields resolved before the deadline are written", func(t *testing.T) {
+		out, err := run(t, true)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"errors":[{"message":"deadline exceeded"}],"data":{"slow":null,"fast":"fastValue"}}`, out)
+	})
+	t.Run("deadline fails the response when disabled", func(t *testing.T) {
+		_, err := run(t, false)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}
+
+func TestResolver_DataSourcePaths(t *testing.T) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newResolver(rCtx, false, false)
+
+	response := &GraphQLResponse{
+		Data: &Object{
+			Fetch: &SingleFetch{
+				BufferId:             0,
+				DataSource:           FakeDataSource(`{"me":{"id":"1"},"topProducts":[{"upc":"top-1"},{"upc":"top-2"}]}`),
+				DataSourceIdentifier: []byte("accounts"),
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("me"),
+					Value: &Object{
+						Path: []string{"me"},
+						Fields: []*Field{
+							{
+								Name: []byte("id"),
+								Value: &String{
+									Path: []string{"id"},
+								},
+							},
+						},
+					},
+				},
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("topProducts"),
+					Value: &Array{
+						Path: []string{"topProducts"},
+						Item: &Object{
+							Fetch: &SingleFetch{
+								BufferId:             1,
+								DataSource:           FakeDataSource(`{"name":"Trilby"}`),
+								DataSourceIdentifier: []byte("products"),
+							},
+							Fields: []*Field{
+								{
+									Name: []byte("upc"),
+									Value: &String{
+										Path: []string{"upc"},
+									},
+								},
+								{
+									BufferID:  1,
+									HasBuffer: true,
+									Name:      []byte("name"),
+									Value: &String{
+										Path: []string{"name"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("paths are recorded per data source", func(t *testing.T) {
+		ctx := NewContext(context.Background())
+		ctx.RecordDataSourcePaths = true
+
+		buf := &bytes.Buffer{}
+		err := r.ResolveGraphQLResponse(ctx, response, nil, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"me":{"id":"1"},"topProducts":[{"upc":"top-1","name":"Trilby"},{"upc":"top-2","name":"Trilby"}]}}`, buf.String())
+		assert.Equal(t, map[string][]string{
+			"accounts": {"/data/me", "/data/topProducts"},
+			"products": {"/data/topProducts/0/name", "/data/topProducts/1/name"},
+		}, ctx.DataSourcePaths())
+	})
+	t.Run("paths are not recorded by default", func(t *testing.T) {
+		ctx := NewContext(context.Background())
+
+		err := r.ResolveGraphQLResponse(ctx, response, nil, &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.Nil(t, ctx.DataSourcePaths())
+	})
+}
+
+type TestFlushWriter struct {
+	flushed   []string
+	buf       bytes.Buffer
+	completed bool
+}
+
+func (t *TestFlushWriter) Write(p []byte) (n int, err error) {
+	return t.buf.Write(p)
+}
+
+func (t *TestFlushWriter) Flush() {
+	t.flushed = append(t.flushed, t.buf.String())
+	t.buf.Reset()
+}
+
+func (t *TestFlushWriter) Complete() {
+	t.completed = true
+}
+
+func FakeStream(cancelFunc func(), messageFunc func(count int) (message string, ok bool)) *_fakeStream {
+	return &_fakeStream{
+		cancel:      cancelFunc,
+		messageFunc: messageFunc,
+	}
+}
+
+type _fakeStream struct {
+	cancel      context.CancelFunc
+	messageFunc func(counter int) (message string, ok bool)
+}
+
+func (f *_fakeStream) Start(ctx context.Context, input []byte, next chan<- []byte) error {
+	go func() {
+		time.Sleep(time.Millisecond)
+		count := 0
+		for {
+			if count == 3 {
+				f.cancel()
+				return
+			}
+			message, ok := f.messageFunc(count)
+			next <- []byte(message)
+			if !ok {
+				f.cancel()
+				return
+			}
+			count++
+		}
+	}()
+	return nil
+}
+
+// _finiteStream sends its messages and closes the stream afterwards
+type _finiteStream struct {
+	messages []string
+	err      error
+}
+
+func (f *_finiteStream) Start(ctx context.Context, input []byte, next chan<- []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	go func() {
+		defer close(next)
+		for _, message := range f.messages {
+			next <- []byte(message)
+		}
+	}()
+	return nil
+}
+
+func TestResolver_ResolveGraphQLSubscription(t *testing.T) {
+
+	setup := func(ctx context.Context, stream *_fakeStream) (*Resolver, *GraphQLSubscription, *TestFlushWriter) {
+		plan := &GraphQLSubscription{
+			Trigger: GraphQLSubscriptionTrigger{
+				Source: stream,
+			},
+			Response: &GraphQLResponse{
+				Data: &Object{
+					Fields: []*Field{
+						{
+							Name: []byte("counter"),
+							Value: &Integer{
+								Path: []string{"counter"},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		out := &TestFlushWriter{
+			buf: bytes.Buffer{},
+		}
+
+		return newResolver(ctx, false, false), plan, out
+	}
+
+	t.Run("should return errors if the upstream data has errors", func(t *testing.T) {
+		c, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		fakeStream := FakeStream(cancel, func(count int) (message string, ok bool) {
+			return `{"errors":[{"message":"Validation error occurred","locations":[{"line":1,"column":1}],"extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}],"data":null}`, false
+		})
+
+		resolver, plan, out := setup(c, fakeStream)
+
+		ctx := Context{
+			Context: c,
+		}
+
+		err := resolver.ResolveGraphQLSubscription(&ctx, plan, out)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(out.flushed))
+		assert.Equal(t, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}]},{"message":"Validation error occurred","locations":[{"line":1,"column":1}],"extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}],"data":null}`, out.flushed[0])
+	})
+
+	t.Run("should successfully get result from upstream", func(t *testing.T) {
+		c, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		fakeStream := FakeStream(cancel, func(count int) (message string, ok bool) {
+			return fmt.Sprintf(`{"data":{"counter":%d}}`, count), true
+		})
+
+		resolver, plan, out := setup(c, fakeStream)
+
+		ctx := Context{
+			Context: c,
+		}
+
+		err := resolver.ResolveGraphQLSubscription(&ctx, plan, out)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(out.flushed))
+		assert.Equal(t, `{"data":{"counter":0}}`, out.flushed[0])
+		assert.Equal(t, `{"data":{"counter":1}}`, out.flushed[1])
+		assert.Equal(t, `{"data":{"counter":2}}`, out.flushed[2])
+		assert.False(t, out.completed)
+	})
+
+	t.Run("should complete when the upstream stream ends", func(t *testing.T) {
+		c, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		resolver, plan, out := setup(c, nil)
+		plan.Trigger.Source = &_finiteStream{
+			messages: []string{`{"data":{"counter":0}}`, `{"data":{"counter":1}}`},
+		}
+
+		err := resolver.ResolveGraphQLSubscription(&Context{Context: c}, plan, out)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`{"data":{"counter":0}}`, `{"data":{"counter":1}}`}, out.flushed)
+		assert.True(t, out.completed)
+	})
+
+	t.Run("should not complete when the upstream can't be resolved", func(t *testing.T) {
+		c, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		resolver, plan, out := setup(c, nil)
+		plan.Trigger.Source = &_finiteStream{
+			err: ErrUnableToResolve,
+		}
+
+		err := resolver.ResolveGraphQLSubscription(&Context{Context: c}, plan, out)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`{"errors":[{"message":"unable to resolve"}]}`}, out.flushed)
+		assert.False(t, out.completed)
+	})
+}
+
+func BenchmarkResolver_ResolveNode(b *testing.B) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resolver := newResolver(rCtx, true, false)
+
+	serviceOneDS := FakeDataSource(`{"serviceOne":{"fieldOne":"fieldOneValue"},"anotherServiceOne":{"fieldOne":"anotherFieldOneValue"},"reusingServiceOne":{"fieldOne":"reUsingFieldOneValue"}}`)
+	serviceTwoDS := FakeDataSource(`{"serviceTwo":{"fieldTwo":"fieldTwoValue"},"secondServiceTwo":{"fieldTwo":"secondFieldTwoValue"}}`)
+	nestedServiceOneDS := FakeDataSource(`{"serviceOne":{"fieldOne":"fieldOneValue"}}`)
+
+	plan := &GraphQLResponse{
+		Data: &Object{
+			Fetch: &ParallelFetch{
+				Fetches: []Fetch{
+					&SingleFetch{
+						BufferId: 0,
+						Input:    `{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":$$1$$,"firstArg":$$0$$}}}`,
+						InputTemplate: InputTemplate{
+							Segments: []TemplateSegment{
+								{
+									SegmentType: StaticSegmentType,
+									Data:        []byte(`{"url":"https://service.one","body":{"query":"query($firstArg: String, $thirdArg: Int){serviceOne(serviceOneArg: $firstArg){fieldOne} anotherServiceOne(anotherServiceOneArg: $thirdArg){fieldOne} reusingServiceOne(reusingServiceOneArg: $firstArg){fieldOne}}","variables":{"thirdArg":`),
+								},
+								{
+									SegmentType:        VariableSegmentType,
+									VariableKind:       ContextVariableKind,
+									VariableSourcePath: []string{"thirdArg"},
+								},
+								{
+									SegmentType: StaticSegmentType,
+									Data:        []byte(`,"firstArg":`),
+								},
+								{
+									SegmentType:        VariableSegmentType,
+									VariableKind:       ContextVariableKind,
+									VariableSourcePath: []string{"firstArg"},
+								},
+								{
+									SegmentType: StaticSegmentType,
+									Data:        []byte(`}}}`),
+								},
+							},
+						},
+						DataSource: serviceOneDS,
+						Variables: NewVariables(
+							&ContextVariable{
+								Path: []string{"firstArg"},
+							},
+							&ContextVariable{
+								Path: []string{"thirdArg"},
+							},
+						),
+					},
+					&SingleFetch{
+						BufferId: 1,
+						Input:    `{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":$$1$$,"secondArg":$$0$$}}}`,
+						InputTemplate: InputTemplate{
+							Segments: []TemplateSegment{
+								{
+									SegmentType: StaticSegmentType,
+									Data:        []byte(`{"url":"https://service.two","body":{"query":"query($secondArg: Boolean, $fourthArg: Float){serviceTwo(serviceTwoArg: $secondArg){fieldTwo} secondServiceTwo(secondServiceTwoArg: $fourthArg){fieldTwo}}","variables":{"fourthArg":`),
+								},
+								{
+									SegmentType:        VariableSegmentType,
+									VariableKind:       ContextVariableKind,
+									VariableSourcePath: []string{"fourthArg"},
+								},
+								{
+									SegmentType: StaticSegmentType,
+									Data:        []byte(`,"secondArg":`),
+								},
+								{
+									SegmentType:        VariableSegmentType,
+									VariableKind:       ContextVariableKind,
+									VariableSourcePath: []string{"secondArg"},
+								},
+								{
+									SegmentType: StaticSegmentType,
+									Data:        []byte(`}}}`),
+								},
+							},
+						},
+						DataSource: serviceTwoDS,
+						Variables: NewVariables(
+							&ContextVariable{
+								Path: []string{"secondArg"},
+							},
+							&ContextVariable{
+								Path: []string{"fourthArg"},
+							},
+						),
+					},
+				},
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("serviceOne"),
+					Value: &Object{
+						Path: []string{"serviceOne"},
+						Fields: []*Field{
+							{
+								Name: []byte("fieldOne"),
+								Value: &String{
+									Path: []string{"fieldOne"},
+								},
+							},
+						},
+					},
+				},
+				{
+					BufferID:  1,
+					HasBuffer: true,
+					Name:      []byte("serviceTwo"),
+					Value: &Object{
+						Path: []string{"serviceTwo"},
+						Fetch: &SingleFetch{
+							BufferId: 2,
+							Input:    `{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`,
+							InputTemplate: InputTemplate{
+								Segments: []TemplateSegment{
+									{
+										SegmentType: StaticSegmentType,
+										Data:        []byte(`{"url":"https://service.one","body":{"query":"{serviceOne {fieldOne}}"}}`),
+									},
+								},
+							},
+							DataSource: nestedServiceOneDS,
+							Variables:  Variables{},
+						},
+						Fields: []*Field{
+							{
+								Name: []byte("fieldTwo"),
+								Value: &String{
+									Path: []string{"fieldTwo"},
+								},
+							},
+							{
+								BufferID:  2,
+								HasBuffer: true,
+								Name:      []byte("serviceOneResponse"),
+								Value: &Object{
+									Path: []string{"serviceOne"},
+									Fields: []*Field{
+										{
+											Name: []byte("fieldOne"),
+											Value: &String{
+												Path: []string{"fieldOne"},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("anotherServiceOne"),
+					Value: &Object{
+						Path: []string{"anotherServiceOne"},
+						Fields: []*Field{
+							{
+								Name: []byte("fieldOne"),
+								Value: &String{
+									Path: []string{"fieldOne"},
+								},
+							},
+						},
+					},
+				},
+				{
+					BufferID:  1,
+					HasBuffer: true,
+					Name:      []byte("secondServiceTwo"),
+					Value: &Object{
+						Path: []string{"secondServiceTwo"},
+						Fields: []*Field{
+							{
+								Name: []byte("fieldTwo"),
+								Value: &String{
+									Path: []string{"fieldTwo"},
+								},
+							},
+						},
+					},
+				},
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("reusingServiceOne"),
+					Value: &Object{
+						Path: []string{"reusingServiceOne"},
+						Fields: []*Field{
+							{
+								Name: []byte("fieldOne"),
+								Value: &String{
+									Path: []string{"fieldOne"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var err error
+	expected := []byte(`{"data":{"serviceOne":{"fieldOne":"fieldOneValue"},"serviceTwo":{"fieldTwo":"fieldTwoValue","serviceOneResponse":{"fieldOne":"fieldOneValue"}},"anotherServiceOne":{"fieldOne":"anotherFieldOneValue"},"secondServiceTwo":{"fieldTwo":"secondFieldTwoValue"},"reusingServiceOne":{"fieldOne":"reUsingFieldOneValue"}}}`)
+
+	pool := sync.Pool{
+		New: func() interface{} {
+			return bytes.NewBuffer(make([]byte, 0, 1024))
+		},
+	}
+
+	variables := []byte(`{"firstArg":"firstArgValue","thirdArg":123,"secondArg": true, "fourthArg": 12.34}`)
+
+	ctxPool := sync.Pool{
+		New: func() interface{} {
+			return NewContext(context.Background())
+		},
+	}
+
+	runBench := func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(expected)))
+		b.ResetTimer()
+
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				// _ = resolver.ResolveGraphQLResponse(ctx, plan, nil, ioutil.Discard)
+				ctx := ctxPool.Get().(*Context)
+				ctx.Variables = variables
+				buf := pool.Get().(*bytes.Buffer)
+				err = resolver.ResolveGraphQLResponse(ctx, plan, nil, buf)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if !bytes.Equal(expected, buf.Bytes()) {
+					b.Fatalf("want:\n%s\ngot:\n%s\n", string(expected), buf.String())
+				}
+
+				buf.Reset()
+				pool.Put(buf)
+
+				ctx.Free()
+				ctxPool.Put(ctx)
+			}
+		})
+	}
+
+	b.Run("singleflight enabled (latency 0)", func(b *testing.B) {
+		serviceOneDS.artificialLatency = 0
+		serviceTwoDS.artificialLatency = 0
+		nestedServiceOneDS.artificialLatency = 0
+		runBench(b)
+	})
+}
+
+// _inFlightTrackingDataSource records the highest number of concurrent loads and of goroutines seen during a load
+type _inFlightTrackingDataSource struct {
+	data          []byte
+	inFlight      int64
+	maxInFlight   int64
+	maxGoroutines int64
+}
+
+func (f *_inFlightTrackingDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	current := atomic.AddInt64(&f.inFlight, 1)
+	defer atomic.AddInt64(&f.inFlight, -1)
+	storeMax(&f.maxInFlight, current)
+	storeMax(&f.maxGoroutines, int64(runtime.NumGoroutine()))
+	runtime.Gosched()
+	_, err = w.Write(f.data)
+	return
+}
+
+func storeMax(addr *int64, value int64) {
+	for {
+		max := atomic.LoadInt64(addr)
+		if value <= max || atomic.CompareAndSwapInt64(addr, max, value) {
+			return
+		}
+	}
+}
+
+func asynchronousArrayResponse(itemCount, maxConcurrency int) (*GraphQLResponse, *_inFlightTrackingDataSource) {
+	items := &bytes.Buffer{}
+	items.WriteString(`{"items":[`)
+	for i := 0; i < itemCount; i++ {
+		if i != 0 {
+			items.WriteString(`,`)
+		}
+		items.WriteString(fmt.Sprintf(`{"id":%d}`, i))
+	}
+	items.WriteString(`]}`)
+
+	itemDS := &_inFlightTrackingDataSource{
+		data: []byte(`{"name":"item"}`),
+	}
+
+	return &GraphQLResponse{
+		Data: &Object{
+			Fetch: &SingleFetch{
+				BufferId:   0,
+				DataSource: FakeDataSource(items.String()),
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("items"),
+					Value: &Array{
+						Path:                []string{"items"},
+						ResolveAsynchronous: true,
+						MaxConcurrency:      maxConcurrency,
+						Item: &Object{
+							Fetch: &SingleFetch{
+								BufferId:   1,
+								DataSource: itemDS,
+							},
+							Fields: []*Field{
+								{
+									Name: []byte("id"),
+									Value: &Integer{
+										Path: []string{"id"},
+									},
+								},
+								{
+									BufferID:  1,
+									HasBuffer: true,
+									Name:      []byte("name"),
+									Value: &String{
+										Path: []string{"name"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, itemDS
+}
+
+func TestResolver_ResolveArrayAsynchronousMaxConcurrency(t *testing.T) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resolver := newResolver(rCtx, false, false)
+
+	unlimited, _ := asynchronousArrayResponse(100, 0)
+	expected := &bytes.Buffer{}
+	err := resolver.ResolveGraphQLResponse(NewContext(context.Background()), unlimited, nil, expected)
+	assert.NoError(t, err)
+
+	limited, itemDS := asynchronousArrayResponse(100, 4)
+	out := &bytes.Buffer{}
+	err = resolver.ResolveGraphQLResponse(NewContext(context.Background()), limited, nil, out)
+	assert.NoError(t, err)
+
+	assert.Equal(t, expected.String(), out.String())
+	assert.LessOrEqual(t, atomic.LoadInt64(&itemDS.maxInFlight), int64(4))
+}
+
+func BenchmarkResolver_ResolveArrayAsynchronous(b *testing.B) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resolver := newResolver(rCtx, false, false)
+
+	runBench := func(maxConcurrency int) func(b *testing.B) {
+		return func(b *testing.B) {
+			plan, itemDS := asynchronousArrayResponse(10000, maxConcurrency)
+			buf := &bytes.Buffer{}
+			// every item is resolved in its own goroutine, so the goroutines started while resolving show how many items run at once
+			goroutines := runtime.NumGoroutine()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				if err := resolver.ResolveGraphQLResponse(NewContext(context.Background()), plan, nil, buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(atomic.LoadInt64(&itemDS.maxInFlight)), "max-in-flight")
+			b.ReportMetric(float64(atomic.LoadInt64(&itemDS.maxGoroutines)-int64(goroutines)), "max-goroutines")
+		}
+	}
+
+	b.Run("unlimited", runBench(0))
+	b.Run("max concurrency 16", runBench(16))
+}
+
+func BenchmarkResolver_MergeArrayItems(b *testing.B) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resolver := newResolver(rCtx, false, false)
+
+	itemBufs := make([]*BufPair, 5000)
+	for i := range itemBufs {
+		itemBufs[i] = NewBufPair()
+	}
+	fill := func() {
+		for i := range itemBufs {
+			itemBufs[i].Data.WriteBytes([]byte(`{"id":1234,"name":"item"}`))
+		}
+	}
+
+	b.Run("merge item by item", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			fill()
+			arrayBuf := NewBufPair()
+			for j := range itemBufs {
+				resolver.MergeBufPairs(itemBufs[j], arrayBuf, j != 0)
+			}
+		}
+	})
+	b.Run("pre-sized merge", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			fill()
+			arrayBuf := NewBufPair()
+			resolver.mergeArrayItems(itemBufs, arrayBuf)
+		}
+	})
+}
+
+func sharedUpstreamFailureArrayResponse(itemCount int) *GraphQLResponse {
+	items := &bytes.Buffer{}
+	items.WriteString(`{"items":[`)
+	for i := 0; i < itemCount; i++ {
+		if i != 0 {
+			items.WriteString(`,`)
+		}
+		items.WriteString(fmt.Sprintf(`{"id":%d}`, i))
+	}
+	items.WriteString(`]}`)
+
+	return &GraphQLResponse{
+		Data: &Object{
+			Fetch: &SingleFetch{
+				BufferId:   0,
+				DataSource: FakeDataSource(items.String()),
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("items"),
+					Value: &Array{
+						Path: []string{"items"},
+						Item: &Object{
+							Fetch: &SingleFetch{
+								BufferId:   1,
+								DataSource: FakeDataSource(`{"errors":[{"message":"upstream failed","path":["item"]}],"data":{"name":null}}`),
+								ProcessResponseConfig: ProcessResponseConfig{
+									ExtractGraphqlResponse: true,
+								},
+							},
+							Fields: []*Field{
+								{
+									Name: []byte("id"),
+									Value: &Integer{
+										Path: []string{"id"},
+									},
+								},
+								{
+									BufferID:  1,
+									HasBuffer: true,
+									Name:      []byte("name"),
+									Value: &String{
+										Path:     []string{"name"},
+										Nullable: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestResolver_InvalidUpstreamJSON(t *testing.T) {
+	rootFetch := func(data string) *SingleFetch {
+		return &SingleFetch{
+			BufferId:             0,
+			DataSource:           FakeDataSource(data),
+			DataSourceIdentifier: []byte("accounts"),
+			ProcessResponseConfig: ProcessResponseConfig{
+				ExtractGraphqlResponse: true,
+			},
+		}
+	}
+	nestedResponse := func(data string) *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: rootFetch(`{"data":{"me":{"id":1}}}`),
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("me"),
+						Value: &Object{
+							Path:     []string{"me"},
+							Nullable: true,
+							Fetch: &SingleFetch{
+								BufferId:             1,
+								DataSource:           FakeDataSource(data),
+								DataSourceIdentifier: []byte("reviews"),
+							},
+							Fields: []*Field{
+								{
+									Name: []byte("id"),
+									Value: &Integer{
+										Path: []string{"id"},
+									},
+								},
+								{
+									BufferID:  1,
+									HasBuffer: true,
+									Name:      []byte("reviewCount"),
+									Value: &Integer{
+										Path:     []string{"count"},
+										Nullable: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	rootResponse := func(data string) *GraphQLResponse {
+		return &GraphQLResponse{
+			Data: &Object{
+				Fetch: rootFetch(data),
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("name"),
+						Value: &String{
+							Path:     []string{"name"},
+							Nullable: true,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	for _, enableDataLoader := range []bool{false, true} {
+		for _, enableSingleFlight := range []bool{false, true} {
+			run := func(response *GraphQLResponse, expectedOutput string) func(t *testing.T) {
+				return func(t *testing.T) {
+					rCtx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					r := newResolver(rCtx, enableSingleFlight, enableDataLoader)
+
+					buf := &bytes.Buffer{}
+					err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+					if !assert.NoError(t, err) {
+						return
+					}
+					assert.Equal(t, expectedOutput, buf.String())
+				}
+			}
+
+			t.Run(fmt.Sprintf("data loader: %t, single flight: %t", enableDataLoader, enableSingleFlight), func(t *testing.T) {
+				t.Run("truncated root response", run(rootResponse(`{"data":{"name":"Je`),
+					`{"errors":[{"message":"upstream returned invalid JSON: accounts"}],"data":{"name":null}}`))
+				t.Run("non JSON root response", run(rootResponse(`<html>502 Bad Gateway</html>`),
+					`{"errors":[{"message":"upstream returned invalid JSON: accounts"}],"data":{"name":null}}`))
+				t.Run("truncated nested response", run(nestedResponse(`{"count":`),
+					`{"errors":[{"message":"upstream returned invalid JSON: reviews","path":["me"]}],"data":{"me":{"id":1,"reviewCount":null}}}`))
+				t.Run("valid nested response", run(nestedResponse(`{"count":3}`),
+					`{"data":{"me":{"id":1,"reviewCount":3}}}`))
+			})
+		}
+	}
+}
+
+func TestResolver_DeduplicateErrors(t *testing.T) {
+	t.Run("errors of a shared upstream failure", func(t *testing.T) {
+		run := func(t *testing.T, deduplicate bool, expectedOutput string) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+			r.DeduplicateErrors = deduplicate
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), sharedUpstreamFailureArrayResponse(3), nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOutput, buf.String())
+		}
+
+		t.Run("deduplicated", func(t *testing.T) {
+			run(t, true, `{"errors":[{"message":"upstream failed","path":["item"]}],"data":{"items":[{"id":0,"name":null},{"id":1,"name":null},{"id":2,"name":null}]}}`)
+		})
+		t.Run("disabled", func(t *testing.T) {
+			run(t, false, `{"errors":[{"message":"upstream failed","path":["item"]},{"message":"upstream failed","path":["item"]},{"message":"upstream failed","path":["item"]}],"data":{"items":[{"id":0,"name":null},{"id":1,"name":null},{"id":2,"name":null}]}}`)
+		})
+	})
+	t.Run("distinct errors are preserved", func(t *testing.T) {
+		buf := NewBufPair()
+		buf.Errors.WriteBytes([]byte(`{"message":"a","path":["items",0]},{"message":"a","path":["items",1]},{"message":"a","path":["items",0]},{"message":"b"}`))
+		deduplicateErrors(buf)
+		assert.Equal(t, `{"message":"a","path":["items",0]},{"message":"a","path":["items",1]},{"message":"b"}`, buf.Errors.String())
+	})
+}
+
+func BenchmarkResolver_DeduplicateErrors(b *testing.B) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runBench := func(deduplicate bool) func(b *testing.B) {
+		return func(b *testing.B) {
+			resolver := newResolver(rCtx, false, false)
+			resolver.DeduplicateErrors = deduplicate
+			plan := sharedUpstreamFailureArrayResponse(1000)
+			var responseSize int
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// a new buffer for each response, as it would be the case when writing to a client
+				buf := &bytes.Buffer{}
+				if err := resolver.ResolveGraphQLResponse(NewContext(context.Background()), plan, nil, buf); err != nil {
+					b.Fatal(err)
+				}
+				responseSize = buf.Len()
+			}
+			b.ReportMetric(float64(responseSize), "response-bytes")
+		}
+	}
+
+	b.Run("disabled", runBench(false))
+	b.Run("deduplicated", runBench(true))
+}
+
+type hookContextPathMatcher struct {
+	path string
+}
+
+func (h hookContextPathMatcher) Matches(x interface{}) bool {
+	path := string(x.(HookContext).CurrentPath)
+	return path == h.path
+}
+
+func (h hookContextPathMatcher) String() string {
+	return fmt.Sprintf("is equal to %s", h.path)
+}
+
+func TestInputTemplate_Render(t *testing.T) {
+	runTest := func(t *testing.T, variables string, sourcePath []string, jsonSchema string, expectErr bool, expected string) {
+		t.Helper()
+
+		template := InputTemplate{
+			Segments: []TemplateSegment{
+				{
+					SegmentType:        VariableSegmentType,
+					VariableKind:       ContextVariableKind,
+					VariableSourcePath: sourcePath,
+					Renderer:           NewPlainVariableRendererWithValidation(jsonSchema),
+				},
+			},
+		}
+		ctx := &Context{
+			Variables: []byte(variables),
+		}
+		buf := fastbuffer.New()
+		err := template.Render(ctx, nil, buf)
+		if expectErr {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+		out := buf.String()
+		assert.Equal(t, expected, out)
+	}
+
+	t.Run("string scalar", func(t *testing.T) {
+		runTest(t, `{"foo":"bar"}`, []string{"foo"}, `{"type":"string"}`, false, `"bar"`)
+	})
+	t.Run("boolean scalar", func(t *testing.T) {
+		runTest(t, `{"foo":true}`, []string{"foo"}, `{"type":"boolean"}`, false, "true")
+	})
+	t.Run("json object pass through", func(t *testing.T) {
+		runTest(t, `{"foo":{"bar":"baz"}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"string"}}}`, false, `{"bar":"baz"}`)
+	})
+	t.Run("json object as graphql object", func(t *testing.T) {
+		runTest(t, `{"foo":{"bar":"baz"}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"string"}}}`, false, `{"bar":"baz"}`)
+	})
+	t.Run("json object as graphql object with null", func(t *testing.T) {
+		runTest(t, `{"foo":null}`, []string{"foo"}, `{"type":"string"}`, false, `null`)
+	})
+	t.Run("json object as graphql object with number", func(t *testing.T) {
+		runTest(t, `{"foo":123}`, []string{"foo"}, `{"type":"integer"}`, false, `123`)
+	})
+	t.Run("json object as graphql object with invalid number", func(t *testing.T) {
+		runTest(t, `{"foo":123}`, []string{"foo"}, `{"type":"string"}`, true, "")
+	})
+	t.Run("json object as graphql object with boolean", func(t *testing.T) {
+		runTest(t, `{"foo":{"bar":true}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"boolean"}}}`, false, `{"bar":true}`)
+	})
+	t.Run("json object as graphql object with number", func(t *testing.T) {
+		runTest(t, `{"foo":{"bar":123}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"integer"}}}`, false, `{"bar":123}`)
+	})
+	t.Run("json object as graphql object with float", func(t *testing.T) {
+		runTest(t, `{"foo":{"bar":1.23}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"number"}}}`, false, `{"bar":1.23}`)
+	})
+	t.Run("json object as graphql object with nesting", func(t *testing.T) {
+		runTest(t, `{"foo":{"bar":{"baz":"bat"}}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"object","properties":{"baz":{"type":"string"}}}}}`, false, `{"bar":{"baz":"bat"}}`)
+	})
+	t.Run("json object as graphql object with single array", func(t *testing.T) {
+		runTest(t, `{"foo":["bar"]}`, []string{"foo"}, `{"type":"array","item":{"type":"string"}}`, false, `["bar"]`)
+	})
+	t.Run("json object as graphql object with array", func(t *testing.T) {
+		runTest(t, `{"foo":["bar","baz"]}`, []string{"foo"}, `{"type":"array","item":{"type":"string"}}`, false, `["bar","baz"]`)
+	})
+	t.Run("json object as graphql object with object array", func(t *testing.T) {
+		runTest(t, `{"foo":[{"bar":"baz"},{"bar":"bat"}]}`, []string{"foo"}, `{"type":"array","item":{"type":"object","properties":{"bar":{"type":"string"}}}}`, false, `[{"bar":"baz"},{"bar":"bat"}]`)
+	})
+	t.Run("array with csv render string", func(t *testing.T) {
+		template := InputTemplate{
 			Segments: []TemplateSegment{
 				{
 					SegmentType:        VariableSegmentType,
 					VariableKind:       ContextVariableKind,
-					VariableSourcePath: sourcePath,
-					Renderer:           NewPlainVariableRendererWithValidation(jsonSchema),
+					VariableSourcePath: []string{"a"},
+					Renderer:           NewCSVVariableRenderer(JsonRootType{Value: jsonparser.String, Kind: JsonRootTypeKindSingle}),
+				},
+			},
+		}
+		ctx := &Context{
+			Variables: []byte(`{"a":["foo","bar"]}`),
+		}
+		buf := fastbuffer.New()
+		err := template.Render(ctx, nil, buf)
+		assert.NoError(t, err)
+		out := buf.String()
+		assert.Equal(t, "foo,bar", out)
+	})
+	t.Run("array with csv render int", func(t *testing.T) {
+		template := InputTemplate{
+			Segments: []TemplateSegment{
+				{
+					SegmentType:        VariableSegmentType,
+					VariableKind:       ContextVariableKind,
+					VariableSourcePath: []string{"a"},
+					Renderer:           NewCSVVariableRenderer(JsonRootType{Value: jsonparser.Number}),
+				},
+			},
+		}
+		ctx := &Context{
+			Variables: []byte(`{"a":[1,2,3]}`),
+		}
+		buf := fastbuffer.New()
+		err := template.Render(ctx, nil, buf)
+		assert.NoError(t, err)
+		out := buf.String()
+		assert.Equal(t, "1,2,3", out)
+	})
+	t.Run("array with default render int", func(t *testing.T) {
+		template := InputTemplate{
+			Segments: []TemplateSegment{
+				{
+					SegmentType:        VariableSegmentType,
+					VariableKind:       ContextVariableKind,
+					VariableSourcePath: []string{"a"},
+					Renderer:           NewGraphQLVariableRenderer(`{"type":"array","items":{"type":"number"}}`),
+				},
+			},
+		}
+		ctx := &Context{
+			Variables: []byte(`{"a":[1,2,3]}`),
+		}
+		buf := fastbuffer.New()
+		err := template.Render(ctx, nil, buf)
+		assert.NoError(t, err)
+		out := buf.String()
+		assert.Equal(t, "[1,2,3]", out)
+	})
+	t.Run("json render with value missing", func(t *testing.T) {
+		template := InputTemplate{
+			Segments: []TemplateSegment{
+				{
+					SegmentType: StaticSegmentType,
+					Data:        []byte(`{"key":`),
+				},
+				{
+					SegmentType:        VariableSegmentType,
+					VariableKind:       ContextVariableKind,
+					VariableSourcePath: []string{"a"},
+					Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
+				},
+				{
+					SegmentType: StaticSegmentType,
+					Data:        []byte(`}`),
+				},
+			},
+		}
+		ctx := &Context{
+			Variables: []byte(""),
+		}
+		buf := fastbuffer.New()
+		err := template.Render(ctx, nil, buf)
+		assert.NoError(t, err)
+		out := buf.String()
+		assert.Equal(t, `{"key":null}`, out)
+	})
+}
+
+func TestInputTemplate_RenderEncoding(t *testing.T) {
+	const uuid = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+	render := func(t *testing.T, variables string, encoding EncodingKind) (string, error) {
+		template := InputTemplate{
+			Segments: []TemplateSegment{
+				{
+					SegmentType: StaticSegmentType,
+					Data:        []byte(`/users/`),
+				},
+				{
+					SegmentType:        VariableSegmentType,
+					VariableKind:       ContextVariableKind,
+					VariableSourcePath: []string{"id"},
+					Renderer: &PlainVariableRenderer{
+						rootValueType: JsonRootType{Value: jsonparser.String, Kind: JsonRootTypeKindSingle},
+					},
+					Encoding: encoding,
 				},
 			},
 		}
@@ -3370,143 +6921,768 @@ func TestInputTemplate_Render(t *testing.T) {
 		}
 		buf := fastbuffer.New()
 		err := template.Render(ctx, nil, buf)
-		if expectErr {
-			assert.Error(t, err)
-		} else {
+		return buf.String(), err
+	}
+
+	t.Run("base64", func(t *testing.T) {
+		out, err := render(t, `{"id":"`+uuid+`"}`, EncodingBase64)
+		assert.NoError(t, err)
+		assert.Equal(t, `/users/NmJhN2I4MTAtOWRhZC0xMWQxLTgwYjQtMDBjMDRmZDQzMGM4`, out)
+	})
+	t.Run("base64 url", func(t *testing.T) {
+		out, err := render(t, `{"id":"~~~"}`, EncodingBase64URL)
+		assert.NoError(t, err)
+		assert.Equal(t, `/users/fn5-`, out)
+	})
+	t.Run("escaped string is encoded unescaped", func(t *testing.T) {
+		out, err := render(t, `{"id":"\u007e~~"}`, EncodingBase64)
+		assert.NoError(t, err)
+		assert.Equal(t, `/users/fn5+`, out)
+	})
+	t.Run("missing variable", func(t *testing.T) {
+		out, err := render(t, `{}`, EncodingBase64)
+		assert.NoError(t, err)
+		assert.Equal(t, `/users/null`, out)
+	})
+	t.Run("round trip uuid", func(t *testing.T) {
+		template := InputTemplate{
+			Segments: []TemplateSegment{
+				{
+					SegmentType: StaticSegmentType,
+					Data:        []byte(`{"id":`),
+				},
+				{
+					SegmentType:        VariableSegmentType,
+					VariableKind:       ContextVariableKind,
+					VariableSourcePath: []string{"id"},
+					Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
+					Encoding:           EncodingBase64URL,
+				},
+				{
+					SegmentType: StaticSegmentType,
+					Data:        []byte(`}`),
+				},
+			},
+		}
+		ctx := &Context{
+			Variables: []byte(`{"id":"` + uuid + `"}`),
+		}
+		encoded := fastbuffer.New()
+		err := template.Render(ctx, nil, encoded)
+		assert.NoError(t, err)
+
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+
+		response := &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					BufferId:   0,
+					DataSource: FakeDataSource(encoded.String()),
+				},
+				Fields: []*Field{
+					{
+						BufferID:  0,
+						HasBuffer: true,
+						Name:      []byte("id"),
+						Value: &String{
+							Path:     []string{"id"},
+							Decoding: EncodingBase64URL,
+						},
+					},
+				},
+			},
+		}
+
+		buf := &bytes.Buffer{}
+		err = r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"id":"`+uuid+`"}}`, buf.String())
+	})
+}
+
+func TestResolver_ResolveStringDecoding(t *testing.T) {
+	run := func(upstreamValue string, nullable bool, expectedOutput string) func(t *testing.T) {
+		return func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SingleFetch{
+						BufferId:   0,
+						DataSource: FakeDataSource(fmt.Sprintf(`{"blob":%s}`, upstreamValue)),
+					},
+					Nullable: true,
+					Fields: []*Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("blob"),
+							Value: &String{
+								Path:     []string{"blob"},
+								Nullable: nullable,
+								Decoding: EncodingBase64,
+							},
+						},
+					},
+				},
+			}
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(NewContext(context.Background()), response, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedOutput, buf.String())
+		}
+	}
+
+	t.Run("decoded value", run(`"YT9iPmM="`, false, `{"data":{"blob":"a?b>c"}}`))
+	t.Run("decoded value is escaped", run(`"ImEi"`, false, `{"data":{"blob":"\"a\""}}`))
+	t.Run("null on nullable string", run(`null`, true, `{"data":{"blob":null}}`))
+	t.Run("invalid value on nullable string", run(`"not base64!"`, true,
+		`{"errors":[{"message":"String cannot decode value: not base64!","path":["blob"]}],"data":{"blob":null}}`))
+	t.Run("decoded bytes which aren't valid UTF-8", run(`"/w=="`, true,
+		`{"errors":[{"message":"String cannot decode value: /w==","path":["blob"]}],"data":{"blob":null}}`))
+	t.Run("invalid value on non nullable string", run(`"not base64!"`, false,
+		`{"errors":[{"message":"String cannot decode value: not base64!","path":["blob"]},{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["blob"]}],"data":null}`))
+}
+
+func TestInputTemplate_RenderEscapeForURL(t *testing.T) {
+	run := func(variables string, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			template := InputTemplate{
+				Segments: []TemplateSegment{
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`/search?q=`),
+					},
+					{
+						SegmentType:        VariableSegmentType,
+						VariableKind:       ContextVariableKind,
+						VariableSourcePath: []string{"term"},
+						Renderer: &PlainVariableRenderer{
+							rootValueType: JsonRootType{Value: jsonparser.String, Kind: JsonRootTypeKindSingle},
+						},
+						EscapeForURL: true,
+					},
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`&limit=10`),
+					},
+				},
+			}
+			ctx := &Context{
+				Variables: []byte(variables),
+			}
+			buf := fastbuffer.New()
+			err := template.Render(ctx, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, buf.String())
+		}
+	}
+
+	t.Run("search term with spaces", run(`{"term":"star wars"}`, `/search?q=star%20wars&limit=10`))
+	t.Run("search term with reserved characters", run(`{"term":"r2&d2/c3po?+=#100%"}`, `/search?q=r2%26d2%2Fc3po%3F%2B%3D%23100%25&limit=10`))
+	t.Run("already encoded search term", run(`{"term":"star%20wars"}`, `/search?q=star%20wars&limit=10`))
+	t.Run("empty search term", run(`{"term":""}`, `/search?q=&limit=10`))
+	t.Run("missing search term", run(`{}`, `/search?q=&limit=10`))
+}
+
+func TestInputTemplate_RenderDefault(t *testing.T) {
+	run := func(variableKind VariableKind, variables string, defaultValue []byte, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			template := InputTemplate{
+				Segments: []TemplateSegment{
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`{"limit":`),
+					},
+					{
+						SegmentType:        VariableSegmentType,
+						VariableKind:       variableKind,
+						VariableSourcePath: []string{"limit"},
+						Renderer:           NewJSONVariableRenderer(),
+						Default:            defaultValue,
+					},
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`}`),
+					},
+				},
+			}
+			ctx := &Context{
+				Variables: []byte(variables),
+				Request: Request{
+					Header: http.Header{},
+				},
+			}
+			buf := fastbuffer.New()
+			err := template.Render(ctx, []byte(variables), buf)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, buf.String())
+		}
+	}
+
+	t.Run("context variable", func(t *testing.T) {
+		t.Run("missing variable with default", run(ContextVariableKind, `{}`, []byte(`10`), `{"limit":10}`))
+		t.Run("missing variable with empty string default", run(ContextVariableKind, `{}`, []byte(`""`), `{"limit":""}`))
+		t.Run("missing variable with null default", run(ContextVariableKind, `{}`, []byte(`null`), `{"limit":null}`))
+		t.Run("missing variable without default", run(ContextVariableKind, `{}`, nil, `{"limit":null}`))
+		t.Run("present variable ignores default", run(ContextVariableKind, `{"limit":5}`, []byte(`10`), `{"limit":5}`))
+		t.Run("null variable ignores default", run(ContextVariableKind, `{"limit":null}`, []byte(`10`), `{"limit":null}`))
+	})
+	t.Run("object variable", func(t *testing.T) {
+		t.Run("missing variable with default", run(ObjectVariableKind, `{}`, []byte(`10`), `{"limit":10}`))
+		t.Run("present variable ignores default", run(ObjectVariableKind, `{"limit":5}`, []byte(`10`), `{"limit":5}`))
+	})
+	t.Run("header variable", func(t *testing.T) {
+		t.Run("missing header with default", run(HeaderVariableKind, `{}`, []byte(`10`), `{"limit":10}`))
+		t.Run("missing header without default", run(HeaderVariableKind, `{}`, nil, `{"limit":}`))
+	})
+}
+
+func TestInputTemplate_RenderListVariable(t *testing.T) {
+	run := func(variables string, listRendering ListRenderingKind, escapeForURL bool, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			template := InputTemplate{
+				Segments: []TemplateSegment{
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`/users?`),
+					},
+					{
+						SegmentType:        VariableSegmentType,
+						VariableKind:       ContextVariableKind,
+						VariableSourcePath: []string{"ids"},
+						Renderer:           NewJSONVariableRenderer(),
+						ListRendering:      listRendering,
+						ListKey:            []byte("id"),
+						EscapeForURL:       escapeForURL,
+					},
+				},
+			}
+			ctx := &Context{
+				Variables: []byte(variables),
+			}
+			buf := fastbuffer.New()
+			err := template.Render(ctx, nil, buf)
 			assert.NoError(t, err)
+			assert.Equal(t, expected, buf.String())
 		}
-		out := buf.String()
-		assert.Equal(t, expected, out)
 	}
 
-	t.Run("string scalar", func(t *testing.T) {
-		runTest(t, `{"foo":"bar"}`, []string{"foo"}, `{"type":"string"}`, false, `"bar"`)
-	})
-	t.Run("boolean scalar", func(t *testing.T) {
-		runTest(t, `{"foo":true}`, []string{"foo"}, `{"type":"boolean"}`, false, "true")
+	t.Run("json array", func(t *testing.T) {
+		t.Run("list", run(`{"ids":["1","2"]}`, ListRenderingJSONArray, false, `/users?["1","2"]`))
+		t.Run("empty list", run(`{"ids":[]}`, ListRenderingJSONArray, false, `/users?[]`))
 	})
-	t.Run("json object pass through", func(t *testing.T) {
-		runTest(t, `{"foo":{"bar":"baz"}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"string"}}}`, false, `{"bar":"baz"}`)
+	t.Run("comma separated", func(t *testing.T) {
+		t.Run("list", run(`{"ids":["1","2",3]}`, ListRenderingCommaSeparated, false, `/users?1,2,3`))
+		t.Run("empty list", run(`{"ids":[]}`, ListRenderingCommaSeparated, false, `/users?`))
+		t.Run("null list", run(`{"ids":null}`, ListRenderingCommaSeparated, false, `/users?`))
+		t.Run("single value", run(`{"ids":"1"}`, ListRenderingCommaSeparated, false, `/users?1`))
+		t.Run("items escaped for url", run(`{"ids":["a b","c,d"]}`, ListRenderingCommaSeparated, true, `/users?a%20b,c%2Cd`))
 	})
-	t.Run("json object as graphql object", func(t *testing.T) {
-		runTest(t, `{"foo":{"bar":"baz"}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"string"}}}`, false, `{"bar":"baz"}`)
+	t.Run("repeated", func(t *testing.T) {
+		t.Run("list", run(`{"ids":["1","2"]}`, ListRenderingRepeated, false, `/users?id=1&id=2`))
+		t.Run("empty list", run(`{"ids":[]}`, ListRenderingRepeated, false, `/users?`))
+		t.Run("missing list", run(`{}`, ListRenderingRepeated, false, `/users?`))
+		t.Run("items escaped for url", run(`{"ids":["a&b","c=d"]}`, ListRenderingRepeated, true, `/users?id=a%26b&id=c%3Dd`))
 	})
-	t.Run("json object as graphql object with null", func(t *testing.T) {
-		runTest(t, `{"foo":null}`, []string{"foo"}, `{"type":"string"}`, false, `null`)
+}
+
+func TestInputTemplate_RenderHeaderVariable(t *testing.T) {
+	run := func(path []string, header http.Header, expectedErr error, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			variable := &HeaderVariable{
+				Path: path,
+			}
+			template := InputTemplate{
+				Segments: []TemplateSegment{
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`{"tenant":"`),
+					},
+					variable.TemplateSegment(),
+					{
+						SegmentType: StaticSegmentType,
+						Data:        []byte(`"}`),
+					},
+				},
+			}
+			ctx := &Context{
+				Request: Request{
+					Header: header,
+				},
+			}
+			buf := fastbuffer.New()
+			err := template.Render(ctx, nil, buf)
+			assert.Equal(t, expectedErr, err)
+			if expectedErr == nil {
+				assert.Equal(t, expected, buf.String())
+			}
+		}
+	}
+
+	t.Run("single value", run([]string{"X-Tenant-Id"}, http.Header{"X-Tenant-Id": {"tenant-1"}}, nil, `{"tenant":"tenant-1"}`))
+	t.Run("multiple values are joined", run([]string{"X-Tenant-Id"}, http.Header{"X-Tenant-Id": {"tenant-1", "tenant-2"}}, nil, `{"tenant":"tenant-1,tenant-2"}`))
+	t.Run("header name is case insensitive", run([]string{"x-tenant-id"}, http.Header{"X-Tenant-Id": {"tenant-1"}}, nil, `{"tenant":"tenant-1"}`))
+	t.Run("missing header", run([]string{"X-Tenant-Id"}, http.Header{}, nil, `{"tenant":""}`))
+	t.Run("path with more than the header name", run([]string{"X-Tenant-Id", "value"}, http.Header{}, errHeaderPathInvalid, ``))
+}
+
+func TestFetcher_SingleFlightKeyHeaders(t *testing.T) {
+	fetcher := NewFetcher(true)
+	input := []byte(`{"method":"GET","url":"https://example.com/me"}`)
+
+	key := func(fetch *SingleFetch, authorization string) uint64 {
+		ctx := NewContext(context.Background())
+		ctx.Request.Header = http.Header{
+			"Authorization": []string{authorization},
+		}
+		return fetcher.singleFlightKey(ctx, fetch, input)
+	}
+
+	t.Run("different tokens don't collide", func(t *testing.T) {
+		fetch := &SingleFetch{SingleFlightKeyHeaders: []string{"Authorization"}}
+		assert.NotEqual(t, key(fetch, "token a"), key(fetch, "token b"))
 	})
-	t.Run("json object as graphql object with number", func(t *testing.T) {
-		runTest(t, `{"foo":123}`, []string{"foo"}, `{"type":"integer"}`, false, `123`)
+	t.Run("same token shares the key", func(t *testing.T) {
+		fetch := &SingleFetch{SingleFlightKeyHeaders: []string{"Authorization"}}
+		assert.Equal(t, key(fetch, "token a"), key(fetch, "token a"))
 	})
-	t.Run("json object as graphql object with invalid number", func(t *testing.T) {
-		runTest(t, `{"foo":123}`, []string{"foo"}, `{"type":"string"}`, true, "")
+	t.Run("headers are ignored if not configured", func(t *testing.T) {
+		fetch := &SingleFetch{}
+		assert.Equal(t, key(fetch, "token a"), key(fetch, "token b"))
 	})
-	t.Run("json object as graphql object with boolean", func(t *testing.T) {
-		runTest(t, `{"foo":{"bar":true}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"boolean"}}}`, false, `{"bar":true}`)
+
+	headersKey := func(fetch *SingleFetch, header http.Header) uint64 {
+		ctx := NewContext(context.Background())
+		ctx.Request.Header = header
+		return fetcher.singleFlightKey(ctx, fetch, input)
+	}
+
+	t.Run("multiple values don't collide with their concatenation", func(t *testing.T) {
+		fetch := &SingleFetch{SingleFlightKeyHeaders: []string{"Authorization"}}
+		assert.NotEqual(t,
+			headersKey(fetch, http.Header{"Authorization": {"a", "b"}}),
+			headersKey(fetch, http.Header{"Authorization": {"ab"}}),
+		)
 	})
-	t.Run("json object as graphql object with number", func(t *testing.T) {
-		runTest(t, `{"foo":{"bar":123}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"integer"}}}`, false, `{"bar":123}`)
+	t.Run("values don't move to the next header", func(t *testing.T) {
+		fetch := &SingleFetch{SingleFlightKeyHeaders: []string{"X-A", "X-B"}}
+		assert.NotEqual(t,
+			headersKey(fetch, http.Header{"X-A": {"X-B"}}),
+			headersKey(fetch, http.Header{"X-B": {"X-B"}}),
+		)
 	})
-	t.Run("json object as graphql object with float", func(t *testing.T) {
-		runTest(t, `{"foo":{"bar":1.23}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"number"}}}`, false, `{"bar":1.23}`)
+	t.Run("input and header names don't run into each other", func(t *testing.T) {
+		ctx := NewContext(context.Background())
+		ctx.Request.Header = http.Header{}
+		assert.NotEqual(t,
+			fetcher.singleFlightKey(ctx, &SingleFetch{SingleFlightKeyHeaders: []string{"Authorization"}}, []byte("input")),
+			fetcher.singleFlightKey(ctx, &SingleFetch{SingleFlightKeyHeaders: []string{"orization"}}, []byte("inputAuth")),
+		)
 	})
-	t.Run("json object as graphql object with nesting", func(t *testing.T) {
-		runTest(t, `{"foo":{"bar":{"baz":"bat"}}}`, []string{"foo"}, `{"type":"object","properties":{"bar":{"type":"object","properties":{"baz":{"type":"string"}}}}}`, false, `{"bar":{"baz":"bat"}}`)
+}
+
+func TestFetcher_SingleFlightNamespace(t *testing.T) {
+	fetcher := NewFetcher(true)
+	input := []byte(`{"method":"GET","url":"https://example.com/me"}`)
+
+	key := func(namespace, dataSourceIdentifier string) uint64 {
+		ctx := NewContext(context.Background())
+		if namespace != "" {
+			ctx.SetSingleFlightNamespace([]byte(namespace))
+		}
+		return fetcher.singleFlightKey(ctx, &SingleFetch{DataSourceIdentifier: []byte(dataSourceIdentifier)}, input)
+	}
+
+	t.Run("different namespaces don't collide", func(t *testing.T) {
+		assert.NotEqual(t, key("schema a", "users-service"), key("schema b", "users-service"))
 	})
-	t.Run("json object as graphql object with single array", func(t *testing.T) {
-		runTest(t, `{"foo":["bar"]}`, []string{"foo"}, `{"type":"array","item":{"type":"string"}}`, false, `["bar"]`)
+	t.Run("same namespace shares the key", func(t *testing.T) {
+		assert.Equal(t, key("schema a", "users-service"), key("schema a", "users-service"))
 	})
-	t.Run("json object as graphql object with array", func(t *testing.T) {
-		runTest(t, `{"foo":["bar","baz"]}`, []string{"foo"}, `{"type":"array","item":{"type":"string"}}`, false, `["bar","baz"]`)
+	t.Run("different data sources don't collide", func(t *testing.T) {
+		assert.NotEqual(t, key("", "users-service"), key("", "accounts-service"))
 	})
-	t.Run("json object as graphql object with object array", func(t *testing.T) {
-		runTest(t, `{"foo":[{"bar":"baz"},{"bar":"bat"}]}`, []string{"foo"}, `{"type":"array","item":{"type":"object","properties":{"bar":{"type":"string"}}}}`, false, `[{"bar":"baz"},{"bar":"bat"}]`)
+	t.Run("namespace and identifier don't run into each other", func(t *testing.T) {
+		assert.NotEqual(t, key("schema", "users-service"), key("schemausers", "-service"))
 	})
-	t.Run("array with csv render string", func(t *testing.T) {
-		template := InputTemplate{
-			Segments: []TemplateSegment{
-				{
-					SegmentType:        VariableSegmentType,
-					VariableKind:       ContextVariableKind,
-					VariableSourcePath: []string{"a"},
-					Renderer:           NewCSVVariableRenderer(JsonRootType{Value: jsonparser.String, Kind: JsonRootTypeKindSingle}),
-				},
-			},
-		}
-		ctx := &Context{
-			Variables: []byte(`{"a":["foo","bar"]}`),
+
+	t.Run("data sources of different schemas sharing an identifier load separately", func(t *testing.T) {
+		load := func(namespace string, dataSource DataSource) (string, error) {
+			ctx := NewContext(context.Background())
+			ctx.SetSingleFlightNamespace([]byte(namespace))
+			preparedInput := fastbuffer.New()
+			preparedInput.WriteBytes(input)
+			buf := NewBufPair()
+			err := fetcher.Fetch(ctx, &SingleFetch{
+				DataSource:           dataSource,
+				DataSourceIdentifier: []byte("users-service"),
+			}, preparedInput, buf)
+			return string(buf.Data.Bytes()), err
 		}
-		buf := fastbuffer.New()
-		err := template.Render(ctx, nil, buf)
-		assert.NoError(t, err)
-		out := buf.String()
-		assert.Equal(t, "foo,bar", out)
+
+		var (
+			wg                     sync.WaitGroup
+			schemaA, schemaB       string
+			schemaAErr, schemaBErr error
+		)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			schemaA, schemaAErr = load("schema a", &_fakeDataSource{data: []byte(`{"name":"a"}`), artificialLatency: 50 * time.Millisecond})
+		}()
+		go func() {
+			defer wg.Done()
+			schemaB, schemaBErr = load("schema b", &_fakeDataSource{data: []byte(`{"name":"b"}`), artificialLatency: 50 * time.Millisecond})
+		}()
+		wg.Wait()
+
+		assert.NoError(t, schemaAErr)
+		assert.NoError(t, schemaBErr)
+		assert.Equal(t, `{"name":"a"}`, schemaA)
+		assert.Equal(t, `{"name":"b"}`, schemaB)
 	})
-	t.Run("array with csv render int", func(t *testing.T) {
-		template := InputTemplate{
-			Segments: []TemplateSegment{
-				{
-					SegmentType:        VariableSegmentType,
-					VariableKind:       ContextVariableKind,
-					VariableSourcePath: []string{"a"},
-					Renderer:           NewCSVVariableRenderer(JsonRootType{Value: jsonparser.Number}),
-				},
-			},
+}
+
+func TestFetcher_SingleFlightDataSourceResolver(t *testing.T) {
+	fetcher := NewFetcher(true)
+	input := []byte(`{"method":"GET","url":"https://example.com/me"}`)
+
+	upstreams := map[string]DataSource{
+		"a": &_fakeDataSource{data: []byte(`{"name":"tenant a"}`), artificialLatency: 50 * time.Millisecond},
+		"b": &_fakeDataSource{data: []byte(`{"name":"tenant b"}`), artificialLatency: 50 * time.Millisecond},
+	}
+	resolveDataSource := func(ctx *Context, dataSourceIdentifier []byte, defaultDataSource DataSource) DataSource {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return upstreams[tenant]
+	}
+
+	// both tenants load concurrently with the same fetch, the second one would wait for the first one if they shared the key
+	loadTenants := func(namespace func(tenant string) []byte) (tenantA, tenantB string) {
+		load := func(tenant string) string {
+			ctx := NewContext(context.Background())
+			ctx.WithValue(tenantKey{}, tenant)
+			ctx.SetDataSourceResolver(resolveDataSource)
+			if namespace != nil {
+				ctx.SetSingleFlightNamespace(namespace(tenant))
+			}
+			preparedInput := fastbuffer.New()
+			preparedInput.WriteBytes(input)
+			buf := NewBufPair()
+			assert.NoError(t, fetcher.Fetch(ctx, &SingleFetch{
+				DataSource:           FakeDataSource(`{"name":"default"}`),
+				DataSourceIdentifier: []byte("tenants"),
+			}, preparedInput, buf))
+			return string(buf.Data.Bytes())
 		}
-		ctx := &Context{
-			Variables: []byte(`{"a":[1,2,3]}`),
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			tenantA = load("a")
+		}()
+		go func() {
+			defer wg.Done()
+			tenantB = load("b")
+		}()
+		wg.Wait()
+		return
+	}
+
+	t.Run("tenants without a namespace skip singleFlight", func(t *testing.T) {
+		tenantA, tenantB := loadTenants(nil)
+		assert.Equal(t, `{"name":"tenant a"}`, tenantA)
+		assert.Equal(t, `{"name":"tenant b"}`, tenantB)
+	})
+	t.Run("tenants with their own namespace load separately", func(t *testing.T) {
+		tenantA, tenantB := loadTenants(func(tenant string) []byte {
+			return []byte("tenant " + tenant)
+		})
+		assert.Equal(t, `{"name":"tenant a"}`, tenantA)
+		assert.Equal(t, `{"name":"tenant b"}`, tenantB)
+	})
+}
+
+// _hangingDataSource ignores the context and only returns once it gets released
+type _hangingDataSource struct {
+	loads   int64
+	release chan struct{}
+}
+
+func (h *_hangingDataSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	atomic.AddInt64(&h.loads, 1)
+	<-h.release
+	_, err = w.Write([]byte(`{"name":"Jens"}`))
+	return
+}
+
+func TestFetcher_SingleFlightCancellation(t *testing.T) {
+	fetcher := NewFetcher(true)
+	dataSource := &_hangingDataSource{release: make(chan struct{})}
+	defer close(dataSource.release)
+
+	load := func(ctx context.Context) error {
+		preparedInput := fastbuffer.New()
+		preparedInput.WriteBytes([]byte(`{"method":"GET","url":"https://example.com/me"}`))
+		return fetcher.Fetch(NewContext(ctx), &SingleFetch{
+			DataSource:           dataSource,
+			DataSourceIdentifier: []byte("users-service"),
+		}, preparedInput, NewBufPair())
+	}
+	loads := func() int64 {
+		return atomic.LoadInt64(&dataSource.loads)
+	}
+	inflightFetches := func() int {
+		fetcher.inflightFetchMu.Lock()
+		defer fetcher.inflightFetchMu.Unlock()
+		return len(fetcher.inflightFetches)
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	go func() {
+		_ = load(leaderCtx)
+	}()
+	assert.Eventually(t, func() bool { return loads() == 1 }, time.Second, time.Millisecond)
+
+	t.Run("waiting fetch returns once its context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error)
+		go func() {
+			errCh <- load(ctx)
+		}()
+		cancel()
+		select {
+		case err := <-errCh:
+			assert.Equal(t, context.Canceled, err)
+		case <-time.After(time.Second):
+			t.Fatal("waiting fetch didn't return after its context got cancelled")
 		}
-		buf := fastbuffer.New()
-		err := template.Render(ctx, nil, buf)
-		assert.NoError(t, err)
-		out := buf.String()
-		assert.Equal(t, "1,2,3", out)
+		assert.Equal(t, int64(1), loads())
 	})
-	t.Run("array with default render int", func(t *testing.T) {
-		template := InputTemplate{
-			Segments: []TemplateSegment{
-				{
-					SegmentType:        VariableSegmentType,
-					VariableKind:       ContextVariableKind,
-					VariableSourcePath: []string{"a"},
-					Renderer:           NewGraphQLVariableRenderer(`{"type":"array","items":{"type":"number"}}`),
+
+	t.Run("cancelled load is removed from the inflight fetches", func(t *testing.T) {
+		assert.Equal(t, 1, inflightFetches())
+		cancelLeader()
+		assert.Eventually(t, func() bool { return inflightFetches() == 0 }, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			_ = load(ctx)
+		}()
+		assert.Eventually(t, func() bool { return loads() == 2 }, time.Second, time.Millisecond)
+	})
+}
+
+type afterFetchCall struct {
+	output       string
+	singleFlight bool
+}
+
+type _recordingAfterFetchHook struct {
+	mu     sync.Mutex
+	data   []afterFetchCall
+	errors []afterFetchCall
+}
+
+func (h *_recordingAfterFetchHook) OnData(ctx HookContext, output []byte, singleFlight bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.data = append(h.data, afterFetchCall{output: string(output), singleFlight: singleFlight})
+}
+
+func (h *_recordingAfterFetchHook) OnError(ctx HookContext, output []byte, singleFlight bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errors = append(h.errors, afterFetchCall{output: string(output), singleFlight: singleFlight})
+}
+
+// _tracingFlagHook records HookContext.TracingEnabled of each call
+type _tracingFlagHook struct {
+	mu    sync.Mutex
+	flags []bool
+}
+
+func (h *_tracingFlagHook) record(ctx HookContext) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.flags = append(h.flags, ctx.TracingEnabled)
+}
+
+func (h *_tracingFlagHook) OnBeforeFetch(ctx HookContext, input []byte) {
+	h.record(ctx)
+}
+
+func (h *_tracingFlagHook) OnData(ctx HookContext, output []byte, singleFlight bool) {
+	h.record(ctx)
+}
+
+func (h *_tracingFlagHook) OnError(ctx HookContext, output []byte, singleFlight bool) {
+	h.record(ctx)
+}
+
+func TestResolver_EnableTracing(t *testing.T) {
+	run := func(enableTracing bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+
+			response := &GraphQLResponse{
+				Data: &Object{
+					Fetch: &SingleFetch{
+						BufferId:   0,
+						DataSource: FakeDataSource(`{"id":"1"}`),
+					},
+					Fields: []*Field{
+						{
+							BufferID:  0,
+							HasBuffer: true,
+							Name:      []byte("id"),
+							Value: &String{
+								Path: []string{"id"},
+							},
+						},
+					},
 				},
-			},
-		}
-		ctx := &Context{
-			Variables: []byte(`{"a":[1,2,3]}`),
+			}
+
+			hook := &_tracingFlagHook{}
+			ctx := NewContext(context.Background())
+			ctx.EnableTracing = enableTracing
+			ctx.SetBeforeFetchHook(hook)
+			ctx.SetAfterFetchHook(hook)
+
+			buf := &bytes.Buffer{}
+			err := r.ResolveGraphQLResponse(ctx, response, nil, buf)
+			assert.NoError(t, err)
+			assert.Equal(t, `{"data":{"id":"1"}}`, buf.String())
+			assert.Equal(t, []bool{enableTracing, enableTracing}, hook.flags)
 		}
-		buf := fastbuffer.New()
-		err := template.Render(ctx, nil, buf)
+	}
+
+	t.Run("enabled", run(true))
+	t.Run("disabled", run(false))
+}
+
+func TestFetcher_AfterFetchHook(t *testing.T) {
+	fetch := func(fetcher *Fetcher, hook AfterFetchHook, singleFetch *SingleFetch) {
+		ctx := NewContext(context.Background())
+		ctx.SetAfterFetchHook(hook)
+		input := fastbuffer.New()
+		input.WriteBytes([]byte("fakeInput"))
+		err := fetcher.Fetch(ctx, singleFetch, input, NewBufPair())
 		assert.NoError(t, err)
-		out := buf.String()
-		assert.Equal(t, "[1,2,3]", out)
+	}
+
+	t.Run("empty response", func(t *testing.T) {
+		hook := &_recordingAfterFetchHook{}
+		fetch(NewFetcher(false), hook, &SingleFetch{DataSource: FakeDataSource("")})
+		assert.Equal(t, []afterFetchCall{{output: "", singleFlight: false}}, hook.data)
+		assert.Nil(t, hook.errors)
 	})
-	t.Run("json render with value missing", func(t *testing.T) {
-		template := InputTemplate{
-			Segments: []TemplateSegment{
-				{
-					SegmentType: StaticSegmentType,
-					Data:        []byte(`{"key":`),
-				},
-				{
-					SegmentType:        VariableSegmentType,
-					VariableKind:       ContextVariableKind,
-					VariableSourcePath: []string{"a"},
-					Renderer:           NewJSONVariableRendererWithValidation(`{"type":"string"}`),
-				},
-				{
-					SegmentType: StaticSegmentType,
-					Data:        []byte(`}`),
-				},
-			},
+	t.Run("empty response with single flight", func(t *testing.T) {
+		hook := &_recordingAfterFetchHook{}
+		fetch(NewFetcher(true), hook, &SingleFetch{DataSource: FakeDataSource("")})
+		assert.Equal(t, []afterFetchCall{{output: "", singleFlight: false}}, hook.data)
+		assert.Nil(t, hook.errors)
+	})
+	t.Run("shared single flight result", func(t *testing.T) {
+		hook := &_recordingAfterFetchHook{}
+		fetcher := NewFetcher(true)
+		singleFetch := &SingleFetch{
+			DataSource: &_fakeDataSource{data: []byte(`{"data":{"name":"Jens"}}`), artificialLatency: 50 * time.Millisecond},
 		}
-		ctx := &Context{
-			Variables: []byte(""),
+
+		wg := sync.WaitGroup{}
+		wg.Add(2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				defer wg.Done()
+				fetch(fetcher, hook, singleFetch)
+			}()
 		}
-		buf := fastbuffer.New()
-		err := template.Render(ctx, nil, buf)
-		assert.NoError(t, err)
-		out := buf.String()
-		assert.Equal(t, `{"key":null}`, out)
+		wg.Wait()
+
+		assert.ElementsMatch(t, []afterFetchCall{
+			{output: `{"data":{"name":"Jens"}}`, singleFlight: false},
+			{output: `{"data":{"name":"Jens"}}`, singleFlight: true},
+		}, hook.data)
+	})
+	t.Run("errors of a fresh load", func(t *testing.T) {
+		hook := &_recordingAfterFetchHook{}
+		fetch(NewFetcher(true), hook, &SingleFetch{
+			DataSource:            FakeDataSource(`{"errors":[{"message":"errorMessage"}]}`),
+			ProcessResponseConfig: ProcessResponseConfig{ExtractGraphqlResponse: true},
+		})
+		assert.Equal(t, []afterFetchCall{{output: `{"message":"errorMessage"}`, singleFlight: false}}, hook.errors)
+		assert.Nil(t, hook.data)
 	})
 }
+
+type _recordingNullValueHook struct {
+	null   []string
+	absent []string
+}
+
+func (h *_recordingNullValueHook) OnNull(ctx HookContext) {
+	h.null = append(h.null, string(ctx.CurrentPath))
+}
+
+func (h *_recordingNullValueHook) OnAbsent(ctx HookContext) {
+	h.absent = append(h.absent, string(ctx.CurrentPath))
+}
+
+func TestResolver_NullValueHook(t *testing.T) {
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := newResolver(rCtx, false, false)
+
+	field := func(name string, value Node) *Field {
+		return &Field{
+			Name:      []byte(name),
+			Value:     value,
+			BufferID:  0,
+			HasBuffer: true,
+		}
+	}
+
+	response := &GraphQLResponse{
+		Data: &Object{
+			Fetch: &SingleFetch{
+				BufferId:   0,
+				DataSource: FakeDataSource(`{"name":"Jens","nickname":null,"age":null,"pet":null}`),
+			},
+			Fields: []*Field{
+				field("name", &String{Path: []string{"name"}, Nullable: true}),
+				field("nickname", &String{Path: []string{"nickname"}, Nullable: true}),
+				field("email", &String{Path: []string{"email"}, Nullable: true}),
+				field("age", &Integer{Path: []string{"age"}, Nullable: true}),
+				field("registered", &Boolean{Path: []string{"registered"}, Nullable: true}),
+				field("pet", &Object{Path: []string{"pet"}, Nullable: true, Fields: []*Field{
+					{Name: []byte("name"), Value: &String{Path: []string{"name"}}},
+				}}),
+				field("owner", &Object{Path: []string{"owner"}, Nullable: true, Fields: []*Field{
+					{Name: []byte("name"), Value: &String{Path: []string{"name"}}},
+				}}),
+			},
+		},
+	}
+
+	hook := &_recordingNullValueHook{}
+	ctx := NewContext(context.Background())
+	ctx.SetNullValueHook(hook)
+
+	buf := &bytes.Buffer{}
+	err := r.ResolveGraphQLResponse(ctx, response, nil, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"data":{"name":"Jens","nickname":null,"email":null,"age":null,"registered":null,"pet":null,"owner":null}}`, buf.String())
+	assert.Equal(t, []string{"/data/nickname", "/data/age", "/data/pet"}, hook.null)
+	assert.Equal(t, []string{"/data/email", "/data/registered", "/data/owner"}, hook.absent)
+}
@@ -183,6 +183,90 @@ func TestArrayStream_InitialBatch_1(t *testing.T) {
 	assert.JSONEq(t, string(expected), writer.flushed[1])
 }
 
+func TestArrayStream_Label(t *testing.T) {
+	run := func(label string, expectedPatches string) func(t *testing.T) {
+		return func(t *testing.T) {
+			flushed := resolveStreamWithLabel(t, []byte(label))
+			assert.Equal(t, 2, len(flushed))
+			assert.Equal(t, `{"data":{"users":[{"id":1}]},"hasNext":true}`, flushed[0])
+			assert.Equal(t, expectedPatches, flushed[1])
+		}
+	}
+
+	t.Run("label", run("users", `[{"op":"add","path":"/data/users/1","value":{"id":2},"label":"users"},{"op":"replace","path":"/hasNext","value":false}]`))
+	t.Run("label is escaped", run(`say "hi" \o/`, `[{"op":"add","path":"/data/users/1","value":{"id":2},"label":"say \"hi\" \\o/"},{"op":"replace","path":"/hasNext","value":false}]`))
+}
+
+// resolveStreamWithLabel streams the users with the label and returns the flushed payloads
+func resolveStreamWithLabel(t *testing.T, label []byte) []string {
+
+	controller := gomock.NewController(t)
+
+	userService := fakeService(t, controller, "user", "./testdata/users.json",
+		"")
+
+	item := func() *Object {
+		return &Object{
+			Fields: []*Field{
+				{
+					Name: []byte("id"),
+					Value: &Integer{
+						Path: []string{"id"},
+					},
+				},
+			},
+		}
+	}
+
+	res := &GraphQLStreamingResponse{
+		InitialResponse: &GraphQLResponse{
+			Data: &Object{
+				Fetch: &SingleFetch{
+					DataSource: userService,
+					BufferId:   0,
+				},
+				Fields: []*Field{
+					{
+						HasBuffer: true,
+						BufferID:  0,
+						Name:      []byte("users"),
+						Value: &Array{
+							Stream: Stream{
+								Enabled:          true,
+								InitialBatchSize: 1,
+								PatchIndex:       0,
+								Label:            label,
+							},
+							Item: item(),
+						},
+					},
+				},
+			},
+		},
+		Patches: []*GraphQLResponsePatch{
+			{
+				Operation: literal.ADD,
+				Label:     label,
+				Value:     item(),
+			},
+		},
+	}
+
+	rCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resolver := New(rCtx, NewFetcher(false), false)
+
+	ctx := NewContext(context.Background())
+
+	writer := &TestFlushWriter{}
+
+	err := resolver.ResolveGraphQLStreamingResponse(ctx, res, nil, writer)
+	assert.NoError(t, err)
+
+	return writer.flushed
+}
+
 func TestArrayStream_InitialBatch_2(t *testing.T) {
 
 	controller := gomock.NewController(t)
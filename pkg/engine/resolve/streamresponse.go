@@ -0,0 +1,160 @@
+package resolve
+
+import (
+	"io"
+)
+
+// streamFlushBytes is the number of bytes a responseStream writes before it flushes a FlushWriter
+const streamFlushBytes = 32 << 10
+
+var streamedDataPrefix = []byte(`{"data":`)
+
+// responseStream writes the data of a response to the writer while it's resolved, see Context.StreamResponse
+// Only the buffer attached to the stream gets written, at points where the data written so far can't turn into null anymore.
+// Nodes are attached by their parent if isStreamableNode holds, starting with the root object.
+type responseStream struct {
+	writer io.Writer
+	// buf is the buffer of the node currently attached to the stream
+	buf       *BufPair
+	started   bool
+	unflushed int
+}
+
+// canStreamResponse reports whether the data of the response can be written while it's resolved
+// Detecting duplicate keys and limiting the response size need the whole response, so they disable streaming.
+func (r *Resolver) canStreamResponse(response *GraphQLResponse) bool {
+	return r.MaxResponseBytes <= 0 && r.DuplicateKeys == DuplicateKeysAllowed && isStreamableNode(response.Data)
+}
+
+// isStreamableNode reports whether the data of a node can be written before the node is resolved completely
+// Objects and arrays turn into null if one of their values violates a non-null type,
+// so all fields of an object and the items of an array have to be nullable.
+func isStreamableNode(node Node) bool {
+	switch n := node.(type) {
+	case *Object:
+		for i := range n.Fields {
+			if !isNullableNode(n.Fields[i].Value) {
+				return false
+			}
+		}
+		return true
+	case *Array:
+		return !n.Stream.Enabled && isNullableNode(n.Item)
+	default:
+		return false
+	}
+}
+
+func isNullableNode(node Node) bool {
+	switch n := node.(type) {
+	case *Object:
+		return n.Nullable
+	case *Array:
+		return n.Nullable
+	case *String:
+		return n.Nullable
+	case *Boolean:
+		return n.Nullable
+	case *Integer:
+		return n.Nullable
+	case *Float:
+		return n.Nullable
+	case *Enum:
+		return n.Nullable
+	case *CustomScalar:
+		return n.Nullable
+	case *EmptyObject:
+		return n.Nullable
+	case *EmptyArray:
+		return n.Nullable
+	case *Null, *StaticString:
+		return true
+	default:
+		return false
+	}
+}
+
+// attach writes the data of the parent and attaches the buffer of the child if the parent is attached and the child is streamable
+// It's a no-op for a nil stream.
+func (s *responseStream) attach(parent, child *BufPair, node Node) error {
+	if s == nil || s.buf != parent || !isStreamableNode(node) {
+		return nil
+	}
+	if err := s.drain(parent); err != nil {
+		return err
+	}
+	s.buf = child
+	return nil
+}
+
+// detach attaches the parent again once the child is resolved, the rest of the child's data gets merged into the parent
+func (s *responseStream) detach(child, parent *BufPair) {
+	if s == nil || s.buf != child {
+		return
+	}
+	s.buf = parent
+}
+
+// drain writes the data of buf to the writer and resets it if buf is attached to the stream
+func (s *responseStream) drain(buf *BufPair) error {
+	if s == nil || s.buf != buf || !buf.HasData() {
+		return nil
+	}
+	if !s.started {
+		s.started = true
+		if _, err := s.writer.Write(streamedDataPrefix); err != nil {
+			return err
+		}
+	}
+	n, err := s.writer.Write(buf.Data.Bytes())
+	buf.Data.Reset()
+	if err != nil {
+		return err
+	}
+	s.unflushed += n
+	if s.unflushed >= streamFlushBytes {
+		s.unflushed = 0
+		if flusher, ok := s.writer.(FlushWriter); ok {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// writeGraphqlResponse writes the response, or completes it if the data has already been streamed
+func (s *responseStream) writeGraphqlResponse(buf *BufPair, extensions []byte, writer io.Writer, ignoreData bool) error {
+	if s == nil || !s.started {
+		return writeGraphqlResponseWithExtensions(buf, extensions, writer, ignoreData)
+	}
+	return writeStreamedGraphqlResponse(buf, extensions, writer)
+}
+
+// writeStreamedGraphqlResponse writes the rest of the data, followed by the errors and the extensions
+// The errors come after the data, as they are only known once the data is resolved.
+func writeStreamedGraphqlResponse(buf *BufPair, extensions []byte, writer io.Writer) (err error) {
+	err = writeSafe(err, writer, buf.Data.Bytes())
+
+	if buf.Errors.Len() != 0 {
+		err = writeSafe(err, writer, comma)
+		err = writeSafe(err, writer, quote)
+		err = writeSafe(err, writer, literalErrors)
+		err = writeSafe(err, writer, quote)
+		err = writeSafe(err, writer, colon)
+		err = writeSafe(err, writer, lBrack)
+		err = writeSafe(err, writer, buf.Errors.Bytes())
+		err = writeSafe(err, writer, rBrack)
+	}
+
+	if len(extensions) != 0 {
+		err = writeSafe(err, writer, comma)
+		err = writeSafe(err, writer, quote)
+		err = writeSafe(err, writer, literalExtensions)
+		err = writeSafe(err, writer, quote)
+		err = writeSafe(err, writer, colon)
+		err = writeSafe(err, writer, extensions)
+	}
+
+	err = writeSafe(err, writer, rBrace)
+
+	return err
+}
@@ -0,0 +1,258 @@
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// _recordingFlushWriter records every write and counts the flushes
+type _recordingFlushWriter struct {
+	writes  []string
+	flushes int
+}
+
+func (r *_recordingFlushWriter) Write(p []byte) (n int, err error) {
+	r.writes = append(r.writes, string(p))
+	return len(p), nil
+}
+
+func (r *_recordingFlushWriter) Flush() {
+	r.flushes++
+}
+
+func (r *_recordingFlushWriter) String() string {
+	return strings.Join(r.writes, "")
+}
+
+// usersResponse has a nullable list of nullable users and the nullable total of users, loaded by a single fetch
+func usersResponse(source DataSource) *GraphQLResponse {
+	return &GraphQLResponse{
+		Data: &Object{
+			Fetch: &SingleFetch{
+				BufferId:   0,
+				DataSource: source,
+			},
+			Fields: []*Field{
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("users"),
+					Value: &Array{
+						Path:     []string{"users"},
+						Nullable: true,
+						Item: &Object{
+							Nullable: true,
+							Fields: []*Field{
+								{
+									Name: []byte("id"),
+									Info: &FieldInfo{Name: []byte("id"), ParentTypeName: []byte("User")},
+									Value: &Integer{
+										Path: []string{"id"},
+									},
+								},
+								{
+									Name: []byte("name"),
+									Value: &String{
+										Path:     []string{"name"},
+										Nullable: true,
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					BufferID:  0,
+					HasBuffer: true,
+					Name:      []byte("total"),
+					Value: &Integer{
+						Path:     []string{"total"},
+						Nullable: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestResolver_StreamResponse(t *testing.T) {
+	resolve := func(t *testing.T, r *Resolver, response *GraphQLResponse, stream bool) (*_recordingFlushWriter, *Context) {
+		ctx := NewContext(context.Background())
+		ctx.StreamResponse = stream
+		writer := &_recordingFlushWriter{}
+		require.NoError(t, r.ResolveGraphQLResponse(ctx, response, nil, writer))
+		return writer, ctx
+	}
+
+	t.Run("data of nullable fields is written while it's resolved", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+
+		response := usersResponse(FakeDataSource(`{"users":[{"id":1,"name":"Jens"},{"id":null,"name":"Stefan"},{"id":3}],"total":3}`))
+
+		writer, ctx := resolve(t, r, response, true)
+		assert.Equal(t, `{"data":{"users":[{"id":1,"name":"Jens"},null,{"id":3,"name":null}],"total":3},"errors":[{"message":"Cannot return null for non-nullable field User.id.","locations":[{"line":0,"column":0}],"path":["users",1,"id"]}]}`, writer.String())
+		assert.True(t, ctx.ResponseStreamed())
+		// the key of the list is written before the list, which is written per user
+		assert.Equal(t, []string{`{"data":`, `{"users":`, `[{"id":1,"name":"Jens"}`, `,null`, `,{"id":3,"name":null}`, `]`, `,"total":3`}, writer.writes[:7])
+
+		buffered, ctx := resolve(t, r, response, false)
+		assert.JSONEq(t, buffered.String(), writer.String())
+		assert.False(t, ctx.ResponseStreamed())
+	})
+
+	t.Run("extensions are written after the data", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+
+		ctx := NewContext(context.Background())
+		ctx.StreamResponse = true
+		ctx.AddWarning("deprecated")
+		writer := &_recordingFlushWriter{}
+		err := r.ResolveGraphQLResponse(ctx, usersResponse(FakeDataSource(`{"users":[],"total":0}`)), nil, writer)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"data":{"users":[],"total":0},"extensions":{"warnings":[{"message":"deprecated"}]}}`, writer.String())
+	})
+
+	t.Run("non-nullable root field is buffered", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+
+		response := usersResponse(FakeDataSource(`{"users":[{"id":1,"name":"Jens"}]}`))
+		response.Data.(*Object).Fields[1].Value.(*Integer).Nullable = false
+
+		writer, ctx := resolve(t, r, response, true)
+		assert.Equal(t, `{"errors":[{"message":"unable to resolve","locations":[{"line":0,"column":0}],"path":["total"]}],"data":null}`, writer.String())
+		assert.False(t, ctx.ResponseStreamed())
+	})
+
+	t.Run("list of non-nullable items is written at once", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+
+		response := usersResponse(FakeDataSource(`{"users":[{"id":1},{"id":null}],"total":2}`))
+		response.Data.(*Object).Fields[0].Value.(*Array).Item.(*Object).Nullable = false
+
+		writer, _ := resolve(t, r, response, true)
+		assert.Equal(t, `{"data":{"users":null,"total":2},"errors":[{"message":"Cannot return null for non-nullable field User.id.","locations":[{"line":0,"column":0}],"path":["users",1,"id"]}]}`, writer.String())
+		assert.Equal(t, []string{`{"data":`, `{"users":null`, `,"total":2`, `}`}, writer.writes[:4])
+	})
+
+	t.Run("response size limit disables streaming", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+		r.MaxResponseBytes = 1024
+
+		writer, ctx := resolve(t, r, usersResponse(FakeDataSource(`{"users":[{"id":1}],"total":1}`)), true)
+		assert.Equal(t, `{"data":{"users":[{"id":1,"name":null}],"total":1}}`, writer.String())
+		assert.False(t, ctx.ResponseStreamed())
+	})
+
+	t.Run("large responses are flushed progressively", func(t *testing.T) {
+		rCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := newResolver(rCtx, false, false)
+
+		writer, _ := resolve(t, r, usersResponse(FakeDataSource(string(usersData(1000)))), true)
+		assert.Equal(t, writer.String(), `{"data":`+string(usersData(1000))+`}`)
+		assert.Greater(t, writer.flushes, 1)
+	})
+}
+
+// usersData creates the upstream data for usersResponse with count users of about 100 bytes each
+func usersData(count int) []byte {
+	data := &bytes.Buffer{}
+	data.WriteString(`{"users":[`)
+	for i := 0; i < count; i++ {
+		if i != 0 {
+			data.WriteString(",")
+		}
+		data.WriteString(fmt.Sprintf(`{"id":%d,"name":"user %d %s"}`, i, i, strings.Repeat("x", 64)))
+	}
+	data.WriteString(fmt.Sprintf(`],"total":%d}`, count))
+	return data.Bytes()
+}
+
+// _peakHeapWriter discards the response like a connection to a client and samples the allocated heap while it's written
+type _peakHeapWriter struct {
+	unsampled int
+	peak      uint64
+}
+
+func (p *_peakHeapWriter) Write(b []byte) (n int, err error) {
+	p.unsampled += len(b)
+	if p.unsampled >= 256<<10 {
+		p.unsampled = 0
+		p.sample()
+	}
+	return len(b), nil
+}
+
+func (p *_peakHeapWriter) Flush() {}
+
+func (p *_peakHeapWriter) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	if stats.HeapAlloc > p.peak {
+		p.peak = stats.HeapAlloc
+	}
+}
+
+// BenchmarkResolver_StreamResponse resolves a response of 10MB and reports the peak of the heap above the heap before resolving
+// The upstream data of 10MB is loaded into memory either way, the streamed response doesn't buffer the response a second time.
+func BenchmarkResolver_StreamResponse(b *testing.B) {
+	data := usersData(100_000)
+
+	for _, stream := range []bool{false, true} {
+		name := "buffered"
+		if stream {
+			name = "streamed"
+		}
+		b.Run(name, func(b *testing.B) {
+			rCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := newResolver(rCtx, false, false)
+			response := usersResponse(FakeDataSource(string(data)))
+
+			var peak uint64
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				// the second collection clears the victim cache of the pools, so the pooled buffers of the previous run don't count
+				runtime.GC()
+				runtime.GC()
+				var stats runtime.MemStats
+				runtime.ReadMemStats(&stats)
+				writer := &_peakHeapWriter{}
+				ctx := NewContext(context.Background())
+				ctx.StreamResponse = stream
+				b.StartTimer()
+
+				if err := r.ResolveGraphQLResponse(ctx, response, nil, writer); err != nil {
+					b.Fatal(err)
+				}
+
+				b.StopTimer()
+				writer.sample()
+				if writer.peak > stats.HeapAlloc && writer.peak-stats.HeapAlloc > peak {
+					peak = writer.peak - stats.HeapAlloc
+				}
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+		})
+	}
+}
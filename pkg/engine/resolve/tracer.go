@@ -0,0 +1,31 @@
+package resolve
+
+import (
+	"context"
+)
+
+// Tracer creates a span for each SingleFetch, e.g. to integrate OpenTelemetry without this package depending on it
+type Tracer interface {
+	// StartFetchSpan starts the span of a fetch as child of the span in ctx
+	// uniqueIdentifier names the DataSource of the fetch, see UniqueIdentifierDataSource, and inputSize is the length of the rendered input.
+	// The returned context is passed to the DataSource, so that the span is the parent of the spans created by the DataSource.
+	StartFetchSpan(ctx context.Context, uniqueIdentifier []byte, inputSize int) (context.Context, FetchSpan)
+}
+
+// FetchSpan is the span of a single fetch including its retries
+type FetchSpan interface {
+	// End is called once the fetch is done, also if it failed or the context got cancelled
+	// err is nil if the fetch succeeded, errors returned by the upstream within its response are not passed.
+	End(err error)
+}
+
+// NoopTracer is the default Tracer of the Resolver, it doesn't create any spans
+type NoopTracer struct{}
+
+func (NoopTracer) StartFetchSpan(ctx context.Context, uniqueIdentifier []byte, inputSize int) (context.Context, FetchSpan) {
+	return ctx, noopFetchSpan{}
+}
+
+type noopFetchSpan struct{}
+
+func (noopFetchSpan) End(err error) {}
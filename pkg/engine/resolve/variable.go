@@ -510,6 +510,9 @@ func (o *ObjectVariable) GetVariableKind() VariableKind {
 	return ObjectVariableKind
 }
 
+// HeaderVariable renders the value of a header of the client request, e.g. to forward an auth token or tenant ID
+// Path must contain exactly the name of the header. Multiple values of the header are joined by commas,
+// a missing header renders empty or as the Default of the TemplateSegment.
 type HeaderVariable struct {
 	Path []string
 }
@@ -32,6 +32,17 @@ func (f *FastBuffer) WriteString(s string) {
 	f.b = append(f.b, s...)
 }
 
+// Grow grows the capacity of the buffer, if necessary, to guarantee space for another n bytes
+// Use it before writing many small chunks of a known total size to avoid repeated reallocations.
+func (f *FastBuffer) Grow(n int) {
+	if cap(f.b)-len(f.b) >= n {
+		return
+	}
+	grown := make([]byte, len(f.b), len(f.b)+n)
+	copy(grown, f.b)
+	f.b = grown
+}
+
 func (f *FastBuffer) Bytes() []byte {
 	return f.b
 }
@@ -28,6 +28,18 @@ func TestFastBuffer(t *testing.T) {
 	assert.Equal(t, "FooBar", string(buf.Bytes()))
 }
 
+func TestFastBuffer_Grow(t *testing.T) {
+	buf := New()
+	buf.WriteBytes([]byte("Hello"))
+	buf.Grow(4096)
+	assert.GreaterOrEqual(t, cap(buf.b)-buf.Len(), 4096)
+	assert.Equal(t, "Hello", string(buf.Bytes()))
+
+	capacity := cap(buf.b)
+	buf.Grow(10)
+	assert.Equal(t, capacity, cap(buf.b))
+}
+
 func BenchmarkFastBuffer(b *testing.B) {
 	data := []byte("Hello, World!")
 	b.Run("bytes.Buffer", func(b *testing.B) {
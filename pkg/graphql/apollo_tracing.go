@@ -0,0 +1,291 @@
+package graphql
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/resolve"
+)
+
+const apolloTracingExtensionName = "tracing"
+
+// apolloTracing collects the timings of the fetches of an operation and reports them in the Apollo tracing format,
+// see https://github.com/apollographql/apollo-tracing
+// The timings are collected as BeforeFetchHook and AfterFetchHook, the hooks of the execution are called as well.
+type apolloTracing struct {
+	mu    sync.Mutex
+	start time.Time
+	// spans contains the fetches by the response path they were made at, in the order of their start
+	spans       []*apolloTracingSpan
+	spansByPath map[string]*apolloTracingSpan
+	// fetchedFields contains the response keys of the fields resolved by the fetch of an object,
+	// keyed by the response path of the object without list indices, e.g. /data/heroes
+	fetchedFields map[string][]string
+	// fields contains the type information of the fields of the operation,
+	// keyed by the response path of the field without list indices, e.g. /data/heroes/name
+	fields          map[string]apolloTracingField
+	beforeFetchHook resolve.BeforeFetchHook
+	afterFetchHook  resolve.AfterFetchHook
+}
+
+// apolloTracingSpan spans from the start of the first to the end of the last fetch made at path
+type apolloTracingSpan struct {
+	path       string
+	start, end time.Time
+}
+
+type apolloTracingField struct {
+	fieldName  string
+	parentType string
+	returnType string
+}
+
+type apolloTracingExtension struct {
+	Version   int                    `json:"version"`
+	StartTime string                 `json:"startTime"`
+	EndTime   string                 `json:"endTime"`
+	Duration  int64                  `json:"duration"`
+	Execution apolloTracingExecution `json:"execution"`
+}
+
+type apolloTracingExecution struct {
+	Resolvers []apolloTracingResolver `json:"resolvers"`
+}
+
+type apolloTracingResolver struct {
+	Path        []interface{} `json:"path"`
+	ParentType  string        `json:"parentType"`
+	FieldName   string        `json:"fieldName"`
+	ReturnType  string        `json:"returnType"`
+	StartOffset int64         `json:"startOffset"`
+	Duration    int64         `json:"duration"`
+}
+
+// enableApolloTracing adds the tracing of the operation to the extensions of the response
+func enableApolloTracing(ctx *resolve.Context, operation *Request, schema *Schema, response *resolve.GraphQLResponse) {
+	tracing := &apolloTracing{
+		start:           time.Now(),
+		spansByPath:     map[string]*apolloTracingSpan{},
+		fetchedFields:   map[string][]string{},
+		fields:          map[string]apolloTracingField{},
+		beforeFetchHook: ctx.BeforeFetchHook(),
+		afterFetchHook:  ctx.AfterFetchHook(),
+	}
+	tracing.collectFetchedFields(response.Data, "/data")
+	tracing.collectOperationFields(operation, &schema.document)
+
+	ctx.SetBeforeFetchHook(tracing)
+	ctx.SetAfterFetchHook(tracing)
+	ctx.AddExtension(apolloTracingExtensionName, tracing.extension)
+}
+
+// collectFetchedFields collects the fields which get resolved by the fetch of their enclosing object from the plan
+func (t *apolloTracing) collectFetchedFields(node resolve.Node, path string) {
+	switch n := node.(type) {
+	case *resolve.Object:
+		for _, field := range n.Fields {
+			if n.Fetch != nil && field.HasBuffer {
+				t.addFetchedField(path, string(field.Name))
+			}
+			t.collectFetchedFields(field.Value, path+"/"+string(field.Name))
+		}
+	case *resolve.Array:
+		t.collectFetchedFields(n.Item, path)
+	}
+}
+
+func (t *apolloTracing) addFetchedField(path, responseKey string) {
+	for _, existing := range t.fetchedFields[path] {
+		if existing == responseKey {
+			// fields of different inline fragments can share the response key
+			return
+		}
+	}
+	t.fetchedFields[path] = append(t.fetchedFields[path], responseKey)
+}
+
+// collectOperationFields collects the parent and return types of the fields of the normalized operation
+func (t *apolloTracing) collectOperationFields(operation *Request, definition *ast.Document) {
+	operationDefinitionRef := operation.operationDefinitionRef()
+	if operationDefinitionRef == ast.InvalidRef || !operation.document.OperationDefinitions[operationDefinitionRef].HasSelections {
+		return
+	}
+
+	var rootTypeName ast.ByteSlice
+	switch operation.document.OperationDefinitions[operationDefinitionRef].OperationType {
+	case ast.OperationTypeQuery:
+		rootTypeName = definition.Index.QueryTypeName
+	case ast.OperationTypeMutation:
+		rootTypeName = definition.Index.MutationTypeName
+	case ast.OperationTypeSubscription:
+		rootTypeName = definition.Index.SubscriptionTypeName
+	}
+	rootType, exists := definition.Index.FirstNodeByNameBytes(rootTypeName)
+	if !exists {
+		return
+	}
+
+	t.collectSelectionSetFields(&operation.document, definition, operation.document.OperationDefinitions[operationDefinitionRef].SelectionSet, rootType, "/data")
+}
+
+func (t *apolloTracing) collectSelectionSetFields(operation, definition *ast.Document, set int, enclosingType ast.Node, path string) {
+	operation.EachSelection(set, func(kind ast.SelectionKind, ref int) bool {
+		switch kind {
+		case ast.SelectionKindField:
+			fieldDefinition, exists := definition.NodeFieldDefinitionByName(enclosingType, operation.FieldNameBytes(ref))
+			if !exists {
+				return true
+			}
+			fieldType := definition.FieldDefinitionType(fieldDefinition)
+			returnType, err := definition.PrintTypeBytes(fieldType, nil)
+			if err != nil {
+				return true
+			}
+			fieldPath := path + "/" + operation.FieldAliasOrNameString(ref)
+			if _, exists := t.fields[fieldPath]; !exists {
+				t.fields[fieldPath] = apolloTracingField{
+					fieldName:  operation.FieldNameString(ref),
+					parentType: definition.NodeNameString(enclosingType),
+					returnType: string(returnType),
+				}
+			}
+			if !operation.FieldHasSelections(ref) {
+				return true
+			}
+			fieldTypeNode, exists := definition.Index.FirstNodeByNameBytes(definition.ResolveTypeNameBytes(fieldType))
+			if exists {
+				t.collectSelectionSetFields(operation, definition, operation.Fields[ref].SelectionSet, fieldTypeNode, fieldPath)
+			}
+		case ast.SelectionKindInlineFragment:
+			if !operation.InlineFragments[ref].HasSelections {
+				return true
+			}
+			fragmentType := enclosingType
+			if operation.InlineFragmentHasTypeCondition(ref) {
+				typeConditionNode, exists := definition.Index.FirstNodeByNameBytes(operation.InlineFragmentTypeConditionName(ref))
+				if !exists {
+					return true
+				}
+				fragmentType = typeConditionNode
+			}
+			t.collectSelectionSetFields(operation, definition, operation.InlineFragments[ref].SelectionSet, fragmentType, path)
+		}
+		return true
+	})
+}
+
+func (t *apolloTracing) OnBeforeFetch(ctx resolve.HookContext, input []byte) {
+	now := time.Now()
+	t.mu.Lock()
+	path := string(ctx.CurrentPath)
+	if _, exists := t.spansByPath[path]; !exists {
+		span := &apolloTracingSpan{
+			path:  path,
+			start: now,
+		}
+		t.spans = append(t.spans, span)
+		t.spansByPath[path] = span
+	}
+	t.mu.Unlock()
+
+	if t.beforeFetchHook != nil {
+		t.beforeFetchHook.OnBeforeFetch(ctx, input)
+	}
+}
+
+func (t *apolloTracing) OnData(ctx resolve.HookContext, output []byte, singleFlight bool) {
+	t.endFetch(ctx)
+	if t.afterFetchHook != nil {
+		t.afterFetchHook.OnData(ctx, output, singleFlight)
+	}
+}
+
+func (t *apolloTracing) OnError(ctx resolve.HookContext, output []byte, singleFlight bool) {
+	t.endFetch(ctx)
+	if t.afterFetchHook != nil {
+		t.afterFetchHook.OnError(ctx, output, singleFlight)
+	}
+}
+
+func (t *apolloTracing) endFetch(ctx resolve.HookContext) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span, exists := t.spansByPath[string(ctx.CurrentPath)]
+	if exists && now.After(span.end) {
+		span.end = now
+	}
+}
+
+// extension returns the tracing, each field resolved by a fetch reports the span of the fetches made at its parent path
+func (t *apolloTracing) extension() ([]byte, error) {
+	end := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	resolvers := make([]apolloTracingResolver, 0, len(t.spans))
+	for _, span := range t.spans {
+		if span.end.IsZero() {
+			continue
+		}
+		pathWithoutIndices := withoutListIndices(span.path)
+		for _, responseKey := range t.fetchedFields[pathWithoutIndices] {
+			field, exists := t.fields[pathWithoutIndices+"/"+responseKey]
+			if !exists {
+				continue
+			}
+			resolvers = append(resolvers, apolloTracingResolver{
+				Path:        append(tracingResponsePath(span.path), responseKey),
+				ParentType:  field.parentType,
+				FieldName:   field.fieldName,
+				ReturnType:  field.returnType,
+				StartOffset: span.start.Sub(t.start).Nanoseconds(),
+				Duration:    span.end.Sub(span.start).Nanoseconds(),
+			})
+		}
+	}
+
+	return json.Marshal(apolloTracingExtension{
+		Version:   1,
+		StartTime: t.start.UTC().Format(time.RFC3339Nano),
+		EndTime:   end.UTC().Format(time.RFC3339Nano),
+		Duration:  end.Sub(t.start).Nanoseconds(),
+		Execution: apolloTracingExecution{
+			Resolvers: resolvers,
+		},
+	})
+}
+
+// tracingResponsePath turns a fetch path like /data/heroes/0 into a response path like ["heroes",0]
+func tracingResponsePath(path string) []interface{} {
+	elements := strings.Split(strings.TrimPrefix(path, "/data"), "/")
+	responsePath := make([]interface{}, 0, len(elements))
+	for _, element := range elements {
+		if element == "" {
+			continue
+		}
+		if index, err := strconv.Atoi(element); err == nil {
+			responsePath = append(responsePath, index)
+			continue
+		}
+		responsePath = append(responsePath, element)
+	}
+	return responsePath
+}
+
+// withoutListIndices removes the list indices from a fetch path, e.g. /data/heroes/0 becomes /data/heroes
+func withoutListIndices(path string) string {
+	elements := strings.Split(path, "/")
+	out := elements[:0]
+	for _, element := range elements {
+		if _, err := strconv.Atoi(element); err == nil {
+			continue
+		}
+		out = append(out, element)
+	}
+	return strings.Join(out, "/")
+}
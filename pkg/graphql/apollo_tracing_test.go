@@ -0,0 +1,173 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jensneuse/abstractlogger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/rest_datasource"
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/plan"
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/resolve"
+)
+
+func TestExecutionEngineV2_ApolloTracing(t *testing.T) {
+	schema := starwarsSchema(t)
+
+	engineConf := NewEngineV2Configuration(schema)
+	engineConf.SetDataSources([]plan.DataSourceConfiguration{
+		{
+			RootNodes: []plan.TypeField{
+				{TypeName: "Query", FieldNames: []string{"hero"}},
+			},
+			Factory: &rest_datasource.Factory{
+				Client: testNetHttpClient(t, roundTripperTestCase{
+					expectedHost:     "example.com",
+					expectedPath:     "/",
+					expectedBody:     "",
+					sendResponseBody: `{"hero": {"name": "Luke Skywalker"}}`,
+					sendStatusCode:   200,
+				}),
+			},
+			Custom: rest_datasource.ConfigJSON(rest_datasource.Configuration{
+				Fetch: rest_datasource.FetchConfiguration{
+					URL:    "https://example.com/",
+					Method: "GET",
+				},
+			}),
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	engine, err := NewExecutionEngineV2(ctx, abstractlogger.Noop{}, engineConf)
+	require.NoError(t, err)
+
+	before := &beforeFetchHook{}
+	operation := Request{
+		Query: `{ hero { name } }`,
+	}
+	resultWriter := NewEngineResultWriter()
+	err = engine.Execute(context.Background(), &operation, &resultWriter, WithApolloTracing(), WithBeforeFetchHook(before))
+	require.NoError(t, err)
+	assert.NotEmpty(t, before.input)
+
+	var response struct {
+		Data       json.RawMessage `json:"data"`
+		Extensions struct {
+			Tracing apolloTracingExtension `json:"tracing"`
+		} `json:"extensions"`
+	}
+	require.NoError(t, json.Unmarshal(resultWriter.Bytes(), &response))
+	assert.Equal(t, `{"hero":{"name":"Luke Skywalker"}}`, string(response.Data))
+
+	tracing := response.Extensions.Tracing
+	assert.Equal(t, 1, tracing.Version)
+	assert.NotEmpty(t, tracing.StartTime)
+	assert.NotEmpty(t, tracing.EndTime)
+	require.Len(t, tracing.Execution.Resolvers, 1)
+	resolver := tracing.Execution.Resolvers[0]
+	assert.Equal(t, []interface{}{"hero"}, resolver.Path)
+	assert.Equal(t, "Query", resolver.ParentType)
+	assert.Equal(t, "hero", resolver.FieldName)
+	assert.Equal(t, "Character", resolver.ReturnType)
+	assert.GreaterOrEqual(t, tracing.Duration, resolver.StartOffset+resolver.Duration)
+}
+
+func TestApolloTracing(t *testing.T) {
+	schema := starwarsSchema(t)
+	operation := Request{
+		Query: `{ hero { friends { friendName: name __typename } } }`,
+	}
+	result, err := operation.Normalize(schema)
+	require.NoError(t, err)
+	require.True(t, result.Successful)
+
+	// the root fetch resolves hero, a nested fetch per friend resolves its fields
+	response := &resolve.GraphQLResponse{
+		Data: &resolve.Object{
+			Fetch: &resolve.SingleFetch{},
+			Fields: []*resolve.Field{
+				{
+					Name:      []byte("hero"),
+					HasBuffer: true,
+					Value: &resolve.Object{
+						Path: []string{"hero"},
+						Fields: []*resolve.Field{
+							{
+								Name: []byte("friends"),
+								Value: &resolve.Array{
+									Path: []string{"friends"},
+									Item: &resolve.Object{
+										Fetch: &resolve.SingleFetch{},
+										Fields: []*resolve.Field{
+											{
+												Name:      []byte("friendName"),
+												HasBuffer: true,
+												Value:     &resolve.String{},
+											},
+											{
+												Name:      []byte("__typename"),
+												HasBuffer: true,
+												Value:     &resolve.String{},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	before := &beforeFetchHook{}
+	after := &afterFetchHook{}
+	ctx := resolve.NewContext(context.Background())
+	ctx.SetBeforeFetchHook(before)
+	ctx.SetAfterFetchHook(after)
+	enableApolloTracing(ctx, &operation, schema, response)
+
+	fetch := func(path string) {
+		hookCtx := resolve.HookContext{CurrentPath: []byte(path)}
+		ctx.BeforeFetchHook().OnBeforeFetch(hookCtx, []byte("input"))
+		ctx.AfterFetchHook().OnData(hookCtx, []byte("data"), false)
+	}
+	fetch("/data")
+	fetch("/data/hero/friends/0")
+	fetch("/data/hero/friends/1")
+
+	assert.Equal(t, "inputinputinput", before.input)
+	assert.Equal(t, "datadatadata", after.data)
+
+	tracing := ctx.BeforeFetchHook().(*apolloTracing)
+	out, err := tracing.extension()
+	require.NoError(t, err)
+
+	var extension apolloTracingExtension
+	require.NoError(t, json.Unmarshal(out, &extension))
+
+	paths := make([][]interface{}, 0, len(extension.Execution.Resolvers))
+	for _, resolver := range extension.Execution.Resolvers {
+		paths = append(paths, resolver.Path)
+		assert.GreaterOrEqual(t, resolver.StartOffset, int64(0))
+		assert.GreaterOrEqual(t, resolver.Duration, int64(0))
+	}
+	assert.Equal(t, [][]interface{}{
+		{"hero"},
+		{"hero", "friends", float64(0), "friendName"},
+		{"hero", "friends", float64(0), "__typename"},
+		{"hero", "friends", float64(1), "friendName"},
+		{"hero", "friends", float64(1), "__typename"},
+	}, paths)
+
+	friendName := extension.Execution.Resolvers[1]
+	assert.Equal(t, "Character", friendName.ParentType)
+	assert.Equal(t, "name", friendName.FieldName)
+	assert.Equal(t, "String!", friendName.ReturnType)
+}
@@ -0,0 +1,84 @@
+package graphql
+
+import (
+	"fmt"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
+	"github.com/jensneuse/graphql-go-tools/pkg/astvisitor"
+	"github.com/jensneuse/graphql-go-tools/pkg/introspection"
+	"github.com/jensneuse/graphql-go-tools/pkg/operationreport"
+)
+
+// DeprecationWarnings returns a warning message for each deprecated field selected by the request
+// Each deprecated field is reported once, even if it's selected multiple times
+func DeprecationWarnings(request *Request, schema *Schema) ([]string, error) {
+	if !request.IsNormalized() {
+		result, err := request.Normalize(schema)
+		if err != nil {
+			return nil, err
+		}
+
+		if !result.Successful {
+			return nil, result.Errors
+		}
+	}
+
+	walker := astvisitor.NewWalker(48)
+	visitor := deprecationVisitor{
+		Walker:     &walker,
+		operation:  &request.document,
+		definition: &schema.document,
+		reported:   map[string]struct{}{},
+	}
+
+	walker.RegisterEnterFieldVisitor(&visitor)
+
+	var report operationreport.Report
+	walker.Walk(&request.document, &schema.document, &report)
+	if report.HasErrors() {
+		return nil, report
+	}
+
+	return visitor.warnings, nil
+}
+
+type deprecationVisitor struct {
+	*astvisitor.Walker
+	operation, definition *ast.Document
+	reported              map[string]struct{}
+	warnings              []string
+}
+
+func (d *deprecationVisitor) EnterField(ref int) {
+	fieldDefinition, ok := d.FieldDefinition(ref)
+	if !ok {
+		return
+	}
+
+	directiveRef, deprecated := d.definition.FieldDefinitionDirectiveByName(fieldDefinition, []byte(introspection.DeprecatedDirectiveName))
+	if !deprecated {
+		return
+	}
+
+	coordinate := fmt.Sprintf("%s.%s", d.definition.NodeNameString(d.EnclosingTypeDefinition), d.operation.FieldNameString(ref))
+	if _, ok := d.reported[coordinate]; ok {
+		return
+	}
+	d.reported[coordinate] = struct{}{}
+
+	message := fmt.Sprintf("The field \"%s\" is deprecated.", coordinate)
+	if reason := d.deprecationReason(directiveRef); reason != "" {
+		message = fmt.Sprintf("%s Reason: %s", message, reason)
+	}
+
+	d.warnings = append(d.warnings, message)
+}
+
+func (d *deprecationVisitor) deprecationReason(directiveRef int) string {
+	argValue, exists := d.definition.DirectiveArgumentValueByName(directiveRef, []byte(introspection.DeprecationReasonArgName))
+	if exists {
+		return d.definition.ValueContentString(argValue)
+	}
+
+	return d.definition.DirectiveDefinitionArgumentDefaultValueString(introspection.DeprecatedDirectiveName, introspection.DeprecationReasonArgName)
+}
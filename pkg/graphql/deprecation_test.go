@@ -0,0 +1,47 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecationWarnings(t *testing.T) {
+	schema, err := NewSchemaFromString(`
+		schema {
+			query: Query
+		}
+		type Query {
+			hero: Character @deprecated
+			droid: Character @deprecated(reason: "Use hero instead")
+			search: Character
+		}
+		type Character {
+			name: String
+			height: String @deprecated(reason: "Use size instead")
+		}`)
+	require.NoError(t, err)
+
+	run := func(query string, expectedWarnings []string) func(t *testing.T) {
+		return func(t *testing.T) {
+			request := Request{
+				Query: query,
+			}
+
+			warnings, err := DeprecationWarnings(&request, schema)
+			require.NoError(t, err)
+			assert.Equal(t, expectedWarnings, warnings)
+		}
+	}
+
+	t.Run("no deprecated fields", run(`{ search { name } }`, nil))
+	t.Run("deprecated fields with default and custom reason", run(`{ hero { name height } droid { height } }`, []string{
+		`The field "Query.hero" is deprecated. Reason: No longer supported`,
+		`The field "Character.height" is deprecated. Reason: Use size instead`,
+		`The field "Query.droid" is deprecated. Reason: Use hero instead`,
+	}))
+	t.Run("deprecated field in fragment", run(`{ search { ...CharacterFields } } fragment CharacterFields on Character { height }`, []string{
+		`The field "Character.height" is deprecated. Reason: Use size instead`,
+	}))
+}
@@ -18,6 +18,11 @@ type EngineV2Configuration struct {
 	plannerConfig            plan.Configuration
 	websocketBeforeStartHook WebsocketBeforeStartHook
 	dataLoaderConfig         dataLoaderConfig
+	deprecationWarnings      bool
+	introspectionDisabled    bool
+	responseCache            ResponseCacheConfiguration
+	planCacheKeyFunc         PlanCacheKeyFunc
+	maxQueryDepth            int
 }
 
 func NewEngineV2Configuration(schema *Schema) EngineV2Configuration {
@@ -27,6 +32,7 @@ func NewEngineV2Configuration(schema *Schema) EngineV2Configuration {
 			DefaultFlushIntervalMillis: DefaultFlushIntervalInMilliseconds,
 			DataSources:                []plan.DataSourceConfiguration{},
 			Fields:                     plan.FieldConfigurations{},
+			IncludeInfo:                true,
 		},
 		dataLoaderConfig: dataLoaderConfig{
 			EnableSingleFlightLoader: false,
@@ -68,6 +74,31 @@ func (e *EngineV2Configuration) EnableDataLoader(enable bool) {
 	e.dataLoaderConfig.EnableDataLoader = enable
 }
 
+// EnableDeprecationWarnings - adds a warning to the response extensions for each deprecated field selected by an operation
+func (e *EngineV2Configuration) EnableDeprecationWarnings(enable bool) {
+	e.deprecationWarnings = enable
+}
+
+// DisableIntrospection - rejects operations selecting __schema or __type with ErrIntrospectionDisabled
+func (e *EngineV2Configuration) DisableIntrospection(disable bool) {
+	e.introspectionDisabled = disable
+}
+
+// SetMaxQueryDepth - rejects operations with more nested fields than maxDepth with ErrMaxDepthExceeded, 0 disables the limit
+func (e *EngineV2Configuration) SetMaxQueryDepth(maxDepth int) {
+	e.maxQueryDepth = maxDepth
+}
+
+// SetResponseCache - sets a cache for whole responses of query operations, mutations and subscriptions are never cached
+func (e *EngineV2Configuration) SetResponseCache(config ResponseCacheConfiguration) {
+	e.responseCache = config
+}
+
+// SetPlanCacheKeyFunc - sets the function creating the key of the execution plan cache, nil restores DefaultPlanCacheKey
+func (e *EngineV2Configuration) SetPlanCacheKeyFunc(keyFunc PlanCacheKeyFunc) {
+	e.planCacheKeyFunc = keyFunc
+}
+
 // SetWebsocketBeforeStartHook - sets before start hook which will be called before processing any operation sent over websockets
 func (e *EngineV2Configuration) SetWebsocketBeforeStartHook(hook WebsocketBeforeStartHook) {
 	e.websocketBeforeStartHook = hook
@@ -0,0 +1,89 @@
+package graphql
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/httpclient"
+)
+
+// EngineHTTPWriter writes the response of the ExecutionEngineV2 directly to an http.ResponseWriter
+// Other than with EngineResultWriter and AsHTTPResponse the response isn't buffered a second time before it's sent,
+// neither uncompressed nor compressed, which keeps the memory of large responses down.
+// Executed WithStreamedResponse the data is written to the client while it's resolved, Flush is called progressively then.
+// The response gets compressed according to the Content-Encoding header set on the http.ResponseWriter before the first write,
+// unsupported encodings are removed like in AsHTTPResponse. Close must be called once the response is written.
+type EngineHTTPWriter struct {
+	w          http.ResponseWriter
+	status     int
+	started    bool
+	writer     io.Writer
+	compressor io.WriteCloser
+}
+
+func NewEngineHTTPWriter(w http.ResponseWriter, status int) *EngineHTTPWriter {
+	return &EngineHTTPWriter{
+		w:      w,
+		status: status,
+	}
+}
+
+// start writes the header and sets up the compression on the first write
+func (e *EngineHTTPWriter) start() {
+	e.started = true
+
+	header := e.w.Header()
+	switch header.Get(httpclient.ContentEncodingHeader) {
+	case "gzip":
+		e.compressor = gzip.NewWriter(e.w)
+	case "deflate":
+		e.compressor, _ = flate.NewWriter(e.w, 1)
+	case "br":
+		e.compressor = brotli.NewWriter(e.w)
+	default:
+		header.Del(httpclient.ContentEncodingHeader) // delete unsupported compression header
+	}
+	// the length isn't known upfront as the response is written while it's produced
+	header.Del("Content-Length")
+
+	e.writer = e.w
+	if e.compressor != nil {
+		e.writer = e.compressor
+	}
+	e.w.WriteHeader(e.status)
+}
+
+func (e *EngineHTTPWriter) Write(p []byte) (n int, err error) {
+	if !e.started {
+		e.start()
+	}
+	return e.writer.Write(p)
+}
+
+// Flush sends everything written so far to the client, e.g. for each message of a subscription
+func (e *EngineHTTPWriter) Flush() {
+	if !e.started {
+		e.start()
+	}
+	if flusher, ok := e.compressor.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close completes the compressed stream, the http.ResponseWriter itself isn't closed
+func (e *EngineHTTPWriter) Close() error {
+	if !e.started {
+		e.start()
+	}
+	if e.compressor == nil {
+		return nil
+	}
+	return e.compressor.Close()
+}
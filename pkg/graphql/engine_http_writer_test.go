@@ -0,0 +1,162 @@
+package graphql
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/jensneuse/abstractlogger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/httpclient"
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/staticdatasource"
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/plan"
+)
+
+func TestEngineHTTPWriter(t *testing.T) {
+	write := func(t *testing.T, contentEncoding string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		recorder.Header().Set("Content-Type", "application/json")
+		if contentEncoding != "" {
+			recorder.Header().Set(httpclient.ContentEncodingHeader, contentEncoding)
+		}
+
+		writer := NewEngineHTTPWriter(recorder, http.StatusOK)
+		_, err := writer.Write([]byte(`{"key":`))
+		require.NoError(t, err)
+		_, err = writer.Write([]byte(` "value"}`))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		return recorder
+	}
+
+	t.Run("no compression", func(t *testing.T) {
+		recorder := write(t, "")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, `{"key": "value"}`, recorder.Body.String())
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		recorder := write(t, "gzip")
+		assert.Equal(t, "gzip", recorder.Header().Get(httpclient.ContentEncodingHeader))
+
+		reader, err := gzip.NewReader(recorder.Body)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, `{"key": "value"}`, string(body))
+	})
+
+	t.Run("brotli", func(t *testing.T) {
+		recorder := write(t, "br")
+		assert.Equal(t, "br", recorder.Header().Get(httpclient.ContentEncodingHeader))
+
+		body, err := ioutil.ReadAll(brotli.NewReader(recorder.Body))
+		require.NoError(t, err)
+		assert.Equal(t, `{"key": "value"}`, string(body))
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		recorder := write(t, "compress")
+		assert.Equal(t, "", recorder.Header().Get(httpclient.ContentEncodingHeader))
+		assert.Equal(t, `{"key": "value"}`, recorder.Body.String())
+	})
+
+	t.Run("flush sends the written data", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		recorder.Header().Set(httpclient.ContentEncodingHeader, "gzip")
+
+		writer := NewEngineHTTPWriter(recorder, http.StatusOK)
+		_, err := writer.Write([]byte(`{"data":{"count":1}}`))
+		require.NoError(t, err)
+		writer.Flush()
+		assert.True(t, recorder.Flushed)
+
+		reader, err := gzip.NewReader(recorder.Body)
+		require.NoError(t, err)
+		message := make([]byte, len(`{"data":{"count":1}}`))
+		_, err = reader.Read(message)
+		require.NoError(t, err)
+		assert.Equal(t, `{"data":{"count":1}}`, string(message))
+		require.NoError(t, writer.Close())
+	})
+}
+
+// discardResponseWriter is an http.ResponseWriter which doesn't keep the body, like a connection to a client
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (d *discardResponseWriter) Header() http.Header {
+	return d.header
+}
+
+func (d *discardResponseWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (d *discardResponseWriter) WriteHeader(statusCode int) {}
+
+func BenchmarkEngineHTTPWriter(b *testing.B) {
+	schema, err := NewSchemaFromString(`type Query { hello: String }`)
+	require.NoError(b, err)
+
+	engineConf := NewEngineV2Configuration(schema)
+	engineConf.SetDataSources([]plan.DataSourceConfiguration{
+		{
+			RootNodes: []plan.TypeField{
+				{TypeName: "Query", FieldNames: []string{"hello"}},
+			},
+			Factory: &staticdatasource.Factory{},
+			Custom: staticdatasource.ConfigJSON(staticdatasource.Configuration{
+				Data: strings.Repeat("a", 10<<20),
+			}),
+		},
+	})
+	engineConf.SetFieldConfigurations([]plan.FieldConfiguration{
+		{
+			TypeName:              "Query",
+			FieldName:             "hello",
+			DisableDefaultMapping: true,
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	engine, err := NewExecutionEngineV2(ctx, abstractlogger.NoopLogger, engineConf)
+	require.NoError(b, err)
+
+	header := func() http.Header {
+		header := http.Header{}
+		header.Set(httpclient.ContentEncodingHeader, "gzip")
+		return header
+	}
+
+	b.Run("EngineResultWriter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			writer := NewEngineResultWriter()
+			_ = engine.Execute(ctx, &Request{Query: "{hello}"}, &writer)
+			response := writer.AsHTTPResponse(http.StatusOK, header())
+			_, _ = io.Copy(ioutil.Discard, response.Body)
+		}
+	})
+
+	b.Run("EngineHTTPWriter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			writer := NewEngineHTTPWriter(&discardResponseWriter{header: header()}, http.StatusOK)
+			_ = engine.Execute(ctx, &Request{Query: "{hello}"}, writer)
+			_ = writer.Close()
+		}
+	})
+}
@@ -6,11 +6,16 @@ import (
 	"compress/gzip"
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/andybalholm/brotli"
+	"github.com/buger/jsonparser"
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/jensneuse/abstractlogger"
 	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/introspection_datasource"
@@ -90,6 +95,10 @@ func (e *EngineResultWriter) AsHTTPResponse(status int, headers http.Header) *ht
 		fw, _ := flate.NewWriter(b, 1)
 		_, _ = fw.Write(e.Bytes())
 		_ = fw.Close()
+	case "br":
+		bw := brotli.NewWriter(b)
+		_, _ = bw.Write(e.Bytes())
+		_ = bw.Close()
 	default:
 		headers.Del(httpclient.ContentEncodingHeader) // delete unsupported compression header
 		b = e.buf
@@ -105,8 +114,16 @@ func (e *EngineResultWriter) AsHTTPResponse(status int, headers http.Header) *ht
 }
 
 type internalExecutionContext struct {
-	resolveContext *resolve.Context
-	postProcessor  *postprocess.Processor
+	resolveContext      *resolve.Context
+	postProcessor       *postprocess.Processor
+	operationDirectives []OperationDirective
+	// responseCacheTTL overrides the TTL of the response cache if overrideResponseCacheTTL is set
+	responseCacheTTL         time.Duration
+	overrideResponseCacheTTL bool
+	operationTimeout         time.Duration
+	skipValidation           bool
+	apolloTracing            bool
+	overrideVariables        []byte
 }
 
 func newInternalExecutionContext() *internalExecutionContext {
@@ -116,10 +133,11 @@ func newInternalExecutionContext() *internalExecutionContext {
 	}
 }
 
-func (e *internalExecutionContext) prepare(ctx context.Context, variables []byte, request resolve.Request) {
+func (e *internalExecutionContext) prepare(ctx context.Context, variables []byte, request resolve.Request, operationDirectives []OperationDirective) {
 	e.setContext(ctx)
 	e.setVariables(variables)
 	e.setRequest(request)
+	e.operationDirectives = operationDirectives
 }
 
 func (e *internalExecutionContext) setRequest(request resolve.Request) {
@@ -136,9 +154,18 @@ func (e *internalExecutionContext) setVariables(variables []byte) {
 
 func (e *internalExecutionContext) reset() {
 	e.resolveContext.Free()
+	e.operationDirectives = nil
+	e.responseCacheTTL = 0
+	e.overrideResponseCacheTTL = false
+	e.operationTimeout = 0
+	e.skipValidation = false
+	e.apolloTracing = false
+	e.overrideVariables = nil
 }
 
 type ExecutionEngineV2 struct {
+	// planCacheCounters are updated atomically, they're the first field to be 64-bit aligned on 32-bit platforms
+	planCacheCounters            planCacheCounters
 	logger                       abstractlogger.Logger
 	config                       EngineV2Configuration
 	planner                      *plan.Planner
@@ -148,6 +175,36 @@ type ExecutionEngineV2 struct {
 	executionPlanCache           *lru.Cache
 }
 
+// PlanCacheKeyFunc creates the key of the execution plan cache for the normalized operation
+// Operations with the same key share a plan, so the key must identify the operation,
+// e.g. a trusted hash of a persisted query combined with the schema version.
+type PlanCacheKeyFunc func(operation, definition *ast.Document, operationName string) (uint64, error)
+
+// DefaultPlanCacheKey hashes the printed operation
+func DefaultPlanCacheKey(operation, definition *ast.Document, operationName string) (uint64, error) {
+	hash := pool.Hash64.Get()
+	hash.Reset()
+	defer pool.Hash64.Put(hash)
+	err := astprinter.Print(operation, definition, hash)
+	if err != nil {
+		return 0, err
+	}
+	return hash.Sum64(), nil
+}
+
+type planCacheCounters struct {
+	hits, misses, evictions uint64
+}
+
+// CacheStats are the statistics of the execution plan cache
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Size is the number of currently cached plans
+	Size int
+}
+
 type WebsocketBeforeStartHook interface {
 	OnBeforeStart(reqCtx context.Context, operation *Request) error
 }
@@ -166,6 +223,95 @@ func WithAfterFetchHook(hook resolve.AfterFetchHook) ExecutionOptionsV2 {
 	}
 }
 
+// WithDataSourceResolver resolves the DataSource of each fetch for the execution, e.g. to load from the upstream of a tenant
+// Cached plans are still shared, see resolve.Context.SetDataSourceResolver for the interaction with singleFlight.
+// Responses are neither read from nor stored in the response cache unless a namespace is set with WithSingleFlightNamespace.
+func WithDataSourceResolver(resolver resolve.DataSourceResolver) ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		ctx.resolveContext.SetDataSourceResolver(resolver)
+	}
+}
+
+// WithSingleFlightNamespace only shares the results of fetches through singleFlight with executions of the same namespace
+// Executions with a DataSourceResolver skip singleFlight unless they set a namespace, e.g. the tenant of the resolved DataSource.
+// The namespace is part of the response cache key as well.
+func WithSingleFlightNamespace(namespace []byte) ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		ctx.resolveContext.SetSingleFlightNamespace(namespace)
+	}
+}
+
+// WithOperationDirectives applies the options returned by fn for the directives of the executed operation,
+// e.g. to set the response cache TTL from a custom @cacheControl directive
+func WithOperationDirectives(fn func(directives []OperationDirective) []ExecutionOptionsV2) ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		for _, option := range fn(ctx.operationDirectives) {
+			option(ctx)
+		}
+	}
+}
+
+// WithResponseCacheTTL overrides the TTL of the response cache for the execution
+// If ttl is not positive the response doesn't get stored.
+func WithResponseCacheTTL(ttl time.Duration) ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		ctx.responseCacheTTL = ttl
+		ctx.overrideResponseCacheTTL = true
+	}
+}
+
+// WithOperationTimeout limits the time for resolving a query or mutation, all pending fetches get cancelled on the deadline
+// If the deadline is exceeded the response contains an ErrOperationTimeout error instead of data.
+// Subscriptions are not limited.
+func WithOperationTimeout(timeout time.Duration) ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		ctx.operationTimeout = timeout
+	}
+}
+
+// WithSkipValidation skips the validation of the operation against the schema, e.g. for persisted operations validated on registration
+// The operation still gets normalized, only use it for trusted operations as invalid operations can't be planned reliably.
+func WithSkipValidation() ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		ctx.skipValidation = true
+	}
+}
+
+// WithTracingEnabled marks the execution as traced, hooks read it as resolve.HookContext.TracingEnabled
+// It allows hooks collecting timings to skip untraced executions cheaply.
+func WithTracingEnabled() ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		ctx.resolveContext.EnableTracing = true
+	}
+}
+
+// WithStreamedResponse writes the data of a query or mutation to the writer while it's resolved instead of buffering the whole response,
+// e.g. together with an EngineHTTPWriter to keep the memory of large responses down. See resolve.Context.StreamResponse for the responses which stream.
+// Responses stored in the response cache are still buffered.
+func WithStreamedResponse() ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		ctx.resolveContext.StreamResponse = true
+	}
+}
+
+// WithApolloTracing adds the timings of the fetches in the Apollo tracing format as "tracing" to the response extensions
+// Each field resolved by a fetch reports the timing of that fetch. Responses with tracing don't use the response cache.
+// Subscriptions are not traced.
+func WithApolloTracing() ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		ctx.apolloTracing = true
+	}
+}
+
+// WithOverrideVariables deep merges the JSON object over the variables of the operation before resolving it,
+// e.g. to inject server side defaults or a tenant scope without rewriting the operation of the client
+// Keys of the override win, nested objects are merged and arrays are replaced.
+func WithOverrideVariables(variables []byte) ExecutionOptionsV2 {
+	return func(ctx *internalExecutionContext) {
+		ctx.overrideVariables = variables
+	}
+}
+
 func WithAdditionalHttpHeaders(headers http.Header, excludeByKeys ...string) ExecutionOptionsV2 {
 	return func(ctx *internalExecutionContext) {
 		if len(headers) == 0 {
@@ -236,23 +382,91 @@ func (e *ExecutionEngineV2) Execute(ctx context.Context, operation *Request, wri
 		}
 	}
 
-	result, err := operation.ValidateForSchema(e.config.schema)
+	if e.config.maxQueryDepth > 0 {
+		depth, err := operation.Depth()
+		if err != nil {
+			return err
+		}
+		if depth > e.config.maxQueryDepth {
+			return fmt.Errorf("%w: depth %d, max allowed: %d", ErrMaxDepthExceeded, depth, e.config.maxQueryDepth)
+		}
+	}
+
+	operationDirectives, err := operation.OperationDirectives()
 	if err != nil {
 		return err
 	}
-	if !result.Valid {
-		return result.Errors
-	}
 
 	execContext := e.getExecutionCtx()
 	defer e.putExecutionCtx(execContext)
 
-	execContext.prepare(ctx, operation.Variables, operation.request)
+	execContext.prepare(ctx, operation.Variables, operation.request, operationDirectives)
 
 	for i := range options {
 		options[i](execContext)
 	}
 
+	if execContext.overrideVariables != nil {
+		variables, err := mergeVariables(operation.Variables, execContext.overrideVariables)
+		if err != nil {
+			return err
+		}
+		execContext.setVariables(variables)
+	}
+
+	if !execContext.skipValidation {
+		result, err := operation.ValidateForSchema(e.config.schema)
+		if err != nil {
+			return err
+		}
+		if !result.Valid {
+			return result.Errors
+		}
+	}
+
+	if e.config.introspectionDisabled {
+		hasIntrospectionFields, err := operation.HasIntrospectionFields()
+		if err != nil {
+			return err
+		}
+		if hasIntrospectionFields {
+			return ErrIntrospectionDisabled
+		}
+	}
+
+	if e.config.deprecationWarnings {
+		warnings, err := DeprecationWarnings(operation, e.config.schema)
+		if err != nil {
+			return err
+		}
+		for i := range warnings {
+			execContext.resolveContext.AddWarning(warnings[i])
+		}
+	}
+
+	var responseCacheKey uint64
+	cacheResponse := false
+	// like singleFlight, executions resolving their own data sources only share responses within their namespace, e.g. the tenant
+	namespace := execContext.resolveContext.SingleFlightNamespace()
+	sharedDataSources := execContext.resolveContext.DataSourceResolver() == nil || len(namespace) != 0
+	if e.config.responseCache.enabled() && !execContext.apolloTracing && sharedDataSources {
+		operationType, err := operation.OperationType()
+		if err != nil {
+			return err
+		}
+		if operationType == OperationTypeQuery {
+			responseCacheKey, err = e.config.responseCache.responseCacheKey(operation, execContext.resolveContext.Variables, namespace, e.config.schema, execContext.resolveContext.Request.Header)
+			if err != nil {
+				return err
+			}
+			if cached, ok := e.config.responseCache.Cache.Get(responseCacheKey); ok {
+				_, err = writer.Write(cached)
+				return err
+			}
+			cacheResponse = true
+		}
+	}
+
 	var report operationreport.Report
 	cachedPlan := e.getCachedPlan(execContext, &operation.document, &e.config.schema.document, operation.OperationName, &report)
 	if report.HasErrors() {
@@ -261,7 +475,23 @@ func (e *ExecutionEngineV2) Execute(ctx context.Context, operation *Request, wri
 
 	switch p := cachedPlan.(type) {
 	case *plan.SynchronousResponsePlan:
-		err = e.resolver.ResolveGraphQLResponse(execContext.resolveContext, p.Response, nil, writer)
+		if execContext.apolloTracing {
+			enableApolloTracing(execContext.resolveContext, operation, e.config.schema, p.Response)
+		}
+		if execContext.operationTimeout > 0 {
+			timeoutCtx, cancel := context.WithTimeout(execContext.resolveContext.Context, execContext.operationTimeout)
+			defer cancel()
+			execContext.resolveContext.Context = timeoutCtx
+		}
+		if cacheResponse {
+			err = e.resolveAndCacheResponse(execContext, p, responseCacheKey, writer)
+		} else {
+			err = e.resolver.ResolveGraphQLResponse(execContext.resolveContext, p.Response, nil, writer)
+		}
+		// a streamed response can't be replaced anymore
+		if err != nil && execContext.operationTimeout > 0 && execContext.resolveContext.Err() == context.DeadlineExceeded && !execContext.resolveContext.ResponseStreamed() {
+			_, err = RequestErrorsFromError(ErrOperationTimeout).WriteResponse(writer)
+		}
 	case *plan.SubscriptionResponsePlan:
 		err = e.resolver.ResolveGraphQLSubscription(execContext.resolveContext, p.Response, writer)
 	default:
@@ -271,24 +501,54 @@ func (e *ExecutionEngineV2) Execute(ctx context.Context, operation *Request, wri
 	return err
 }
 
+// resolveAndCacheResponse resolves the response into a buffer so that it can be stored in the response cache
+// Responses containing errors are not cached.
+func (e *ExecutionEngineV2) resolveAndCacheResponse(ctx *internalExecutionContext, p *plan.SynchronousResponsePlan, key uint64, writer resolve.FlushWriter) error {
+	buf := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(buf)
+
+	err := e.resolver.ResolveGraphQLResponse(ctx.resolveContext, p.Response, nil, buf)
+	if err != nil {
+		return err
+	}
+
+	ttl := e.config.responseCache.TTL
+	if ctx.overrideResponseCacheTTL {
+		ttl = ctx.responseCacheTTL
+	}
+
+	response := buf.Bytes()
+	if ttl > 0 || !ctx.overrideResponseCacheTTL {
+		if _, _, _, err := jsonparser.Get(response, "errors"); err == jsonparser.KeyPathNotFoundError {
+			cached := make([]byte, len(response))
+			copy(cached, response)
+			e.config.responseCache.Cache.Set(key, cached, ttl)
+		}
+	}
+
+	_, err = writer.Write(response)
+	return err
+}
+
 func (e *ExecutionEngineV2) getCachedPlan(ctx *internalExecutionContext, operation, definition *ast.Document, operationName string, report *operationreport.Report) plan.Plan {
 
-	hash := pool.Hash64.Get()
-	hash.Reset()
-	defer pool.Hash64.Put(hash)
-	err := astprinter.Print(operation, definition, hash)
+	keyFunc := e.config.planCacheKeyFunc
+	if keyFunc == nil {
+		keyFunc = DefaultPlanCacheKey
+	}
+	cacheKey, err := keyFunc(operation, definition, operationName)
 	if err != nil {
 		report.AddInternalError(err)
 		return nil
 	}
 
-	cacheKey := hash.Sum64()
-
 	if cached, ok := e.executionPlanCache.Get(cacheKey); ok {
 		if p, ok := cached.(plan.Plan); ok {
+			atomic.AddUint64(&e.planCacheCounters.hits, 1)
 			return p
 		}
 	}
+	atomic.AddUint64(&e.planCacheCounters.misses, 1)
 
 	e.plannerMu.Lock()
 	defer e.plannerMu.Unlock()
@@ -298,10 +558,22 @@ func (e *ExecutionEngineV2) getCachedPlan(ctx *internalExecutionContext, operati
 	}
 
 	p := ctx.postProcessor.Process(planResult)
-	e.executionPlanCache.Add(cacheKey, p)
+	if evicted := e.executionPlanCache.Add(cacheKey, p); evicted {
+		atomic.AddUint64(&e.planCacheCounters.evictions, 1)
+	}
 	return p
 }
 
+// CacheStats returns the statistics of the execution plan cache
+func (e *ExecutionEngineV2) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&e.planCacheCounters.hits),
+		Misses:    atomic.LoadUint64(&e.planCacheCounters.misses),
+		Evictions: atomic.LoadUint64(&e.planCacheCounters.evictions),
+		Size:      e.executionPlanCache.Len(),
+	}
+}
+
 func (e *ExecutionEngineV2) GetWebsocketBeforeStartHook() WebsocketBeforeStartHook {
 	return e.config.websocketBeforeStartHook
 }
@@ -1,6 +1,7 @@
 package graphql
 
 import (
+	"bytes"
 	"compress/flate"
 	"compress/gzip"
 	"context"
@@ -8,10 +9,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/andybalholm/brotli"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/jensneuse/abstractlogger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,6 +24,7 @@ import (
 	accounts "github.com/jensneuse/graphql-go-tools/examples/federation/accounts/graph"
 	products "github.com/jensneuse/graphql-go-tools/examples/federation/products/graph"
 	reviews "github.com/jensneuse/graphql-go-tools/examples/federation/reviews/graph"
+	"github.com/jensneuse/graphql-go-tools/pkg/ast"
 	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/graphql_datasource"
 	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/httpclient"
 	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/rest_datasource"
@@ -86,6 +91,34 @@ func TestEngineResponseWriter_AsHTTPResponse(t *testing.T) {
 
 			assert.Equal(t, `{"key": "value"}`, string(body))
 		})
+
+		t.Run("brotli", func(t *testing.T) {
+			headers.Set(httpclient.ContentEncodingHeader, "br")
+
+			response := rw.AsHTTPResponse(http.StatusOK, headers)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+			assert.Equal(t, "br", response.Header.Get(httpclient.ContentEncodingHeader))
+
+			reader := brotli.NewReader(response.Body)
+			body, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+
+			assert.Equal(t, `{"key": "value"}`, string(body))
+		})
+
+		t.Run("unsupported encoding", func(t *testing.T) {
+			headers.Set(httpclient.ContentEncodingHeader, "compress")
+
+			response := rw.AsHTTPResponse(http.StatusOK, headers)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, "", response.Header.Get(httpclient.ContentEncodingHeader))
+
+			body, err := ioutil.ReadAll(response.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, `{"key": "value"}`, string(body))
+		})
 	})
 }
 
@@ -300,6 +333,34 @@ func TestExecutionEngineV2_Execute(t *testing.T) {
 			},
 		))
 
+		t.Run("execute type introspection query with deprecation reasons", runWithoutError(
+			ExecutionEngineV2TestCase{
+				schema: schema,
+				operation: func(t *testing.T) Request {
+					return Request{
+						OperationName: "myIntrospection",
+						Query: `query myIntrospection(){
+							q: __type(name: "Query") {
+								fields(includeDeprecated: true) {
+									name
+									isDeprecated
+									deprecationReason
+								}
+							}
+							e: __type(name: "Episode") {
+								enumValues(includeDeprecated: true) {
+									name
+									isDeprecated
+									deprecationReason
+								}
+							}
+						}`,
+					}
+				},
+				expectedResponse: `{"data":{"q":{"fields":[{"name":"hero","isDeprecated":true,"deprecationReason":"No longer supported"},{"name":"droid","isDeprecated":false,"deprecationReason":null},{"name":"search","isDeprecated":false,"deprecationReason":null}]},"e":{"enumValues":[{"name":"NEWHOPE","isDeprecated":false,"deprecationReason":null},{"name":"EMPIRE","isDeprecated":false,"deprecationReason":null},{"name":"JEDI","isDeprecated":true,"deprecationReason":"No longer supported"}]}}}`,
+			},
+		))
+
 		t.Run("execute full introspection query", runWithoutError(
 			ExecutionEngineV2TestCase{
 				schema: schema,
@@ -1141,6 +1202,64 @@ func TestExecutionEngineV2_Execute(t *testing.T) {
 			expectedResponse: `{"data":{"hero":"Human"}}`,
 		},
 	))
+
+	t.Run("execute operation with overridden variables", runWithoutError(
+		ExecutionEngineV2TestCase{
+			schema: heroWithArgumentSchema(t),
+			operation: func(t *testing.T) Request {
+				return Request{
+					OperationName: "MyHero",
+					Variables: stringify(map[string]interface{}{
+						"heroName": "luke",
+					}),
+					Query: `query MyHero($heroName: String){
+						hero(name: $heroName)
+					}`,
+				}
+			},
+			dataSources: []plan.DataSourceConfiguration{
+				{
+					RootNodes: []plan.TypeField{
+						{TypeName: "Query", FieldNames: []string{"hero"}},
+					},
+					Factory: &rest_datasource.Factory{
+						Client: testNetHttpClient(t, roundTripperTestCase{
+							expectedHost:     "example.com",
+							expectedPath:     "/name/leia",
+							expectedBody:     "",
+							sendResponseBody: `{"race": "Human"}`,
+							sendStatusCode:   200,
+						}),
+					},
+					Custom: rest_datasource.ConfigJSON(rest_datasource.Configuration{
+						Fetch: rest_datasource.FetchConfiguration{
+							URL:    "https://example.com/name/{{ .arguments.name }}",
+							Method: "POST",
+							Body:   "",
+						},
+					}),
+				},
+			},
+			fields: []plan.FieldConfiguration{
+				{
+					TypeName:              "Query",
+					FieldName:             "hero",
+					DisableDefaultMapping: false,
+					Path:                  []string{"race"},
+					Arguments: []plan.ArgumentConfiguration{
+						{
+							Name:         "name",
+							RenderConfig: plan.RenderArgumentDefault,
+						},
+					},
+				},
+			},
+			engineOptions: []ExecutionOptionsV2{
+				WithOverrideVariables([]byte(`{"heroName":"leia"}`)),
+			},
+			expectedResponse: `{"data":{"hero":"Human"}}`,
+		},
+	))
 }
 
 func TestExecutionEngineV2_FederationAndSubscription_IntegrationTest(t *testing.T) {
@@ -1304,11 +1423,13 @@ func testNetHttpClient(t *testing.T, testCase roundTripperTestCase) *http.Client
 }
 
 type beforeFetchHook struct {
-	input string
+	input          string
+	tracingEnabled bool
 }
 
 func (b *beforeFetchHook) OnBeforeFetch(ctx resolve.HookContext, input []byte) {
 	b.input += string(input)
+	b.tracingEnabled = ctx.TracingEnabled
 }
 
 type afterFetchHook struct {
@@ -1378,7 +1499,235 @@ func TestExecutionWithOptions(t *testing.T) {
 	assert.Equal(t, `{"method":"GET","url":"https://example.com/","body":{"query":"{hero}"}}`, before.input)
 	assert.Equal(t, `{"hero":{"name":"Luke Skywalker"}}`, after.data)
 	assert.Equal(t, "", after.err)
+	assert.False(t, before.tracingEnabled)
 	assert.NoError(t, err)
+
+	traced := &beforeFetchHook{}
+	operation = testCase.operation(t)
+	resultWriter = NewEngineResultWriter()
+	err = engine.Execute(context.Background(), &operation, &resultWriter, WithBeforeFetchHook(traced), WithTracingEnabled())
+	assert.NoError(t, err)
+	assert.True(t, traced.tracingEnabled)
+}
+
+func TestExecutionEngineV2_DisableIntrospection(t *testing.T) {
+	schema := starwarsSchema(t)
+
+	engineConf := NewEngineV2Configuration(schema)
+	engineConf.SetDataSources([]plan.DataSourceConfiguration{
+		{
+			RootNodes: []plan.TypeField{
+				{TypeName: "Query", FieldNames: []string{"hero"}},
+			},
+			Factory: &rest_datasource.Factory{
+				Client: testNetHttpClient(t, roundTripperTestCase{
+					expectedHost:     "example.com",
+					expectedPath:     "/",
+					expectedBody:     "",
+					sendResponseBody: `{"hero": {"name": "Luke Skywalker"}}`,
+					sendStatusCode:   200,
+				}),
+			},
+			Custom: rest_datasource.ConfigJSON(rest_datasource.Configuration{
+				Fetch: rest_datasource.FetchConfiguration{
+					URL:    "https://example.com/",
+					Method: "GET",
+				},
+			}),
+		},
+	})
+	engineConf.DisableIntrospection(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	engine, err := NewExecutionEngineV2(ctx, abstractlogger.Noop{}, engineConf)
+	require.NoError(t, err)
+
+	run := func(query string, expectedErr error, expectedResponse string) func(t *testing.T) {
+		return func(t *testing.T) {
+			operation := Request{
+				Query: query,
+			}
+			resultWriter := NewEngineResultWriter()
+			err := engine.Execute(context.Background(), &operation, &resultWriter)
+			assert.Equal(t, expectedErr, err)
+			assert.Equal(t, expectedResponse, resultWriter.String())
+		}
+	}
+
+	t.Run("schema introspection", run(`{ __schema { queryType { name } } }`, ErrIntrospectionDisabled, ""))
+	t.Run("type introspection", run(`{ __type(name: "Droid") { name } }`, ErrIntrospectionDisabled, ""))
+	t.Run("introspection mixed with other fields", run(`{ hero { name } __type(name: "Droid") { name } }`, ErrIntrospectionDisabled, ""))
+	t.Run("operation without introspection fields", run(`{ hero { name } }`, nil, `{"data":{"hero":{"name":"Luke Skywalker"}}}`))
+
+	t.Run("error response", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		_, err := RequestErrorsFromError(ErrIntrospectionDisabled).WriteResponse(buf)
+		require.NoError(t, err)
+		assert.Equal(t, `{"errors":[{"message":"introspection is disabled"}]}`, buf.String())
+	})
+}
+
+func TestExecutionEngineV2_MaxQueryDepth(t *testing.T) {
+	schema := starwarsSchema(t)
+
+	engineConf := NewEngineV2Configuration(schema)
+	engineConf.SetDataSources([]plan.DataSourceConfiguration{
+		{
+			RootNodes: []plan.TypeField{
+				{TypeName: "Query", FieldNames: []string{"hero"}},
+			},
+			Factory: &rest_datasource.Factory{
+				Client: testNetHttpClient(t, roundTripperTestCase{
+					expectedHost:     "example.com",
+					expectedPath:     "/",
+					expectedBody:     "",
+					sendResponseBody: `{"hero": {"name": "Luke Skywalker"}}`,
+					sendStatusCode:   200,
+				}),
+			},
+			Custom: rest_datasource.ConfigJSON(rest_datasource.Configuration{
+				Fetch: rest_datasource.FetchConfiguration{
+					URL:    "https://example.com/",
+					Method: "GET",
+				},
+			}),
+		},
+	})
+	engineConf.SetMaxQueryDepth(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	engine, err := NewExecutionEngineV2(ctx, abstractlogger.Noop{}, engineConf)
+	require.NoError(t, err)
+
+	t.Run("query at the limit", func(t *testing.T) {
+		operation := Request{
+			Query: `{ hero { name } }`,
+		}
+		resultWriter := NewEngineResultWriter()
+		err := engine.Execute(context.Background(), &operation, &resultWriter)
+		require.NoError(t, err)
+		assert.Equal(t, `{"data":{"hero":{"name":"Luke Skywalker"}}}`, resultWriter.String())
+	})
+
+	t.Run("query over the limit", func(t *testing.T) {
+		operation := Request{
+			Query: `{ hero { ...heroFriends } } fragment heroFriends on Character { friends { name } }`,
+		}
+		resultWriter := NewEngineResultWriter()
+		err := engine.Execute(context.Background(), &operation, &resultWriter)
+		assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+		assert.EqualError(t, err, "operation exceeds the maximum depth: depth 3, max allowed: 2")
+		assert.Equal(t, "", resultWriter.String())
+	})
+}
+
+func TestExecutionEngineV2_SkipValidation(t *testing.T) {
+	schema := starwarsSchema(t)
+
+	engineConf := NewEngineV2Configuration(schema)
+	engineConf.SetDataSources([]plan.DataSourceConfiguration{
+		{
+			RootNodes: []plan.TypeField{
+				{TypeName: "Query", FieldNames: []string{"hero"}},
+			},
+			Factory: &rest_datasource.Factory{
+				Client: testNetHttpClient(t, roundTripperTestCase{
+					expectedHost:     "example.com",
+					expectedPath:     "/",
+					expectedBody:     "",
+					sendResponseBody: `{"hero": {"name": "Luke Skywalker"}}`,
+					sendStatusCode:   200,
+				}),
+			},
+			Custom: rest_datasource.ConfigJSON(rest_datasource.Configuration{
+				Fetch: rest_datasource.FetchConfiguration{
+					URL:    "https://example.com/",
+					Method: "GET",
+				},
+			}),
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	engine, err := NewExecutionEngineV2(ctx, abstractlogger.Noop{}, engineConf)
+	require.NoError(t, err)
+
+	// the undefined argument makes the operation invalid without affecting planning
+	query := `{ hero(unknown: 1) { name } }`
+
+	t.Run("invalid operation fails validation", func(t *testing.T) {
+		operation := Request{
+			Query: query,
+		}
+		resultWriter := NewEngineResultWriter()
+		err := engine.Execute(context.Background(), &operation, &resultWriter)
+		assert.Error(t, err)
+		assert.Equal(t, "", resultWriter.String())
+	})
+
+	t.Run("skipped validation executes the operation", func(t *testing.T) {
+		operation := Request{
+			Query: query,
+		}
+		resultWriter := NewEngineResultWriter()
+		err := engine.Execute(context.Background(), &operation, &resultWriter, WithSkipValidation())
+		require.NoError(t, err)
+		assert.Equal(t, `{"data":{"hero":{"name":"Luke Skywalker"}}}`, resultWriter.String())
+	})
+}
+
+// blockingRoundTripper responds once the request context is done, like a slow upstream honoring cancellation
+type blockingRoundTripper struct{}
+
+func (blockingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestExecutionEngineV2_OperationTimeout(t *testing.T) {
+	schema := starwarsSchema(t)
+
+	engineConf := NewEngineV2Configuration(schema)
+	engineConf.SetDataSources([]plan.DataSourceConfiguration{
+		{
+			RootNodes: []plan.TypeField{
+				{TypeName: "Query", FieldNames: []string{"hero"}},
+			},
+			Factory: &rest_datasource.Factory{
+				Client: &http.Client{
+					Transport: blockingRoundTripper{},
+				},
+			},
+			Custom: rest_datasource.ConfigJSON(rest_datasource.Configuration{
+				Fetch: rest_datasource.FetchConfiguration{
+					URL:    "https://example.com/",
+					Method: "GET",
+				},
+			}),
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	engine, err := NewExecutionEngineV2(ctx, abstractlogger.Noop{}, engineConf)
+	require.NoError(t, err)
+
+	operation := Request{
+		Query: `{ hero { name } }`,
+	}
+	resultWriter := NewEngineResultWriter()
+	start := time.Now()
+	err = engine.Execute(context.Background(), &operation, &resultWriter, WithOperationTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, `{"errors":[{"message":"operation timed out"}]}`, resultWriter.String())
 }
 
 func TestExecutionEngineV2_GetCachedPlan(t *testing.T) {
@@ -1495,6 +1844,73 @@ func TestExecutionEngineV2_GetCachedPlan(t *testing.T) {
 		assert.Equal(t, 2, engine.executionPlanCache.Len())
 		assert.NotEqual(t, cachedPlan, oldestCachedPlan.(*plan.SubscriptionResponsePlan))
 	})
+
+	t.Run("should count hits, misses and evictions", func(t *testing.T) {
+		planCache := engine.executionPlanCache
+		t.Cleanup(func() {
+			engine.executionPlanCache = planCache
+			engine.planCacheCounters = planCacheCounters{}
+		})
+
+		singleEntryCache, err := lru.New(1)
+		require.NoError(t, err)
+		engine.executionPlanCache = singleEntryCache
+		engine.planCacheCounters = planCacheCounters{}
+
+		report := operationreport.Report{}
+		engine.getCachedPlan(newInternalExecutionContext(), &gqlRequest.document, &schema.document, gqlRequest.OperationName, &report)
+		engine.getCachedPlan(newInternalExecutionContext(), &gqlRequest.document, &schema.document, gqlRequest.OperationName, &report)
+		engine.getCachedPlan(newInternalExecutionContext(), &differentGqlRequest.document, &schema.document, differentGqlRequest.OperationName, &report)
+		require.False(t, report.HasErrors())
+
+		assert.Equal(t, CacheStats{
+			Hits:      1,
+			Misses:    2,
+			Evictions: 1,
+			Size:      1,
+		}, engine.CacheStats())
+	})
+
+	t.Run("should use the configured plan cache key func", func(t *testing.T) {
+		t.Cleanup(func() {
+			engine.config.planCacheKeyFunc = nil
+			engine.executionPlanCache.Purge()
+		})
+		require.Equal(t, 0, engine.executionPlanCache.Len())
+
+		var operationNames []string
+		engine.config.planCacheKeyFunc = func(operation, definition *ast.Document, operationName string) (uint64, error) {
+			operationNames = append(operationNames, operationName)
+			return 42, nil
+		}
+
+		report := operationreport.Report{}
+		cachedPlan := engine.getCachedPlan(newInternalExecutionContext(), &gqlRequest.document, &schema.document, gqlRequest.OperationName, &report)
+		assert.False(t, report.HasErrors())
+
+		// both operations share the plan as they have the same key
+		sharedPlan := engine.getCachedPlan(newInternalExecutionContext(), &differentGqlRequest.document, &schema.document, differentGqlRequest.OperationName, &report)
+		assert.False(t, report.HasErrors())
+		assert.Equal(t, cachedPlan, sharedPlan)
+		assert.Equal(t, 1, engine.executionPlanCache.Len())
+		assert.Equal(t, []string{"LastRegisteredUser", "LiveUserCount"}, operationNames)
+	})
+
+	t.Run("should report plan cache key func errors", func(t *testing.T) {
+		t.Cleanup(func() {
+			engine.config.planCacheKeyFunc = nil
+		})
+
+		engine.config.planCacheKeyFunc = func(operation, definition *ast.Document, operationName string) (uint64, error) {
+			return 0, fmt.Errorf("unknown persisted query")
+		}
+
+		report := operationreport.Report{}
+		cachedPlan := engine.getCachedPlan(newInternalExecutionContext(), &gqlRequest.document, &schema.document, gqlRequest.OperationName, &report)
+		assert.Nil(t, cachedPlan)
+		assert.True(t, report.HasErrors())
+		assert.Equal(t, 0, engine.executionPlanCache.Len())
+	})
 }
 
 func BenchmarkExecutionEngineV2(b *testing.B) {
@@ -1576,6 +1992,63 @@ func BenchmarkExecutionEngineV2(b *testing.B) {
 
 }
 
+func BenchmarkExecutionEngineV2_SkipValidation(b *testing.B) {
+	schema, err := NewSchemaFromString(`type Query { hello: String }`)
+	require.NoError(b, err)
+
+	engineConf := NewEngineV2Configuration(schema)
+	engineConf.SetDataSources([]plan.DataSourceConfiguration{
+		{
+			RootNodes: []plan.TypeField{
+				{TypeName: "Query", FieldNames: []string{"hello"}},
+			},
+			Factory: &staticdatasource.Factory{},
+			Custom: staticdatasource.ConfigJSON(staticdatasource.Configuration{
+				Data: "world",
+			}),
+		},
+	})
+	engineConf.SetFieldConfigurations([]plan.FieldConfiguration{
+		{
+			TypeName:              "Query",
+			FieldName:             "hello",
+			DisableDefaultMapping: true,
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	engine, err := NewExecutionEngineV2(ctx, abstractlogger.NoopLogger, engineConf)
+	require.NoError(b, err)
+
+	query := strings.Builder{}
+	query.WriteString("query MediumQuery {")
+	for i := 0; i < 50; i++ {
+		query.WriteString(fmt.Sprintf(" hello%d: hello", i))
+	}
+	query.WriteString(" }")
+
+	run := func(options ...ExecutionOptionsV2) func(b *testing.B) {
+		return func(b *testing.B) {
+			writer := NewEngineResultWriter()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// the validation result is stored on the request, so each iteration needs a fresh one
+				req := Request{
+					Query: query.String(),
+				}
+				writer.Reset()
+				_ = engine.Execute(ctx, &req, &writer, options...)
+			}
+		}
+	}
+
+	b.Run("with validation", run())
+	b.Run("without validation", run(WithSkipValidation()))
+}
+
 type federationSetup struct {
 	accountsUpstreamServer *httptest.Server
 	productsUpstreamServer *httptest.Server
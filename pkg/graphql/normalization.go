@@ -1,7 +1,10 @@
 package graphql
 
 import (
+	"bytes"
+
 	"github.com/jensneuse/graphql-go-tools/pkg/astnormalization"
+	"github.com/jensneuse/graphql-go-tools/pkg/astprinter"
 	"github.com/jensneuse/graphql-go-tools/pkg/operationreport"
 )
 
@@ -45,6 +48,21 @@ func (r *Request) Normalize(schema *Schema) (result NormalizationResult, err err
 	return NormalizationResult{Successful: true, Errors: nil}, nil
 }
 
+// NormalizedOperation returns the printed operation after normalization, e.g. for logging or registering persisted queries
+// It returns ErrRequestNotNormalized if the request wasn't normalized yet.
+func (r *Request) NormalizedOperation() ([]byte, error) {
+	if !r.isNormalized {
+		return nil, ErrRequestNotNormalized
+	}
+
+	buf := &bytes.Buffer{}
+	if err := astprinter.Print(&r.document, nil, buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
 func normalizationResultFromReport(report operationreport.Report) (NormalizationResult, error) {
 	result := NormalizationResult{
 		Successful: false,
@@ -49,6 +49,22 @@ func TestRequest_Normalize(t *testing.T) {
 		assert.Equal(t, normalizedOperation, op)
 	})
 
+	t.Run("should return the normalized operation", func(t *testing.T) {
+		schema := starwarsSchema(t)
+		request := requestForQuery(t, starwars.FileFragmentsQuery)
+
+		_, err := request.NormalizedOperation()
+		assert.Equal(t, ErrRequestNotNormalized, err)
+
+		result, err := request.Normalize(schema)
+		require.NoError(t, err)
+		require.True(t, result.Successful)
+
+		normalizedOperation, err := request.NormalizedOperation()
+		require.NoError(t, err)
+		assert.Equal(t, `query Fragments($droidID: ID!){hero {name} droid(id: $droidID){name}}`, string(normalizedOperation))
+	})
+
 	runNormalizationWithSchema := func(t *testing.T, schema *Schema, request *Request, expectedVars string, expectedNormalizedOperation string) {
 		t.Helper()
 
@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"net/http"
 
+	"github.com/buger/jsonparser"
+
 	"github.com/jensneuse/graphql-go-tools/pkg/ast"
 	"github.com/jensneuse/graphql-go-tools/pkg/astparser"
 	"github.com/jensneuse/graphql-go-tools/pkg/engine/resolve"
@@ -29,8 +31,14 @@ const (
 )
 
 var (
-	ErrEmptyRequest = errors.New("the provided request is empty")
-	ErrNilSchema    = errors.New("the provided schema is nil")
+	ErrEmptyRequest         = errors.New("the provided request is empty")
+	ErrNilSchema            = errors.New("the provided schema is nil")
+	ErrRequestNotNormalized = errors.New("the request is not normalized")
+
+	ErrIntrospectionDisabled    = errors.New("introspection is disabled")
+	ErrMaxDepthExceeded         = errors.New("operation exceeds the maximum depth")
+	ErrOperationTimeout         = errors.New("operation timed out")
+	ErrInvalidOverrideVariables = errors.New("override variables must be a JSON object")
 )
 
 type Request struct {
@@ -114,31 +122,7 @@ func (r *Request) IsIntrospectionQuery() (result bool, err error) {
 		return false, report
 	}
 
-	var operationDefinitionRef = ast.InvalidRef
-	var possibleOperationDefinitionRefs = make([]int, 0)
-
-	for i := 0; i < len(r.document.RootNodes); i++ {
-		if r.document.RootNodes[i].Kind == ast.NodeKindOperationDefinition {
-			possibleOperationDefinitionRefs = append(possibleOperationDefinitionRefs, r.document.RootNodes[i].Ref)
-		}
-	}
-
-	if len(possibleOperationDefinitionRefs) == 0 {
-		return
-	} else if len(possibleOperationDefinitionRefs) == 1 {
-		operationDefinitionRef = possibleOperationDefinitionRefs[0]
-	} else {
-		for i := 0; i < len(possibleOperationDefinitionRefs); i++ {
-			ref := possibleOperationDefinitionRefs[i]
-			name := r.document.OperationDefinitionNameString(ref)
-
-			if r.OperationName == name {
-				operationDefinitionRef = ref
-				break
-			}
-		}
-	}
-
+	operationDefinitionRef := r.operationDefinitionRef()
 	if operationDefinitionRef == ast.InvalidRef {
 		return
 	}
@@ -174,6 +158,195 @@ func (r *Request) IsIntrospectionQuery() (result bool, err error) {
 	return true, nil
 }
 
+// HasIntrospectionFields returns true if the operation selects __schema or __type,
+// either exclusively or mixed with other root fields.
+func (r *Request) HasIntrospectionFields() (result bool, err error) {
+	report := r.parseQueryOnce()
+	if report.HasErrors() {
+		return false, report
+	}
+
+	operationDefinitionRef := r.operationDefinitionRef()
+	if operationDefinitionRef == ast.InvalidRef {
+		return
+	}
+
+	operationDef := r.document.OperationDefinitions[operationDefinitionRef]
+	if operationDef.OperationType != ast.OperationTypeQuery || !operationDef.HasSelections {
+		return
+	}
+
+	return r.selectionSetHasIntrospectionFields(operationDef.SelectionSet, map[int]struct{}{}), nil
+}
+
+func (r *Request) selectionSetHasIntrospectionFields(set int, visitedFragments map[int]struct{}) (result bool) {
+	r.document.EachSelection(set, func(kind ast.SelectionKind, ref int) bool {
+		switch kind {
+		case ast.SelectionKindField:
+			switch r.document.FieldNameUnsafeString(ref) {
+			case schemaIntrospectionFieldName, typeIntrospectionFieldName:
+				result = true
+			}
+		case ast.SelectionKindInlineFragment:
+			inlineFragment := r.document.InlineFragments[ref]
+			if inlineFragment.HasSelections {
+				result = r.selectionSetHasIntrospectionFields(inlineFragment.SelectionSet, visitedFragments)
+			}
+		case ast.SelectionKindFragmentSpread:
+			fragmentRef, exists := r.document.FragmentDefinitionRef(r.document.FragmentSpreadNameBytes(ref))
+			if !exists {
+				return true
+			}
+			if _, visited := visitedFragments[fragmentRef]; visited {
+				return true
+			}
+			visitedFragments[fragmentRef] = struct{}{}
+			result = r.selectionSetHasIntrospectionFields(r.document.FragmentDefinitions[fragmentRef].SelectionSet, visitedFragments)
+		}
+		return !result
+	})
+	return result
+}
+
+// Depth returns the maximum number of nested fields of the operation
+// Fragment spreads count with the depth of their fragment, inline fragments don't add to the depth.
+func (r *Request) Depth() (depth int, err error) {
+	report := r.parseQueryOnce()
+	if report.HasErrors() {
+		return 0, report
+	}
+
+	operationDefinitionRef := r.operationDefinitionRef()
+	if operationDefinitionRef == ast.InvalidRef || !r.document.OperationDefinitions[operationDefinitionRef].HasSelections {
+		return 0, nil
+	}
+
+	return r.selectionSetDepth(r.document.OperationDefinitions[operationDefinitionRef].SelectionSet, map[int]struct{}{}), nil
+}
+
+// selectionSetDepth returns the depth of the selection set
+// visitingFragments contains the fragments on the current path, so that fragment cycles don't recurse endlessly.
+func (r *Request) selectionSetDepth(set int, visitingFragments map[int]struct{}) (depth int) {
+	r.document.EachSelection(set, func(kind ast.SelectionKind, ref int) bool {
+		selectionDepth := 0
+		switch kind {
+		case ast.SelectionKindField:
+			selectionDepth = 1
+			if r.document.FieldHasSelections(ref) {
+				selectionDepth += r.selectionSetDepth(r.document.Fields[ref].SelectionSet, visitingFragments)
+			}
+		case ast.SelectionKindInlineFragment:
+			inlineFragment := r.document.InlineFragments[ref]
+			if inlineFragment.HasSelections {
+				selectionDepth = r.selectionSetDepth(inlineFragment.SelectionSet, visitingFragments)
+			}
+		case ast.SelectionKindFragmentSpread:
+			fragmentRef, exists := r.document.FragmentDefinitionRef(r.document.FragmentSpreadNameBytes(ref))
+			if !exists {
+				return true
+			}
+			if _, visiting := visitingFragments[fragmentRef]; visiting {
+				return true
+			}
+			visitingFragments[fragmentRef] = struct{}{}
+			selectionDepth = r.selectionSetDepth(r.document.FragmentDefinitions[fragmentRef].SelectionSet, visitingFragments)
+			delete(visitingFragments, fragmentRef)
+		}
+		if selectionDepth > depth {
+			depth = selectionDepth
+		}
+		return true
+	})
+	return depth
+}
+
+// OperationDirective is a directive of the executed operation, e.g. @cacheControl(maxAge: 60) on a query
+type OperationDirective struct {
+	Name string
+	// Arguments are the JSON encoded argument values by argument name
+	Arguments map[string]json.RawMessage
+}
+
+// OperationDirectives returns the directives of the operation selected by the operation name
+// Arguments with a variable value are resolved from the request variables, missing variables are null.
+func (r *Request) OperationDirectives() (directives []OperationDirective, err error) {
+	report := r.parseQueryOnce()
+	if report.HasErrors() {
+		return nil, report
+	}
+
+	operationDefinitionRef := r.operationDefinitionRef()
+	if operationDefinitionRef == ast.InvalidRef || !r.document.OperationDefinitions[operationDefinitionRef].HasDirectives {
+		return nil, nil
+	}
+
+	for _, directiveRef := range r.document.OperationDefinitions[operationDefinitionRef].Directives.Refs {
+		directive := OperationDirective{
+			Name:      r.document.DirectiveNameString(directiveRef),
+			Arguments: map[string]json.RawMessage{},
+		}
+		for _, argumentRef := range r.document.DirectiveArgumentSet(directiveRef) {
+			value, err := r.argumentValueToJSON(r.document.ArgumentValue(argumentRef))
+			if err != nil {
+				return nil, err
+			}
+			directive.Arguments[r.document.ArgumentNameString(argumentRef)] = value
+		}
+		directives = append(directives, directive)
+	}
+
+	return directives, nil
+}
+
+func (r *Request) argumentValueToJSON(value ast.Value) (json.RawMessage, error) {
+	if value.Kind != ast.ValueKindVariable {
+		return r.document.ValueToJSON(value)
+	}
+
+	variableValue, dataType, offset, err := jsonparser.Get(r.Variables, r.document.VariableValueNameString(value.Ref))
+	if err == jsonparser.KeyPathNotFoundError {
+		return json.RawMessage("null"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dataType == jsonparser.String {
+		// jsonparser strips the quotes of string values
+		return r.Variables[offset-len(variableValue)-2 : offset], nil
+	}
+	return variableValue, nil
+}
+
+// operationDefinitionRef returns the ref of the operation definition selected by the operation name
+func (r *Request) operationDefinitionRef() int {
+	var operationDefinitionRef = ast.InvalidRef
+	var possibleOperationDefinitionRefs = make([]int, 0)
+
+	for i := 0; i < len(r.document.RootNodes); i++ {
+		if r.document.RootNodes[i].Kind == ast.NodeKindOperationDefinition {
+			possibleOperationDefinitionRefs = append(possibleOperationDefinitionRefs, r.document.RootNodes[i].Ref)
+		}
+	}
+
+	if len(possibleOperationDefinitionRefs) == 0 {
+		return operationDefinitionRef
+	} else if len(possibleOperationDefinitionRefs) == 1 {
+		operationDefinitionRef = possibleOperationDefinitionRefs[0]
+	} else {
+		for i := 0; i < len(possibleOperationDefinitionRefs); i++ {
+			ref := possibleOperationDefinitionRefs[i]
+			name := r.document.OperationDefinitionNameString(ref)
+
+			if r.OperationName == name {
+				operationDefinitionRef = ref
+				break
+			}
+		}
+	}
+
+	return operationDefinitionRef
+}
+
 func (r *Request) OperationType() (OperationType, error) {
 	report := r.parseQueryOnce()
 	if report.HasErrors() {
@@ -2,6 +2,7 @@ package graphql
 
 import (
 	"bytes"
+	"encoding/json"
 	"strings"
 	"testing"
 
@@ -157,6 +158,86 @@ func TestRequest_IsIntrospectionQuery(t *testing.T) {
 	})
 }
 
+func TestRequest_HasIntrospectionFields(t *testing.T) {
+	run := func(queryPayload string, expectedHasIntrospectionFields bool) func(t *testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+
+			var request Request
+			err := UnmarshalRequest(strings.NewReader(queryPayload), &request)
+			assert.NoError(t, err)
+
+			actualHasIntrospectionFields, err := request.HasIntrospectionFields()
+			assert.NoError(t, err)
+			assert.Equal(t, expectedHasIntrospectionFields, actualHasIntrospectionFields)
+		}
+	}
+
+	t.Run("schema introspection query", run(namedIntrospectionQuery, true))
+	t.Run("schema introspection query with fragment", run(fragmentedIntrospectionQuery, true))
+	t.Run("schema introspection query with inline fragment on type query", run(inlineFragmentedIntrospectionQueryWithFragmentOnQuery, true))
+	t.Run("type introspection query", run(typeIntrospectionQuery, true))
+	t.Run("schema introspection mixed with other fields", run(nonSchemaIntrospectionQueryWithAdditionalFields, true))
+	t.Run("type introspection mixed with other fields", run(nonTypeIntrospectionQueryWithAdditionalFields, true))
+	t.Run("introspection field names used as aliases", run(nonSchemaIntrospectionQueryWithAliases, false))
+	t.Run("query without introspection fields", run(nonIntrospectionQuery, false))
+	t.Run("mutation", run(mutationQuery, false))
+}
+
+func TestRequest_Depth(t *testing.T) {
+	run := func(query string, expectedDepth int) func(t *testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+
+			request := Request{
+				Query: query,
+			}
+			depth, err := request.Depth()
+			assert.NoError(t, err)
+			assert.Equal(t, expectedDepth, depth)
+		}
+	}
+
+	t.Run("nested fields", run(`{ hero { name friends { name } } droid(id: 1) { name } }`, 3))
+	t.Run("inline fragments don't add depth", run(`{ hero { ... on Droid { friends { name } } } }`, 3))
+	t.Run("fragment spreads", run(`{ hero { ...heroFriends } } fragment heroFriends on Character { friends { friends { name } } }`, 4))
+	t.Run("fragment cycle", run(`{ hero { ...a } } fragment a on Character { friends { ...a } }`, 2))
+}
+
+func TestRequest_OperationDirectives(t *testing.T) {
+	run := func(request Request, expectedDirectives []OperationDirective) func(t *testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+
+			directives, err := request.OperationDirectives()
+			assert.NoError(t, err)
+			assert.Equal(t, expectedDirectives, directives)
+		}
+	}
+
+	t.Run("operation without directives", run(Request{
+		Query: `query Hero { hero { name } }`,
+	}, nil))
+	t.Run("directives with literal arguments", run(Request{
+		Query: `query Hero @cacheControl(maxAge: 60, scope: PRIVATE) @trace { hero { name } }`,
+	}, []OperationDirective{
+		{Name: "cacheControl", Arguments: map[string]json.RawMessage{"maxAge": json.RawMessage(`60`), "scope": json.RawMessage(`"PRIVATE"`)}},
+		{Name: "trace", Arguments: map[string]json.RawMessage{}},
+	}))
+	t.Run("directive with variable arguments", run(Request{
+		Query:     `query Hero($maxAge: Int, $scope: String, $missing: Int) @cacheControl(maxAge: $maxAge, scope: $scope, other: $missing) { hero { name } }`,
+		Variables: json.RawMessage(`{"maxAge":30,"scope":"public"}`),
+	}, []OperationDirective{
+		{Name: "cacheControl", Arguments: map[string]json.RawMessage{"maxAge": json.RawMessage(`30`), "scope": json.RawMessage(`"public"`), "other": json.RawMessage(`null`)}},
+	}))
+	t.Run("directives of the named operation", run(Request{
+		OperationName: "Droid",
+		Query:         `query Hero @cacheControl(maxAge: 60) { hero { name } } query Droid @cacheControl(maxAge: 10) { droid(id: 1) { name } }`,
+	}, []OperationDirective{
+		{Name: "cacheControl", Arguments: map[string]json.RawMessage{"maxAge": json.RawMessage(`10`)}},
+	}))
+}
+
 func TestRequest_OperationType(t *testing.T) {
 	request := Request{
 		OperationName: "",
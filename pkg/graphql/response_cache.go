@@ -0,0 +1,73 @@
+package graphql
+
+import (
+	"encoding/binary"
+	"hash"
+	"net/http"
+	"time"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/astprinter"
+	"github.com/jensneuse/graphql-go-tools/pkg/pool"
+)
+
+// ResponseCache caches whole responses of query operations executed by the ExecutionEngineV2
+// Implementations must be safe for concurrent use.
+type ResponseCache interface {
+	Get(key uint64) (response []byte, ok bool)
+	Set(key uint64, response []byte, ttl time.Duration)
+}
+
+type ResponseCacheConfiguration struct {
+	Cache ResponseCache
+	// TTL is passed to the ResponseCache when a response gets stored
+	TTL time.Duration
+	// Headers are the names of the request headers which are part of the cache key,
+	// e.g. "Authorization" if responses differ per user
+	Headers []string
+}
+
+func (r ResponseCacheConfiguration) enabled() bool {
+	return r.Cache != nil
+}
+
+// responseCacheKey creates the cache key from the normalized operation, its variables, the namespace and the configured headers
+// The namespace is the singleFlight namespace of the execution, e.g. the tenant of a DataSourceResolver.
+// Every part is prefixed with its length, so that adjacent parts can't run into each other and collide.
+func (r ResponseCacheConfiguration) responseCacheKey(operation *Request, variables, namespace []byte, schema *Schema, header http.Header) (uint64, error) {
+	hash := pool.Hash64.Get()
+	hash.Reset()
+	defer pool.Hash64.Put(hash)
+
+	printed := pool.BytesBuffer.Get()
+	defer pool.BytesBuffer.Put(printed)
+
+	err := astprinter.Print(&operation.document, &schema.document, printed)
+	if err != nil {
+		return 0, err
+	}
+	writeResponseCacheKeyPart(hash, printed.Bytes())
+	writeResponseCacheKeyPart(hash, []byte(operation.OperationName))
+	writeResponseCacheKeyPart(hash, variables)
+	writeResponseCacheKeyPart(hash, namespace)
+	for _, name := range r.Headers {
+		values := header.Values(name)
+		writeResponseCacheKeyPart(hash, []byte(name))
+		writeResponseCacheKeyLength(hash, len(values))
+		for _, value := range values {
+			writeResponseCacheKeyPart(hash, []byte(value))
+		}
+	}
+
+	return hash.Sum64(), nil
+}
+
+func writeResponseCacheKeyPart(h hash.Hash64, part []byte) {
+	writeResponseCacheKeyLength(h, len(part))
+	_, _ = h.Write(part)
+}
+
+func writeResponseCacheKeyLength(h hash.Hash64, length int) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(length))
+	_, _ = h.Write(buf[:])
+}
@@ -0,0 +1,299 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jensneuse/abstractlogger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/rest_datasource"
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/plan"
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/resolve"
+)
+
+type testResponseCache struct {
+	mu        sync.Mutex
+	responses map[uint64][]byte
+	ttl       time.Duration
+	gets      int
+}
+
+func (c *testResponseCache) Get(key uint64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets++
+	response, ok := c.responses[key]
+	return response, ok
+}
+
+func (c *testResponseCache) Set(key uint64, response []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[key] = response
+	c.ttl = ttl
+}
+
+// testTenantDataSource responds with the name of its tenant for hello
+type testTenantDataSource string
+
+func (t testTenantDataSource) Load(ctx context.Context, input []byte, w io.Writer) error {
+	_, err := fmt.Fprintf(w, `{"hello":"tenant %s"}`, string(t))
+	return err
+}
+
+func TestExecutionEngineV2_ResponseCache(t *testing.T) {
+	schema, err := NewSchemaFromString(`
+		schema {
+			query: Query
+			mutation: Mutation
+		}
+		type Query {
+			hello: String
+		}
+		type Mutation {
+			increment: Int
+		}
+		directive @cacheControl(maxAge: Int) on QUERY`)
+	require.NoError(t, err)
+
+	setup := func(t *testing.T, responseBody string) (engine *ExecutionEngineV2, cache *testResponseCache, upstreamCalls *int) {
+		upstreamCalls = new(int)
+		roundTripper := createTestRoundTripper(t, roundTripperTestCase{
+			expectedHost:     "example.com",
+			expectedPath:     "/",
+			expectedBody:     "",
+			sendResponseBody: responseBody,
+			sendStatusCode:   200,
+		})
+		client := &http.Client{
+			Transport: testRoundTripper(func(req *http.Request) *http.Response {
+				*upstreamCalls++
+				return roundTripper(req)
+			}),
+		}
+
+		dataSourceConfig := func(typeName, fieldName, method string) plan.DataSourceConfiguration {
+			return plan.DataSourceConfiguration{
+				RootNodes: []plan.TypeField{
+					{TypeName: typeName, FieldNames: []string{fieldName}},
+				},
+				Factory: &rest_datasource.Factory{
+					Client: client,
+				},
+				Custom: rest_datasource.ConfigJSON(rest_datasource.Configuration{
+					Fetch: rest_datasource.FetchConfiguration{
+						URL:    "https://example.com/",
+						Method: method,
+					},
+				}),
+			}
+		}
+
+		cache = &testResponseCache{
+			responses: map[uint64][]byte{},
+		}
+
+		engineConf := NewEngineV2Configuration(schema)
+		engineConf.SetDataSources([]plan.DataSourceConfiguration{
+			dataSourceConfig("Query", "hello", "GET"),
+			dataSourceConfig("Mutation", "increment", "POST"),
+		})
+		engineConf.SetResponseCache(ResponseCacheConfiguration{
+			Cache:   cache,
+			TTL:     time.Minute,
+			Headers: []string{"Authorization"},
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		engine, err := NewExecutionEngineV2(ctx, abstractlogger.Noop{}, engineConf)
+		require.NoError(t, err)
+		return engine, cache, upstreamCalls
+	}
+
+	execute := func(t *testing.T, engine *ExecutionEngineV2, query, authorization string) string {
+		operation := Request{
+			Query: query,
+		}
+		operation.request.Header = http.Header{
+			"Authorization": []string{authorization},
+		}
+		resultWriter := NewEngineResultWriter()
+		err := engine.Execute(context.Background(), &operation, &resultWriter)
+		require.NoError(t, err)
+		return resultWriter.String()
+	}
+
+	t.Run("miss resolves and stores the response", func(t *testing.T) {
+		engine, cache, upstreamCalls := setup(t, `{"hello":"world"}`)
+
+		response := execute(t, engine, `{ hello }`, "token")
+		assert.Equal(t, `{"data":{"hello":"world"}}`, response)
+		assert.Equal(t, 1, *upstreamCalls)
+		assert.Len(t, cache.responses, 1)
+		assert.Equal(t, time.Minute, cache.ttl)
+	})
+
+	t.Run("hit writes the cached response without resolving", func(t *testing.T) {
+		engine, cache, upstreamCalls := setup(t, `{"hello":"world"}`)
+
+		first := execute(t, engine, `{ hello }`, "token")
+		second := execute(t, engine, `{ hello }`, "token")
+		assert.Equal(t, first, second)
+		assert.Equal(t, 1, *upstreamCalls)
+		assert.Equal(t, 2, cache.gets)
+	})
+
+	t.Run("configured headers are part of the cache key", func(t *testing.T) {
+		engine, cache, upstreamCalls := setup(t, `{"hello":"world"}`)
+
+		execute(t, engine, `{ hello }`, "token")
+		execute(t, engine, `{ hello }`, "other token")
+		assert.Equal(t, 2, *upstreamCalls)
+		assert.Len(t, cache.responses, 2)
+	})
+
+	t.Run("mutations bypass the cache", func(t *testing.T) {
+		engine, cache, upstreamCalls := setup(t, `{"increment":1}`)
+
+		execute(t, engine, `mutation { increment }`, "token")
+		response := execute(t, engine, `mutation { increment }`, "token")
+		assert.Equal(t, `{"data":{"increment":1}}`, response)
+		assert.Equal(t, 2, *upstreamCalls)
+		assert.Equal(t, 0, cache.gets)
+		assert.Len(t, cache.responses, 0)
+	})
+
+	t.Run("tenants of a DataSourceResolver don't share responses", func(t *testing.T) {
+		executeForTenant := func(t *testing.T, engine *ExecutionEngineV2, tenant string, options ...ExecutionOptionsV2) string {
+			dataSourceResolver := WithDataSourceResolver(func(ctx *resolve.Context, dataSourceIdentifier []byte, defaultDataSource resolve.DataSource) resolve.DataSource {
+				return testTenantDataSource(tenant)
+			})
+			resultWriter := NewEngineResultWriter()
+			err := engine.Execute(context.Background(), &Request{Query: `{ hello }`}, &resultWriter, append(options, dataSourceResolver)...)
+			require.NoError(t, err)
+			return resultWriter.String()
+		}
+
+		t.Run("without namespace", func(t *testing.T) {
+			engine, cache, _ := setup(t, `{"hello":"world"}`)
+
+			assert.Equal(t, `{"data":{"hello":"tenant a"}}`, executeForTenant(t, engine, "a"))
+			assert.Equal(t, `{"data":{"hello":"tenant b"}}`, executeForTenant(t, engine, "b"))
+			assert.Equal(t, 0, cache.gets)
+			assert.Len(t, cache.responses, 0)
+		})
+
+		t.Run("with namespace", func(t *testing.T) {
+			engine, cache, _ := setup(t, `{"hello":"world"}`)
+
+			namespace := func(tenant string) ExecutionOptionsV2 {
+				return WithSingleFlightNamespace([]byte(tenant))
+			}
+			assert.Equal(t, `{"data":{"hello":"tenant a"}}`, executeForTenant(t, engine, "a", namespace("a")))
+			assert.Equal(t, `{"data":{"hello":"tenant b"}}`, executeForTenant(t, engine, "b", namespace("b")))
+			assert.Len(t, cache.responses, 2)
+
+			// the cached response of the tenant is written, even if its DataSource would respond differently now
+			assert.Equal(t, `{"data":{"hello":"tenant a"}}`, executeForTenant(t, engine, "changed", namespace("a")))
+			assert.Equal(t, 3, cache.gets)
+			assert.Len(t, cache.responses, 2)
+		})
+	})
+
+	cacheControl := WithOperationDirectives(func(directives []OperationDirective) []ExecutionOptionsV2 {
+		for _, directive := range directives {
+			if directive.Name != "cacheControl" {
+				continue
+			}
+			maxAge, err := strconv.Atoi(string(directive.Arguments["maxAge"]))
+			if err != nil {
+				return nil
+			}
+			return []ExecutionOptionsV2{WithResponseCacheTTL(time.Duration(maxAge) * time.Second)}
+		}
+		return nil
+	})
+
+	t.Run("operation directive sets the ttl", func(t *testing.T) {
+		engine, cache, _ := setup(t, `{"hello":"world"}`)
+
+		resultWriter := NewEngineResultWriter()
+		err := engine.Execute(context.Background(), &Request{Query: `query @cacheControl(maxAge: 30) { hello }`}, &resultWriter, cacheControl)
+		require.NoError(t, err)
+		assert.Equal(t, `{"data":{"hello":"world"}}`, resultWriter.String())
+		assert.Len(t, cache.responses, 1)
+		assert.Equal(t, 30*time.Second, cache.ttl)
+	})
+
+	t.Run("operation directive disables storing the response", func(t *testing.T) {
+		engine, cache, _ := setup(t, `{"hello":"world"}`)
+
+		resultWriter := NewEngineResultWriter()
+		err := engine.Execute(context.Background(), &Request{Query: `query @cacheControl(maxAge: 0) { hello }`}, &resultWriter, cacheControl)
+		require.NoError(t, err)
+		assert.Equal(t, `{"data":{"hello":"world"}}`, resultWriter.String())
+		assert.Len(t, cache.responses, 0)
+	})
+}
+
+func TestResponseCacheConfiguration_ResponseCacheKey(t *testing.T) {
+	schema := starwarsSchema(t)
+	config := ResponseCacheConfiguration{Headers: []string{"Authorization", "X-Tenant-Id"}}
+
+	key := func(t *testing.T, operationName string, variables string, header http.Header) uint64 {
+		operation := Request{Query: `{ hero { name } }`}
+		result, err := operation.Normalize(schema)
+		require.NoError(t, err)
+		require.True(t, result.Successful)
+		operation.OperationName = operationName
+		key, err := config.responseCacheKey(&operation, []byte(variables), nil, schema, header)
+		require.NoError(t, err)
+		return key
+	}
+
+	t.Run("same request shares the key", func(t *testing.T) {
+		assert.Equal(t,
+			key(t, "Hero", `{}`, http.Header{"Authorization": {"token"}}),
+			key(t, "Hero", `{}`, http.Header{"Authorization": {"token"}}),
+		)
+	})
+	t.Run("multiple values don't collide with their concatenation", func(t *testing.T) {
+		assert.NotEqual(t,
+			key(t, "Hero", `{}`, http.Header{"Authorization": {"a", "b"}}),
+			key(t, "Hero", `{}`, http.Header{"Authorization": {"ab"}}),
+		)
+	})
+	t.Run("values don't move to the next header", func(t *testing.T) {
+		assert.NotEqual(t,
+			key(t, "Hero", `{}`, http.Header{"Authorization": {"X-Tenant-Id"}}),
+			key(t, "Hero", `{}`, http.Header{"X-Tenant-Id": {"X-Tenant-Id"}}),
+		)
+	})
+	t.Run("namespace is part of the key", func(t *testing.T) {
+		operation := Request{Query: `{ hero { name } }`}
+		result, err := operation.Normalize(schema)
+		require.NoError(t, err)
+		require.True(t, result.Successful)
+
+		tenantA, err := config.responseCacheKey(&operation, []byte(`{}`), []byte("a"), schema, http.Header{})
+		require.NoError(t, err)
+		tenantB, err := config.responseCacheKey(&operation, []byte(`{}`), []byte("b"), schema, http.Header{})
+		require.NoError(t, err)
+		assert.NotEqual(t, tenantA, tenantB)
+	})
+	t.Run("operation name and variables don't run into each other", func(t *testing.T) {
+		assert.NotEqual(t,
+			key(t, "Hero", `{}`, http.Header{}),
+			key(t, "Her", `o{}`, http.Header{}),
+		)
+	})
+}
@@ -0,0 +1,42 @@
+package graphql
+
+import (
+	"github.com/buger/jsonparser"
+)
+
+// mergeVariables deep merges the override variables over the variables of the operation
+// Keys of the override win, nested objects are merged and all other values, including arrays, are replaced.
+func mergeVariables(variables, override []byte) ([]byte, error) {
+	if _, dataType, _, err := jsonparser.Get(override); err != nil || dataType != jsonparser.Object {
+		return nil, ErrInvalidOverrideVariables
+	}
+	if _, dataType, _, err := jsonparser.Get(variables); err != nil || dataType != jsonparser.Object {
+		variables = []byte("{}")
+	} else {
+		variables = append([]byte(nil), variables...)
+	}
+	return mergeJSONObjects(variables, override)
+}
+
+func mergeJSONObjects(base, override []byte) ([]byte, error) {
+	err := jsonparser.ObjectEach(override, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) (err error) {
+		switch dataType {
+		case jsonparser.String:
+			value = append(append([]byte(`"`), value...), '"')
+		case jsonparser.Object:
+			baseValue, baseDataType, _, _ := jsonparser.Get(base, string(key))
+			if baseDataType == jsonparser.Object {
+				value, err = mergeJSONObjects(append([]byte(nil), baseValue...), value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		base, err = jsonparser.Set(base, value, string(key))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return base, nil
+}
@@ -0,0 +1,35 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeVariables(t *testing.T) {
+	run := func(variables, override, expected string) func(t *testing.T) {
+		return func(t *testing.T) {
+			merged, err := mergeVariables([]byte(variables), []byte(override))
+			require.NoError(t, err)
+			assert.JSONEq(t, expected, string(merged))
+		}
+	}
+
+	t.Run("merge", run(`{"a":1}`, `{"b":"two"}`, `{"a":1,"b":"two"}`))
+	t.Run("override", run(`{"a":1,"b":"two"}`, `{"b":"three","c":null}`, `{"a":1,"b":"three","c":null}`))
+	t.Run("nested objects", run(
+		`{"filter":{"name":"luke","scope":{"tenant":"a","region":"eu"}}}`,
+		`{"filter":{"scope":{"tenant":"b"}}}`,
+		`{"filter":{"name":"luke","scope":{"tenant":"b","region":"eu"}}}`,
+	))
+	t.Run("object replaces scalar", run(`{"a":1}`, `{"a":{"b":2}}`, `{"a":{"b":2}}`))
+	t.Run("arrays are replaced", run(`{"ids":[1,2,3]}`, `{"ids":[4]}`, `{"ids":[4]}`))
+	t.Run("empty variables", run(``, `{"a":1}`, `{"a":1}`))
+	t.Run("null variables", run(`null`, `{"a":"b"}`, `{"a":"b"}`))
+
+	t.Run("invalid override", func(t *testing.T) {
+		_, err := mergeVariables([]byte(`{"a":1}`), []byte(`[1]`))
+		assert.Equal(t, ErrInvalidOverrideVariables, err)
+	})
+}
@@ -263,3 +263,8 @@ func ErrImplementingTypeDoesNotHaveFields(typeName ast.ByteSlice) (err ExternalE
 	err.Message = fmt.Sprintf("type '%s' implements an interface but does not have any fields defined", typeName)
 	return err
 }
+
+func ErrSelectionSetExceedsMaxSelections(count, max int) (err ExternalError) {
+	err.Message = fmt.Sprintf("selection set has %d selections after normalization, max allowed: %d", count, max)
+	return err
+}
@@ -1,15 +1,23 @@
 package postprocess
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/jensneuse/graphql-go-tools/internal/pkg/unsafeparser"
+	"github.com/jensneuse/graphql-go-tools/pkg/astnormalization"
+	"github.com/jensneuse/graphql-go-tools/pkg/asttransform"
+	"github.com/jensneuse/graphql-go-tools/pkg/astvalidation"
+	"github.com/jensneuse/graphql-go-tools/pkg/engine/datasource/staticdatasource"
 	"github.com/jensneuse/graphql-go-tools/pkg/engine/plan"
 	"github.com/jensneuse/graphql-go-tools/pkg/engine/resolve"
 	"github.com/jensneuse/graphql-go-tools/pkg/lexer/literal"
+	"github.com/jensneuse/graphql-go-tools/pkg/operationreport"
 )
 
 func TestProcessDefer_Process(t *testing.T) {
@@ -933,9 +941,151 @@ func TestProcessDefer_Process_ShouldSkipWithoutDefer(t *testing.T) {
 	assert.Equal(t, clone, actual)
 }
 
+func TestProcessDefer_Resolve(t *testing.T) {
+	definition := `
+		directive @defer on FIELD | INLINE_FRAGMENT
+		schema { query: Query }
+		type Query { hero: Hero }
+		type Hero { name: String friends: [String] }
+	`
+
+	config := plan.Configuration{
+		DataSources: []plan.DataSourceConfiguration{
+			{
+				RootNodes: []plan.TypeField{
+					{
+						TypeName:   "Query",
+						FieldNames: []string{"hero"},
+					},
+				},
+				ChildNodes: []plan.TypeField{
+					{
+						TypeName:   "Hero",
+						FieldNames: []string{"name"},
+					},
+				},
+				Custom: staticdatasource.ConfigJSON(staticdatasource.Configuration{
+					Data: `{"__typename":"Hero","name":"Luke"}`,
+				}),
+				Factory: &staticdatasource.Factory{},
+			},
+			{
+				RootNodes: []plan.TypeField{
+					{
+						TypeName:   "Hero",
+						FieldNames: []string{"friends"},
+					},
+				},
+				Custom: staticdatasource.ConfigJSON(staticdatasource.Configuration{
+					Data: `["Leia","Han"]`,
+				}),
+				Factory: &staticdatasource.Factory{},
+			},
+		},
+		Fields: []plan.FieldConfiguration{
+			{
+				TypeName:              "Query",
+				FieldName:             "hero",
+				DisableDefaultMapping: true,
+			},
+			{
+				TypeName:              "Hero",
+				FieldName:             "friends",
+				DisableDefaultMapping: true,
+			},
+		},
+	}
+
+	run := func(operation, variables string, expectedFlushes ...string) func(t *testing.T) {
+		return func(t *testing.T) {
+			def := unsafeparser.ParseGraphqlDocumentString(definition)
+			op := unsafeparser.ParseGraphqlDocumentString(operation)
+			require.NoError(t, asttransform.MergeDefinitionWithBaseSchema(&def))
+
+			report := &operationreport.Report{}
+			astnormalization.NewNormalizer(true, true).NormalizeOperation(&op, &def, report)
+			astvalidation.DefaultOperationValidator().Validate(&op, &def, report)
+			require.False(t, report.HasErrors(), report.Error())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			planned := plan.NewPlanner(ctx, config).Plan(&op, &def, "", report)
+			require.False(t, report.HasErrors(), report.Error())
+
+			streaming, ok := DefaultProcessor().Process(planned).(*plan.StreamingResponsePlan)
+			require.True(t, ok)
+			require.Len(t, streaming.Response.Patches, 1)
+
+			writer := &flushWriter{}
+			fetch := streaming.Response.Patches[0].Fetch.(*resolve.SingleFetch)
+			source := &flushRecordingSource{source: fetch.DataSource, writer: writer}
+			fetch.DataSource = source
+
+			resolver := resolve.New(ctx, resolve.NewFetcher(false), false)
+			resolveCtx := resolve.NewContext(context.Background())
+			resolveCtx.Variables = []byte(variables)
+			require.NoError(t, resolver.ResolveGraphQLStreamingResponse(resolveCtx, streaming.Response, nil, writer))
+
+			assert.Equal(t, expectedFlushes, writer.flushed)
+			// the fetch of the deferred field is loaded after the initial response got flushed
+			assert.Equal(t, []int{1}, source.flushedBeforeLoad)
+		}
+	}
+
+	t.Run("deferred field", run(`{ hero { name friends @defer } }`, ``,
+		`{"data":{"hero":{"name":"Luke","friends":null}},"hasNext":true}`,
+		`[{"op":"replace","path":"/data/hero/friends","value":["Leia","Han"]},{"op":"replace","path":"/hasNext","value":false}]`,
+	))
+	t.Run("deferred inline fragment", run(`{ hero { name ... @defer { friends } } }`, ``,
+		`{"data":{"hero":{"name":"Luke","friends":null}},"hasNext":true}`,
+		`[{"op":"replace","path":"/data/hero/friends","value":["Leia","Han"]},{"op":"replace","path":"/hasNext","value":false}]`,
+	))
+	t.Run("fragment nested in a deferred inline fragment", run(`
+		query Hero($withFriends: Boolean!) {
+			hero {
+				name
+				... @defer {
+					... on Hero @include(if: $withFriends) {
+						friends
+					}
+				}
+			}
+		}`, `{"withFriends":true}`,
+		`{"data":{"hero":{"name":"Luke","friends":null}},"hasNext":true}`,
+		`[{"op":"replace","path":"/data/hero/friends","value":["Leia","Han"]},{"op":"replace","path":"/hasNext","value":false}]`,
+	))
+}
+
 type fakeService struct {
 }
 
 func (f *fakeService) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
 	panic("implement me")
 }
+
+type flushWriter struct {
+	flushed []string
+	buf     bytes.Buffer
+}
+
+func (f *flushWriter) Write(p []byte) (n int, err error) {
+	return f.buf.Write(p)
+}
+
+func (f *flushWriter) Flush() {
+	f.flushed = append(f.flushed, f.buf.String())
+	f.buf.Reset()
+}
+
+// flushRecordingSource records how many payloads were flushed when it gets loaded
+type flushRecordingSource struct {
+	source            resolve.DataSource
+	writer            *flushWriter
+	flushedBeforeLoad []int
+}
+
+func (f *flushRecordingSource) Load(ctx context.Context, input []byte, w io.Writer) (err error) {
+	f.flushedBeforeLoad = append(f.flushedBeforeLoad, len(f.writer.flushed))
+	return f.source.Load(ctx, input, w)
+}
@@ -59,6 +59,7 @@ func (p *ProcessStream) traverseNode(node resolve.Node) {
 				case *resolve.Array:
 					array.Stream.Enabled = true
 					array.Stream.InitialBatchSize = n.Fields[i].Stream.InitialBatchSize
+					array.Stream.Label = n.Fields[i].Stream.Label
 					n.Fields[i].Stream = nil
 				}
 			}
@@ -70,6 +71,7 @@ func (p *ProcessStream) traverseNode(node resolve.Node) {
 			patch := &resolve.GraphQLResponsePatch{
 				Value:     n.Item,
 				Operation: literal.ADD,
+				Label:     n.Stream.Label,
 			}
 			if n.Stream.InitialBatchSize == 0 {
 				n.Item = nil